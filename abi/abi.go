@@ -33,7 +33,8 @@ const (
 	ChannelID
 	// BlockHeight is a *types.BlockHeight
 	BlockHeight
-	// Integer is a *big.Int
+	// Integer is a *big.Int. It is serialized as the big-endian bytes of its
+	// absolute value, prefixed with a zero byte if it is negative.
 	Integer
 	// Bytes is a []byte
 	Bytes
@@ -51,6 +52,8 @@ const (
 	PoStProofs
 	// Boolean is a bool
 	Boolean
+	// Uint64 is a uint64, such as a deal ID
+	Uint64
 )
 
 func (t Type) String() string {
@@ -85,6 +88,8 @@ func (t Type) String() string {
 		return "[]proofs.PoStProof"
 	case Boolean:
 		return "bool"
+	case Uint64:
+		return "uint64"
 	default:
 		return "<unknown type>"
 	}
@@ -128,6 +133,8 @@ func (av *Value) String() string {
 		return fmt.Sprint(av.Val.([]proofs.PoStProof))
 	case Boolean:
 		return fmt.Sprint(av.Val.(bool))
+	case Uint64:
+		return fmt.Sprint(av.Val.(uint64))
 	default:
 		return "<unknown type>"
 	}
@@ -185,6 +192,12 @@ func (av *Value) Serialize() ([]byte, error) {
 		if !ok {
 			return nil, &typeError{&big.Int{}, av.Val}
 		}
+		if intgr.Sign() < 0 {
+			// big.Int.Bytes() never produces a leading zero byte, so one is
+			// used to mark negative values without changing the encoding of
+			// non-negative ones.
+			return append([]byte{0}, intgr.Bytes()...), nil
+		}
 		return intgr.Bytes(), nil
 	case Bytes:
 		b, ok := av.Val.([]byte)
@@ -213,7 +226,7 @@ func (av *Value) Serialize() ([]byte, error) {
 		}
 
 		return []byte(pid), nil
-	case SectorID:
+	case SectorID, Uint64:
 		n, ok := av.Val.(uint64)
 		if !ok {
 			return nil, &typeError{0, av.Val}
@@ -350,6 +363,12 @@ func Deserialize(data []byte, t Type) (*Value, error) {
 			Val:  types.NewBlockHeightFromBytes(data),
 		}, nil
 	case Integer:
+		if len(data) > 0 && data[0] == 0 {
+			return &Value{
+				Type: t,
+				Val:  big.NewInt(0).Neg(big.NewInt(0).SetBytes(data[1:])),
+			}, nil
+		}
 		return &Value{
 			Type: t,
 			Val:  big.NewInt(0).SetBytes(data),
@@ -378,7 +397,7 @@ func Deserialize(data []byte, t Type) (*Value, error) {
 			Type: t,
 			Val:  id,
 		}, nil
-	case SectorID:
+	case SectorID, Uint64:
 		return &Value{
 			Type: t,
 			Val:  leb128.ToUInt64(data),
@@ -432,6 +451,7 @@ var typeTable = map[Type]reflect.Type{
 	CommitmentsMap: reflect.TypeOf(map[string]types.Commitments{}),
 	PoStProofs:     reflect.TypeOf([]proofs.PoStProof{}),
 	Boolean:        reflect.TypeOf(false),
+	Uint64:         reflect.TypeOf(uint64(0)),
 }
 
 // TypeMatches returns whether or not 'val' is the go type expected for the given ABI type
@@ -18,6 +18,7 @@ func TestBasicEncodingRoundTrip(t *testing.T) {
 	cases := map[string][]interface{}{
 		"empty":      nil,
 		"one-int":    {big.NewInt(579)},
+		"negative":   {big.NewInt(-579)},
 		"one addr":   {addrGetter()},
 		"two addrs":  {addrGetter(), addrGetter()},
 		"one []byte": {[]byte("foo")},
@@ -50,6 +51,52 @@ func TestBasicEncodingRoundTrip(t *testing.T) {
 	}
 }
 
+func TestIntegerSerialization(t *testing.T) {
+	cases := []struct {
+		name string
+		val  *big.Int
+		data []byte
+	}{
+		{"zero", big.NewInt(0), []byte{}},
+		{"positive", big.NewInt(579), []byte{0x02, 0x43}},
+		{"positive with a high bit", big.NewInt(255), []byte{0xff}},
+		{"negative", big.NewInt(-579), []byte{0x00, 0x02, 0x43}},
+		{"minus one", big.NewInt(-1), []byte{0x00, 0x01}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			data, err := (&Value{Type: Integer, Val: tc.val}).Serialize()
+			assert.NoError(err)
+			assert.Equal(tc.data, data)
+
+			v, err := Deserialize(tc.data, Integer)
+			assert.NoError(err)
+			assert.Equal(0, tc.val.Cmp(v.Val.(*big.Int)))
+		})
+	}
+}
+
+func TestUint64Serialization(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := (&Value{Type: Uint64, Val: uint64(1234)}).Serialize()
+	assert.NoError(err)
+
+	// go uint64 values are passed as sector ids, which are encoded the same way
+	vals, err := ToValues([]interface{}{uint64(1234)})
+	assert.NoError(err)
+	sectorData, err := vals[0].Serialize()
+	assert.NoError(err)
+	assert.Equal(sectorData, data)
+
+	v, err := Deserialize(data, Uint64)
+	assert.NoError(err)
+	assert.Equal(uint64(1234), v.Val)
+}
+
 type fooTestStruct struct {
 	Bar string
 	Baz uint64
@@ -0,0 +1,249 @@
+// Package initactor implements the init actor, which assigns sequential IDs
+// to new actors and resolves ID addresses back to the addresses of the actors
+// they were assigned to.
+package initactor
+
+import (
+	"context"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func init() {
+	cbor.RegisterCborType(State{})
+}
+
+const (
+	// ErrNotIDAddress indicates an address that was expected to be an ID address uses another protocol.
+	ErrNotIDAddress = 33
+)
+
+// Errors map error codes to revert errors this actor may return.
+var Errors = map[uint8]error{
+	ErrNotIDAddress: errors.NewCodedRevertError(ErrNotIDAddress, "address is not an ID address"),
+}
+
+// Actor is the init actor. It keeps track of the IDs it has assigned to
+// actors so that they can be addressed by their much shorter ID address.
+type Actor struct{}
+
+// State is the init actor's storage.
+type State struct {
+	// NextID is the ID that will be assigned to the next actor.
+	NextID uint64
+
+	// IDs is a lookup from ID addresses to the bytes of the actor address
+	// the ID was assigned to.
+	IDs cid.Cid `refmt:",omitempty"`
+
+	// Addresses is a lookup from actor addresses to the bytes of their ID
+	// address.
+	Addresses cid.Cid `refmt:",omitempty"`
+}
+
+// NewActor returns a new init actor.
+func NewActor() *actor.Actor {
+	return actor.NewActor(types.InitActorCodeCid, types.NewZeroAttoFIL())
+}
+
+// IsInit tests whether an actor is the init actor.
+func IsInit(act *actor.Actor) bool {
+	return types.InitActorCodeCid.Equals(act.Code)
+}
+
+// InitializeState stores the init actor's initial data structure.
+func (ia *Actor) InitializeState(storage exec.Storage, _ interface{}) error {
+	return writeState(storage, &State{})
+}
+
+var _ exec.ExecutableActor = (*Actor)(nil)
+
+var initExports = exec.Exports{
+	"getIDAddress": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: []abi.Type{abi.Address},
+	},
+	"getActorAddress": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: []abi.Type{abi.Address},
+	},
+}
+
+// Exports returns the init actor's exported functions.
+func (ia *Actor) Exports() exec.Exports {
+	return initExports
+}
+
+// GetIDAddress returns the ID address assigned to the given actor address, or
+// an empty address if the actor was never assigned an ID.
+func (ia *Actor) GetIDAddress(vmctx exec.VMContext, addr address.Address) (address.Address, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return address.Undef, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	idAddr, err := LookupID(context.Background(), vmctx.Storage(), addr)
+	if err != nil {
+		return address.Undef, errors.CodeError(err), err
+	}
+
+	return idAddr, 0, nil
+}
+
+// GetActorAddress returns the actor address the given ID address was assigned
+// to, or an empty address if the ID has not been assigned.
+func (ia *Actor) GetActorAddress(vmctx exec.VMContext, idAddr address.Address) (address.Address, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return address.Undef, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	addr, err := LookupAddress(context.Background(), vmctx.Storage(), idAddr)
+	if err != nil {
+		return address.Undef, errors.CodeError(err), err
+	}
+
+	return addr, 0, nil
+}
+
+// AssignID assigns the next ID to addr and returns the resulting ID address.
+// If addr already has an ID, that ID is returned instead. Messages cannot
+// assign IDs; the VM calls this directly with the init actor's storage
+// whenever a new actor is created.
+func AssignID(ctx context.Context, storage exec.Storage, addr address.Address) (address.Address, error) {
+	state, err := readState(storage)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	existing, err := findAddress(ctx, storage, state.Addresses, addr.String())
+	if err != nil {
+		return address.Undef, err
+	}
+	if !existing.Empty() {
+		return existing, nil
+	}
+
+	idAddr, err := address.NewIDAddress(state.NextID)
+	if err != nil {
+		return address.Undef, errors.FaultErrorWrap(err, "could not create ID address")
+	}
+
+	state.IDs, err = actor.SetKeyValue(ctx, storage, state.IDs, idAddr.String(), addr.Bytes())
+	if err != nil {
+		return address.Undef, errors.FaultErrorWrap(err, "could not store actor address")
+	}
+
+	state.Addresses, err = actor.SetKeyValue(ctx, storage, state.Addresses, addr.String(), idAddr.Bytes())
+	if err != nil {
+		return address.Undef, errors.FaultErrorWrap(err, "could not store ID address")
+	}
+
+	state.NextID++
+
+	if err := writeState(storage, state); err != nil {
+		return address.Undef, err
+	}
+
+	return idAddr, nil
+}
+
+// LookupAddress returns the actor address the given ID address was assigned
+// to, or an empty address if the ID has not been assigned.
+func LookupAddress(ctx context.Context, storage exec.Storage, idAddr address.Address) (address.Address, error) {
+	if idAddr.Empty() || idAddr.Protocol() != address.ID {
+		return address.Undef, Errors[ErrNotIDAddress]
+	}
+
+	state, err := readState(storage)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	return findAddress(ctx, storage, state.IDs, idAddr.String())
+}
+
+// LookupID returns the ID address assigned to the given actor address, or an
+// empty address if the actor was never assigned an ID. Empty and ID addresses
+// are returned as they are.
+func LookupID(ctx context.Context, storage exec.Storage, addr address.Address) (address.Address, error) {
+	if addr.Empty() || addr.Protocol() == address.ID {
+		return addr, nil
+	}
+
+	state, err := readState(storage)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	return findAddress(ctx, storage, state.Addresses, addr.String())
+}
+
+// findAddress loads the address stored under key in the given lookup. It
+// returns an empty address if there is none.
+func findAddress(ctx context.Context, storage exec.Storage, id cid.Cid, key string) (address.Address, error) {
+	if !id.Defined() {
+		return address.Undef, nil
+	}
+
+	lookup, err := actor.LoadLookup(ctx, storage, id)
+	if err != nil {
+		return address.Undef, errors.FaultErrorWrapf(err, "could not load lookup with CID: %s", id)
+	}
+
+	value, err := lookup.Find(ctx, key)
+	if err != nil {
+		if err == hamt.ErrNotFound {
+			return address.Undef, nil
+		}
+		return address.Undef, errors.FaultErrorWrapf(err, "could not find %s", key)
+	}
+
+	raw, ok := value.([]byte)
+	if !ok {
+		return address.Undef, errors.NewFaultErrorf("expected address bytes in lookup, got %T", value)
+	}
+
+	addr, err := address.NewFromBytes(raw)
+	if err != nil {
+		return address.Undef, errors.FaultErrorWrap(err, "could not decode address")
+	}
+
+	return addr, nil
+}
+
+func readState(storage exec.Storage) (*State, error) {
+	chunk, err := storage.Get(storage.Head())
+	if err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not read init actor storage")
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not unmarshal init actor storage")
+	}
+
+	return &state, nil
+}
+
+func writeState(storage exec.Storage, state *State) error {
+	stateBytes, err := cbor.DumpObject(state)
+	if err != nil {
+		return xerrors.Wrap(err, "failed to cbor marshal object")
+	}
+
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+
+	return storage.Commit(id, storage.Head())
+}
@@ -0,0 +1,142 @@
+package initactor_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func queryAddress(t *testing.T, st state.Tree, vms vm.StorageMap, method string, addr address.Address) address.Address {
+	ret, code, err := consensus.CallQueryMethod(context.Background(), st, vms, address.InitAddress, method, actor.MustConvertParams(addr), address.Undef, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint8(0), code)
+
+	out, err := address.NewFromBytes(ret[0])
+	require.NoError(t, err)
+	return out
+}
+
+func mustIDAddress(t *testing.T, id uint64) address.Address {
+	addr, err := address.NewIDAddress(id)
+	require.NoError(t, err)
+	return addr
+}
+
+func TestInitActorAssignsIDs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	t.Run("builtin actors have IDs from genesis", func(t *testing.T) {
+		assert.Equal(mustIDAddress(t, 0), queryAddress(t, st, vms, "getIDAddress", address.InitAddress))
+		assert.Equal(mustIDAddress(t, 1), queryAddress(t, st, vms, "getIDAddress", address.StorageMarketAddress))
+		assert.Equal(mustIDAddress(t, 2), queryAddress(t, st, vms, "getIDAddress", address.PaymentBrokerAddress))
+		assert.Equal(address.StorageMarketAddress, queryAddress(t, st, vms, "getActorAddress", mustIDAddress(t, 1)))
+	})
+
+	t.Run("other genesis actors have IDs from genesis", func(t *testing.T) {
+		for _, addr := range []address.Address{address.NetworkAddress, address.TestAddress, address.TestAddress2} {
+			id := queryAddress(t, st, vms, "getIDAddress", addr)
+			require.False(id.Empty())
+			assert.Equal(addr, queryAddress(t, st, vms, "getActorAddress", id))
+		}
+	})
+
+	t.Run("actors created by other actors are assigned the next ID", func(t *testing.T) {
+		pdata := actor.MustConvertParams(big.NewInt(10), []byte{}, th.RequireRandomPeerID(require))
+		msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, core.MustGetNonce(st, address.TestAddress), types.NewAttoFILFromFIL(100), "createMiner", pdata)
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+		require.NoError(err)
+		require.NoError(result.ExecutionError)
+
+		minerAddr, err := address.NewFromBytes(result.Receipt.Return[0])
+		require.NoError(err)
+
+		// the default genesis holds six actors
+		assert.Equal(mustIDAddress(t, 6), queryAddress(t, st, vms, "getIDAddress", minerAddr))
+		assert.Equal(minerAddr, queryAddress(t, st, vms, "getActorAddress", mustIDAddress(t, 6)))
+	})
+
+	t.Run("actors created by transfers to new addresses are assigned the next ID", func(t *testing.T) {
+		newAddr := address.NewForTestGetter()()
+		msg := types.NewMessage(address.TestAddress, newAddr, core.MustGetNonce(st, address.TestAddress), types.NewAttoFILFromFIL(100), "", nil)
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+		require.NoError(err)
+		require.NoError(result.ExecutionError)
+
+		assert.Equal(mustIDAddress(t, 7), queryAddress(t, st, vms, "getIDAddress", newAddr))
+	})
+
+	t.Run("unknown addresses resolve to the empty address", func(t *testing.T) {
+		assert.Equal(address.Undef, queryAddress(t, st, vms, "getIDAddress", address.NewForTestGetter()()))
+		assert.Equal(address.Undef, queryAddress(t, st, vms, "getActorAddress", mustIDAddress(t, 1000)))
+	})
+
+	t.Run("getActorAddress rejects addresses that are not ID addresses", func(t *testing.T) {
+		_, code, err := consensus.CallQueryMethod(ctx, st, vms, address.InitAddress, "getActorAddress", actor.MustConvertParams(address.TestAddress), address.Undef, nil)
+		assert.Error(err)
+		assert.Equal(uint8(ErrNotIDAddress), code)
+	})
+}
+
+func TestMessagesToIDAddresses(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	before, err := st.GetActor(ctx, address.StorageMarketAddress)
+	require.NoError(err)
+	balance := before.Balance
+
+	msg := types.NewMessage(address.TestAddress, mustIDAddress(t, 1), core.MustGetNonce(st, address.TestAddress), types.NewAttoFILFromFIL(100), "", nil)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	after, err := st.GetActor(ctx, address.StorageMarketAddress)
+	require.NoError(err)
+	assert.Equal(balance.Add(types.NewAttoFILFromFIL(100)), after.Balance)
+
+	// a message to an unassigned ID address cannot be included in a block,
+	// else its sender would get block space without paying for gas
+	nonce := core.MustGetNonce(st, address.TestAddress)
+	msg = types.NewMessage(address.TestAddress, mustIDAddress(t, 1000), nonce, types.NewAttoFILFromFIL(100), "", nil)
+	smsg := &types.SignedMessage{MeteredMessage: types.MeteredMessage{Message: *msg, GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(1000)}}
+	_, err = th.NewTestProcessor().ApplyMessage(ctx, st, vms, smsg, address.Undef, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
+	require.Error(err)
+	assert.True(errors.IsApplyErrorPermanent(err))
+	assert.Contains(err.Error(), "has not been assigned")
+	assert.Equal(nonce, core.MustGetNonce(st, address.TestAddress))
+
+	// the sender is resolved too, so sending to yourself by ID is caught
+	testID := queryAddress(t, st, vms, "getIDAddress", address.TestAddress)
+	for _, msg := range []*types.Message{
+		types.NewMessage(address.TestAddress, testID, core.MustGetNonce(st, address.TestAddress), types.NewAttoFILFromFIL(100), "", nil),
+		types.NewMessage(testID, address.TestAddress, core.MustGetNonce(st, address.TestAddress), types.NewAttoFILFromFIL(100), "", nil),
+	} {
+		smsg := &types.SignedMessage{MeteredMessage: types.MeteredMessage{Message: *msg, GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(1000)}}
+		_, err := th.NewTestProcessor().ApplyMessage(ctx, st, vms, smsg, address.Undef, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
+		require.Error(err)
+		assert.Contains(err.Error(), "cannot send to self")
+	}
+}
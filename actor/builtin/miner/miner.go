@@ -1,10 +1,13 @@
 package miner
 
 import (
+	"context"
 	"math/big"
 	"os"
+	"sort"
 	"strconv"
 
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"
 	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
@@ -23,8 +26,13 @@ import (
 func init() {
 	cbor.RegisterCborType(State{})
 	cbor.RegisterCborType(Ask{})
+	cbor.RegisterCborType(SectorInfo{})
 }
 
+// EventAskAdded is the name of the event a miner emits when it adds an ask.
+// The event data is the new Ask.
+const EventAskAdded = "askAdded"
+
 // MaximumPublicKeySize is a limit on how big a public key can be.
 const MaximumPublicKeySize = 100
 
@@ -39,6 +47,17 @@ var ProvingPeriodBlocks = types.NewBlockHeight(20000)
 // See https://github.com/filecoin-project/go-filecoin/issues/1887
 var GracePeriodBlocks = types.NewBlockHeight(100)
 
+// MinimumCollateralPerSector is the minimum amount of collateral required per sector
+var MinimumCollateralPerSector, _ = types.NewAttoFILFromFILString("0.001")
+
+// LatePoStFeePerSectorPerBlock is the amount of collateral a miner forfeits
+// for each sector of power and each block its PoSt is submitted after the end
+// of its proving period. It spreads the minimum collateral of a sector over
+// the grace period: a PoSt submitted at the very end of the grace period costs
+// the collateral that the miner's power requires, while missing the grace
+// period altogether costs all of it (see SlashStorageFault).
+var LatePoStFeePerSectorPerBlock = MinimumCollateralPerSector.DivBigInt(GracePeriodBlocks.AsBigInt())
+
 const (
 	// ErrPublicKeyTooBig indicates an invalid public key.
 	ErrPublicKeyTooBig = 33
@@ -58,6 +77,10 @@ const (
 	ErrAskNotFound = 40
 	// ErrInvalidSealProof signals that the passed in seal proof was invalid.
 	ErrInvalidSealProof = 41
+	// ErrPoStTooLate signals that the PoSt was submitted after the grace period.
+	ErrPoStTooLate = 42
+	// ErrInsufficientCollateral indicates the collateral does not cover what you are trying to do.
+	ErrInsufficientCollateral = 43
 )
 
 // Errors map error codes to revert errors this actor may return.
@@ -71,6 +94,8 @@ var Errors = map[uint8]error{
 	ErrInvalidPoSt:             errors.NewCodedRevertErrorf(ErrInvalidPoSt, "PoSt proof did not validate"),
 	ErrAskNotFound:             errors.NewCodedRevertErrorf(ErrAskNotFound, "no ask was found"),
 	ErrInvalidSealProof:        errors.NewCodedRevertErrorf(ErrInvalidSealProof, "seal proof was invalid"),
+	ErrPoStTooLate:             errors.NewCodedRevertErrorf(ErrPoStTooLate, "PoSt submitted after the grace period"),
+	ErrInsufficientCollateral:  errors.NewCodedRevertErrorf(ErrInsufficientCollateral, "collateral must be more than %s FIL per sector", MinimumCollateralPerSector),
 }
 
 // Actor is the miner actor.
@@ -96,8 +121,20 @@ type Ask struct {
 
 // State is the miner actors storage.
 type State struct {
+	// Owner is the address of the account that owns the miner. It controls
+	// the miner's funds.
 	Owner address.Address
 
+	// Worker is the address of the account that sends commitSector and
+	// submitPoSt messages on behalf of the owner. Miners created before
+	// workers were introduced have no worker; the owner acts as the worker
+	// for them.
+	Worker address.Address
+
+	// PendingOwner is the address the owner has proposed to transfer the
+	// miner to. The transfer completes when it is accepted from that address.
+	PendingOwner address.Address
+
 	// PeerID references the libp2p identity that the miner is operating.
 	PeerID peer.ID
 
@@ -115,12 +152,30 @@ type State struct {
 	Asks      []*Ask
 	NextAskID *big.Int
 
-	// SectorCommitments maps sector id to commitments, for all sectors this
-	// miner has committed. Due to a bug in refmt, the sector id-keys need to be
-	// stringified.
+	// Sectors is a lookup of the commitments of all sectors this miner has
+	// committed, keyed by stringified sector id.
+	Sectors cid.Cid `refmt:",omitempty"`
+
+	// SectorCommitments holds the sector commitments of miners created before
+	// Sectors was introduced. They are moved into Sectors by the network
+	// upgrade that runs MigrateSectorCommitments over every miner.
+	// Due to a bug in refmt, the sector id-keys need to be stringified.
 	//
 	// See also: https://github.com/polydawn/refmt/issues/35
-	SectorCommitments map[string]types.Commitments
+	SectorCommitments map[string]types.Commitments `refmt:",omitempty"`
+
+	// SectorExpirations is a lookup of the block heights at which the deals
+	// of each sector have all ended, keyed by stringified sector id. Sectors
+	// without deals never expire and have no entry. Expired sectors are
+	// removed when the next PoSt is submitted.
+	SectorExpirations cid.Cid `refmt:",omitempty"`
+
+	// ExpirationHeights lists the distinct heights in SectorExpirations in
+	// ascending order, and ExpiringSectors the ids of the sectors expiring at
+	// each of them, keyed by stringified height. They let expired sectors be
+	// found without reading the expirations of sectors that are still live.
+	ExpirationHeights []*types.BlockHeight
+	ExpiringSectors   cid.Cid `refmt:",omitempty"`
 
 	LastUsedSectorID uint64
 
@@ -130,6 +185,16 @@ type State struct {
 	Power *big.Int
 }
 
+// SectorInfo describes a sector the miner has committed.
+type SectorInfo struct {
+	SectorID    uint64
+	Commitments types.Commitments
+
+	// Expiration is the block height at which the sector expires, or nil if
+	// it never does.
+	Expiration *types.BlockHeight
+}
+
 // NewActor returns a new miner actor
 func NewActor() *actor.Actor {
 	return actor.NewActor(types.MinerActorCodeCid, types.NewZeroAttoFIL())
@@ -138,14 +203,14 @@ func NewActor() *actor.Actor {
 // NewState creates a miner state struct
 func NewState(owner address.Address, key []byte, pledge *big.Int, pid peer.ID, collateral *types.AttoFIL) *State {
 	return &State{
-		Owner:             owner,
-		PeerID:            pid,
-		PublicKey:         key,
-		PledgeSectors:     pledge,
-		Collateral:        collateral,
-		SectorCommitments: make(map[string]types.Commitments),
-		Power:             big.NewInt(0),
-		NextAskID:         big.NewInt(0),
+		Owner:         owner,
+		Worker:        owner,
+		PeerID:        pid,
+		PublicKey:     key,
+		PledgeSectors: pledge,
+		Collateral:    collateral,
+		Power:         big.NewInt(0),
+		NextAskID:     big.NewInt(0),
 	}
 }
 
@@ -190,16 +255,44 @@ var minerExports = exec.Exports{
 		Params: []abi.Type{abi.Integer},
 		Return: []abi.Type{abi.Bytes},
 	},
+	"cancelAsk": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: []abi.Type{},
+	},
+	"updateAsk": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer, abi.AttoFIL, abi.Integer},
+		Return: []abi.Type{},
+	},
 	"getOwner": &exec.FunctionSignature{
 		Params: nil,
 		Return: []abi.Type{abi.Address},
 	},
+	"proposeOwner": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: []abi.Type{},
+	},
+	"acceptOwner": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{},
+	},
+	"getPendingOwner": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Address},
+	},
+	"getWorker": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Address},
+	},
+	"changeWorker": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: []abi.Type{},
+	},
 	"getLastUsedSectorID": &exec.FunctionSignature{
 		Params: nil,
 		Return: []abi.Type{abi.SectorID},
 	},
 	"commitSector": &exec.FunctionSignature{
-		Params: []abi.Type{abi.SectorID, abi.Bytes, abi.Bytes, abi.Bytes, abi.Bytes},
+		Params: []abi.Type{abi.SectorID, abi.Bytes, abi.Bytes, abi.Bytes, abi.Bytes, abi.UintArray},
 		Return: []abi.Type{},
 	},
 	"getKey": &exec.FunctionSignature{
@@ -231,13 +324,41 @@ var minerExports = exec.Exports{
 		Return: []abi.Type{abi.BlockHeight},
 	},
 	"getSectorCommitments": &exec.FunctionSignature{
-		Params: nil,
+		Params: []abi.Type{abi.SectorID, abi.Integer},
 		Return: []abi.Type{abi.CommitmentsMap},
 	},
+	"getSectorInfo": &exec.FunctionSignature{
+		Params: []abi.Type{abi.SectorID},
+		Return: []abi.Type{abi.Bytes},
+	},
+	"listSectors": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Bytes},
+	},
 	"isBootstrapMiner": &exec.FunctionSignature{
 		Params: nil,
 		Return: []abi.Type{abi.Boolean},
 	},
+	"getCollateral": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.AttoFIL},
+	},
+	"getLastPoSt": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.BlockHeight},
+	},
+	"slashStorageFault": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{},
+	},
+	"addCollateral": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: []abi.Type{},
+	},
+	"withdrawCollateral": &exec.FunctionSignature{
+		Params: []abi.Type{abi.AttoFIL},
+		Return: []abi.Type{},
+	},
 }
 
 // Exports returns the miner actors exported functions.
@@ -261,14 +382,7 @@ func (ma *Actor) AddAsk(ctx exec.VMContext, price *types.AttoFIL, expiry *big.In
 		id := big.NewInt(0).Set(state.NextAskID)
 		state.NextAskID = state.NextAskID.Add(state.NextAskID, big.NewInt(1))
 
-		// filter out expired asks
-		asks := state.Asks
-		state.Asks = state.Asks[:0]
-		for _, a := range asks {
-			if ctx.BlockHeight().LessThan(a.Expiry) {
-				state.Asks = append(state.Asks, a)
-			}
-		}
+		pruneExpiredAsks(&state, ctx.BlockHeight())
 
 		if !expiry.IsUint64() {
 			return nil, errors.NewRevertError("expiry was invalid")
@@ -281,18 +395,100 @@ func (ma *Actor) AddAsk(ctx exec.VMContext, price *types.AttoFIL, expiry *big.In
 			ID:     id,
 		})
 
-		return id, nil
+		return state.Asks[len(state.Asks)-1], nil
 	})
 	if err != nil {
 		return nil, errors.CodeError(err), err
 	}
 
-	askID, ok := out.(*big.Int)
+	ask, ok := out.(*Ask)
 	if !ok {
-		return nil, 1, errors.NewRevertErrorf("expected an Integer return value from call, but got %T instead", out)
+		return nil, 1, errors.NewRevertErrorf("expected an Ask return value from call, but got %T instead", out)
+	}
+
+	if err := ctx.Emit(EventAskAdded, ask); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return ask.ID, 0, nil
+}
+
+// CancelAsk removes the ask with the given ID from this miners ask list.
+func (ma *Actor) CancelAsk(ctx exec.VMContext, askid *big.Int) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		pruneExpiredAsks(&state, ctx.BlockHeight())
+
+		for i, a := range state.Asks {
+			if a.ID.Cmp(askid) == 0 {
+				state.Asks = append(state.Asks[:i], state.Asks[i+1:]...)
+				return nil, nil
+			}
+		}
+
+		return nil, Errors[ErrAskNotFound]
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// UpdateAsk changes the price of the ask with the given ID and sets it to
+// expire expiry blocks from now. Expired asks cannot be updated.
+func (ma *Actor) UpdateAsk(ctx exec.VMContext, askid *big.Int, price *types.AttoFIL, expiry *big.Int) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		if !expiry.IsUint64() {
+			return nil, errors.NewRevertError("expiry was invalid")
+		}
+
+		pruneExpiredAsks(&state, ctx.BlockHeight())
+
+		for _, a := range state.Asks {
+			if a.ID.Cmp(askid) == 0 {
+				a.Price = price
+				a.Expiry = ctx.BlockHeight().Add(types.NewBlockHeight(expiry.Uint64()))
+				return nil, nil
+			}
+		}
+
+		return nil, Errors[ErrAskNotFound]
+	})
+	if err != nil {
+		return errors.CodeError(err), err
 	}
 
-	return askID, 0, nil
+	return 0, nil
+}
+
+// pruneExpiredAsks removes the asks that have expired at the given height
+// from state.
+func pruneExpiredAsks(state *State, height *types.BlockHeight) {
+	asks := state.Asks
+	state.Asks = state.Asks[:0]
+	for _, a := range asks {
+		if height.LessThan(a.Expiry) {
+			state.Asks = append(state.Asks, a)
+		}
+	}
 }
 
 // GetAsks returns all the asks for this miner. (TODO: this isnt a great function signature, it returns the asks in a
@@ -386,144 +582,51 @@ func (ma *Actor) GetOwner(ctx exec.VMContext) (address.Address, uint8, error) {
 	return a, 0, nil
 }
 
-// GetLastUsedSectorID returns the last used sector id.
-func (ma *Actor) GetLastUsedSectorID(ctx exec.VMContext) (uint64, uint8, error) {
+// ProposeOwner starts the transfer of the miner to a new owner. The transfer
+// completes once the new owner accepts it by calling AcceptOwner. Only the
+// owner may propose a new owner; a later proposal replaces an earlier one.
+func (ma *Actor) ProposeOwner(ctx exec.VMContext, newOwner address.Address) (uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
-		return 0, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
-	var state State
-	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		return state.LastUsedSectorID, nil
-	})
-	if err != nil {
-		return 0, errors.CodeError(err), err
-	}
-
-	a, ok := out.(uint64)
-	if !ok {
-		return 0, 1, errors.NewFaultErrorf("expected a uint64 sector id, but got %T instead", out)
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	return a, 0, nil
-}
-
-// IsBootstrapMiner indicates whether the receiving miner was created in the
-// genesis block, i.e. used to bootstrap the network
-func (ma *Actor) IsBootstrapMiner(ctx exec.VMContext) (bool, uint8, error) {
-	return ma.Bootstrap, 0, nil
-}
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
 
-// GetSectorCommitments returns all sector commitments posted by this miner.
-func (ma *Actor) GetSectorCommitments(ctx exec.VMContext) (map[string]types.Commitments, uint8, error) {
-	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
-	}
+		state.PendingOwner = newOwner
 
-	var state State
-	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		return state.SectorCommitments, nil
+		return nil, nil
 	})
 	if err != nil {
-		return map[string]types.Commitments{}, errors.CodeError(err), err
-	}
-
-	a, ok := out.(map[string]types.Commitments)
-	if !ok {
-		return map[string]types.Commitments{}, 1, errors.NewFaultErrorf("expected a map[string]types.Commitments, but got %T instead", out)
+		return errors.CodeError(err), err
 	}
 
-	return a, 0, nil
+	return 0, nil
 }
 
-// CommitSector adds a commitment to the specified sector. The sector must not
-// already be committed.
-func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR, commRStar, proof []byte) (uint8, error) {
+// AcceptOwner completes the transfer of the miner to the proposed owner. It
+// must be sent by the proposed owner. If the previous owner was also the
+// worker, the new owner becomes the worker as well.
+func (ma *Actor) AcceptOwner(ctx exec.VMContext) (uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
-	if len(commD) != int(proofs.CommitmentBytesLen) {
-		return 1, errors.NewRevertError("invalid sized commD")
-	}
-	if len(commR) != int(proofs.CommitmentBytesLen) {
-		return 1, errors.NewRevertError("invalid sized commR")
-	}
-	if len(commRStar) != int(proofs.CommitmentBytesLen) {
-		return 1, errors.NewRevertError("invalid sized commRStar")
-	}
-
-	// As with submitPoSt messages, bootstrap miner actors don't verify
-	// the commitSector messages that they are sent.
-	//
-	// This switching will be removed when issue #2270 is completed.
-	if !ma.Bootstrap {
-		// This unfortunate environment variable-checking needs to happen because
-		// the PoRep verification operation needs to know some things (e.g. size)
-		// about the sector for which the proof was generated in order to verify.
-		//
-		// It is undefined behavior for a miner in "Live" mode to verify a proof
-		// created by a miner in "ProofsTest" mode (and vice-versa).
-		//
-		sectorStoreType := proofs.Live
-		if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
-			sectorStoreType = proofs.Test
-		}
-
-		req := proofs.VerifySealRequest{}
-		copy(req.CommD[:], commD)
-		copy(req.CommR[:], commR)
-		copy(req.CommRStar[:], commRStar)
-		copy(req.Proof[:], proof)
-		req.ProverID = sectorbuilder.AddressToProverID(ctx.Message().To)
-		req.SectorID = sectorbuilder.SectorIDToBytes(sectorID)
-		req.StoreType = sectorStoreType
-
-		res, err := (&proofs.RustVerifier{}).VerifySeal(req)
-		if err != nil {
-			return 1, errors.RevertErrorWrap(err, "failed to verify seal proof")
-		}
-		if !res.IsValid {
-			return ErrInvalidSealProof, Errors[ErrInvalidSealProof]
-		}
-	}
-
-	// TODO: use uint64 instead of this abomination, once refmt is fixed
-	// https://github.com/polydawn/refmt/issues/35
-	sectorIDstr := strconv.FormatUint(sectorID, 10)
 
 	var state State
 	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		// verify that the caller is authorized to perform update
-		if ctx.Message().From != state.Owner {
+		if state.PendingOwner.Empty() || ctx.Message().From != state.PendingOwner {
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
-		_, ok := state.SectorCommitments[sectorIDstr]
-		if ok {
-			return nil, Errors[ErrSectorCommitted]
+		if state.worker() == state.Owner {
+			state.Worker = state.PendingOwner
 		}
+		state.Owner = state.PendingOwner
+		state.PendingOwner = address.Undef
 
-		if state.Power.Cmp(big.NewInt(0)) == 0 {
-			state.ProvingPeriodStart = ctx.BlockHeight()
-		}
-		inc := big.NewInt(1)
-		state.Power = state.Power.Add(state.Power, inc)
-		comms := types.Commitments{
-			CommD:     proofs.CommD{},
-			CommR:     proofs.CommR{},
-			CommRStar: proofs.CommRStar{},
-		}
-		copy(comms.CommD[:], commD)
-		copy(comms.CommR[:], commR)
-		copy(comms.CommRStar[:], commRStar)
-		state.LastUsedSectorID = sectorID
-		state.SectorCommitments[sectorIDstr] = comms
-		_, ret, err := ctx.Send(address.StorageMarketAddress, "updatePower", nil, []interface{}{inc})
-		if err != nil {
-			return nil, err
-		}
-		if ret != 0 {
-			return nil, Errors[ErrStoragemarketCallFailed]
-		}
 		return nil, nil
 	})
 	if err != nil {
@@ -533,62 +636,60 @@ func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR,
 	return 0, nil
 }
 
-// GetKey returns the public key for this miner.
-func (ma *Actor) GetKey(ctx exec.VMContext) ([]byte, uint8, error) {
+// GetPendingOwner returns the proposed new owner of the miner, or the empty
+// address if no transfer is pending.
+func (ma *Actor) GetPendingOwner(ctx exec.VMContext) (address.Address, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
-		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+		return address.Undef, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	var state State
-	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		return state.PublicKey, nil
-	})
+	chunk, err := ctx.ReadStorage()
 	if err != nil {
-		return nil, errors.CodeError(err), err
+		return address.Undef, errors.CodeError(err), err
 	}
 
-	validOut, ok := out.([]byte)
-	if !ok {
-		return nil, 1, errors.NewRevertError("expected a byte slice")
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return address.Undef, errors.CodeError(err), err
 	}
 
-	return validOut, 0, nil
+	return state.PendingOwner, 0, nil
 }
 
-// GetPeerID returns the libp2p peer ID that this miner can be reached at.
-func (ma *Actor) GetPeerID(ctx exec.VMContext) (peer.ID, uint8, error) {
+// GetWorker returns the address allowed to commit sectors and submit PoSts
+// for the miner.
+func (ma *Actor) GetWorker(ctx exec.VMContext) (address.Address, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
-		return peer.ID(""), exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+		return address.Undef, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	var state State
-
 	chunk, err := ctx.ReadStorage()
 	if err != nil {
-		return peer.ID(""), errors.CodeError(err), err
+		return address.Undef, errors.CodeError(err), err
 	}
 
+	var state State
 	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
-		return peer.ID(""), errors.CodeError(err), err
+		return address.Undef, errors.CodeError(err), err
 	}
 
-	return state.PeerID, 0, nil
+	return state.worker(), 0, nil
 }
 
-// UpdatePeerID is used to update the peerID this miner is operating under.
-func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
+// ChangeWorker sets the address allowed to commit sectors and submit PoSts
+// for the miner. Only the owner may change the worker.
+func (ma *Actor) ChangeWorker(ctx exec.VMContext, worker address.Address) (uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	var storage State
-	_, err := actor.WithState(ctx, &storage, func() (interface{}, error) {
-		// verify that the caller is authorized to perform update
-		if ctx.Message().From != storage.Owner {
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
-		storage.PeerID = pid
+		state.Worker = worker
 
 		return nil, nil
 	})
@@ -599,118 +700,269 @@ func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
 	return 0, nil
 }
 
-// GetPledge returns the number of pledged sectors
-func (ma *Actor) GetPledge(ctx exec.VMContext) (*big.Int, uint8, error) {
+// GetLastUsedSectorID returns the last used sector id.
+func (ma *Actor) GetLastUsedSectorID(ctx exec.VMContext) (uint64, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return 0, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	var state State
+	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.LastUsedSectorID, nil
+	})
+	if err != nil {
+		return 0, errors.CodeError(err), err
+	}
+
+	a, ok := out.(uint64)
+	if !ok {
+		return 0, 1, errors.NewFaultErrorf("expected a uint64 sector id, but got %T instead", out)
+	}
+
+	return a, 0, nil
+}
+
+// IsBootstrapMiner indicates whether the receiving miner was created in the
+// genesis block, i.e. used to bootstrap the network
+func (ma *Actor) IsBootstrapMiner(ctx exec.VMContext) (bool, uint8, error) {
+	return ma.Bootstrap, 0, nil
+}
+
+// GetSectorCommitments returns the commitments of the sectors posted by this
+// miner whose ids are in the range [start, start+limit). Only the sectors in
+// the range are read, so callers page through all commitments by repeating
+// the call with start increased by limit until it passes the last used
+// sector id.
+func (ma *Actor) GetSectorCommitments(ctx exec.VMContext, start uint64, limit *big.Int) (map[string]types.Commitments, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
+	if limit.Sign() <= 0 || !limit.IsUint64() {
+		return nil, 1, errors.NewRevertError("limit must be positive")
+	}
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
 	var state State
-	ret, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		return state.PledgeSectors, nil
-	})
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	sectors, err := state.loadSectors(context.Background(), ctx.Storage())
 	if err != nil {
 		return nil, errors.CodeError(err), err
 	}
 
-	pledgeSectors, ok := ret.(*big.Int)
-	if !ok {
-		return nil, 1, errors.NewFaultError("Failed to retrieve pledge sectors")
+	commitments := map[string]types.Commitments{}
+	for sectorID := start; sectorID-start < limit.Uint64() && sectorID <= state.LastUsedSectorID; sectorID++ {
+		key := strconv.FormatUint(sectorID, 10)
+		value, err := sectors.Find(context.Background(), key)
+		if err == hamt.ErrNotFound {
+			continue
+		} else if err != nil {
+			err = errors.FaultErrorWrapf(err, "could not look up sector %d", sectorID)
+			return nil, errors.CodeError(err), err
+		}
+
+		comms, ok := value.(types.Commitments)
+		if !ok {
+			return nil, 1, errors.NewFaultError("Expected Commitments from sectors lookup")
+		}
+		commitments[key] = comms
 	}
 
-	return pledgeSectors, 0, nil
+	return commitments, 0, nil
 }
 
-// GetPower returns the amount of proven sectors for this miner.
-func (ma *Actor) GetPower(ctx exec.VMContext) (*big.Int, uint8, error) {
+// GetSectorInfo returns the cbor encoded SectorInfo of the given committed
+// sector.
+func (ma *Actor) GetSectorInfo(ctx exec.VMContext, sectorID uint64) ([]byte, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
 	var state State
-	ret, err := actor.WithState(ctx, &state, func() (interface{}, error) {
-		return state.Power, nil
-	})
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	info, err := state.sectorInfo(context.Background(), ctx.Storage(), sectorID)
 	if err != nil {
 		return nil, errors.CodeError(err), err
 	}
 
-	power, ok := ret.(*big.Int)
-	if !ok {
-		return nil, 1, errors.NewFaultErrorf("expected *big.Int to be returned, but got %T instead", ret)
+	out, err := actor.MarshalStorage(info)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "Error marshalling sector info")
 	}
 
-	return power, 0, nil
+	return out, 0, nil
 }
 
-// SubmitPoSt is used to submit a coalesced PoST to the chain to convince the chain
-// that you have been actually storing the files you claim to be.
-func (ma *Actor) SubmitPoSt(ctx exec.VMContext, postProofs []proofs.PoStProof) (uint8, error) {
+// ListSectors returns the cbor encoded SectorInfo of every committed sector,
+// ordered by sector id.
+func (ma *Actor) ListSectors(ctx exec.VMContext) ([]byte, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	sectors, err := state.listSectors(context.Background(), ctx.Storage())
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	out, err := actor.MarshalStorage(sectors)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "Error marshalling sectors")
+	}
+
+	return out, 0, nil
+}
+
+// CommitSector adds a commitment to the specified sector. The sector must not
+// already be committed. dealIDs are the published storage market deals whose
+// pieces the sector contains; they are marked as committed to the sector.
+func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR, commRStar, proof []byte, dealIDs []uint64) (uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
+	if len(commD) != int(proofs.CommitmentBytesLen) {
+		return 1, errors.NewRevertError("invalid sized commD")
+	}
+	if len(commR) != int(proofs.CommitmentBytesLen) {
+		return 1, errors.NewRevertError("invalid sized commR")
+	}
+	if len(commRStar) != int(proofs.CommitmentBytesLen) {
+		return 1, errors.NewRevertError("invalid sized commRStar")
+	}
+
+	// As with submitPoSt messages, bootstrap miner actors don't verify
+	// the commitSector messages that they are sent.
+	//
+	// This switching will be removed when issue #2270 is completed.
+	if !ma.Bootstrap {
+		// This unfortunate environment variable-checking needs to happen because
+		// the PoRep verification operation needs to know some things (e.g. size)
+		// about the sector for which the proof was generated in order to verify.
+		//
+		// It is undefined behavior for a miner in "Live" mode to verify a proof
+		// created by a miner in "ProofsTest" mode (and vice-versa).
+		//
+		sectorStoreType := proofs.Live
+		if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
+			sectorStoreType = proofs.Test
+		}
+
+		req := proofs.VerifySealRequest{}
+		copy(req.CommD[:], commD)
+		copy(req.CommR[:], commR)
+		copy(req.CommRStar[:], commRStar)
+		copy(req.Proof[:], proof)
+		req.ProverID = sectorbuilder.AddressToProverID(ctx.Message().To)
+		req.SectorID = sectorbuilder.SectorIDToBytes(sectorID)
+		req.StoreType = sectorStoreType
+
+		res, err := ctx.VerifySeal(req)
+		if err != nil {
+			return 1, errors.RevertErrorWrap(err, "failed to verify seal proof")
+		}
+		if !res.IsValid {
+			return ErrInvalidSealProof, Errors[ErrInvalidSealProof]
+		}
+	}
+
+	// TODO: use uint64 instead of this abomination, once refmt is fixed
+	// https://github.com/polydawn/refmt/issues/35
+	sectorIDstr := strconv.FormatUint(sectorID, 10)
 
 	var state State
 	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
 		// verify that the caller is authorized to perform update
-		if ctx.Message().From != state.Owner {
+		if !state.isOwnerOrWorker(ctx.Message().From) {
 			return nil, Errors[ErrCallerUnauthorized]
 		}
 
-		// Check if we submitted it in time
-		provingPeriodEnd := state.ProvingPeriodStart.Add(ProvingPeriodBlocks)
-		if ctx.BlockHeight().GreaterThan(provingPeriodEnd) {
-			// Not great.
-			// TODO: charge penalty
-			return nil, errors.NewRevertErrorf("submitted PoSt late, need to pay a fee")
+		sectors, err := state.loadSectors(context.Background(), ctx.Storage())
+		if err != nil {
+			return nil, err
 		}
 
-		// As with commitSector messages, bootstrap miner actors don't verify
-		// the submitPoSt messages that they are sent.
-		//
-		// This switching will be removed when issue #2270 is completed.
-		if !ma.Bootstrap {
-			// See comment above, in CommitSector.
-			//
-			// It is undefined behavior for a miner in "Live" mode to verify a proof
-			// created by a miner in "ProofsTest" mode (and vice-versa).
-			//
-			sectorStoreType := proofs.Live
-			if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
-				sectorStoreType = proofs.Test
-			}
+		_, err = sectors.Find(context.Background(), sectorIDstr)
+		if err == nil {
+			return nil, Errors[ErrSectorCommitted]
+		}
+		if err != hamt.ErrNotFound {
+			return nil, errors.FaultErrorWrapf(err, "could not look up sector %d", sectorID)
+		}
 
-			seed, err := currentProvingPeriodPoStChallengeSeed(ctx, state)
-			if err != nil {
-				return nil, errors.FaultErrorWrap(err, "failed to sample chain for challenge seed")
+		if state.Power.Cmp(big.NewInt(0)) == 0 {
+			oldDeadline := provingDeadline(&state)
+			state.ProvingPeriodStart = ctx.BlockHeight()
+			if err := updateProvingDeadline(ctx, oldDeadline, provingDeadline(&state)); err != nil {
+				return nil, err
 			}
+		}
+		inc := big.NewInt(1)
+		state.Power = state.Power.Add(state.Power, inc)
+		comms := types.Commitments{
+			CommD:     proofs.CommD{},
+			CommR:     proofs.CommR{},
+			CommRStar: proofs.CommRStar{},
+		}
+		copy(comms.CommD[:], commD)
+		copy(comms.CommR[:], commR)
+		copy(comms.CommRStar[:], commRStar)
+		state.LastUsedSectorID = sectorID
+		if err := sectors.Set(context.Background(), sectorIDstr, comms); err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not set commitments of sector %d", sectorID)
+		}
+		state.Sectors, err = sectors.Commit(context.Background())
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not commit sectors")
+		}
+		_, ret, err := ctx.Send(address.StorageMarketAddress, "updatePower", nil, []interface{}{inc})
+		if err != nil {
+			return nil, err
+		}
+		if ret != 0 {
+			return nil, Errors[ErrStoragemarketCallFailed]
+		}
 
-			var commRs []proofs.CommR
-			for _, v := range state.SectorCommitments {
-				commRs = append(commRs, v.CommR)
+		if len(dealIDs) > 0 {
+			out, ret, err := ctx.Send(address.StorageMarketAddress, "commitDeals", nil, []interface{}{sectorID, dealIDs})
+			if err != nil {
+				return nil, err
 			}
-
-			req := proofs.VerifyPoSTRequest{
-				ChallengeSeed: seed,
-				CommRs:        commRs,
-				Faults:        []uint64{},
-				Proofs:        postProofs,
-				StoreType:     sectorStoreType,
+			if ret != 0 {
+				return nil, Errors[ErrStoragemarketCallFailed]
 			}
 
-			res, err := (&proofs.RustVerifier{}).VerifyPoST(req)
+			state.SectorExpirations, err = actor.SetKeyValue(context.Background(), ctx.Storage(), state.SectorExpirations, sectorIDstr, out[0])
 			if err != nil {
-				return nil, errors.RevertErrorWrap(err, "failed to verify PoSt")
+				return nil, errors.FaultErrorWrapf(err, "could not set expiration of sector %d", sectorID)
 			}
-			if !res.IsValid {
-				return nil, Errors[ErrInvalidPoSt]
+			if err := state.queueExpiration(context.Background(), ctx.Storage(), sectorID, types.NewBlockHeightFromBytes(out[0])); err != nil {
+				return nil, err
 			}
 		}
-
-		// transition to the next proving period
-		state.ProvingPeriodStart = provingPeriodEnd
-		state.LastPoSt = ctx.BlockHeight()
-
 		return nil, nil
 	})
 	if err != nil {
@@ -720,23 +972,798 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, postProofs []proofs.PoStProof) (
 	return 0, nil
 }
 
-// GetProvingPeriodStart returns the current ProvingPeriodStart value.
-func (ma *Actor) GetProvingPeriodStart(ctx exec.VMContext) (*types.BlockHeight, uint8, error) {
+// GetKey returns the public key for this miner.
+func (ma *Actor) GetKey(ctx exec.VMContext) ([]byte, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	chunk, err := ctx.ReadStorage()
+	var state State
+	out, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.PublicKey, nil
+	})
 	if err != nil {
 		return nil, errors.CodeError(err), err
 	}
 
+	validOut, ok := out.([]byte)
+	if !ok {
+		return nil, 1, errors.NewRevertError("expected a byte slice")
+	}
+
+	return validOut, 0, nil
+}
+
+// GetPeerID returns the libp2p peer ID that this miner can be reached at.
+func (ma *Actor) GetPeerID(ctx exec.VMContext) (peer.ID, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return peer.ID(""), exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
 	var state State
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return peer.ID(""), errors.CodeError(err), err
+	}
+
 	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
-		return nil, errors.CodeError(err), err
+		return peer.ID(""), errors.CodeError(err), err
 	}
 
-	return state.ProvingPeriodStart, 0, nil
+	return state.PeerID, 0, nil
+}
+
+// UpdatePeerID is used to update the peerID this miner is operating under.
+func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var storage State
+	_, err := actor.WithState(ctx, &storage, func() (interface{}, error) {
+		// verify that the caller is authorized to perform update
+		if ctx.Message().From != storage.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		storage.PeerID = pid
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetPledge returns the number of pledged sectors
+func (ma *Actor) GetPledge(ctx exec.VMContext) (*big.Int, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	ret, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.PledgeSectors, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	pledgeSectors, ok := ret.(*big.Int)
+	if !ok {
+		return nil, 1, errors.NewFaultError("Failed to retrieve pledge sectors")
+	}
+
+	return pledgeSectors, 0, nil
+}
+
+// GetPower returns the amount of proven sectors for this miner.
+func (ma *Actor) GetPower(ctx exec.VMContext) (*big.Int, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	ret, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		return state.Power, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	power, ok := ret.(*big.Int)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected *big.Int to be returned, but got %T instead", ret)
+	}
+
+	return power, 0, nil
+}
+
+// SubmitPoSt is used to submit a coalesced PoST to the chain to convince the chain
+// that you have been actually storing the files you claim to be.
+func (ma *Actor) SubmitPoSt(ctx exec.VMContext, postProofs []proofs.PoStProof) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		// verify that the caller is authorized to perform update
+		if !state.isOwnerOrWorker(ctx.Message().From) {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		// Check if we submitted it in time. Once the grace period is over the
+		// miner is considered to have missed the proving period and will be
+		// slashed; see SlashStorageFault.
+		provingPeriodEnd := state.ProvingPeriodStart.Add(ProvingPeriodBlocks)
+		if ctx.BlockHeight().GreaterThan(provingPeriodEnd.Add(GracePeriodBlocks)) {
+			return nil, Errors[ErrPoStTooLate]
+		}
+
+		// As with commitSector messages, bootstrap miner actors don't verify
+		// the submitPoSt messages that they are sent.
+		//
+		// This switching will be removed when issue #2270 is completed.
+		if !ma.Bootstrap {
+			// See comment above, in CommitSector.
+			//
+			// It is undefined behavior for a miner in "Live" mode to verify a proof
+			// created by a miner in "ProofsTest" mode (and vice-versa).
+			//
+			sectorStoreType := proofs.Live
+			if os.Getenv("FIL_USE_SMALL_SECTORS") == "true" {
+				sectorStoreType = proofs.Test
+			}
+
+			seed, err := currentProvingPeriodPoStChallengeSeed(ctx, state)
+			if err != nil {
+				return nil, errors.FaultErrorWrap(err, "failed to sample chain for challenge seed")
+			}
+
+			sectors, err := state.listSectors(context.Background(), ctx.Storage())
+			if err != nil {
+				return nil, err
+			}
+
+			var commRs []proofs.CommR
+			for _, sector := range sectors {
+				commRs = append(commRs, sector.Commitments.CommR)
+			}
+
+			req := proofs.VerifyPoSTRequest{
+				ChallengeSeed: seed,
+				CommRs:        commRs,
+				Faults:        []uint64{},
+				Proofs:        postProofs,
+				StoreType:     sectorStoreType,
+			}
+
+			res, err := ctx.VerifyPoST(req)
+			if err != nil {
+				return nil, errors.RevertErrorWrap(err, "failed to verify PoSt")
+			}
+			if !res.IsValid {
+				return nil, Errors[ErrInvalidPoSt]
+			}
+		}
+
+		// late submissions are accepted within the grace period, at a fee
+		// that is deducted from the miner's collateral.
+		if ctx.BlockHeight().GreaterThan(provingPeriodEnd) {
+			fee := LatePoStFee(ctx.BlockHeight().Sub(provingPeriodEnd), state.Power, state.Collateral)
+			if err := burnCollateral(ctx, &state, fee); err != nil {
+				return nil, err
+			}
+		}
+
+		// sectors whose deals have ended no longer need to be proven
+		if err := removeExpiredSectors(ctx, &state); err != nil {
+			return nil, err
+		}
+
+		// transition to the next proving period
+		oldDeadline := provingDeadline(&state)
+		state.ProvingPeriodStart = provingPeriodEnd
+		state.LastPoSt = ctx.BlockHeight()
+		if err := updateProvingDeadline(ctx, oldDeadline, provingDeadline(&state)); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetProvingPeriodStart returns the current ProvingPeriodStart value.
+func (ma *Actor) GetProvingPeriodStart(ctx exec.VMContext) (*types.BlockHeight, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return state.ProvingPeriodStart, 0, nil
+}
+
+// GetCollateral returns the amount of collateral the miner currently holds.
+func (ma *Actor) GetCollateral(ctx exec.VMContext) (*types.AttoFIL, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return state.Collateral, 0, nil
+}
+
+// GetLastPoSt returns the block height at which the miner last submitted a
+// PoSt, or nil if it never has.
+func (ma *Actor) GetLastPoSt(ctx exec.VMContext) (*types.BlockHeight, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return state.LastPoSt, 0, nil
+}
+
+// AddCollateral adds the value of the message to the miner's collateral and
+// increases its pledge by the given number of sectors. The resulting
+// collateral must cover the new pledge. Anyone may add collateral, but only
+// the owner may increase the pledge.
+func (ma *Actor) AddCollateral(ctx exec.VMContext, pledge *big.Int) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	if pledge.Sign() < 0 {
+		return 1, errors.NewRevertError("pledge increase must not be negative")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if pledge.Sign() > 0 && ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		collateral := state.Collateral
+		if ctx.Message().Value != nil {
+			collateral = collateral.Add(ctx.Message().Value)
+		}
+		newPledge := big.NewInt(0).Add(state.PledgeSectors, pledge)
+		if collateral.LessThan(MinimumCollateral(newPledge)) {
+			return nil, Errors[ErrInsufficientCollateral]
+		}
+
+		state.Collateral = collateral
+		state.PledgeSectors = newPledge
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// WithdrawCollateral sends the given amount of collateral back to the owner.
+// Only collateral that is not backing committed sectors may be withdrawn.
+func (ma *Actor) WithdrawCollateral(ctx exec.VMContext, amount *types.AttoFIL) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	if !amount.IsPositive() {
+		return 1, errors.NewRevertError("amount to withdraw must be positive")
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if ctx.Message().From != state.Owner {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		committedSectors, err := state.sectorCount(context.Background(), ctx.Storage())
+		if err != nil {
+			return nil, err
+		}
+		if WithdrawableCollateral(state.Collateral, committedSectors).LessThan(amount) {
+			return nil, Errors[ErrInsufficientCollateral]
+		}
+
+		_, ret, err := ctx.Send(state.Owner, "", amount, nil)
+		if err != nil {
+			return nil, err
+		}
+		if ret != 0 {
+			return nil, errors.NewRevertErrorf("failed to send collateral to owner (exit code %d)", ret)
+		}
+		state.Collateral = state.Collateral.Sub(amount)
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// MinimumCollateral returns the minimum required amount of collateral for a given pledge
+func MinimumCollateral(sectors *big.Int) *types.AttoFIL {
+	return MinimumCollateralPerSector.MulBigInt(sectors)
+}
+
+// WithdrawableCollateral returns the part of the given collateral that is not
+// backing the given number of committed sectors.
+func WithdrawableCollateral(collateral *types.AttoFIL, committedSectors *big.Int) *types.AttoFIL {
+	locked := MinimumCollateral(committedSectors)
+	if collateral.LessEqual(locked) {
+		return types.NewZeroAttoFIL()
+	}
+	return collateral.Sub(locked)
+}
+
+// SlashStorageFault penalizes a miner that has missed its proving period,
+// that is, one that has not submitted a PoSt by the end of the grace period.
+// The miner loses all of its power, sectors and collateral. It is sent by the
+// network to the miners whose proving deadline has passed and does nothing for
+// miners that are not at fault, so it is safe to call repeatedly.
+func (ma *Actor) SlashStorageFault(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	if ctx.Message().From != address.NetworkAddress {
+		return ErrCallerUnauthorized, Errors[ErrCallerUnauthorized]
+	}
+
+	var state State
+	_, err := actor.WithState(ctx, &state, func() (interface{}, error) {
+		if !IsStorageFault(&state, ctx.BlockHeight()) {
+			return nil, nil
+		}
+
+		delta := big.NewInt(0).Neg(state.Power)
+		_, ret, err := ctx.Send(address.StorageMarketAddress, "updatePower", nil, []interface{}{delta})
+		if err != nil {
+			return nil, err
+		}
+		if ret != 0 {
+			return nil, Errors[ErrStoragemarketCallFailed]
+		}
+		state.Power = big.NewInt(0)
+
+		// the faulted sectors are lost, so that committing sectors again
+		// starts the miner over with a new proving period
+		state.Sectors = cid.Undef
+		state.SectorCommitments = nil
+		state.SectorExpirations = cid.Undef
+		state.ExpirationHeights = nil
+		state.ExpiringSectors = cid.Undef
+		if err := updateProvingDeadline(ctx, provingDeadline(&state), types.NewBlockHeight(0)); err != nil {
+			return nil, err
+		}
+		state.ProvingPeriodStart = nil
+
+		return nil, burnCollateral(ctx, &state, state.Collateral)
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// IsStorageFault returns true if the miner with the given state has power but
+// has not submitted a PoSt for its current proving period by the end of the
+// grace period at block height bh.
+func IsStorageFault(state *State, bh *types.BlockHeight) bool {
+	if state.Power.Sign() == 0 || state.ProvingPeriodStart == nil {
+		return false
+	}
+	return bh.GreaterThan(provingDeadline(state))
+}
+
+// provingDeadline returns the height at which the grace period of the miner's
+// current proving period ends, or zero if the miner has no proving period.
+func provingDeadline(state *State) *types.BlockHeight {
+	if state.ProvingPeriodStart == nil {
+		return types.NewBlockHeight(0)
+	}
+	return state.ProvingPeriodStart.Add(ProvingPeriodBlocks).Add(GracePeriodBlocks)
+}
+
+// updateProvingDeadline tells the storage market that the miner's proving
+// deadline moved from oldDeadline to newDeadline, so that the network checks
+// the miner for storage faults once the new deadline has passed.
+func updateProvingDeadline(ctx exec.VMContext, oldDeadline, newDeadline *types.BlockHeight) error {
+	if oldDeadline.Equal(newDeadline) {
+		return nil
+	}
+
+	_, ret, err := ctx.Send(address.StorageMarketAddress, "updateProvingDeadline", nil, []interface{}{oldDeadline, newDeadline})
+	if err != nil {
+		return err
+	}
+	if ret != 0 {
+		return Errors[ErrStoragemarketCallFailed]
+	}
+
+	return nil
+}
+
+// LatePoStFee returns the fee charged to a miner with the given power for a
+// PoSt submitted the given number of blocks after the end of a proving period.
+// The fee never exceeds the given collateral.
+func LatePoStFee(blocksLate *types.BlockHeight, power *big.Int, collateral *types.AttoFIL) *types.AttoFIL {
+	fee := LatePoStFeePerSectorPerBlock.MulBigInt(power).MulBigInt(blocksLate.AsBigInt())
+	if fee.GreaterThan(collateral) {
+		return collateral
+	}
+	return fee
+}
+
+// burnCollateral removes amount from the miner's collateral and returns the
+// funds to the network.
+func burnCollateral(ctx exec.VMContext, state *State, amount *types.AttoFIL) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+
+	_, ret, err := ctx.Send(address.NetworkAddress, "", amount, nil)
+	if err != nil {
+		return err
+	}
+	if ret != 0 {
+		return errors.NewRevertErrorf("failed to burn collateral (exit code %d)", ret)
+	}
+	state.Collateral = state.Collateral.Sub(amount)
+
+	return nil
+}
+
+// removeExpiredSectors removes the sectors that have expired at the current
+// block height and reduces the miner's power accordingly. Only the
+// expirations up to the current height are read.
+func removeExpiredSectors(ctx exec.VMContext, state *State) error {
+	if len(state.ExpirationHeights) == 0 || ctx.BlockHeight().LessThan(state.ExpirationHeights[0]) {
+		return nil
+	}
+
+	sectors, err := state.loadSectors(context.Background(), ctx.Storage())
+	if err != nil {
+		return err
+	}
+
+	expirations, err := actor.LoadLookup(context.Background(), ctx.Storage(), state.SectorExpirations)
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not load sector expirations with CID: %s", state.SectorExpirations)
+	}
+
+	expiring, err := actor.LoadTypedLookup(context.Background(), ctx.Storage(), state.ExpiringSectors, []uint64{})
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not load expiring sectors with CID: %s", state.ExpiringSectors)
+	}
+
+	removed := big.NewInt(0)
+	for len(state.ExpirationHeights) > 0 && state.ExpirationHeights[0].LessEqual(ctx.BlockHeight()) {
+		heightKey := state.ExpirationHeights[0].String()
+		sectorIDs, err := findExpiringSectors(context.Background(), expiring, heightKey)
+		if err != nil {
+			return err
+		}
+
+		for _, sectorID := range sectorIDs {
+			key := strconv.FormatUint(sectorID, 10)
+			if err := sectors.Delete(context.Background(), key); err != nil && err != hamt.ErrNotFound {
+				return errors.FaultErrorWrapf(err, "could not remove sector %s", key)
+			}
+			if err := expirations.Delete(context.Background(), key); err != nil && err != hamt.ErrNotFound {
+				return errors.FaultErrorWrapf(err, "could not remove expiration of sector %s", key)
+			}
+			removed = removed.Add(removed, big.NewInt(1))
+		}
+
+		if err := expiring.Delete(context.Background(), heightKey); err != nil && err != hamt.ErrNotFound {
+			return errors.FaultErrorWrapf(err, "could not remove sectors expiring at %s", heightKey)
+		}
+		state.ExpirationHeights = state.ExpirationHeights[1:]
+	}
+	if len(state.ExpirationHeights) == 0 {
+		state.ExpirationHeights = nil
+	}
+
+	state.Sectors, err = sectors.Commit(context.Background())
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not commit sectors")
+	}
+	state.SectorExpirations, err = expirations.Commit(context.Background())
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not commit sector expirations")
+	}
+	state.ExpiringSectors, err = expiring.Commit(context.Background())
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not commit expiring sectors")
+	}
+
+	if removed.Sign() == 0 {
+		return nil
+	}
+
+	delta := big.NewInt(0).Neg(removed)
+	_, ret, err := ctx.Send(address.StorageMarketAddress, "updatePower", nil, []interface{}{delta})
+	if err != nil {
+		return err
+	}
+	if ret != 0 {
+		return Errors[ErrStoragemarketCallFailed]
+	}
+	state.Power = state.Power.Add(state.Power, delta)
+
+	return nil
+}
+
+// queueExpiration records that the sector expires at the given height in
+// ExpirationHeights and ExpiringSectors.
+func (state *State) queueExpiration(ctx context.Context, storage exec.Storage, sectorID uint64, expiration *types.BlockHeight) error {
+	expiring, err := actor.LoadTypedLookup(ctx, storage, state.ExpiringSectors, []uint64{})
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not load expiring sectors with CID: %s", state.ExpiringSectors)
+	}
+
+	heightKey := expiration.String()
+	sectorIDs, err := findExpiringSectors(ctx, expiring, heightKey)
+	if err != nil {
+		return err
+	}
+	if len(sectorIDs) == 0 {
+		i := sort.Search(len(state.ExpirationHeights), func(i int) bool {
+			return state.ExpirationHeights[i].GreaterEqual(expiration)
+		})
+		state.ExpirationHeights = append(state.ExpirationHeights, nil)
+		copy(state.ExpirationHeights[i+1:], state.ExpirationHeights[i:])
+		state.ExpirationHeights[i] = expiration
+	}
+
+	if err := expiring.Set(ctx, heightKey, append(sectorIDs, sectorID)); err != nil {
+		return errors.FaultErrorWrapf(err, "could not queue expiration of sector %d", sectorID)
+	}
+	state.ExpiringSectors, err = expiring.Commit(ctx)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not commit expiring sectors")
+	}
+
+	return nil
+}
+
+// findExpiringSectors returns the ids of the sectors expiring at the height
+// with the given key.
+func findExpiringSectors(ctx context.Context, expiring exec.Lookup, heightKey string) ([]uint64, error) {
+	value, err := expiring.Find(ctx, heightKey)
+	if err == hamt.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not look up sectors expiring at %s", heightKey)
+	}
+
+	sectorIDs, ok := value.([]uint64)
+	if !ok {
+		return nil, errors.NewFaultErrorf("expected sector ids in expiring sectors, got %T", value)
+	}
+	return sectorIDs, nil
+}
+
+// MigrateSectorCommitments moves the commitments in the legacy
+// State.SectorCommitments map into the Sectors lookup. It does nothing for
+// miners that have already been migrated.
+func MigrateSectorCommitments(ctx context.Context, storage exec.Storage, state *State) error {
+	if len(state.SectorCommitments) == 0 {
+		state.SectorCommitments = nil
+		return nil
+	}
+
+	sectors, err := actor.LoadLookup(ctx, storage, state.Sectors)
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not load sectors with CID: %s", state.Sectors)
+	}
+
+	for sectorID, comms := range state.SectorCommitments {
+		if err := sectors.Set(ctx, sectorID, comms); err != nil {
+			return errors.FaultErrorWrapf(err, "could not set commitments of sector %s", sectorID)
+		}
+	}
+
+	state.Sectors, err = sectors.Commit(ctx)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not commit sectors")
+	}
+	state.SectorCommitments = nil
+
+	return nil
+}
+
+// loadSectors returns the lookup of the miner's sector commitments.
+func (state *State) loadSectors(ctx context.Context, storage exec.Storage) (exec.Lookup, error) {
+	if len(state.SectorCommitments) > 0 {
+		return nil, errors.NewFaultError("miner sector commitments have not been migrated (bad invariant)")
+	}
+
+	sectors, err := actor.LoadTypedLookup(ctx, storage, state.Sectors, types.Commitments{})
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load sectors with CID: %s", state.Sectors)
+	}
+
+	return sectors, nil
+}
+
+// sectorInfo returns the SectorInfo of a committed sector.
+func (state *State) sectorInfo(ctx context.Context, storage exec.Storage, sectorID uint64) (*SectorInfo, error) {
+	sectors, err := state.loadSectors(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	key := strconv.FormatUint(sectorID, 10)
+	value, err := sectors.Find(ctx, key)
+	if err != nil {
+		if err == hamt.ErrNotFound {
+			return nil, Errors[ErrInvalidSector]
+		}
+		return nil, errors.FaultErrorWrapf(err, "could not look up sector %d", sectorID)
+	}
+
+	comms, ok := value.(types.Commitments)
+	if !ok {
+		return nil, errors.NewFaultError("Expected Commitments from sectors lookup")
+	}
+
+	expirations, err := actor.LoadLookup(ctx, storage, state.SectorExpirations)
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load sector expirations with CID: %s", state.SectorExpirations)
+	}
+
+	expiration, err := findExpiration(ctx, expirations, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SectorInfo{
+		SectorID:    sectorID,
+		Commitments: comms,
+		Expiration:  expiration,
+	}, nil
+}
+
+// sectorCount returns the number of committed sectors.
+func (state *State) sectorCount(ctx context.Context, storage exec.Storage) (*big.Int, error) {
+	sectors, err := state.loadSectors(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := sectors.Values(ctx)
+	if err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not read sectors")
+	}
+
+	return big.NewInt(int64(len(kvs))), nil
+}
+
+// listSectors returns the SectorInfo of every committed sector, ordered by
+// sector id.
+func (state *State) listSectors(ctx context.Context, storage exec.Storage) ([]*SectorInfo, error) {
+	sectors, err := state.loadSectors(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := sectors.Values(ctx)
+	if err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not read sectors")
+	}
+
+	expirations, err := actor.LoadLookup(ctx, storage, state.SectorExpirations)
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load sector expirations with CID: %s", state.SectorExpirations)
+	}
+
+	infos := []*SectorInfo{}
+	for _, kv := range kvs {
+		sectorID, err := strconv.ParseUint(kv.Key, 10, 64)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "invalid sector id %s", kv.Key)
+		}
+
+		comms, ok := kv.Value.(types.Commitments)
+		if !ok {
+			return nil, errors.NewFaultError("Expected Commitments from sectors lookup")
+		}
+
+		expiration, err := findExpiration(ctx, expirations, kv.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, &SectorInfo{
+			SectorID:    sectorID,
+			Commitments: comms,
+			Expiration:  expiration,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].SectorID < infos[j].SectorID })
+
+	return infos, nil
+}
+
+// findExpiration returns the expiration of the sector with the given key in
+// the sector expirations lookup, or nil if the sector never expires.
+func findExpiration(ctx context.Context, expirations exec.Lookup, key string) (*types.BlockHeight, error) {
+	value, err := expirations.Find(ctx, key)
+	if err == hamt.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not look up expiration of sector %s", key)
+	}
+
+	return decodeExpiration(value)
+}
+
+// decodeExpiration decodes a value of the sector expirations lookup.
+func decodeExpiration(value interface{}) (*types.BlockHeight, error) {
+	raw, ok := value.([]byte)
+	if !ok {
+		return nil, errors.NewFaultErrorf("expected block height bytes in sector expirations, got %T", value)
+	}
+	return types.NewBlockHeightFromBytes(raw), nil
+}
+
+// worker returns the worker of the miner, falling back to the owner for
+// miners that have none.
+func (state *State) worker() address.Address {
+	if state.Worker.Empty() {
+		return state.Owner
+	}
+	return state.Worker
+}
+
+// isOwnerOrWorker returns true if addr may act as the worker of the miner.
+func (state *State) isOwnerOrWorker(addr address.Address) bool {
+	return addr == state.Owner || addr == state.worker()
 }
 
 func currentProvingPeriodPoStChallengeSeed(ctx exec.VMContext, state State) (proofs.PoStChallengeSeed, error) {
@@ -6,10 +6,13 @@ import (
 	"testing"
 
 	peer "gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 
+	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	. "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/core"
@@ -99,6 +102,15 @@ func TestAskFunctions(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(big.NewInt(1), big.NewInt(0).SetBytes(result.Receipt.Return[0]))
 
+	// adding the ask emits an event describing it
+	require.Len(result.Receipt.Events, 1)
+	assert.Equal(minerAddr, result.Receipt.Events[0].Actor)
+	assert.Equal(EventAskAdded, result.Receipt.Events[0].Name)
+	var addedAsk Ask
+	require.NoError(actor.UnmarshalStorage(result.Receipt.Events[0].Data, &addedAsk))
+	assert.Equal(uint64(1), addedAsk.ID.Uint64())
+	assert.True(types.NewAttoFILFromFIL(110).Equal(addedAsk.Price))
+
 	pdata = actor.MustConvertParams(big.NewInt(1))
 	msg = types.NewMessage(address.TestAddress, minerAddr, 4, types.NewZeroAttoFIL(), "getAsk", pdata)
 	result, err = th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(4))
@@ -120,6 +132,70 @@ func TestAskFunctions(t *testing.T) {
 	assert.Len(askids, 2)
 }
 
+func TestAskLifecycle(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("abcd123"), th.RequireRandomPeerID(require))
+
+	applyAt := func(height uint64, from address.Address, method string, params ...interface{}) *consensus.ApplicationResult {
+		msg := types.NewMessage(from, minerAddr, 0, nil, method, actor.MustConvertParams(params...))
+		res, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(height))
+		require.NoError(err)
+		return res
+	}
+
+	getAsks := func(height uint64) []uint64 {
+		res := applyAt(height, address.TestAddress, "getAsks")
+		require.NoError(res.ExecutionError)
+		var askids []uint64
+		require.NoError(actor.UnmarshalStorage(res.Receipt.Return[0], &askids))
+		return askids
+	}
+
+	// ask 0 expires at 11, ask 1 at 101
+	require.NoError(applyAt(1, address.TestAddress, "addAsk", types.NewAttoFILFromFIL(5), big.NewInt(10)).ExecutionError)
+	require.NoError(applyAt(1, address.TestAddress, "addAsk", types.NewAttoFILFromFIL(6), big.NewInt(100)).ExecutionError)
+	require.Equal([]uint64{0, 1}, getAsks(2))
+
+	t.Run("only the owner may cancel or update asks", func(t *testing.T) {
+		res := applyAt(2, address.TestAddress2, "cancelAsk", big.NewInt(0))
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+
+		res = applyAt(2, address.TestAddress2, "updateAsk", big.NewInt(0), types.NewAttoFILFromFIL(1), big.NewInt(10))
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
+
+	t.Run("updateAsk changes price and expiry", func(t *testing.T) {
+		require.NoError(applyAt(5, address.TestAddress, "updateAsk", big.NewInt(1), types.NewAttoFILFromFIL(7), big.NewInt(200)).ExecutionError)
+
+		res := applyAt(5, address.TestAddress, "getAsk", big.NewInt(1))
+		require.NoError(res.ExecutionError)
+		var ask Ask
+		require.NoError(actor.UnmarshalStorage(res.Receipt.Return[0], &ask))
+		require.True(types.NewAttoFILFromFIL(7).Equal(ask.Price))
+		require.Equal(types.NewBlockHeight(205), ask.Expiry)
+	})
+
+	t.Run("expired asks are pruned and cannot be updated", func(t *testing.T) {
+		res := applyAt(20, address.TestAddress, "updateAsk", big.NewInt(0), types.NewAttoFILFromFIL(1), big.NewInt(10))
+		require.Equal(Errors[ErrAskNotFound], res.ExecutionError)
+
+		require.NoError(applyAt(20, address.TestAddress, "addAsk", types.NewAttoFILFromFIL(8), big.NewInt(10)).ExecutionError)
+		require.Equal([]uint64{1, 2}, getAsks(20))
+	})
+
+	t.Run("cancelAsk removes the ask", func(t *testing.T) {
+		require.NoError(applyAt(21, address.TestAddress, "cancelAsk", big.NewInt(1)).ExecutionError)
+		require.Equal([]uint64{2}, getAsks(21))
+
+		res := applyAt(21, address.TestAddress, "cancelAsk", big.NewInt(1))
+		require.Equal(Errors[ErrAskNotFound], res.ExecutionError)
+	})
+}
+
 func TestGetKey(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -141,6 +217,7 @@ func TestCBOREncodeState(t *testing.T) {
 	require := require.New(t)
 	state := NewState(address.TestAddress, []byte{}, big.NewInt(1), th.RequireRandomPeerID(require), types.NewZeroAttoFIL())
 
+	state.SectorCommitments = map[string]types.Commitments{}
 	state.SectorCommitments["1"] = types.Commitments{
 		CommD:     proofs.CommD{},
 		CommR:     proofs.CommR{},
@@ -288,7 +365,7 @@ func TestMinerCommitSector(t *testing.T) {
 	commRStar := th.MakeCommitment()
 	commD := th.MakeCommitment()
 
-	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", nil, uint64(1), commD, commR, commRStar, th.MakeRandomBytes(int(proofs.SealBytesLen)))
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", nil, uint64(1), commD, commR, commRStar, th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
 	require.NoError(err)
 	require.NoError(res.ExecutionError)
 	require.Equal(uint8(0), res.Receipt.ExitCode)
@@ -301,12 +378,91 @@ func TestMinerCommitSector(t *testing.T) {
 	require.Equal(types.NewBlockHeight(3), types.NewBlockHeightFromBytes(res.Receipt.Return[0]))
 
 	// fail because commR already exists
-	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSector", nil, uint64(1), commD, commR, commRStar, th.MakeRandomBytes(int(proofs.SealBytesLen)))
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSector", nil, uint64(1), commD, commR, commRStar, th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
 	require.NoError(err)
 	require.EqualError(res.ExecutionError, "sector already committed")
 	require.Equal(uint8(0x23), res.Receipt.ExitCode)
 }
 
+func TestMinerGetSectorCommitments(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID(require))
+
+	for i := uint64(1); i <= 5; i++ {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", nil, i, th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+	}
+
+	getPage := func(start uint64, limit int64) map[string]types.Commitments {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "getSectorCommitments", nil, start, big.NewInt(limit))
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+
+		val, err := abi.Deserialize(res.Receipt.Return[0], abi.CommitmentsMap)
+		require.NoError(err)
+		return val.Val.(map[string]types.Commitments)
+	}
+
+	// sector ids start at 1
+	page := getPage(0, 2)
+	require.Len(page, 1)
+	require.Contains(page, "1")
+
+	page = getPage(2, 2)
+	require.Len(page, 2)
+	require.Contains(page, "2")
+	require.Contains(page, "3")
+
+	page = getPage(4, 2)
+	require.Len(page, 2)
+	require.Contains(page, "4")
+	require.Contains(page, "5")
+
+	// ranges past the last used sector id are empty
+	page = getPage(6, 2)
+	require.Len(page, 0)
+
+	// a limit must be given
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "getSectorCommitments", nil, uint64(0), big.NewInt(0))
+	require.NoError(err)
+	require.Error(res.ExecutionError)
+}
+
+func TestMigrateSectorCommitments(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	_, vms := core.CreateStorages(ctx, t)
+
+	comms := types.Commitments{
+		CommD:     proofs.CommD{1},
+		CommR:     proofs.CommR{2},
+		CommRStar: proofs.CommRStar{3},
+	}
+
+	state := NewState(address.TestAddress, []byte{}, big.NewInt(1), th.RequireRandomPeerID(require), types.NewZeroAttoFIL())
+	state.SectorCommitments = map[string]types.Commitments{"1": comms}
+
+	storage := vms.NewStorage(address.TestAddress, &actor.Actor{})
+	require.NoError(MigrateSectorCommitments(ctx, storage, state))
+	require.Nil(state.SectorCommitments)
+	require.True(state.Sectors.Defined())
+
+	sectors, err := actor.LoadTypedLookup(ctx, storage, state.Sectors, types.Commitments{})
+	require.NoError(err)
+	value, err := sectors.Find(ctx, "1")
+	require.NoError(err)
+	require.Equal(comms, value)
+
+	// migrating again leaves the sectors alone
+	migrated := state.Sectors
+	require.NoError(MigrateSectorCommitments(ctx, storage, state))
+	require.Equal(migrated, state.Sectors)
+}
+
 func TestMinerSubmitPoSt(t *testing.T) {
 	require := require.New(t)
 	ctx := context.Background()
@@ -318,13 +474,13 @@ func TestMinerSubmitPoSt(t *testing.T) {
 	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), origPid)
 
 	// add a sector
-	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", ancestors, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)))
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", ancestors, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
 	require.NoError(err)
 	require.NoError(res.ExecutionError)
 	require.Equal(uint8(0), res.Receipt.ExitCode)
 
 	// add another sector
-	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSector", ancestors, uint64(2), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)))
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "commitSector", ancestors, uint64(2), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
 	require.NoError(err)
 	require.NoError(res.ExecutionError)
 	require.Equal(uint8(0), res.Receipt.ExitCode)
@@ -342,9 +498,378 @@ func TestMinerSubmitPoSt(t *testing.T) {
 	require.NoError(res.ExecutionError)
 	require.Equal(types.NewBlockHeightFromBytes(res.Receipt.Return[0]), types.NewBlockHeight(20003))
 
-	// fail to submit inside the proving period
+	// submit late, within the grace period
 	proof = th.MakeRandomPoSTProofForTest()
 	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 40008, "submitPoSt", ancestors, []proofs.PoStProof{proof})
 	require.NoError(err)
-	require.EqualError(res.ExecutionError, "submitted PoSt late, need to pay a fee")
+	require.NoError(res.ExecutionError)
+	require.Equal(uint8(0), res.Receipt.ExitCode)
+
+	// a fee for the 5 late blocks and 2 sectors was deducted from the collateral
+	fee := LatePoStFeePerSectorPerBlock.MulBigInt(big.NewInt(10))
+	collateral := callQueryMethodSuccess("getCollateral", ctx, t, st, vms, address.TestAddress, minerAddr)
+	require.True(types.NewAttoFILFromFIL(100).Sub(fee).Equal(types.NewAttoFILFromBytes(collateral[0])))
+
+	lastPoSt := callQueryMethodSuccess("getLastPoSt", ctx, t, st, vms, address.TestAddress, minerAddr)
+	require.Equal(types.NewBlockHeight(40008), types.NewBlockHeightFromBytes(lastPoSt[0]))
+
+	// the proving period still advances by a full period
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 40009, "getProvingPeriodStart", ancestors)
+	require.NoError(err)
+	require.Equal(types.NewBlockHeight(40003), types.NewBlockHeightFromBytes(res.Receipt.Return[0]))
+
+	// fail to submit after the grace period
+	proof = th.MakeRandomPoSTProofForTest()
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 60104, "submitPoSt", ancestors, []proofs.PoStProof{proof})
+	require.NoError(err)
+	require.Equal(Errors[ErrPoStTooLate], res.ExecutionError)
+}
+
+func TestMinerSectorExpiration(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	ancestors := th.RequireTipSetChain(t, 10)
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID(require))
+
+	// publish a 10 block deal with the miner
+	signer, _ := types.NewMockSignersAndKeyInfo(1)
+	proposal, err := storagemarket.NewSignedDealProposal(storagemarket.DealProposal{
+		Client:     signer.Addresses[0],
+		Miner:      minerAddr,
+		PieceRef:   types.SomeCid(),
+		Size:       types.NewBytesAmount(1024),
+		Duration:   10,
+		TotalPrice: types.NewAttoFILFromFIL(1),
+	}, signer)
+	require.NoError(err)
+	deals, err := cbor.DumpObject([]*storagemarket.SignedDealProposal{proposal})
+	require.NoError(err)
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, address.StorageMarketAddress, 0, 1, "publishStorageDeals", ancestors, deals)
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+
+	// sector 1 holds the deal, sector 2 holds none
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", ancestors, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{0})
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", ancestors, uint64(2), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+
+	getSectorInfo := func(sectorID uint64) *SectorInfo {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "getSectorInfo", ancestors, sectorID)
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+		var info SectorInfo
+		require.NoError(actor.UnmarshalStorage(res.Receipt.Return[0], &info))
+		return &info
+	}
+
+	listSectors := func() []*SectorInfo {
+		ret := callQueryMethodSuccess("listSectors", ctx, t, st, vms, address.TestAddress, minerAddr)
+		var sectors []*SectorInfo
+		require.NoError(actor.UnmarshalStorage(ret[0], &sectors))
+		return sectors
+	}
+
+	getState := func() *State {
+		act, err := st.GetActor(ctx, minerAddr)
+		require.NoError(err)
+		var minerState State
+		builtin.RequireReadState(t, vms, minerAddr, act, &minerState)
+		return &minerState
+	}
+
+	require.Equal(types.NewBlockHeight(13), getSectorInfo(1).Expiration)
+	require.Nil(getSectorInfo(2).Expiration)
+	require.Equal([]*types.BlockHeight{types.NewBlockHeight(13)}, getState().ExpirationHeights)
+
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "getSectorInfo", ancestors, uint64(3))
+	require.NoError(err)
+	require.Equal(Errors[ErrInvalidSector], res.ExecutionError)
+
+	sectors := listSectors()
+	require.Len(sectors, 2)
+	require.Equal(uint64(1), sectors[0].SectorID)
+	require.Equal(uint64(2), sectors[1].SectorID)
+
+	totalStorage := func() *big.Int {
+		ret := callQueryMethodSuccess("getTotalStorage", ctx, t, st, vms, address.TestAddress, address.StorageMarketAddress)
+		return big.NewInt(0).SetBytes(ret[0])
+	}
+	totalBefore := totalStorage()
+
+	// the expired sector is removed when the next PoSt is submitted
+	res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 20, "submitPoSt", ancestors, []proofs.PoStProof{th.MakeRandomPoSTProofForTest()})
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+
+	sectors = listSectors()
+	require.Len(sectors, 1)
+	require.Equal(uint64(2), sectors[0].SectorID)
+	require.Empty(getState().ExpirationHeights)
+
+	power := callQueryMethodSuccess("getPower", ctx, t, st, vms, address.TestAddress, minerAddr)
+	require.Equal(big.NewInt(1), big.NewInt(0).SetBytes(power[0]))
+
+	require.Equal(big.NewInt(0).Sub(totalBefore, big.NewInt(1)), totalStorage())
+}
+
+func TestMinerSlashStorageFault(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	ancestors := th.RequireTipSetChain(t, 10)
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID(require))
+
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", ancestors, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+
+	totalStorage := func() *big.Int {
+		ret, _, err := consensus.CallQueryMethod(ctx, st, vms, address.StorageMarketAddress, "getTotalStorage", []byte{}, address.TestAddress, nil)
+		require.NoError(err)
+		return big.NewInt(0).SetBytes(ret[0])
+	}
+	require.Equal(big.NewInt(1), totalStorage())
+
+	t.Run("only the network may slash a miner", func(t *testing.T) {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "slashStorageFault", ancestors)
+		require.NoError(err)
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
+
+	t.Run("miners within the grace period keep their power", func(t *testing.T) {
+		_, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 20103, "getPower", ancestors)
+		require.NoError(err)
+
+		power := callQueryMethodSuccess("getPower", ctx, t, st, vms, address.TestAddress, minerAddr)
+		require.Equal(big.NewInt(1), big.NewInt(0).SetBytes(power[0]))
+	})
+
+	t.Run("block processing slashes miners that missed a proving period", func(t *testing.T) {
+		// processing any message after the grace period is enough
+		_, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 20104, "getPower", ancestors)
+		require.NoError(err)
+
+		power := callQueryMethodSuccess("getPower", ctx, t, st, vms, address.TestAddress, minerAddr)
+		require.Equal(0, big.NewInt(0).SetBytes(power[0]).Sign())
+		require.Equal(0, totalStorage().Sign())
+
+		collateral := callQueryMethodSuccess("getCollateral", ctx, t, st, vms, address.TestAddress, minerAddr)
+		require.True(types.NewAttoFILFromBytes(collateral[0]).IsZero())
+
+		ret := callQueryMethodSuccess("listSectors", ctx, t, st, vms, address.TestAddress, minerAddr)
+		var sectors []*SectorInfo
+		require.NoError(actor.UnmarshalStorage(ret[0], &sectors))
+		require.Empty(sectors)
+	})
+
+	t.Run("slashed miners start over when they commit again", func(t *testing.T) {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 20105, "commitSector", ancestors, uint64(2), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+
+		power := callQueryMethodSuccess("getPower", ctx, t, st, vms, address.TestAddress, minerAddr)
+		require.Equal(big.NewInt(1), big.NewInt(0).SetBytes(power[0]))
+
+		start := callQueryMethodSuccess("getProvingPeriodStart", ctx, t, st, vms, address.TestAddress, minerAddr)
+		require.Equal(types.NewBlockHeight(20105), types.NewBlockHeightFromBytes(start[0]))
+	})
+}
+
+func TestMinerCollateral(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	ancestors := th.RequireTipSetChain(t, 10)
+	minerAddr := createTestMinerWith(100, 100, assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID(require))
+
+	getCollateral := func() *types.AttoFIL {
+		ret := callQueryMethodSuccess("getCollateral", ctx, t, st, vms, address.TestAddress, minerAddr)
+		return types.NewAttoFILFromBytes(ret[0])
+	}
+	getPledge := func() *big.Int {
+		ret := callQueryMethodSuccess("getPledge", ctx, t, st, vms, address.TestAddress, minerAddr)
+		return big.NewInt(0).SetBytes(ret[0])
+	}
+
+	t.Run("add collateral must cover the new pledge", func(t *testing.T) {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 1, "addCollateral", ancestors, big.NewInt(1000000))
+		require.NoError(err)
+		require.Equal(Errors[ErrInsufficientCollateral], res.ExecutionError)
+		require.Equal(big.NewInt(100), getPledge())
+	})
+
+	t.Run("only the owner may raise the pledge", func(t *testing.T) {
+		msg := types.NewMessage(address.TestAddress2, minerAddr, 0, types.NewAttoFILFromFIL(1000), "addCollateral", actor.MustConvertParams(big.NewInt(1)))
+		res, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+		require.Equal(big.NewInt(100), getPledge())
+	})
+
+	t.Run("anyone may add collateral without raising the pledge", func(t *testing.T) {
+		msg := types.NewMessage(address.TestAddress2, minerAddr, 0, types.NewAttoFILFromFIL(10), "addCollateral", actor.MustConvertParams(big.NewInt(0)))
+		res, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+
+		require.True(types.NewAttoFILFromFIL(110).Equal(getCollateral()))
+		require.Equal(big.NewInt(100), getPledge())
+	})
+
+	t.Run("add collateral raises collateral and pledge", func(t *testing.T) {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 1000, 1, "addCollateral", ancestors, big.NewInt(1000000))
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+
+		require.True(types.NewAttoFILFromFIL(1110).Equal(getCollateral()))
+		require.Equal(big.NewInt(1000100), getPledge())
+	})
+
+	res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 3, "commitSector", ancestors, uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+	require.NoError(err)
+	require.NoError(res.ExecutionError)
+
+	t.Run("collateral backing committed sectors cannot be withdrawn", func(t *testing.T) {
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "withdrawCollateral", ancestors, types.NewAttoFILFromFIL(1110))
+		require.NoError(err)
+		require.Equal(Errors[ErrInsufficientCollateral], res.ExecutionError)
+	})
+
+	t.Run("only the owner may withdraw collateral", func(t *testing.T) {
+		msg := types.NewMessage(address.TestAddress2, minerAddr, 0, nil, "withdrawCollateral", actor.MustConvertParams(types.NewAttoFILFromFIL(1)))
+		res, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(4))
+		require.NoError(err)
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
+
+	t.Run("withdraw sends unused collateral to the owner", func(t *testing.T) {
+		ownerBalance := th.RequireBalance(t, st, address.TestAddress)
+
+		res, err := th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, 4, "withdrawCollateral", ancestors, types.NewAttoFILFromFIL(1109))
+		require.NoError(err)
+		require.NoError(res.ExecutionError)
+
+		expected := types.NewAttoFILFromFIL(1110).Sub(types.NewAttoFILFromFIL(1109))
+		require.True(expected.Equal(getCollateral()))
+		require.True(ownerBalance.Add(types.NewAttoFILFromFIL(1109)).Equal(th.RequireBalance(t, st, address.TestAddress)))
+	})
+}
+
+func TestMinerWorker(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID(require))
+
+	getWorker := func() address.Address {
+		ret := callQueryMethodSuccess("getWorker", ctx, t, st, vms, address.TestAddress, minerAddr)
+		worker, err := address.NewFromBytes(ret[0])
+		require.NoError(err)
+		return worker
+	}
+
+	applyFrom := func(from address.Address, method string, params ...interface{}) *consensus.ApplicationResult {
+		msg := types.NewMessage(from, minerAddr, 0, nil, method, actor.MustConvertParams(params...))
+		res, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		return res
+	}
+
+	// the owner is the initial worker
+	require.Equal(address.TestAddress, getWorker())
+
+	t.Run("only the owner may change the worker", func(t *testing.T) {
+		res := applyFrom(address.TestAddress2, "changeWorker", address.TestAddress2)
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+		require.Equal(address.TestAddress, getWorker())
+	})
+
+	t.Run("the worker may commit sectors", func(t *testing.T) {
+		res := applyFrom(address.TestAddress, "changeWorker", address.TestAddress2)
+		require.NoError(res.ExecutionError)
+		require.Equal(address.TestAddress2, getWorker())
+
+		res = applyFrom(address.TestAddress2, "commitSector", uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+		require.NoError(res.ExecutionError)
+
+		// the owner still may, too
+		res = applyFrom(address.TestAddress, "commitSector", uint64(2), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+		require.NoError(res.ExecutionError)
+	})
+
+	t.Run("others may not commit sectors", func(t *testing.T) {
+		res := applyFrom(address.NetworkAddress, "commitSector", uint64(3), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
+
+	t.Run("the worker may not withdraw collateral", func(t *testing.T) {
+		res := applyFrom(address.TestAddress2, "withdrawCollateral", types.NewAttoFILFromFIL(1))
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
+}
+
+func TestMinerOwnershipTransfer(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	minerAddr := createTestMiner(assert.New(t), st, vms, address.TestAddress, []byte("my public key"), th.RequireRandomPeerID(require))
+
+	queryAddress := func(method string) address.Address {
+		ret := callQueryMethodSuccess(method, ctx, t, st, vms, address.TestAddress, minerAddr)
+		addr, err := address.NewFromBytes(ret[0])
+		require.NoError(err)
+		return addr
+	}
+
+	applyFrom := func(from address.Address, method string, params ...interface{}) *consensus.ApplicationResult {
+		msg := types.NewMessage(from, minerAddr, 0, nil, method, actor.MustConvertParams(params...))
+		res, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(1))
+		require.NoError(err)
+		return res
+	}
+
+	t.Run("only the owner may propose a new owner", func(t *testing.T) {
+		res := applyFrom(address.TestAddress2, "proposeOwner", address.TestAddress2)
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+		require.Equal(address.Undef, queryAddress("getPendingOwner"))
+	})
+
+	t.Run("nothing can be accepted without a proposal", func(t *testing.T) {
+		res := applyFrom(address.TestAddress2, "acceptOwner")
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
+
+	t.Run("only the proposed owner may accept", func(t *testing.T) {
+		res := applyFrom(address.TestAddress, "proposeOwner", address.TestAddress2)
+		require.NoError(res.ExecutionError)
+		require.Equal(address.TestAddress2, queryAddress("getPendingOwner"))
+
+		res = applyFrom(address.NetworkAddress, "acceptOwner")
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+
+		// proposing does not change the owner
+		require.Equal(address.TestAddress, queryAddress("getOwner"))
+	})
+
+	t.Run("accepting transfers ownership and the worker role", func(t *testing.T) {
+		res := applyFrom(address.TestAddress2, "acceptOwner")
+		require.NoError(res.ExecutionError)
+
+		require.Equal(address.TestAddress2, queryAddress("getOwner"))
+		require.Equal(address.TestAddress2, queryAddress("getWorker"))
+		require.Equal(address.Undef, queryAddress("getPendingOwner"))
+
+		// the previous owner has lost control of the miner
+		res = applyFrom(address.TestAddress, "updatePeerID", th.RequireRandomPeerID(require))
+		require.Equal(Errors[ErrCallerUnauthorized], res.ExecutionError)
+	})
 }
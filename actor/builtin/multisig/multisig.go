@@ -0,0 +1,566 @@
+// Package multisig implements a wallet actor whose funds can only be spent
+// with the approval of a threshold of its signers.
+package multisig
+
+import (
+	"context"
+	"math/big"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func init() {
+	cbor.RegisterCborType(State{})
+	cbor.RegisterCborType(Transaction{})
+}
+
+const (
+	// ErrNotSigner indicates the caller is not one of the multisig's signers.
+	ErrNotSigner = 33
+	// ErrUnknownTransaction indicates no pending transaction was found with the given ID.
+	ErrUnknownTransaction = 34
+	// ErrAlreadyApproved indicates the signer has already approved the transaction.
+	ErrAlreadyApproved = 35
+	// ErrInvalidThreshold indicates the threshold is zero or larger than the number of signers.
+	ErrInvalidThreshold = 36
+	// ErrCallerUnauthorized signals an unauthorized caller.
+	ErrCallerUnauthorized = 37
+	// ErrDuplicateSigner indicates the address is already a signer.
+	ErrDuplicateSigner = 38
+	// ErrTransactionFailed indicates the approved transaction could not be executed.
+	ErrTransactionFailed = 39
+)
+
+// Errors map error codes to revert errors this actor may return.
+var Errors = map[uint8]error{
+	ErrNotSigner:          errors.NewCodedRevertError(ErrNotSigner, "caller is not a signer"),
+	ErrUnknownTransaction: errors.NewCodedRevertError(ErrUnknownTransaction, "transaction is unknown"),
+	ErrAlreadyApproved:    errors.NewCodedRevertError(ErrAlreadyApproved, "transaction already approved by signer"),
+	ErrInvalidThreshold:   errors.NewCodedRevertError(ErrInvalidThreshold, "threshold must be between 1 and the number of signers"),
+	ErrCallerUnauthorized: errors.NewCodedRevertError(ErrCallerUnauthorized, "not authorized to call the method"),
+	ErrDuplicateSigner:    errors.NewCodedRevertError(ErrDuplicateSigner, "address is already a signer"),
+	ErrTransactionFailed:  errors.NewCodedRevertError(ErrTransactionFailed, "approved transaction failed to execute"),
+}
+
+// Actor is the multisig wallet actor. Any signer may propose a message to be
+// sent from the wallet, and the message is sent once the number of signers
+// that approved it reaches the wallet's threshold.
+type Actor struct{}
+
+// State is the multisig actor's storage.
+type State struct {
+	// Signers are the addresses allowed to propose and approve transactions.
+	Signers []address.Address
+
+	// Required is the number of approvals needed before a transaction executes.
+	Required uint64
+
+	// NextTxID is the ID that will be assigned to the next proposed transaction.
+	NextTxID uint64
+
+	// Transactions is a lookup of pending transactions keyed by ID.
+	Transactions cid.Cid `refmt:",omitempty"`
+}
+
+// Transaction is a message proposed by one of the signers that is waiting
+// for approval.
+type Transaction struct {
+	ID       uint64
+	Proposer address.Address
+	To       address.Address
+	Value    *types.AttoFIL
+	Method   string
+	Params   []byte
+	Approved []address.Address
+}
+
+// NewActor returns a new multisig actor with the given balance.
+func NewActor(balance *types.AttoFIL) *actor.Actor {
+	return actor.NewActor(types.MultisigActorCodeCid, balance)
+}
+
+// NewState creates a multisig state struct. Signers must be actor addresses,
+// not ID addresses.
+func NewState(signers []address.Address, required uint64) *State {
+	return &State{
+		Signers:  signers,
+		Required: required,
+	}
+}
+
+// IsMultisig tests whether an actor is a multisig actor.
+func IsMultisig(act *actor.Actor) bool {
+	return types.MultisigActorCodeCid.Equals(act.Code)
+}
+
+// InitializeState stores this multisig's initial data structure.
+func (ma *Actor) InitializeState(storage exec.Storage, initializerData interface{}) error {
+	msigState, ok := initializerData.(*State)
+	if !ok {
+		return errors.NewFaultError("Initial state to multisig actor is not a multisig.State struct")
+	}
+
+	for i, s := range msigState.Signers {
+		// signers are compared to the From address of messages, which is
+		// always an actor address
+		if s.Protocol() == address.ID {
+			return errors.NewRevertErrorf("signer %s must be resolved to an actor address", s)
+		}
+		for _, other := range msigState.Signers[:i] {
+			if s == other {
+				return Errors[ErrDuplicateSigner]
+			}
+		}
+	}
+
+	if msigState.Required == 0 || msigState.Required > uint64(len(msigState.Signers)) {
+		return Errors[ErrInvalidThreshold]
+	}
+
+	stateBytes, err := cbor.DumpObject(msigState)
+	if err != nil {
+		return xerrors.Wrap(err, "failed to cbor marshal object")
+	}
+
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+
+	return storage.Commit(id, cid.Undef)
+}
+
+var _ exec.ExecutableActor = (*Actor)(nil)
+
+var multisigExports = exec.Exports{
+	"propose": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address, abi.AttoFIL, abi.String, abi.Bytes},
+		Return: []abi.Type{abi.Integer},
+	},
+	"approve": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: nil,
+	},
+	"cancel": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Integer},
+		Return: nil,
+	},
+	"getSigners": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Bytes},
+	},
+	"getThreshold": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Integer},
+	},
+	"getPending": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.Bytes},
+	},
+}
+
+// selfMethods are the methods a multisig can only call on itself, through an
+// approved transaction. They are not exported because the VM does not support
+// an actor sending a message to itself.
+var selfMethods = map[string]*exec.FunctionSignature{
+	"changeThreshold": {
+		Params: []abi.Type{abi.Integer},
+		Return: nil,
+	},
+	"addSigner": {
+		Params: []abi.Type{abi.Address},
+		Return: nil,
+	},
+}
+
+// Exports returns the multisig actor's exported functions.
+func (ma *Actor) Exports() exec.Exports {
+	return multisigExports
+}
+
+// Propose creates a pending transaction that sends value and calls method on
+// the given address once enough signers approve it. The proposer's approval
+// is recorded immediately, so a 1-of-n wallet executes the transaction right
+// away. Params must be abi encoded values matching the target method.
+func (ma *Actor) Propose(vmctx exec.VMContext, to address.Address, value *types.AttoFIL, method string, params []byte) (*big.Int, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	out, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		proposer := vmctx.Message().From
+		if !state.isSigner(proposer) {
+			return nil, Errors[ErrNotSigner]
+		}
+
+		tx := &Transaction{
+			ID:       state.NextTxID,
+			Proposer: proposer,
+			To:       to,
+			Value:    value,
+			Method:   method,
+			Params:   params,
+			Approved: []address.Address{proposer},
+		}
+		state.NextTxID++
+
+		if err := ma.applyTransaction(vmctx, &state, tx); err != nil {
+			return nil, err
+		}
+
+		return big.NewInt(0).SetUint64(tx.ID), nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	txID, ok := out.(*big.Int)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected an Integer return value from call, but got %T instead", out)
+	}
+
+	return txID, 0, nil
+}
+
+// Approve records the caller's approval of a pending transaction and
+// executes it if the threshold has been reached.
+func (ma *Actor) Approve(vmctx exec.VMContext, txID *big.Int) (uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		signer := vmctx.Message().From
+		if !state.isSigner(signer) {
+			return nil, Errors[ErrNotSigner]
+		}
+
+		tx, err := findTransaction(vmctx.Storage(), state.Transactions, txID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range tx.Approved {
+			if a == signer {
+				return nil, Errors[ErrAlreadyApproved]
+			}
+		}
+		tx.Approved = append(tx.Approved, signer)
+
+		return nil, ma.applyTransaction(vmctx, &state, tx)
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// Cancel removes a pending transaction. Only the signer who proposed the
+// transaction may cancel it.
+func (ma *Actor) Cancel(vmctx exec.VMContext, txID *big.Int) (uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		tx, err := findTransaction(vmctx.Storage(), state.Transactions, txID)
+		if err != nil {
+			return nil, err
+		}
+
+		if tx.Proposer != vmctx.Message().From {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		return nil, state.deleteTransaction(vmctx.Storage(), tx.ID)
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
+// GetSigners returns the cbor encoded list of signers.
+func (ma *Actor) GetSigners(vmctx exec.VMContext) ([]byte, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	signers, err := actor.MarshalStorage(state.Signers)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "Error marshalling signers")
+	}
+
+	return signers, 0, nil
+}
+
+// GetThreshold returns the number of approvals required to execute a transaction.
+func (ma *Actor) GetThreshold(vmctx exec.VMContext) (*big.Int, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return big.NewInt(0).SetUint64(state.Required), 0, nil
+}
+
+// GetPending returns all pending transactions as a cbor encoded map from
+// stringified transaction ID to Transaction.
+func (ma *Actor) GetPending(vmctx exec.VMContext) ([]byte, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	ctx := context.Background()
+	pending := map[string]*Transaction{}
+
+	lookup, err := actor.LoadTypedLookup(ctx, vmctx.Storage(), state.Transactions, &Transaction{})
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrapf(err, "could not load transactions with CID: %s", state.Transactions)
+	}
+
+	kvs, err := lookup.Values(ctx)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "could not read transactions")
+	}
+
+	for _, kv := range kvs {
+		tx, ok := kv.Value.(*Transaction)
+		if !ok {
+			return nil, 1, errors.NewFaultError("Expected Transaction from transactions lookup")
+		}
+		pending[kv.Key] = tx
+	}
+
+	out, err := actor.MarshalStorage(pending)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "Error marshalling pending transactions")
+	}
+
+	return out, 0, nil
+}
+
+// applyTransaction stores the transaction if it still needs approvals, or
+// removes it and executes it once the threshold is met.
+func (ma *Actor) applyTransaction(vmctx exec.VMContext, state *State, tx *Transaction) error {
+	if uint64(len(tx.Approved)) < state.Required {
+		return state.setTransaction(vmctx.Storage(), tx)
+	}
+
+	if err := state.deleteTransaction(vmctx.Storage(), tx.ID); err != nil {
+		return err
+	}
+
+	// The VM does not support an actor sending a message to itself, so
+	// transactions that change the multisig's own configuration are applied
+	// directly to the state being written. The proposal may address the
+	// multisig by its ID.
+	to, err := resolveAddress(vmctx, tx.To)
+	if err != nil {
+		return err
+	}
+	if to == vmctx.Message().To {
+		return state.applySelfTransaction(vmctx, tx)
+	}
+
+	// Params were encoded by the proposer for the target method. Passing each
+	// encoded value back through Send as raw bytes reproduces the original
+	// encoding without having to know the target's signature.
+	var params []interface{}
+	if len(tx.Params) > 0 {
+		var raw [][]byte
+		if err := cbor.DecodeInto(tx.Params, &raw); err != nil {
+			return errors.RevertErrorWrap(err, "invalid transaction params")
+		}
+		for _, p := range raw {
+			params = append(params, p)
+		}
+	}
+
+	_, code, err := vmctx.Send(to, tx.Method, tx.Value, params)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return Errors[ErrTransactionFailed]
+	}
+
+	return nil
+}
+
+// resolveAddress asks the init actor for the actor address the ID address
+// addr was assigned to. Addresses using any other protocol are returned as
+// they are.
+func resolveAddress(vmctx exec.VMContext, addr address.Address) (address.Address, error) {
+	if addr.Protocol() != address.ID {
+		return addr, nil
+	}
+
+	ret, code, err := vmctx.Send(address.InitAddress, "getActorAddress", nil, []interface{}{addr})
+	if err != nil {
+		return address.Undef, err
+	}
+	if code != 0 || len(ret) == 0 || len(ret[0]) == 0 {
+		return address.Undef, errors.NewRevertErrorf("ID address %s has not been assigned", addr)
+	}
+
+	return address.NewFromBytes(ret[0])
+}
+
+// applySelfTransaction applies an approved transaction calling one of the
+// selfMethods to the multisig's state. New signers are stored by their actor
+// address, so that they match the From address of the messages they send.
+func (state *State) applySelfTransaction(vmctx exec.VMContext, tx *Transaction) error {
+	if !tx.Value.IsZero() {
+		return errors.NewRevertError("multisig cannot send value to itself")
+	}
+
+	sig, ok := selfMethods[tx.Method]
+	if !ok {
+		return errors.NewRevertErrorf("multisig cannot call %s on itself", tx.Method)
+	}
+
+	vals, err := abi.DecodeValues(tx.Params, sig.Params)
+	if err != nil {
+		return errors.RevertErrorWrap(err, "invalid transaction params")
+	}
+	if len(vals) != len(sig.Params) {
+		return errors.NewRevertErrorf("expected %d parameters, but got %d", len(sig.Params), len(vals))
+	}
+
+	switch tx.Method {
+	case "changeThreshold":
+		return state.changeThreshold(vals[0].Val.(*big.Int))
+	default:
+		signer, err := resolveAddress(vmctx, vals[0].Val.(address.Address))
+		if err != nil {
+			return err
+		}
+		return state.addSigner(signer)
+	}
+}
+
+func (state *State) changeThreshold(required *big.Int) error {
+	if !required.IsUint64() || required.Uint64() == 0 || required.Uint64() > uint64(len(state.Signers)) {
+		return Errors[ErrInvalidThreshold]
+	}
+	state.Required = required.Uint64()
+	return nil
+}
+
+func (state *State) addSigner(signer address.Address) error {
+	if state.isSigner(signer) {
+		return Errors[ErrDuplicateSigner]
+	}
+	state.Signers = append(state.Signers, signer)
+	return nil
+}
+
+func (state *State) isSigner(addr address.Address) bool {
+	for _, s := range state.Signers {
+		if s == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (state *State) setTransaction(storage exec.Storage, tx *Transaction) error {
+	txs, err := actor.SetKeyValue(context.Background(), storage, state.Transactions, txKey(tx.ID), tx)
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not set transaction with CID: %s", state.Transactions)
+	}
+	state.Transactions = txs
+	return nil
+}
+
+func (state *State) deleteTransaction(storage exec.Storage, id uint64) error {
+	ctx := context.Background()
+	txs, err := actor.WithLookup(ctx, storage, state.Transactions, func(lookup exec.Lookup) error {
+		err := lookup.Delete(ctx, txKey(id))
+		if err == hamt.ErrNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not delete transaction with CID: %s", state.Transactions)
+	}
+	state.Transactions = txs
+	return nil
+}
+
+func findTransaction(storage exec.Storage, txs cid.Cid, txID *big.Int) (*Transaction, error) {
+	if !txID.IsUint64() {
+		return nil, Errors[ErrUnknownTransaction]
+	}
+
+	ctx := context.Background()
+	lookup, err := actor.LoadTypedLookup(ctx, storage, txs, &Transaction{})
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load transactions with CID: %s", txs)
+	}
+
+	value, err := lookup.Find(ctx, txKey(txID.Uint64()))
+	if err != nil {
+		if err == hamt.ErrNotFound {
+			return nil, Errors[ErrUnknownTransaction]
+		}
+		return nil, errors.FaultErrorWrapf(err, "could not find transaction %s", txID)
+	}
+
+	tx, ok := value.(*Transaction)
+	if !ok {
+		return nil, errors.NewFaultError("Expected Transaction from transactions lookup")
+	}
+
+	return tx, nil
+}
+
+func txKey(id uint64) string {
+	return big.NewInt(0).SetUint64(id).String()
+}
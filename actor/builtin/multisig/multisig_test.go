@@ -0,0 +1,293 @@
+package multisig_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+	vmerrors "github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func createTestMultisig(t *testing.T, st state.Tree, vms vm.StorageMap, addr address.Address, signers []address.Address, required uint64, balance *types.AttoFIL) address.Address {
+	th.RequireInstallActor(t, st, vms, addr, NewActor(balance), &Actor{}, NewState(signers, required))
+	return addr
+}
+
+func TestMultisigInitializeState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+
+	t.Run("rejects a threshold larger than the number of signers", func(t *testing.T) {
+		act := NewActor(types.NewZeroAttoFIL())
+		err := (&Actor{}).InitializeState(vms.NewStorage(addrGetter(), act), NewState(signers, 3))
+		assert.Equal(t, Errors[ErrInvalidThreshold], err)
+	})
+
+	t.Run("rejects a zero threshold", func(t *testing.T) {
+		act := NewActor(types.NewZeroAttoFIL())
+		err := (&Actor{}).InitializeState(vms.NewStorage(addrGetter(), act), NewState(signers, 0))
+		assert.Equal(t, Errors[ErrInvalidThreshold], err)
+	})
+
+	t.Run("rejects duplicate signers", func(t *testing.T) {
+		act := NewActor(types.NewZeroAttoFIL())
+		dupes := []address.Address{address.TestAddress, address.TestAddress}
+		err := (&Actor{}).InitializeState(vms.NewStorage(addrGetter(), act), NewState(dupes, 1))
+		assert.Equal(t, Errors[ErrDuplicateSigner], err)
+	})
+
+	t.Run("rejects ID address signers", func(t *testing.T) {
+		id, err := address.NewIDAddress(100)
+		require.NoError(t, err)
+
+		act := NewActor(types.NewZeroAttoFIL())
+		err = (&Actor{}).InitializeState(vms.NewStorage(addrGetter(), act), NewState([]address.Address{address.TestAddress, id}, 1))
+		assert.Error(t, err)
+	})
+}
+
+func TestMultisigProposeAndApprove(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+	msigAddr := createTestMultisig(t, st, vms, addrGetter(), signers, 2, types.NewAttoFILFromFIL(100))
+	recipient := addrGetter()
+
+	// proposing does not transfer funds until the threshold is met
+	result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", recipient, types.NewAttoFILFromFIL(30), "", []byte{})
+	require.NoError(result.ExecutionError)
+	assert.Equal(uint8(0), result.Receipt.ExitCode)
+	txID := big.NewInt(0).SetBytes(result.Receipt.Return[0])
+	assert.Equal(uint64(0), txID.Uint64())
+	assert.Equal(types.NewAttoFILFromFIL(100), th.RequireBalance(t, st, msigAddr))
+
+	// the proposer has already approved
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "approve", txID)
+	assert.Equal(Errors[ErrAlreadyApproved], result.ExecutionError)
+
+	// non signers may not approve
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.NetworkAddress, msigAddr, 0, "approve", txID)
+	assert.Equal(Errors[ErrNotSigner], result.ExecutionError)
+
+	// the second approval executes the transaction
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, msigAddr, 0, "approve", txID)
+	require.NoError(result.ExecutionError)
+	assert.Equal(uint8(0), result.Receipt.ExitCode)
+	assert.Equal(types.NewAttoFILFromFIL(70), th.RequireBalance(t, st, msigAddr))
+	assert.Equal(types.NewAttoFILFromFIL(30), th.RequireBalance(t, st, recipient))
+
+	// executed transactions are no longer pending
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, msigAddr, 0, "approve", txID)
+	assert.Equal(Errors[ErrUnknownTransaction], result.ExecutionError)
+
+	var pending map[string]*Transaction
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "getPending")
+	require.NoError(result.ExecutionError)
+	require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &pending))
+	assert.Empty(pending)
+}
+
+func TestMultisigExecutesImmediatelyWithThresholdOfOne(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+	msigAddr := createTestMultisig(t, st, vms, addrGetter(), signers, 1, types.NewAttoFILFromFIL(100))
+	recipient := addrGetter()
+
+	result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, msigAddr, 0, "propose", recipient, types.NewAttoFILFromFIL(10), "", []byte{})
+	require.NoError(result.ExecutionError)
+	assert.Equal(types.NewAttoFILFromFIL(90), th.RequireBalance(t, st, msigAddr))
+	assert.Equal(types.NewAttoFILFromFIL(10), th.RequireBalance(t, st, recipient))
+}
+
+func TestMultisigCancel(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+	msigAddr := createTestMultisig(t, st, vms, addrGetter(), signers, 2, types.NewAttoFILFromFIL(100))
+
+	result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", addrGetter(), types.NewAttoFILFromFIL(30), "", []byte{})
+	require.NoError(result.ExecutionError)
+	txID := big.NewInt(0).SetBytes(result.Receipt.Return[0])
+
+	// only the proposer may cancel
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, msigAddr, 0, "cancel", txID)
+	assert.Equal(Errors[ErrCallerUnauthorized], result.ExecutionError)
+
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "cancel", txID)
+	require.NoError(result.ExecutionError)
+
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, msigAddr, 0, "approve", txID)
+	assert.Equal(Errors[ErrUnknownTransaction], result.ExecutionError)
+	assert.Equal(types.NewAttoFILFromFIL(100), th.RequireBalance(t, st, msigAddr))
+}
+
+func TestMultisigChangeThresholdAndAddSigner(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+	msigAddr := createTestMultisig(t, st, vms, addrGetter(), signers, 1, types.NewAttoFILFromFIL(100))
+
+	t.Run("cannot be called directly by a signer", func(t *testing.T) {
+		result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "changeThreshold", big.NewInt(2))
+		assert.Equal(vmerrors.Errors[vmerrors.ErrMissingExport], result.ExecutionError)
+
+		result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "addSigner", address.NetworkAddress)
+		assert.Equal(vmerrors.Errors[vmerrors.ErrMissingExport], result.ExecutionError)
+	})
+
+	t.Run("can be applied through an approved proposal", func(t *testing.T) {
+		params, err := abi.ToEncodedValues(address.NetworkAddress)
+		require.NoError(err)
+		result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", msigAddr, types.NewZeroAttoFIL(), "addSigner", params)
+		require.NoError(result.ExecutionError)
+
+		params, err = abi.ToEncodedValues(big.NewInt(3))
+		require.NoError(err)
+		result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", msigAddr, types.NewZeroAttoFIL(), "changeThreshold", params)
+		require.NoError(result.ExecutionError)
+
+		act, err := st.GetActor(ctx, msigAddr)
+		require.NoError(err)
+
+		var msigState State
+		builtin.RequireReadState(t, vms, msigAddr, act, &msigState)
+		assert.Equal([]address.Address{address.TestAddress, address.TestAddress2, address.NetworkAddress}, msigState.Signers)
+		assert.Equal(uint64(3), msigState.Required)
+	})
+
+	t.Run("rejects a threshold larger than the number of signers", func(t *testing.T) {
+		params, err := abi.ToEncodedValues(big.NewInt(4))
+		require.NoError(err)
+
+		// approvals are now required from all three signers
+		result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", msigAddr, types.NewZeroAttoFIL(), "changeThreshold", params)
+		require.NoError(result.ExecutionError)
+		txID := big.NewInt(0).SetBytes(result.Receipt.Return[0])
+
+		result = th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, msigAddr, 0, "approve", txID)
+		require.NoError(result.ExecutionError)
+
+		result = th.RequireApplyTestMessageFrom(t, st, vms, address.NetworkAddress, msigAddr, 0, "approve", txID)
+		assert.Equal(Errors[ErrInvalidThreshold], result.ExecutionError)
+	})
+}
+
+func TestMultisigRecognizesProposalsToItsOwnIDAddress(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+	msigAddr := createTestMultisig(t, st, vms, addrGetter(), signers, 1, types.NewAttoFILFromFIL(100))
+
+	initAct, err := st.GetActor(ctx, address.InitAddress)
+	require.NoError(err)
+	msigID, err := initactor.AssignID(ctx, vms.NewStorage(address.InitAddress, initAct), msigAddr)
+	require.NoError(err)
+	require.NoError(st.SetActor(ctx, address.InitAddress, initAct))
+
+	params, err := abi.ToEncodedValues(address.NetworkAddress)
+	require.NoError(err)
+	result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", msigID, types.NewZeroAttoFIL(), "addSigner", params)
+	require.NoError(result.ExecutionError)
+
+	act, err := st.GetActor(ctx, msigAddr)
+	require.NoError(err)
+
+	var msigState State
+	builtin.RequireReadState(t, vms, msigAddr, act, &msigState)
+	assert.Equal([]address.Address{address.TestAddress, address.TestAddress2, address.NetworkAddress}, msigState.Signers)
+}
+
+func TestMultisigResolvesAddedSigners(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	signers := []address.Address{address.TestAddress, address.TestAddress2}
+	addrGetter := address.NewForTestGetter()
+	msigAddr := createTestMultisig(t, st, vms, addrGetter(), signers, 1, types.NewAttoFILFromFIL(100))
+
+	initAct, err := st.GetActor(ctx, address.InitAddress)
+	require.NoError(err)
+	signerID, err := initactor.AssignID(ctx, vms.NewStorage(address.InitAddress, initAct), address.NetworkAddress)
+	require.NoError(err)
+	require.NoError(st.SetActor(ctx, address.InitAddress, initAct))
+
+	params, err := abi.ToEncodedValues(signerID)
+	require.NoError(err)
+	result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", msigAddr, types.NewZeroAttoFIL(), "addSigner", params)
+	require.NoError(result.ExecutionError)
+
+	act, err := st.GetActor(ctx, msigAddr)
+	require.NoError(err)
+
+	var msigState State
+	builtin.RequireReadState(t, vms, msigAddr, act, &msigState)
+	assert.Equal([]address.Address{address.TestAddress, address.TestAddress2, address.NetworkAddress}, msigState.Signers)
+
+	// the new signer is recognized by the address its messages come from
+	result = th.RequireApplyTestMessageFrom(t, st, vms, address.NetworkAddress, msigAddr, 0, "propose", address.TestAddress, types.NewAttoFILFromFIL(10), "", []byte{})
+	require.NoError(result.ExecutionError)
+	assert.Equal(types.NewAttoFILFromFIL(90), th.RequireBalance(t, st, msigAddr))
+
+	t.Run("rejects an ID address that has not been assigned", func(t *testing.T) {
+		unassigned, err := address.NewIDAddress(1000)
+		require.NoError(err)
+
+		params, err := abi.ToEncodedValues(unassigned)
+		require.NoError(err)
+		result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress, msigAddr, 0, "propose", msigAddr, types.NewZeroAttoFIL(), "addSigner", params)
+		assert.Error(result.ExecutionError)
+	})
+}
@@ -10,6 +10,42 @@ import (
 
 func init() {
 	cbor.RegisterCborType(PaymentVoucher{})
+	cbor.RegisterCborType(Condition{})
+}
+
+// Condition is a predicate a voucher may carry. A voucher with a condition can
+// only be redeemed if calling Method on the actor at To with Params returns
+// true. Only a few read-only methods may be called, such as the storage
+// market's isPieceCommitted. Params travel with the voucher in their cbor form,
+// so values such as addresses reach the method as bytes, which is how the abi
+// encodes them.
+type Condition struct {
+	To     address.Address `json:"to"`
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+}
+
+// EncodeCondition returns the cbor encoding of a condition, as it is passed to
+// redeem and close. A nil condition is encoded as nil.
+func EncodeCondition(condition *Condition) ([]byte, error) {
+	if condition == nil {
+		return nil, nil
+	}
+	return cbor.DumpObject(condition)
+}
+
+// decodeCondition is the inverse of EncodeCondition.
+func decodeCondition(data []byte) (*Condition, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var condition Condition
+	if err := cbor.DecodeInto(data, &condition); err != nil {
+		return nil, err
+	}
+
+	return &condition, nil
 }
 
 // PaymentVoucher is a voucher for a payment channel that can be transferred off-chain but guarantees a future payment.
@@ -19,6 +55,9 @@ type PaymentVoucher struct {
 	Target    address.Address   `json:"target"`
 	Amount    types.AttoFIL     `json:"amount"`
 	ValidAt   types.BlockHeight `json:"valid_at"`
+	Lane      types.Uint64      `json:"lane"`
+	Nonce     types.Uint64      `json:"nonce"`
+	Condition *Condition        `json:"condition"`
 	Signature types.Signature   `json:"signature"`
 }
 
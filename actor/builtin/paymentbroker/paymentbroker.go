@@ -2,9 +2,11 @@ package paymentbroker
 
 import (
 	"context"
+	"strconv"
 
 	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmSKyB5faguXT4NqbrXpnRXqaVj5DhSm7x9BtzFydBY1UK/go-leb128"
 	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 
 	"github.com/filecoin-project/go-filecoin/abi"
@@ -38,8 +40,38 @@ const (
 	ErrInvalidSignature = 42
 	//ErrTooEarly indicates that the block height is too low to satisfy a voucher
 	ErrTooEarly = 43
+	// ErrStaleNonce indicates a voucher's nonce is not greater than the last nonce redeemed on its lane.
+	ErrStaleNonce = 44
+	// ErrConditionInvalid indicates a voucher's condition could not be evaluated.
+	ErrConditionInvalid = 45
+	// ErrConditionNotMet indicates a voucher's condition did not hold.
+	ErrConditionNotMet = 46
+	// ErrSettled indicates an attempt to redeem a voucher after the channel's settlement period ended.
+	ErrSettled = 47
+	// ErrMissingNonce indicates a voucher was requested without a nonce.
+	ErrMissingNonce = 48
 )
 
+// SettlementPeriod is the number of blocks a closed payment channel stays in
+// the settling state before its remaining funds can be reclaimed by the payer.
+// During that time either party can redeem a higher voucher than the one the
+// channel was closed with.
+const SettlementPeriod = 100
+
+// EventRedeemed is the name of the event the payment broker emits when a
+// voucher is redeemed. The event data is a RedeemedEvent.
+const EventRedeemed = "redeemed"
+
+// RedeemedEvent describes the redemption of a voucher.
+type RedeemedEvent struct {
+	Payer   address.Address  `json:"payer"`
+	Channel *types.ChannelID `json:"channel"`
+	Target  address.Address  `json:"target"`
+
+	// AmountRedeemed is the total amount redeemed from the channel so far.
+	AmountRedeemed *types.AttoFIL `json:"amount_redeemed"`
+}
+
 // Errors map error codes to revert errors this actor may return.
 var Errors = map[uint8]error{
 	ErrTooEarly:                 errors.NewCodedRevertError(ErrTooEarly, "block height too low to redeem voucher"),
@@ -53,10 +85,17 @@ var Errors = map[uint8]error{
 	ErrExpired:                  errors.NewCodedRevertError(ErrExpired, "block height has exceeded channel's end of life"),
 	ErrAlreadyWithdrawn:         errors.NewCodedRevertError(ErrAlreadyWithdrawn, "update amount has already been redeemed"),
 	ErrInvalidSignature:         errors.NewCodedRevertErrorf(ErrInvalidSignature, "signature failed to validate"),
+	ErrStaleNonce:               errors.NewCodedRevertError(ErrStaleNonce, "voucher nonce has already been redeemed on this lane"),
+	ErrConditionInvalid:         errors.NewCodedRevertError(ErrConditionInvalid, "voucher condition could not be evaluated"),
+	ErrConditionNotMet:          errors.NewCodedRevertError(ErrConditionNotMet, "voucher condition is not met"),
+	ErrSettled:                  errors.NewCodedRevertError(ErrSettled, "payment channel has settled"),
+	ErrMissingNonce:             errors.NewCodedRevertError(ErrMissingNonce, "voucher nonce must be greater than zero"),
 }
 
 func init() {
 	cbor.RegisterCborType(PaymentChannel{})
+	cbor.RegisterCborType(Lane{})
+	cbor.RegisterCborType(RedeemedEvent{})
 }
 
 // PaymentChannel records the intent to pay funds to a target account.
@@ -65,6 +104,30 @@ type PaymentChannel struct {
 	Amount         *types.AttoFIL     `json:"amount"`
 	AmountRedeemed *types.AttoFIL     `json:"amount_redeemed"`
 	Eol            *types.BlockHeight `json:"eol"`
+
+	// Lanes tracks redemptions for each lane of the channel that has been
+	// redeemed against, keyed by stringified lane id. Vouchers on different
+	// lanes are independent of each other, which lets a payer pay the target
+	// for several things at once over a single channel. AmountRedeemed is the
+	// total redeemed across all lanes.
+	Lanes map[string]*Lane `json:"lanes"`
+
+	// SettleHeight is the block height at which the settlement period of a
+	// closed channel ends. It is nil while the channel is open.
+	SettleHeight *types.BlockHeight `json:"settle_height"`
+}
+
+// Settling returns whether the channel has been closed and is in, or past,
+// its settlement period.
+func (channel *PaymentChannel) Settling() bool {
+	return channel.SettleHeight != nil
+}
+
+// Lane records the amount redeemed from a single lane of a payment channel
+// and the nonce of the last voucher redeemed on it.
+type Lane struct {
+	AmountRedeemed *types.AttoFIL `json:"amount_redeemed"`
+	Nonce          types.Uint64   `json:"nonce"`
 }
 
 // Actor provides a mechanism for off chain payments.
@@ -88,7 +151,7 @@ var _ exec.ExecutableActor = (*Actor)(nil)
 
 var paymentBrokerExports = exec.Exports{
 	"close": &exec.FunctionSignature{
-		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Bytes},
+		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Uint64, abi.Uint64, abi.Bytes, abi.Bytes},
 		Return: nil,
 	},
 	"createChannel": &exec.FunctionSignature{
@@ -108,11 +171,11 @@ var paymentBrokerExports = exec.Exports{
 		Return: nil,
 	},
 	"redeem": &exec.FunctionSignature{
-		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Bytes},
+		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Uint64, abi.Uint64, abi.Bytes, abi.Bytes},
 		Return: nil,
 	},
 	"voucher": &exec.FunctionSignature{
-		Params: []abi.Type{abi.ChannelID, abi.AttoFIL, abi.BlockHeight},
+		Params: []abi.Type{abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Uint64, abi.Uint64},
 		Return: []abi.Type{abi.Bytes},
 	},
 }
@@ -151,6 +214,7 @@ func (pb *Actor) CreateChannel(vmctx exec.VMContext, target address.Address, eol
 			Amount:         vmctx.Message().Value,
 			AmountRedeemed: types.NewAttoFILFromFIL(0),
 			Eol:            eol,
+			Lanes:          map[string]*Lane{},
 		})
 		if err != nil {
 			return errors.FaultErrorWrap(err, "Could not set payment channel")
@@ -180,23 +244,31 @@ func (pb *Actor) CreateChannel(vmctx exec.VMContext, target address.Address, eol
 // payer createChannel(1000)   -> Payer: 1000, Target: 0, Channel: 1000
 // target Redeem(100)          -> Payer: 1000, Target: 100, Channel: 900
 // target Redeem(200)          -> Payer: 1000, Target: 200, Channel: 800
-// target Close(500)           -> Payer: 1500, Target: 500, Channel: 0
+// target Close(500)           -> Payer: 1000, Target: 500, Channel: 500
+// payer Reclaim()             -> Payer: 1500, Target: 500, Channel: 0
 //
-func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, sig []byte) (uint8, error) {
+// Amounts are tracked separately for each lane of the channel, and each
+// voucher redeemed on a lane must carry a greater nonce than the last one.
+// If the voucher carries a condition (cbor encoded, empty for none), it is
+// evaluated first and the voucher is only paid if it holds.
+func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) (uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	if !VerifyVoucherSignature(payer, chid, amt, validAt, sig) {
+	if !isValidVoucherSignature(payer, chid, amt, validAt, lane, nonce, condition, sig) {
 		return errors.CodeError(Errors[ErrInvalidSignature]), Errors[ErrInvalidSignature]
 	}
 
+	if err := checkCondition(vmctx, condition); err != nil {
+		return errors.CodeError(err), err
+	}
+
 	ctx := context.Background()
 	storage := vmctx.Storage()
 
+	var channel *PaymentChannel
 	err := withPayerChannels(ctx, storage, payer, func(byChannelID exec.Lookup) error {
-		var channel *PaymentChannel
-
 		chInt, err := byChannelID.Find(ctx, chid.KeyString())
 		if err != nil {
 			if err == hamt.ErrNotFound {
@@ -205,13 +277,14 @@ func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types
 			return errors.FaultErrorWrapf(err, "Could not retrieve payment channel with ID: %s", chid)
 		}
 
-		channel, ok := chInt.(*PaymentChannel)
+		var ok bool
+		channel, ok = chInt.(*PaymentChannel)
 		if !ok {
 			return errors.NewFaultError("Expected PaymentChannel from channels lookup")
 		}
 
 		// validate the amount can be sent to the target and send payment to that address.
-		err = updateChannel(vmctx, vmctx.Message().From, channel, amt, validAt)
+		err = updateChannel(vmctx, payer, channel, amt, validAt, lane, nonce)
 		if err != nil {
 			return err
 		}
@@ -227,20 +300,37 @@ func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types
 		return errors.CodeError(err), err
 	}
 
+	err = vmctx.Emit(EventRedeemed, &RedeemedEvent{
+		Payer:          payer,
+		Channel:        chid,
+		Target:         channel.Target,
+		AmountRedeemed: channel.AmountRedeemed,
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
 	return 0, nil
 }
 
-// Close first executes the logic performed in the the Update method, then returns all
-// funds remaining in the channel to the payer account and deletes the channel.
-func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, sig []byte) (uint8, error) {
+// Close first executes the logic performed in the the Update method, then puts the
+// channel into the settling state for SettlementPeriod blocks. While the channel
+// settles, the target or the payer may still redeem a voucher for a higher amount,
+// so that neither side is bound by a stale voucher. Once the channel has settled,
+// the payer reclaims the funds remaining in it.
+func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) (uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	if !VerifyVoucherSignature(payer, chid, amt, validAt, sig) {
+	if !isValidVoucherSignature(payer, chid, amt, validAt, lane, nonce, condition, sig) {
 		return errors.CodeError(Errors[ErrInvalidSignature]), Errors[ErrInvalidSignature]
 	}
 
+	if err := checkCondition(vmctx, condition); err != nil {
+		return errors.CodeError(err), err
+	}
+
 	ctx := context.Background()
 	storage := vmctx.Storage()
 
@@ -259,18 +349,17 @@ func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.
 		}
 
 		// validate the amount can be sent to the target and send payment to that address.
-		err = updateChannel(vmctx, vmctx.Message().From, channel, amt, validAt)
+		err = updateChannel(vmctx, payer, channel, amt, validAt, lane, nonce)
 		if err != nil {
 			return err
 		}
 
-		err = byChannelID.Set(ctx, chid.KeyString(), channel)
-		if err != nil {
-			return err
+		// start the settlement period, unless the channel is already settling
+		if !channel.Settling() {
+			channel.SettleHeight = vmctx.BlockHeight().Add(types.NewBlockHeight(SettlementPeriod))
 		}
 
-		// return funds to payer
-		return reclaim(ctx, vmctx, byChannelID, payer, chid, channel)
+		return byChannelID.Set(ctx, chid.KeyString(), channel)
 	})
 
 	if err != nil {
@@ -335,7 +424,7 @@ func (pb *Actor) Extend(vmctx exec.VMContext, chid *types.ChannelID, eol *types.
 }
 
 // Reclaim is used by the owner of a channel to reclaim unspent funds in timed
-// out payment Channels they own.
+// out or settled payment Channels they own.
 func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
@@ -359,8 +448,8 @@ func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, er
 			return errors.NewFaultError("Expected PaymentChannel from channels lookup")
 		}
 
-		// reclaim may only be called at or after Eol
-		if vmctx.BlockHeight().LessThan(channel.Eol) {
+		// reclaim may only be called at or after Eol, or once the channel has settled
+		if vmctx.BlockHeight().LessThan(channel.Eol) && !hasSettled(vmctx, channel) {
 			return Errors[ErrReclaimBeforeEol]
 		}
 
@@ -383,12 +472,19 @@ func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, er
 // against the given channel.  It also takes a block height parameter "validAt"
 // enforcing that the voucher is not reclaimed until the given block height
 // Voucher errors if the channel doesn't exist or contains less than request
-// amount.
-func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight) ([]byte, uint8, error) {
+// amount. The voucher pays out of the given lane with the given nonce, which
+// must be greater than zero. The payer is responsible for never issuing the
+// same nonce twice on a lane: the chain only knows about redeemed vouchers, so
+// it cannot tell which nonces were already handed out.
+func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64) ([]byte, uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return []byte{}, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
+	if nonce == 0 {
+		return nil, ErrMissingNonce, Errors[ErrMissingNonce]
+	}
+
 	ctx := context.Background()
 	storage := vmctx.Storage()
 	payerAddress := vmctx.Message().From
@@ -422,6 +518,8 @@ func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount *ty
 			Target:  channel.Target,
 			Amount:  *amount,
 			ValidAt: *validAt,
+			Lane:    types.Uint64(lane),
+			Nonce:   types.Uint64(nonce),
 		}
 
 		return nil
@@ -487,11 +585,33 @@ func (pb *Actor) Ls(vmctx exec.VMContext, payer address.Address) ([]byte, uint8,
 	return channelsBytes, 0, nil
 }
 
-func updateChannel(ctx exec.VMContext, target address.Address, channel *PaymentChannel, amt *types.AttoFIL, validAt *types.BlockHeight) error {
-	if target != channel.Target {
+// lane returns the state of the given lane of the channel, which is empty for
+// lanes that have not been redeemed against.
+func (channel *PaymentChannel) lane(id uint64) *Lane {
+	if l, ok := channel.Lanes[strconv.FormatUint(id, 10)]; ok {
+		return l
+	}
+	return &Lane{AmountRedeemed: types.NewAttoFILFromFIL(0)}
+}
+
+// hasSettled returns whether the channel's settlement period has ended.
+func hasSettled(ctx exec.VMContext, channel *PaymentChannel) bool {
+	return channel.Settling() && ctx.BlockHeight().GreaterEqual(channel.SettleHeight)
+}
+
+// updateChannel pays the target the difference between amt and the amount
+// already redeemed on the voucher's lane. Vouchers are redeemed by the target,
+// or by the payer while the channel is settling.
+func updateChannel(ctx exec.VMContext, payer address.Address, channel *PaymentChannel, amt *types.AttoFIL, validAt *types.BlockHeight, laneID uint64, nonce uint64) error {
+	from := ctx.Message().From
+	if from != channel.Target && !(channel.Settling() && from == payer) {
 		return Errors[ErrWrongTarget]
 	}
 
+	if hasSettled(ctx, channel) {
+		return Errors[ErrSettled]
+	}
+
 	if ctx.BlockHeight().LessThan(validAt) {
 		return Errors[ErrTooEarly]
 	}
@@ -500,23 +620,81 @@ func updateChannel(ctx exec.VMContext, target address.Address, channel *PaymentC
 		return Errors[ErrExpired]
 	}
 
-	if amt.GreaterThan(channel.Amount) {
-		return Errors[ErrInsufficientChannelFunds]
+	lane := channel.lane(laneID)
+
+	if nonce <= uint64(lane.Nonce) {
+		return Errors[ErrStaleNonce]
 	}
 
-	if amt.LessEqual(channel.AmountRedeemed) {
+	if amt.LessEqual(lane.AmountRedeemed) {
 		return Errors[ErrAlreadyWithdrawn]
 	}
 
-	// transfer funds to sender
-	updateAmount := amt.Sub(channel.AmountRedeemed)
-	_, _, err := ctx.Send(ctx.Message().From, "", updateAmount, nil)
+	updateAmount := amt.Sub(lane.AmountRedeemed)
+	if channel.AmountRedeemed.Add(updateAmount).GreaterThan(channel.Amount) {
+		return Errors[ErrInsufficientChannelFunds]
+	}
+
+	// transfer funds to target
+	_, _, err := ctx.Send(channel.Target, "", updateAmount, nil)
 	if err != nil {
 		return err
 	}
 
-	// update amount redeemed from this channel
-	channel.AmountRedeemed = amt
+	// update amounts redeemed from this lane and channel
+	lane.AmountRedeemed = amt
+	lane.Nonce = types.Uint64(nonce)
+	if channel.Lanes == nil {
+		channel.Lanes = map[string]*Lane{}
+	}
+	channel.Lanes[strconv.FormatUint(laneID, 10)] = lane
+	channel.AmountRedeemed = channel.AmountRedeemed.Add(updateAmount)
+
+	return nil
+}
+
+// conditionMethods lists the methods a voucher condition may call, by the
+// address of the actor they belong to. Only read-only methods may be listed:
+// conditions run with the payment broker as the sender, so any other method
+// would let the payer act on behalf of the broker.
+var conditionMethods = map[address.Address]map[string]bool{
+	address.StorageMarketAddress: {
+		"isPieceCommitted": true,
+	},
+}
+
+// checkCondition evaluates a voucher's cbor encoded condition and returns an
+// error unless it holds. An empty condition always holds.
+func checkCondition(vmctx exec.VMContext, data []byte) error {
+	condition, err := decodeCondition(data)
+	if err != nil {
+		return errors.RevertErrorWrap(err, Errors[ErrConditionInvalid].Error())
+	}
+	if condition == nil {
+		return nil
+	}
+	if !conditionMethods[condition.To][condition.Method] {
+		return Errors[ErrConditionInvalid]
+	}
+
+	ret, code, err := vmctx.Send(condition.To, condition.Method, nil, condition.Params)
+	if err != nil {
+		if errors.IsFault(err) {
+			return err
+		}
+		return Errors[ErrConditionInvalid]
+	}
+	if code != 0 || len(ret) != 1 {
+		return Errors[ErrConditionInvalid]
+	}
+
+	holds, err := abi.Deserialize(ret[0], abi.Boolean)
+	if err != nil {
+		return Errors[ErrConditionInvalid]
+	}
+	if !holds.Val.(bool) {
+		return Errors[ErrConditionNotMet]
+	}
 
 	return nil
 }
@@ -547,24 +725,49 @@ func reclaim(ctx context.Context, vmctx exec.VMContext, byChannelID exec.Lookup,
 const separator = 0x0
 
 // SignVoucher creates the signature for the given combination of
-// channel, amount, validAt (earliest block height for redeem) and from address.
-// It does so by signing the following bytes: (channelID | 0x0 | amount | 0x0 | validAt)
-func SignVoucher(channelID *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight, addr address.Address, signer types.Signer) (types.Signature, error) {
-	data := createVoucherSignatureData(channelID, amount, validAt)
+// channel, amount, validAt (earliest block height for redeem), lane, nonce,
+// optional condition and from address. It does so by signing the following
+// bytes: (channelID | 0x0 | amount | 0x0 | validAt | 0x0 | lane | 0x0 | nonce)
+// followed by (0x0 | condition) if the voucher has a condition.
+func SignVoucher(channelID *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition *Condition, addr address.Address, signer types.Signer) (types.Signature, error) {
+	conditionData, err := EncodeCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	data := createVoucherSignatureData(channelID, amount, validAt, lane, nonce, conditionData)
 	return signer.SignBytes(data, addr)
 }
 
 // VerifyVoucherSignature returns whether the voucher's signature is valid
-func VerifyVoucherSignature(payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, sig []byte) bool {
-	data := createVoucherSignatureData(chid, amt, validAt)
+func VerifyVoucherSignature(payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition *Condition, sig []byte) bool {
+	conditionData, err := EncodeCondition(condition)
+	if err != nil {
+		return false
+	}
+
+	return isValidVoucherSignature(payer, chid, amt, validAt, lane, nonce, conditionData, sig)
+}
+
+func isValidVoucherSignature(payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) bool {
+	data := createVoucherSignatureData(chid, amt, validAt, lane, nonce, condition)
 	return types.IsValidSignature(data, payer, sig)
 }
 
-func createVoucherSignatureData(channelID *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight) []byte {
+func createVoucherSignatureData(channelID *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte) []byte {
 	data := append(channelID.Bytes(), separator)
 	data = append(data, amount.Bytes()...)
 	data = append(data, separator)
-	return append(data, validAt.Bytes()...)
+	data = append(data, validAt.Bytes()...)
+	data = append(data, separator)
+	data = append(data, leb128.FromUInt64(lane)...)
+	data = append(data, separator)
+	data = append(data, leb128.FromUInt64(nonce)...)
+	if len(condition) > 0 {
+		data = append(data, separator)
+		data = append(data, condition...)
+	}
+	return data
 }
 
 func withPayerChannels(ctx context.Context, storage exec.Storage, payer address.Address, f func(exec.Lookup) error) error {
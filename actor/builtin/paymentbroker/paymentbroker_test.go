@@ -16,9 +16,11 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	. "github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/state"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -97,6 +99,127 @@ func TestPaymentBrokerUpdate(t *testing.T) {
 	assert.Equal(types.NewAttoFILFromFIL(1000), channel.Amount)
 	assert.Equal(types.NewAttoFILFromFIL(100), channel.AmountRedeemed)
 	assert.Equal(sys.target, channel.Target)
+
+	require.Len(result.Receipt.Events, 1)
+	assert.Equal(address.PaymentBrokerAddress, result.Receipt.Events[0].Actor)
+	assert.Equal(EventRedeemed, result.Receipt.Events[0].Name)
+	var redeemed RedeemedEvent
+	require.NoError(actor.UnmarshalStorage(result.Receipt.Events[0].Data, &redeemed))
+	assert.Equal(sys.payer, redeemed.Payer)
+	assert.True(sys.channelID.Equal(redeemed.Channel))
+	assert.Equal(sys.target, redeemed.Target)
+	assert.True(types.NewAttoFILFromFIL(100).Equal(redeemed.AmountRedeemed))
+}
+
+func TestPaymentBrokerRedeemLanes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sys := setup(t)
+
+	redeem := func(amtInt uint64, lane uint64, nonce uint64) *consensus.ApplicationResult {
+		amt := types.NewAttoFILFromFIL(amtInt)
+		signature, err := sys.Signature(amt, sys.defaultValidAt, lane, nonce)
+		require.NoError(err)
+
+		pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, lane, nonce, []byte{}, signature)
+		msg := types.NewMessage(sys.target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "redeem", pdata)
+		result, err := sys.ApplyMessage(msg, 0)
+		require.NoError(err)
+		return result
+	}
+
+	// lanes are redeemed independently
+	require.NoError(redeem(300, 1, 1).ExecutionError)
+	require.NoError(redeem(200, 2, 1).ExecutionError)
+
+	channel := sys.retrieveChannel(state.MustGetActor(sys.st, address.PaymentBrokerAddress))
+	assert.Equal(types.NewAttoFILFromFIL(500), channel.AmountRedeemed)
+	assert.Equal(types.NewAttoFILFromFIL(300), channel.Lanes["1"].AmountRedeemed)
+	assert.Equal(types.Uint64(1), channel.Lanes["1"].Nonce)
+	assert.Equal(types.NewAttoFILFromFIL(200), channel.Lanes["2"].AmountRedeemed)
+
+	// a lane's nonce may not be reused
+	assert.EqualError(redeem(400, 1, 1).ExecutionError, Errors[ErrStaleNonce].Error())
+
+	require.NoError(redeem(400, 1, 2).ExecutionError)
+	assert.Equal(types.NewAttoFILFromFIL(600), state.MustGetActor(sys.st, sys.target).Balance)
+
+	// the lanes together may not redeem more than the channel holds
+	assert.EqualError(redeem(700, 2, 2).ExecutionError, Errors[ErrInsufficientChannelFunds].Error())
+}
+
+func TestPaymentBrokerRedeemWithCondition(t *testing.T) {
+	require := require.New(t)
+
+	sys := setup(t)
+
+	pdata := core.MustConvertParams(big.NewInt(10), []byte{}, th.RequireRandomPeerID(require))
+	msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, 0, types.NewAttoFILFromFIL(100), "createMiner", pdata)
+	result, err := sys.ApplyMessage(msg, 0)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	minerAddr, err := address.NewFromBytes(result.Receipt.Return[0])
+	require.NoError(err)
+
+	// the voucher only pays once the miner has committed the piece
+	pieceRef := types.SomeCid()
+	condition := &Condition{
+		To:     address.StorageMarketAddress,
+		Method: "isPieceCommitted",
+		Params: []interface{}{uint64(0), minerAddr, pieceRef.Bytes()},
+	}
+
+	redeem := func(condition *Condition) *consensus.ApplicationResult {
+		amt := types.NewAttoFILFromFIL(100)
+		signature, err := SignVoucher(sys.channelID, amt, sys.defaultValidAt, 0, 1, condition, sys.payer, mockSigner)
+		require.NoError(err)
+		conditionData, err := EncodeCondition(condition)
+		require.NoError(err)
+
+		pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, uint64(0), uint64(1), conditionData, []byte(signature))
+		msg := types.NewMessage(sys.target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "redeem", pdata)
+		result, err := sys.ApplyMessage(msg, 0)
+		require.NoError(err)
+		return result
+	}
+
+	require.EqualError(redeem(condition).ExecutionError, Errors[ErrConditionNotMet].Error())
+
+	invalid := &Condition{To: address.StorageMarketAddress, Method: "nope"}
+	require.EqualError(redeem(invalid).ExecutionError, Errors[ErrConditionInvalid].Error())
+
+	// conditions may not call methods that change state
+	notReadOnly := &Condition{To: address.StorageMarketAddress, Method: "updatePower", Params: []interface{}{big.NewInt(1)}}
+	require.EqualError(redeem(notReadOnly).ExecutionError, Errors[ErrConditionInvalid].Error())
+
+	// publish and commit a deal for the piece
+	proposal := storagemarket.DealProposal{
+		Client:     sys.payer,
+		Miner:      minerAddr,
+		PieceRef:   pieceRef,
+		Size:       types.NewBytesAmount(1024),
+		Duration:   100,
+		TotalPrice: types.NewAttoFILFromFIL(100),
+	}
+	signed, err := storagemarket.NewSignedDealProposal(proposal, mockSigner)
+	require.NoError(err)
+	deals, err := cbor.DumpObject([]*storagemarket.SignedDealProposal{signed})
+	require.NoError(err)
+
+	msg = types.NewMessage(address.TestAddress, address.StorageMarketAddress, 1, nil, "publishStorageDeals", core.MustConvertParams(deals))
+	result, err = sys.ApplyMessage(msg, 0)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	pdata = core.MustConvertParams(uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{0})
+	msg = types.NewMessage(address.TestAddress, minerAddr, 2, nil, "commitSector", pdata)
+	result, err = sys.ApplyMessage(msg, 0)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	require.NoError(redeem(condition).ExecutionError)
+	require.Equal(types.NewAttoFILFromFIL(100), state.MustGetActor(sys.st, sys.target).Balance)
 }
 
 func TestPaymentBrokerUpdateErrorsWithIncorrectChannel(t *testing.T) {
@@ -243,19 +366,81 @@ func TestPaymentBrokerClose(t *testing.T) {
 
 	paymentBroker := state.MustGetActor(sys.st, address.PaymentBrokerAddress)
 
-	// all funds have been redeemed or returned
-	assert.Equal(types.NewAttoFILFromFIL(0), paymentBroker.Balance)
+	// the remaining funds stay in the channel while it settles
+	assert.Equal(types.NewAttoFILFromFIL(900), paymentBroker.Balance)
 
 	targetActor := state.MustGetActor(sys.st, sys.target)
 
 	// targetActor has been paid
 	assert.Equal(types.NewAttoFILFromFIL(100), targetActor.Balance)
 
-	// remaining balance is returned to payer
+	channel := sys.retrieveChannel(paymentBroker)
+	assert.True(channel.Settling())
+	assert.Equal(types.NewBlockHeight(SettlementPeriod), channel.SettleHeight)
+
+	// remaining balance is returned to payer once the channel has settled
+	pdata := core.MustConvertParams(sys.channelID)
+	msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.NewAttoFILFromFIL(0), "reclaim", pdata)
+	result, err = sys.ApplyMessage(msg, SettlementPeriod)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
 	payerActor = state.MustGetActor(sys.st, sys.payer)
 	assert.Equal(payerBalancePriorToClose.Add(types.NewAttoFILFromFIL(900)), payerActor.Balance)
 }
 
+func TestPaymentBrokerSettlement(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	sys := setup(t)
+
+	// extend the channel past the settlement period
+	pdata := core.MustConvertParams(sys.channelID, types.NewBlockHeight(1000))
+	msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.NewAttoFILFromFIL(0), "extend", pdata)
+	result, err := sys.ApplyMessage(msg, 0)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	// the payer may not redeem vouchers on an open channel
+	result, err = sys.ApplyRedeemMessage(sys.payer, 100, 0)
+	require.NoError(err)
+	assert.EqualError(result.ExecutionError, Errors[ErrWrongTarget].Error())
+
+	// the target closes the channel with a stale voucher
+	result, err = sys.ApplySignatureMessageWithValidAtAndBlockHeight(sys.target, 100, 0, 0, 5, "close")
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+
+	// reclaiming must wait for the channel to settle
+	pdata = core.MustConvertParams(sys.channelID)
+	msg = types.NewMessage(sys.payer, address.PaymentBrokerAddress, 2, types.NewAttoFILFromFIL(0), "reclaim", pdata)
+	result, err = sys.ApplyMessage(msg, 6)
+	require.NoError(err)
+	assert.EqualError(result.ExecutionError, Errors[ErrReclaimBeforeEol].Error())
+
+	// while settling, either party can redeem a higher voucher, which pays the target
+	result, err = sys.ApplyRedeemMessageWithBlockHeight(sys.target, 300, 0, 10)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	result, err = sys.ApplyRedeemMessageWithBlockHeight(sys.payer, 400, 0, 10)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	assert.Equal(types.NewAttoFILFromFIL(400), state.MustGetActor(sys.st, sys.target).Balance)
+
+	channel := sys.retrieveChannel(state.MustGetActor(sys.st, address.PaymentBrokerAddress))
+	assert.Equal(types.NewBlockHeight(5+SettlementPeriod), channel.SettleHeight)
+
+	// once settled, no more vouchers can be redeemed
+	result, err = sys.ApplyRedeemMessageWithBlockHeight(sys.target, 500, 0, 5+SettlementPeriod)
+	require.NoError(err)
+	assert.EqualError(result.ExecutionError, Errors[ErrSettled].Error())
+
+	result, err = sys.ApplyMessage(msg, 5+SettlementPeriod)
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	assert.Equal(types.NewAttoFILFromFIL(0), state.MustGetActor(sys.st, address.PaymentBrokerAddress).Balance)
+}
+
 func TestPaymentBrokerCloseErrorsBeforeValidAt(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
@@ -273,13 +458,13 @@ func TestPaymentBrokerCloseInvalidSig(t *testing.T) {
 	sys := setup(t)
 
 	amt := types.NewAttoFILFromFIL(100)
-	signature, err := sys.Signature(amt, sys.defaultValidAt)
+	signature, err := sys.Signature(amt, sys.defaultValidAt, 0, 1)
 	require.NoError(err)
 	// make the signature invalid
 	signature[0] = 0
 	signature[1] = 1
 
-	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, signature)
+	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, uint64(0), uint64(1), []byte{}, signature)
 	msg := types.NewMessage(sys.target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "close", pdata)
 	res, err := sys.ApplyMessage(msg, 0)
 	require.EqualError(res.ExecutionError, Errors[ErrInvalidSignature].Error())
@@ -291,13 +476,13 @@ func TestPaymentBrokerRedeemInvalidSig(t *testing.T) {
 	sys := setup(t)
 
 	amt := types.NewAttoFILFromFIL(100)
-	signature, err := sys.Signature(amt, sys.defaultValidAt)
+	signature, err := sys.Signature(amt, sys.defaultValidAt, 0, 1)
 	require.NoError(err)
 	// make the signature invalid
 	signature[0] = 0
 	signature[1] = 1
 
-	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, signature)
+	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, uint64(0), uint64(1), []byte{}, signature)
 	msg := types.NewMessage(sys.target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "redeem", pdata)
 	res, err := sys.ApplyMessage(msg, 0)
 	require.EqualError(res.ExecutionError, Errors[ErrInvalidSignature].Error())
@@ -491,7 +676,7 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(100)
-		pdata := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt)
+		pdata := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, uint64(0), uint64(1))
 		msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, nil, "voucher", pdata)
 		res, err := sys.ApplyMessage(msg, 9)
 		assert.NoError(err)
@@ -506,6 +691,17 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 		assert.Equal(sys.payer, voucher.Payer)
 		assert.Equal(sys.target, voucher.Target)
 		assert.Equal(*voucherAmount, voucher.Amount)
+		assert.Equal(types.Uint64(0), voucher.Lane)
+		assert.Equal(types.Uint64(1), voucher.Nonce)
+	})
+
+	t.Run("Errors when no nonce is given", func(t *testing.T) {
+		sys := setup(t)
+
+		voucherAmount := types.NewAttoFILFromFIL(100)
+		_, exitCode, err := sys.CallQueryMethod("voucher", 9, sys.channelID, voucherAmount, sys.defaultValidAt, uint64(0), uint64(0))
+		assert.Equal(uint8(ErrMissingNonce), exitCode)
+		assert.Contains(fmt.Sprintf("%v", err), "nonce must be greater than zero")
 	})
 
 	t.Run("Errors when channel does not exist", func(t *testing.T) {
@@ -515,7 +711,7 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(100)
-		_, exitCode, err := sys.CallQueryMethod("voucher", 9, notChannelID, voucherAmount, sys.defaultValidAt)
+		_, exitCode, err := sys.CallQueryMethod("voucher", 9, notChannelID, voucherAmount, sys.defaultValidAt, uint64(0), uint64(1))
 		assert.NotEqual(uint8(0), exitCode)
 		assert.Contains(fmt.Sprintf("%v", err), "unknown")
 	})
@@ -525,7 +721,7 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(2000)
-		args := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt)
+		args := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, uint64(0), uint64(1))
 
 		msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, nil, "voucher", args)
 		res, err := sys.ApplyMessage(msg, 9)
@@ -584,6 +780,7 @@ type system struct {
 	st             state.Tree
 	vms            vm.StorageMap
 	addressGetter  func() address.Address
+	voucherNonce   uint64
 }
 
 func setup(t *testing.T) system {
@@ -614,8 +811,8 @@ func setup(t *testing.T) system {
 	}
 }
 
-func (sys *system) Signature(amt *types.AttoFIL, validAt *types.BlockHeight) ([]byte, error) {
-	sig, err := SignVoucher(sys.channelID, amt, validAt, sys.payer, mockSigner)
+func (sys *system) Signature(amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64) ([]byte, error) {
+	sig, err := SignVoucher(sys.channelID, amt, validAt, lane, nonce, nil, sys.payer, mockSigner)
 	if err != nil {
 		return nil, err
 	}
@@ -684,11 +881,14 @@ func (sys *system) applySignatureMessage(target address.Address, amtInt uint64,
 
 	require := require.New(sys.t)
 
+	// each voucher on the default lane needs a fresh nonce
+	sys.voucherNonce++
+
 	amt := types.NewAttoFILFromFIL(amtInt)
-	signature, err := sys.Signature(amt, validAt)
+	signature, err := sys.Signature(amt, validAt, 0, sys.voucherNonce)
 	require.NoError(err)
 
-	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, validAt, signature)
+	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, validAt, uint64(0), sys.voucherNonce, []byte{}, signature)
 	msg := types.NewMessage(target, address.PaymentBrokerAddress, nonce, types.NewAttoFILFromFIL(0), method, pdata)
 
 	return sys.ApplyMessage(msg, height)
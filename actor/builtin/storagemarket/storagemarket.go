@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
+	"strconv"
 
 	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -16,6 +18,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/util/convert"
 	"github.com/filecoin-project/go-filecoin/vm/errors"
 )
 
@@ -23,13 +26,23 @@ import (
 var MinimumPledge = big.NewInt(10)
 
 // MinimumCollateralPerSector is the minimum amount of collateral required per sector
-var MinimumCollateralPerSector, _ = types.NewAttoFILFromFILString("0.001")
+var MinimumCollateralPerSector = miner.MinimumCollateralPerSector
 
 const (
 	// ErrPledgeTooLow is the error code for a pledge under the MinimumPledge.
 	ErrPledgeTooLow = 33
 	// ErrUnknownMiner indicates a pledge under the MinimumPledge.
 	ErrUnknownMiner = 34
+	// ErrUnknownDeal indicates that no deal was published with the given ID.
+	ErrUnknownDeal = 35
+	// ErrInvalidDealSignature indicates that a deal proposal was not signed by its client.
+	ErrInvalidDealSignature = 36
+	// ErrCallerUnauthorized signals an unauthorized caller.
+	ErrCallerUnauthorized = 37
+	// ErrDealCommitted indicates that a deal has already been committed to a sector.
+	ErrDealCommitted = 38
+	// ErrDealPublished indicates that a deal proposal has already been published.
+	ErrDealPublished = 39
 	// ErrInsufficientCollateral indicates the collateral is too low.
 	ErrInsufficientCollateral = 43
 )
@@ -38,11 +51,19 @@ const (
 var Errors = map[uint8]error{
 	ErrPledgeTooLow:           errors.NewCodedRevertErrorf(ErrPledgeTooLow, "pledge must be at least %s sectors", MinimumPledge),
 	ErrUnknownMiner:           errors.NewCodedRevertErrorf(ErrUnknownMiner, "unknown miner"),
+	ErrUnknownDeal:            errors.NewCodedRevertErrorf(ErrUnknownDeal, "unknown deal"),
+	ErrInvalidDealSignature:   errors.NewCodedRevertErrorf(ErrInvalidDealSignature, "deal proposal is not signed by its client"),
+	ErrCallerUnauthorized:     errors.NewCodedRevertErrorf(ErrCallerUnauthorized, "not authorized to call the method"),
+	ErrDealCommitted:          errors.NewCodedRevertErrorf(ErrDealCommitted, "deal has already been committed to a sector"),
+	ErrDealPublished:          errors.NewCodedRevertErrorf(ErrDealPublished, "deal proposal has already been published"),
 	ErrInsufficientCollateral: errors.NewCodedRevertErrorf(ErrInsufficientCollateral, "collateral must be more than %s FIL per sector", MinimumCollateralPerSector),
 }
 
 func init() {
 	cbor.RegisterCborType(State{})
+	cbor.RegisterCborType(DealProposal{})
+	cbor.RegisterCborType(SignedDealProposal{})
+	cbor.RegisterCborType(Deal{})
 	cbor.RegisterCborType(struct{}{})
 }
 
@@ -57,6 +78,84 @@ type State struct {
 	// TotalCommitedStorage is the number of sectors that are currently committed
 	// in the whole network.
 	TotalCommittedStorage *big.Int
+
+	// Deals is a lookup of published deals keyed by deal ID.
+	Deals      cid.Cid `refmt:",omitempty"`
+	NextDealID uint64
+
+	// PublishedProposals is a lookup of the IDs of published deals keyed by
+	// the CID of their proposal, so that a proposal is published only once.
+	PublishedProposals cid.Cid `refmt:",omitempty"`
+
+	// MinerDeals is a lookup keyed by miner address of the CIDs of lookups
+	// holding the IDs of the deals published for each miner.
+	MinerDeals cid.Cid `refmt:",omitempty"`
+
+	// ProvingDeadlines is a lookup keyed by block height of the CIDs of
+	// lookups holding the addresses of the miners whose proving period,
+	// grace period included, ends at that height.
+	ProvingDeadlines cid.Cid `refmt:",omitempty"`
+}
+
+// DealProposal is the part of a storage deal that a client signs and a miner
+// publishes on chain.
+type DealProposal struct {
+	Client   address.Address
+	Miner    address.Address
+	PieceRef cid.Cid
+	Size     *types.BytesAmount
+
+	// Duration is the number of blocks the piece is to be stored for.
+	Duration   uint64
+	TotalPrice *types.AttoFIL
+}
+
+// SignedDealProposal is a deal proposal signed by its client.
+type SignedDealProposal struct {
+	Proposal  DealProposal
+	Signature types.Signature
+}
+
+// NewSignedDealProposal signs the proposal with the client's key.
+func NewSignedDealProposal(proposal DealProposal, signer types.Signer) (*SignedDealProposal, error) {
+	data, err := cbor.DumpObject(proposal)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signer.SignBytes(data, proposal.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedDealProposal{
+		Proposal:  proposal,
+		Signature: sig,
+	}, nil
+}
+
+// VerifySignature returns true if the proposal is signed by its client.
+func (sdp *SignedDealProposal) VerifySignature() bool {
+	data, err := cbor.DumpObject(sdp.Proposal)
+	if err != nil {
+		return false
+	}
+	return types.IsValidSignature(data, sdp.Proposal.Client, sdp.Signature)
+}
+
+// Deal is a storage deal that has been published on chain.
+type Deal struct {
+	ID        uint64
+	Proposal  DealProposal
+	Signature types.Signature
+
+	// PublishedAt is the block height at which the deal was published.
+	PublishedAt *types.BlockHeight
+
+	// Committed is set once the miner has committed a sector containing the
+	// deal's piece. SectorID is that sector.
+	Committed bool
+	SectorID  uint64
 }
 
 // NewActor returns a new storage market actor.
@@ -98,10 +197,34 @@ var storageMarketExports = exec.Exports{
 		Params: []abi.Type{abi.Integer},
 		Return: nil,
 	},
+	"updateProvingDeadline": &exec.FunctionSignature{
+		Params: []abi.Type{abi.BlockHeight, abi.BlockHeight},
+		Return: nil,
+	},
 	"getTotalStorage": &exec.FunctionSignature{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.Integer},
 	},
+	"publishStorageDeals": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Bytes},
+		Return: []abi.Type{abi.UintArray},
+	},
+	"commitDeals": &exec.FunctionSignature{
+		Params: []abi.Type{abi.SectorID, abi.UintArray},
+		Return: []abi.Type{abi.BlockHeight},
+	},
+	"getDeal": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Uint64},
+		Return: []abi.Type{abi.Bytes},
+	},
+	"listDealsForMiner": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: []abi.Type{abi.Bytes},
+	},
+	"isPieceCommitted": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Uint64, abi.Address, abi.Bytes},
+		Return: []abi.Type{abi.Boolean},
+	},
 }
 
 // CreateMiner creates a new miner with the a pledge of the given amount of sectors. The
@@ -196,6 +319,60 @@ func (sma *Actor) UpdatePower(vmctx exec.VMContext, delta *big.Int) (uint8, erro
 	return 0, nil
 }
 
+// UpdateProvingDeadline moves the calling miner from the miners whose proving
+// deadline is oldDeadline to those whose deadline is newDeadline. A height of
+// zero stands for no deadline. Miners call it whenever their proving period
+// changes so that the network only checks them for storage faults once their
+// deadline has passed.
+func (sma *Actor) UpdateProvingDeadline(vmctx exec.VMContext, oldDeadline, newDeadline *types.BlockHeight) (uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	_, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		miner := vmctx.Message().From
+		ctx := context.Background()
+
+		miners, err := actor.LoadLookup(ctx, vmctx.Storage(), state.Miners)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with CID: %s", state.Miners)
+		}
+
+		_, err = miners.Find(ctx, miner.String())
+		if err != nil {
+			if err == hamt.ErrNotFound {
+				return nil, Errors[ErrUnknownMiner]
+			}
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with address: %s", miner)
+		}
+
+		state.ProvingDeadlines, err = actor.WithLookup(ctx, vmctx.Storage(), state.ProvingDeadlines, func(index exec.Lookup) error {
+			if !oldDeadline.Equal(types.NewBlockHeight(0)) {
+				if err := setDeadlineMiner(ctx, vmctx.Storage(), index, oldDeadline, miner, false); err != nil {
+					return err
+				}
+			}
+			if !newDeadline.Equal(types.NewBlockHeight(0)) {
+				if err := setDeadlineMiner(ctx, vmctx.Storage(), index, newDeadline, miner, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
 // GetTotalStorage returns the total amount of proven storage in the system.
 func (sma *Actor) GetTotalStorage(vmctx exec.VMContext) (*big.Int, uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
@@ -218,7 +395,556 @@ func (sma *Actor) GetTotalStorage(vmctx exec.VMContext) (*big.Int, uint8, error)
 	return count, 0, nil
 }
 
+// PublishStorageDeals records signed deal proposals on chain so that clients
+// can verify that a miner agreed to store their pieces. deals is the cbor
+// encoding of a []SignedDealProposal. Every proposal must be signed by its
+// client and the message must be sent by the worker of each proposal's miner.
+// A proposal can only be published once; clients that want to make the same
+// deal again must change the proposal. It returns the IDs of the published
+// deals in the order they were given.
+func (sma *Actor) PublishStorageDeals(vmctx exec.VMContext, deals []byte) ([]uint64, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var proposals []SignedDealProposal
+	if err := cbor.DecodeInto(deals, &proposals); err != nil {
+		return nil, 1, errors.RevertErrorWrap(err, "could not decode deal proposals")
+	}
+
+	var state State
+	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		ctx := context.Background()
+
+		miners, err := actor.LoadLookup(ctx, vmctx.Storage(), state.Miners)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load lookup for miner with CID: %s", state.Miners)
+		}
+
+		published, err := actor.LoadLookup(ctx, vmctx.Storage(), state.PublishedProposals)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load published proposals with CID: %s", state.PublishedProposals)
+		}
+
+		// miners whose worker is known to be the sender
+		authorized := map[address.Address]bool{}
+
+		dealIDs := []uint64{}
+		lookupCid, err := actor.WithLookup(ctx, vmctx.Storage(), state.Deals, func(lookup exec.Lookup) error {
+			for _, proposal := range proposals {
+				minerAddr := proposal.Proposal.Miner
+				if !authorized[minerAddr] {
+					if _, err := miners.Find(ctx, minerAddr.String()); err != nil {
+						if err == hamt.ErrNotFound {
+							return Errors[ErrUnknownMiner]
+						}
+						return errors.FaultErrorWrapf(err, "could not load lookup for miner with address: %s", minerAddr)
+					}
+
+					worker, err := minerWorker(vmctx, minerAddr)
+					if err != nil {
+						return err
+					}
+					if worker != vmctx.Message().From {
+						return Errors[ErrCallerUnauthorized]
+					}
+					authorized[minerAddr] = true
+				}
+
+				if !proposal.VerifySignature() {
+					return Errors[ErrInvalidDealSignature]
+				}
+
+				proposalCid, err := convert.ToCid(proposal.Proposal)
+				if err != nil {
+					return errors.FaultErrorWrap(err, "could not compute CID of deal proposal")
+				}
+				_, err = published.Find(ctx, proposalCid.String())
+				if err == nil {
+					return Errors[ErrDealPublished]
+				}
+				if err != hamt.ErrNotFound {
+					return errors.FaultErrorWrapf(err, "could not look up proposal %s", proposalCid)
+				}
+				if err := published.Set(ctx, proposalCid.String(), state.NextDealID); err != nil {
+					return errors.FaultErrorWrapf(err, "could not set proposal %s", proposalCid)
+				}
+
+				deal := &Deal{
+					ID:          state.NextDealID,
+					Proposal:    proposal.Proposal,
+					Signature:   proposal.Signature,
+					PublishedAt: vmctx.BlockHeight(),
+				}
+				if err := lookup.Set(ctx, dealKey(deal.ID), deal); err != nil {
+					return errors.FaultErrorWrap(err, "could not set deal")
+				}
+
+				dealIDs = append(dealIDs, deal.ID)
+				state.NextDealID++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		state.Deals = lookupCid
+
+		state.PublishedProposals, err = published.Commit(ctx)
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not commit published proposals")
+		}
+
+		state.MinerDeals, err = actor.WithLookup(ctx, vmctx.Storage(), state.MinerDeals, func(index exec.Lookup) error {
+			for i, proposal := range proposals {
+				if err := indexDeal(ctx, vmctx.Storage(), index, proposal.Proposal.Miner, dealIDs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return dealIDs, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	dealIDs, ok := ret.([]uint64)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected []uint64 to be returned, but got %T instead", ret)
+	}
+
+	return dealIDs, 0, nil
+}
+
+// CommitDeals marks the given deals as stored in the given sector. It is
+// called by a miner actor when it commits a sector, and every deal must be
+// with that miner. It returns the height at which the last of the deals ends,
+// which is when the sector may expire.
+func (sma *Actor) CommitDeals(vmctx exec.VMContext, sectorID uint64, dealIDs []uint64) (*types.BlockHeight, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	ret, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		ctx := context.Background()
+
+		lookup, err := actor.LoadTypedLookup(ctx, vmctx.Storage(), state.Deals, &Deal{})
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load deals with CID: %s", state.Deals)
+		}
+
+		expiration := vmctx.BlockHeight()
+		for _, id := range dealIDs {
+			deal, err := findDeal(ctx, lookup, id)
+			if err != nil {
+				return nil, err
+			}
+
+			if deal.Proposal.Miner != vmctx.Message().From {
+				return nil, Errors[ErrCallerUnauthorized]
+			}
+			if deal.Committed {
+				return nil, Errors[ErrDealCommitted]
+			}
+
+			deal.Committed = true
+			deal.SectorID = sectorID
+			if err := lookup.Set(ctx, dealKey(id), deal); err != nil {
+				return nil, errors.FaultErrorWrap(err, "could not set deal")
+			}
+
+			end := vmctx.BlockHeight().Add(types.NewBlockHeight(deal.Proposal.Duration))
+			if end.GreaterThan(expiration) {
+				expiration = end
+			}
+		}
+
+		lookupCid, err := lookup.Commit(ctx)
+		if err != nil {
+			return nil, errors.FaultErrorWrap(err, "could not commit deals")
+		}
+		state.Deals = lookupCid
+
+		return expiration, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	expiration, ok := ret.(*types.BlockHeight)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected *types.BlockHeight to be returned, but got %T instead", ret)
+	}
+
+	return expiration, 0, nil
+}
+
+// GetDeal returns the cbor encoded deal with the given ID.
+func (sma *Actor) GetDeal(vmctx exec.VMContext, dealID uint64) ([]byte, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	ctx := context.Background()
+	lookup, err := actor.LoadTypedLookup(ctx, vmctx.Storage(), state.Deals, &Deal{})
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrapf(err, "could not load deals with CID: %s", state.Deals)
+	}
+
+	deal, err := findDeal(ctx, lookup, dealID)
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	out, err := actor.MarshalStorage(deal)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "Error marshalling deal")
+	}
+
+	return out, 0, nil
+}
+
+// ListDealsForMiner returns the cbor encoded deals published for the given
+// miner, keyed by deal ID.
+func (sma *Actor) ListDealsForMiner(vmctx exec.VMContext, minerAddr address.Address) ([]byte, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	ctx := context.Background()
+	lookup, err := actor.LoadTypedLookup(ctx, vmctx.Storage(), state.Deals, &Deal{})
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrapf(err, "could not load deals with CID: %s", state.Deals)
+	}
+
+	dealIDs, err := minerDealIDs(ctx, vmctx.Storage(), state.MinerDeals, minerAddr)
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	deals := map[string]*Deal{}
+	for _, id := range dealIDs {
+		deal, err := findDeal(ctx, lookup, id)
+		if err != nil {
+			return nil, errors.CodeError(err), err
+		}
+		deals[dealKey(id)] = deal
+	}
+
+	out, err := actor.MarshalStorage(deals)
+	if err != nil {
+		return nil, 1, errors.FaultErrorWrap(err, "Error marshalling deals")
+	}
+
+	return out, 0, nil
+}
+
+// IsPieceCommitted returns whether the deal with the given id is a deal of the
+// given miner for the piece with the given cid bytes, and whether the miner has
+// committed a sector containing it. It is meant to be used as the condition of
+// payment vouchers, so that clients only pay for pieces that have been sealed.
+func (sma *Actor) IsPieceCommitted(vmctx exec.VMContext, dealID uint64, minerAddr address.Address, pieceRef []byte) (bool, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return false, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	piece, err := cid.Cast(pieceRef)
+	if err != nil {
+		return false, 1, errors.RevertErrorWrap(err, "invalid piece ref")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return false, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return false, errors.CodeError(err), err
+	}
+
+	ctx := context.Background()
+	lookup, err := actor.LoadTypedLookup(ctx, vmctx.Storage(), state.Deals, &Deal{})
+	if err != nil {
+		return false, 1, errors.FaultErrorWrapf(err, "could not load deals with CID: %s", state.Deals)
+	}
+
+	deal, err := findDeal(ctx, lookup, dealID)
+	if err == Errors[ErrUnknownDeal] {
+		// the deal may not have been published yet
+		return false, 0, nil
+	} else if err != nil {
+		return false, errors.CodeError(err), err
+	}
+
+	return deal.Committed && deal.Proposal.Miner == minerAddr && deal.Proposal.PieceRef.Equals(piece), 0, nil
+}
+
+// MinersWithProvingDeadlines returns the addresses of the miners whose proving
+// deadline is at least from and less than to, given the storage market's
+// storage. The VM uses it to find the miners that may have missed their
+// proving period without visiting every miner.
+func MinersWithProvingDeadlines(ctx context.Context, storage exec.Storage, from, to *types.BlockHeight) ([]address.Address, error) {
+	chunk, err := storage.Get(storage.Head())
+	if err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not read storage market storage")
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.FaultErrorWrap(err, "could not unmarshal storage market storage")
+	}
+	if !state.ProvingDeadlines.Defined() {
+		return nil, nil
+	}
+
+	index, err := actor.LoadLookup(ctx, storage, state.ProvingDeadlines)
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load proving deadlines with CID: %s", state.ProvingDeadlines)
+	}
+
+	var miners []address.Address
+	for deadline := from; deadline.LessThan(to); deadline = deadline.Add(types.NewBlockHeight(1)) {
+		minersCid, err := findDeadlineMiners(ctx, index, deadline)
+		if err != nil {
+			return nil, err
+		}
+		if !minersCid.Defined() {
+			continue
+		}
+
+		lookup, err := actor.LoadLookup(ctx, storage, minersCid)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not load miners with proving deadline %s", deadline)
+		}
+
+		kvs, err := lookup.Values(ctx)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "could not read miners with proving deadline %s", deadline)
+		}
+
+		var due []address.Address
+		for _, kv := range kvs {
+			addr, err := address.NewFromString(kv.Key)
+			if err != nil {
+				return nil, errors.FaultErrorWrapf(err, "invalid miner address %s", kv.Key)
+			}
+			due = append(due, addr)
+		}
+		sort.Slice(due, func(i, j int) bool { return due[i].String() < due[j].String() })
+		miners = append(miners, due...)
+	}
+
+	return miners, nil
+}
+
+// minerWorker asks the given miner for the address of its worker.
+func minerWorker(vmctx exec.VMContext, minerAddr address.Address) (address.Address, error) {
+	ret, code, err := vmctx.Send(minerAddr, "getWorker", nil, nil)
+	if err != nil {
+		return address.Undef, err
+	}
+	if code != 0 {
+		return address.Undef, errors.NewRevertErrorf("could not get worker of miner %s", minerAddr)
+	}
+
+	return address.NewFromBytes(ret[0])
+}
+
+func findDeal(ctx context.Context, lookup exec.Lookup, dealID uint64) (*Deal, error) {
+	value, err := lookup.Find(ctx, dealKey(dealID))
+	if err != nil {
+		if err == hamt.ErrNotFound {
+			return nil, Errors[ErrUnknownDeal]
+		}
+		return nil, errors.FaultErrorWrapf(err, "could not find deal %d", dealID)
+	}
+
+	deal, ok := value.(*Deal)
+	if !ok {
+		return nil, errors.NewFaultError("Expected Deal from deals lookup")
+	}
+
+	return deal, nil
+}
+
+// indexDeal adds dealID to the IDs of the deals published for minerAddr in
+// index, the lookup that State.MinerDeals refers to.
+func indexDeal(ctx context.Context, storage exec.Storage, index exec.Lookup, minerAddr address.Address, dealID uint64) error {
+	dealsCid, err := findMinerDeals(ctx, index, minerAddr)
+	if err != nil {
+		return err
+	}
+
+	dealsCid, err = actor.SetKeyValue(ctx, storage, dealsCid, dealKey(dealID), true)
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not index deal %d", dealID)
+	}
+
+	if err := index.Set(ctx, minerAddr.String(), dealsCid.Bytes()); err != nil {
+		return errors.FaultErrorWrapf(err, "could not index deals of miner %s", minerAddr)
+	}
+
+	return nil
+}
+
+// minerDealIDs returns the IDs of the deals published for minerAddr, in
+// increasing order, given the CID of the deal index.
+func minerDealIDs(ctx context.Context, storage exec.Storage, indexCid cid.Cid, minerAddr address.Address) ([]uint64, error) {
+	index, err := actor.LoadLookup(ctx, storage, indexCid)
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load deal index with CID: %s", indexCid)
+	}
+
+	dealsCid, err := findMinerDeals(ctx, index, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+	if !dealsCid.Defined() {
+		return []uint64{}, nil
+	}
+
+	deals, err := actor.LoadLookup(ctx, storage, dealsCid)
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not load deals of miner %s", minerAddr)
+	}
+
+	kvs, err := deals.Values(ctx)
+	if err != nil {
+		return nil, errors.FaultErrorWrapf(err, "could not read deals of miner %s", minerAddr)
+	}
+
+	ids := make([]uint64, 0, len(kvs))
+	for _, kv := range kvs {
+		id, err := strconv.ParseUint(kv.Key, 10, 64)
+		if err != nil {
+			return nil, errors.FaultErrorWrapf(err, "invalid deal id %s", kv.Key)
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+// findMinerDeals returns the CID of the lookup of the deals published for
+// minerAddr, or an undefined CID if there are none.
+func findMinerDeals(ctx context.Context, index exec.Lookup, minerAddr address.Address) (cid.Cid, error) {
+	value, err := index.Find(ctx, minerAddr.String())
+	if err == hamt.ErrNotFound {
+		return cid.Undef, nil
+	} else if err != nil {
+		return cid.Undef, errors.FaultErrorWrapf(err, "could not find deals of miner %s", minerAddr)
+	}
+
+	raw, ok := value.([]byte)
+	if !ok {
+		return cid.Undef, errors.NewFaultErrorf("expected CID bytes in deal index, got %T", value)
+	}
+
+	dealsCid, err := cid.Cast(raw)
+	if err != nil {
+		return cid.Undef, errors.FaultErrorWrap(err, "could not decode deals CID")
+	}
+
+	return dealsCid, nil
+}
+
+// setDeadlineMiner adds minerAddr to, or removes it from, the miners whose
+// proving deadline is the given height in index, the lookup that
+// State.ProvingDeadlines refers to. Heights without miners are dropped.
+func setDeadlineMiner(ctx context.Context, storage exec.Storage, index exec.Lookup, deadline *types.BlockHeight, minerAddr address.Address, add bool) error {
+	minersCid, err := findDeadlineMiners(ctx, index, deadline)
+	if err != nil {
+		return err
+	}
+
+	miners, err := actor.LoadLookup(ctx, storage, minersCid)
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not load miners with proving deadline %s", deadline)
+	}
+
+	if add {
+		err = miners.Set(ctx, minerAddr.String(), true)
+	} else if err = miners.Delete(ctx, minerAddr.String()); err == hamt.ErrNotFound {
+		err = nil
+	}
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not update miner %s with proving deadline %s", minerAddr, deadline)
+	}
+
+	if miners.IsEmpty() {
+		if err := index.Delete(ctx, deadline.String()); err != nil && err != hamt.ErrNotFound {
+			return errors.FaultErrorWrapf(err, "could not drop proving deadline %s", deadline)
+		}
+		return nil
+	}
+
+	minersCid, err = miners.Commit(ctx)
+	if err != nil {
+		return errors.FaultErrorWrapf(err, "could not commit miners with proving deadline %s", deadline)
+	}
+	if err := index.Set(ctx, deadline.String(), minersCid.Bytes()); err != nil {
+		return errors.FaultErrorWrapf(err, "could not set proving deadline %s", deadline)
+	}
+
+	return nil
+}
+
+// findDeadlineMiners returns the CID of the lookup of the miners whose proving
+// deadline is the given height, or an undefined CID if there are none.
+func findDeadlineMiners(ctx context.Context, index exec.Lookup, deadline *types.BlockHeight) (cid.Cid, error) {
+	value, err := index.Find(ctx, deadline.String())
+	if err == hamt.ErrNotFound {
+		return cid.Undef, nil
+	} else if err != nil {
+		return cid.Undef, errors.FaultErrorWrapf(err, "could not find miners with proving deadline %s", deadline)
+	}
+
+	raw, ok := value.([]byte)
+	if !ok {
+		return cid.Undef, errors.NewFaultErrorf("expected CID bytes in proving deadlines, got %T", value)
+	}
+
+	minersCid, err := cid.Cast(raw)
+	if err != nil {
+		return cid.Undef, errors.FaultErrorWrap(err, "could not decode miners CID")
+	}
+
+	return minersCid, nil
+}
+
+func dealKey(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
 // MinimumCollateral returns the minimum required amount of collateral for a given pledge
 func MinimumCollateral(sectors *big.Int) *types.AttoFIL {
-	return MinimumCollateralPerSector.MulBigInt(sectors)
+	return miner.MinimumCollateral(sectors)
 }
@@ -7,16 +7,21 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	. "github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
 	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
 	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 )
 
 func TestStorageMarketCreateMiner(t *testing.T) {
@@ -146,6 +151,194 @@ func TestMinimumCollateral(t *testing.T) {
 	assert.Equal(MinimumCollateral(numSectors), expected)
 }
 
+func TestStorageMarketPublishStorageDeals(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	pdata := actor.MustConvertParams(big.NewInt(10), []byte{}, th.RequireRandomPeerID(require))
+	msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, 0, types.NewAttoFILFromFIL(100), "createMiner", pdata)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	minerAddr, err := address.NewFromBytes(result.Receipt.Return[0])
+	require.NoError(err)
+
+	signer, _ := types.NewMockSignersAndKeyInfo(1)
+	proposal := DealProposal{
+		Client:     signer.Addresses[0],
+		Miner:      minerAddr,
+		PieceRef:   types.SomeCid(),
+		Size:       types.NewBytesAmount(1024),
+		Duration:   100,
+		TotalPrice: types.NewAttoFILFromFIL(5),
+	}
+	signed, err := NewSignedDealProposal(proposal, signer)
+	require.NoError(err)
+
+	apply := func(to, from address.Address, method string, params ...interface{}) *consensus.ApplicationResult {
+		msg := types.NewMessage(from, to, 0, nil, method, actor.MustConvertParams(params...))
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+		require.NoError(err)
+		return result
+	}
+
+	publish := func(from address.Address, proposals ...*SignedDealProposal) *consensus.ApplicationResult {
+		deals, err := cbor.DumpObject(proposals)
+		require.NoError(err)
+		return apply(address.StorageMarketAddress, from, "publishStorageDeals", deals)
+	}
+
+	t.Run("only the miner's worker may publish its deals", func(t *testing.T) {
+		result := publish(address.TestAddress2, signed)
+		require.Equal(Errors[ErrCallerUnauthorized], result.ExecutionError)
+	})
+
+	t.Run("proposals must be signed by the client", func(t *testing.T) {
+		tampered := *signed
+		tampered.Proposal.TotalPrice = types.NewAttoFILFromFIL(1)
+		result := publish(address.TestAddress, &tampered)
+		require.Equal(Errors[ErrInvalidDealSignature], result.ExecutionError)
+	})
+
+	t.Run("published deals can be queried", func(t *testing.T) {
+		result := publish(address.TestAddress, signed)
+		require.NoError(result.ExecutionError)
+
+		var dealIDs []uint64
+		require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &dealIDs))
+		require.Equal([]uint64{0}, dealIDs)
+
+		result = apply(address.StorageMarketAddress, address.TestAddress2, "getDeal", uint64(0))
+		require.NoError(result.ExecutionError)
+		var deal Deal
+		require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &deal))
+		require.Equal(minerAddr, deal.Proposal.Miner)
+		require.Equal(proposal.Client, deal.Proposal.Client)
+		require.True(proposal.PieceRef.Equals(deal.Proposal.PieceRef))
+		require.False(deal.Committed)
+
+		result = apply(address.StorageMarketAddress, address.TestAddress2, "listDealsForMiner", minerAddr)
+		require.NoError(result.ExecutionError)
+		var deals map[string]*Deal
+		require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &deals))
+		require.Len(deals, 1)
+		require.Contains(deals, "0")
+
+		// deals are indexed by miner
+		result = apply(address.StorageMarketAddress, address.TestAddress2, "listDealsForMiner", address.TestAddress2)
+		require.NoError(result.ExecutionError)
+		deals = nil
+		require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &deals))
+		require.Empty(deals)
+
+		result = apply(address.StorageMarketAddress, address.TestAddress2, "getDeal", uint64(1))
+		require.Equal(Errors[ErrUnknownDeal], result.ExecutionError)
+	})
+
+	t.Run("a proposal can only be published once", func(t *testing.T) {
+		result := publish(address.TestAddress, signed)
+		require.Equal(Errors[ErrDealPublished], result.ExecutionError)
+
+		// not even twice in the same message
+		other := proposal
+		other.PieceRef = types.NewCidForTestGetter()()
+		otherSigned, err := NewSignedDealProposal(other, signer)
+		require.NoError(err)
+		result = publish(address.TestAddress, otherSigned, otherSigned)
+		require.Equal(Errors[ErrDealPublished], result.ExecutionError)
+
+		result = apply(address.StorageMarketAddress, address.TestAddress2, "getDeal", uint64(1))
+		require.Equal(Errors[ErrUnknownDeal], result.ExecutionError)
+	})
+
+	t.Run("committing a sector commits its deals", func(t *testing.T) {
+		commitSector := func(sectorID uint64) *consensus.ApplicationResult {
+			return apply(minerAddr, address.TestAddress, "commitSector", sectorID, th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{0})
+		}
+
+		isPieceCommitted := func(dealID uint64, pieceRef cid.Cid) bool {
+			result := apply(address.StorageMarketAddress, address.TestAddress2, "isPieceCommitted", dealID, minerAddr, pieceRef.Bytes())
+			require.NoError(result.ExecutionError)
+			committed, err := abi.Deserialize(result.Receipt.Return[0], abi.Boolean)
+			require.NoError(err)
+			return committed.Val.(bool)
+		}
+		require.False(isPieceCommitted(0, proposal.PieceRef))
+
+		result := commitSector(1)
+		require.NoError(result.ExecutionError)
+		require.True(isPieceCommitted(0, proposal.PieceRef))
+
+		// the deal must be for the piece and unknown deals are not committed
+		require.False(isPieceCommitted(0, types.SomeCid()))
+		require.False(isPieceCommitted(99, proposal.PieceRef))
+
+		result = apply(address.StorageMarketAddress, address.TestAddress2, "getDeal", uint64(0))
+		require.NoError(result.ExecutionError)
+		var deal Deal
+		require.NoError(actor.UnmarshalStorage(result.Receipt.Return[0], &deal))
+		require.True(deal.Committed)
+		require.Equal(uint64(1), deal.SectorID)
+
+		result = commitSector(2)
+		require.Equal(Errors[ErrDealCommitted], result.ExecutionError)
+	})
+}
+
+func TestStorageMarketProvingDeadlines(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+
+	pdata := actor.MustConvertParams(big.NewInt(10), []byte{}, th.RequireRandomPeerID(require))
+	msg := types.NewMessage(address.TestAddress, address.StorageMarketAddress, 0, types.NewAttoFILFromFIL(100), "createMiner", pdata)
+	result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(0))
+	require.NoError(err)
+	require.NoError(result.ExecutionError)
+	minerAddr, err := address.NewFromBytes(result.Receipt.Return[0])
+	require.NoError(err)
+
+	apply := func(to, from address.Address, bh uint64, method string, params ...interface{}) *consensus.ApplicationResult {
+		msg := types.NewMessage(from, to, 0, nil, method, actor.MustConvertParams(params...))
+		result, err := th.ApplyTestMessage(st, vms, msg, types.NewBlockHeight(bh))
+		require.NoError(err)
+		return result
+	}
+
+	minersDue := func(from, to uint64) []address.Address {
+		marketActor, err := st.GetActor(ctx, address.StorageMarketAddress)
+		require.NoError(err)
+		miners, err := MinersWithProvingDeadlines(ctx, vms.NewStorage(address.StorageMarketAddress, marketActor), types.NewBlockHeight(from), types.NewBlockHeight(to))
+		require.NoError(err)
+		return miners
+	}
+
+	t.Run("only miners may update their proving deadline", func(t *testing.T) {
+		result := apply(address.StorageMarketAddress, address.TestAddress2, 1, "updateProvingDeadline", types.NewBlockHeight(0), types.NewBlockHeight(10))
+		require.Equal(Errors[ErrUnknownMiner], result.ExecutionError)
+		require.Empty(minersDue(0, 20))
+	})
+
+	t.Run("committing the first sector schedules the miner", func(t *testing.T) {
+		result := apply(minerAddr, address.TestAddress, 5, "commitSector", uint64(1), th.MakeCommitment(), th.MakeCommitment(), th.MakeCommitment(), th.MakeRandomBytes(int(proofs.SealBytesLen)), []uint64{})
+		require.NoError(result.ExecutionError)
+
+		deadline := 5 + miner.ProvingPeriodBlocks.AsBigInt().Uint64() + miner.GracePeriodBlocks.AsBigInt().Uint64()
+		require.Equal([]address.Address{minerAddr}, minersDue(deadline, deadline+1))
+		require.Empty(minersDue(0, deadline))
+	})
+
+	t.Run("submitting a PoSt moves the miner to its next deadline", func(t *testing.T) {
+		result := apply(minerAddr, address.TestAddress, 10, "submitPoSt", []proofs.PoStProof{th.MakeRandomPoSTProofForTest()})
+		require.NoError(result.ExecutionError)
+
+		deadline := 5 + 2*miner.ProvingPeriodBlocks.AsBigInt().Uint64() + miner.GracePeriodBlocks.AsBigInt().Uint64()
+		require.Equal([]address.Address{minerAddr}, minersDue(deadline, deadline+1))
+		require.Empty(minersDue(0, deadline))
+	})
+}
+
 // this is used to simulate an attack where someone derives the likely address of another miner's
 // minerActor and sends some FIL. If that FIL creates an actor tha cannot be upgraded to a miner
 // actor, this action will block the other user. Another possibility is that the miner actor will
@@ -0,0 +1,204 @@
+// Package vesting implements a timelock actor whose balance is released to a
+// beneficiary linearly over a fixed number of blocks.
+package vesting
+
+import (
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	xerrors "gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+func init() {
+	cbor.RegisterCborType(State{})
+}
+
+const (
+	// ErrCallerUnauthorized signals an unauthorized caller.
+	ErrCallerUnauthorized = 33
+	// ErrNothingToWithdraw indicates no vested funds are left to withdraw.
+	ErrNothingToWithdraw = 34
+	// ErrInvalidSchedule indicates the vesting total is not positive.
+	ErrInvalidSchedule = 35
+)
+
+// Errors map error codes to revert errors this actor may return.
+var Errors = map[uint8]error{
+	ErrCallerUnauthorized: errors.NewCodedRevertError(ErrCallerUnauthorized, "not authorized to call the method"),
+	ErrNothingToWithdraw:  errors.NewCodedRevertError(ErrNothingToWithdraw, "no vested funds to withdraw"),
+	ErrInvalidSchedule:    errors.NewCodedRevertError(ErrInvalidSchedule, "vesting total must be positive"),
+}
+
+// Actor is the vesting actor. It holds funds on behalf of a beneficiary and
+// releases them linearly from the start height until the end of the vesting
+// duration.
+type Actor struct{}
+
+// State is the vesting actor's storage.
+type State struct {
+	// Beneficiary is the only address that may withdraw and the address
+	// that receives withdrawn funds.
+	Beneficiary address.Address
+
+	// Total is the amount that will have vested at the end of the duration.
+	Total *types.AttoFIL
+
+	// StartHeight is the block height at which vesting begins.
+	StartHeight *types.BlockHeight
+
+	// Duration is the number of blocks it takes for the total to vest.
+	Duration *types.BlockHeight
+
+	// Withdrawn is the amount the beneficiary has withdrawn so far.
+	Withdrawn *types.AttoFIL
+}
+
+// NewActor returns a new vesting actor with the given balance.
+func NewActor(balance *types.AttoFIL) *actor.Actor {
+	return actor.NewActor(types.VestingActorCodeCid, balance)
+}
+
+// NewState creates a vesting state struct.
+func NewState(beneficiary address.Address, total *types.AttoFIL, startHeight *types.BlockHeight, duration *types.BlockHeight) *State {
+	return &State{
+		Beneficiary: beneficiary,
+		Total:       total,
+		StartHeight: startHeight,
+		Duration:    duration,
+		Withdrawn:   types.NewZeroAttoFIL(),
+	}
+}
+
+// IsVesting tests whether an actor is a vesting actor.
+func IsVesting(act *actor.Actor) bool {
+	return types.VestingActorCodeCid.Equals(act.Code)
+}
+
+// InitializeState stores this vesting actor's initial data structure.
+func (va *Actor) InitializeState(storage exec.Storage, initializerData interface{}) error {
+	vestingState, ok := initializerData.(*State)
+	if !ok {
+		return errors.NewFaultError("Initial state to vesting actor is not a vesting.State struct")
+	}
+
+	if !vestingState.Total.IsPositive() {
+		return Errors[ErrInvalidSchedule]
+	}
+
+	stateBytes, err := cbor.DumpObject(vestingState)
+	if err != nil {
+		return xerrors.Wrap(err, "failed to cbor marshal object")
+	}
+
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+
+	return storage.Commit(id, cid.Undef)
+}
+
+var _ exec.ExecutableActor = (*Actor)(nil)
+
+var vestingExports = exec.Exports{
+	"withdraw": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.AttoFIL},
+	},
+	"getWithdrawable": &exec.FunctionSignature{
+		Params: nil,
+		Return: []abi.Type{abi.AttoFIL},
+	},
+}
+
+// Exports returns the vesting actor's exported functions.
+func (va *Actor) Exports() exec.Exports {
+	return vestingExports
+}
+
+// Withdraw sends everything that has vested but not yet been withdrawn to the
+// beneficiary and returns the amount sent. Only the beneficiary may withdraw.
+func (va *Actor) Withdraw(vmctx exec.VMContext) (*types.AttoFIL, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	out, err := actor.WithState(vmctx, &state, func() (interface{}, error) {
+		if vmctx.Message().From != state.Beneficiary {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		amount := state.withdrawable(vmctx.BlockHeight())
+		if !amount.IsPositive() {
+			return nil, Errors[ErrNothingToWithdraw]
+		}
+
+		_, code, err := vmctx.Send(state.Beneficiary, "", amount, nil)
+		if err != nil {
+			return nil, err
+		}
+		if code != 0 {
+			return nil, errors.NewRevertErrorf("failed to send vested funds: exit code %d", code)
+		}
+
+		state.Withdrawn = state.Withdrawn.Add(amount)
+
+		return amount, nil
+	})
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	amount, ok := out.(*types.AttoFIL)
+	if !ok {
+		return nil, 1, errors.NewFaultErrorf("expected an AttoFIL return value from call, but got %T instead", out)
+	}
+
+	return amount, 0, nil
+}
+
+// GetWithdrawable returns the amount the beneficiary could withdraw at the
+// current block height.
+func (va *Actor) GetWithdrawable(vmctx exec.VMContext) (*types.AttoFIL, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	chunk, err := vmctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	var state State
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return state.withdrawable(vmctx.BlockHeight()), 0, nil
+}
+
+// Vested returns the amount that has vested at the given block height,
+// including anything already withdrawn.
+func (state *State) Vested(height *types.BlockHeight) *types.AttoFIL {
+	if height.LessThan(state.StartHeight) {
+		return types.NewZeroAttoFIL()
+	}
+
+	elapsed := height.Sub(state.StartHeight)
+	if elapsed.GreaterEqual(state.Duration) {
+		return state.Total
+	}
+
+	return state.Total.MulBigInt(elapsed.AsBigInt()).DivBigInt(state.Duration.AsBigInt())
+}
+
+func (state *State) withdrawable(height *types.BlockHeight) *types.AttoFIL {
+	return state.Vested(height).Sub(state.Withdrawn)
+}
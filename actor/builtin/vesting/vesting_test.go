@@ -0,0 +1,111 @@
+package vesting_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	. "github.com/filecoin-project/go-filecoin/actor/builtin/vesting"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/core"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+func createTestVesting(t *testing.T, st state.Tree, vms vm.StorageMap, addr, beneficiary address.Address, total *types.AttoFIL, start, duration uint64) {
+	vstate := NewState(beneficiary, total, types.NewBlockHeight(start), types.NewBlockHeight(duration))
+	th.RequireInstallActor(t, st, vms, addr, NewActor(total), &Actor{}, vstate)
+}
+
+func TestVestingInitializeState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, vms := core.CreateStorages(ctx, t)
+
+	act := NewActor(types.NewZeroAttoFIL())
+	vstate := NewState(address.TestAddress, types.NewZeroAttoFIL(), types.NewBlockHeight(0), types.NewBlockHeight(10))
+	err := (&Actor{}).InitializeState(vms.NewStorage(address.NewForTestGetter()(), act), vstate)
+	assert.Equal(t, Errors[ErrInvalidSchedule], err)
+}
+
+func TestVestingVested(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	vstate := NewState(address.TestAddress, types.NewAttoFILFromFIL(1000), types.NewBlockHeight(10), types.NewBlockHeight(100))
+
+	assert.Equal(types.NewZeroAttoFIL(), vstate.Vested(types.NewBlockHeight(5)))
+	assert.Equal(types.NewZeroAttoFIL(), vstate.Vested(types.NewBlockHeight(10)))
+	assert.Equal(types.NewAttoFILFromFIL(250), vstate.Vested(types.NewBlockHeight(35)))
+	assert.Equal(types.NewAttoFILFromFIL(1000), vstate.Vested(types.NewBlockHeight(110)))
+	assert.Equal(types.NewAttoFILFromFIL(1000), vstate.Vested(types.NewBlockHeight(500)))
+
+	immediate := NewState(address.TestAddress, types.NewAttoFILFromFIL(1000), types.NewBlockHeight(10), types.NewBlockHeight(0))
+	assert.Equal(types.NewAttoFILFromFIL(1000), immediate.Vested(types.NewBlockHeight(10)))
+}
+
+func TestVestingWithdraw(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	st, vms := core.CreateStorages(ctx, t)
+	vestingAddr := address.NewForTestGetter()()
+	beneficiary := address.TestAddress
+
+	createTestVesting(t, st, vms, vestingAddr, beneficiary, types.NewAttoFILFromFIL(1000), 10, 100)
+	startBalance := th.RequireBalance(t, st, beneficiary)
+
+	t.Run("nothing can be withdrawn before vesting starts", func(t *testing.T) {
+		result := th.RequireApplyTestMessageFrom(t, st, vms, beneficiary, vestingAddr, 5, "withdraw")
+		assert.Equal(Errors[ErrNothingToWithdraw], result.ExecutionError)
+	})
+
+	t.Run("only the beneficiary can withdraw", func(t *testing.T) {
+		result := th.RequireApplyTestMessageFrom(t, st, vms, address.TestAddress2, vestingAddr, 60, "withdraw")
+		assert.Equal(Errors[ErrCallerUnauthorized], result.ExecutionError)
+	})
+
+	t.Run("withdraw releases the vested portion", func(t *testing.T) {
+		result := th.RequireApplyTestMessageFrom(t, st, vms, beneficiary, vestingAddr, 60, "withdraw")
+		require.NoError(result.ExecutionError)
+
+		withdrawn, err := abi.Deserialize(result.Receipt.Return[0], abi.AttoFIL)
+		require.NoError(err)
+		assert.Equal(types.NewAttoFILFromFIL(500), withdrawn.Val)
+
+		assert.Equal(types.NewAttoFILFromFIL(500), th.RequireBalance(t, st, vestingAddr))
+		assert.Equal(startBalance.Add(types.NewAttoFILFromFIL(500)), th.RequireBalance(t, st, beneficiary))
+	})
+
+	t.Run("withdrawing again at the same height fails", func(t *testing.T) {
+		result := th.RequireApplyTestMessageFrom(t, st, vms, beneficiary, vestingAddr, 60, "withdraw")
+		assert.Equal(Errors[ErrNothingToWithdraw], result.ExecutionError)
+	})
+
+	t.Run("getWithdrawable reports what has vested since the last withdrawal", func(t *testing.T) {
+		ret, code, err := consensus.CallQueryMethod(ctx, st, vms, vestingAddr, "getWithdrawable", nil, address.Undef, types.NewBlockHeight(85))
+		require.NoError(err)
+		require.Equal(uint8(0), code)
+
+		withdrawable, err := abi.Deserialize(ret[0], abi.AttoFIL)
+		require.NoError(err)
+		assert.Equal(types.NewAttoFILFromFIL(250), withdrawable.Val)
+	})
+
+	t.Run("everything has been released once the duration is over", func(t *testing.T) {
+		result := th.RequireApplyTestMessageFrom(t, st, vms, beneficiary, vestingAddr, 200, "withdraw")
+		require.NoError(result.ExecutionError)
+
+		assert.True(th.RequireBalance(t, st, vestingAddr).IsZero())
+		assert.Equal(startBalance.Add(types.NewAttoFILFromFIL(1000)), th.RequireBalance(t, st, beneficiary))
+	})
+}
@@ -74,8 +74,23 @@ var FakeActorExports = exec.Exports{
 		Params: nil,
 		Return: nil,
 	},
+	"emitsEvent": &exec.FunctionSignature{
+		Params: nil,
+		Return: nil,
+	},
+	"emitsEventAndReverts": &exec.FunctionSignature{
+		Params: nil,
+		Return: nil,
+	},
+	"callEmitsEvent": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address},
+		Return: nil,
+	},
 }
 
+// FakeEventName is the name of the events the fake actor emits.
+const FakeEventName = "fake"
+
 // InitializeState stores this actors
 func (ma *FakeActor) InitializeState(storage exec.Storage, initializerData interface{}) error {
 	st, ok := initializerData.(*FakeActorStorage)
@@ -217,6 +232,31 @@ func (ma *FakeActor) BlockLimitTestMethod(ctx exec.VMContext) (uint8, error) {
 	return 0, nil
 }
 
+// EmitsEvent emits an event named FakeEventName.
+func (ma *FakeActor) EmitsEvent(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Emit(FakeEventName, "boom"); err != nil {
+		return errors.CodeError(err), err
+	}
+	return 0, nil
+}
+
+// EmitsEventAndReverts emits an event and then returns a revert error.
+func (ma *FakeActor) EmitsEventAndReverts(ctx exec.VMContext) (uint8, error) {
+	if err := ctx.Emit(FakeEventName, "boom"); err != nil {
+		return errors.CodeError(err), err
+	}
+	return 1, errors.NewRevertError("boom")
+}
+
+// CallEmitsEvent emits an event and then tells the target to emit one too.
+func (ma *FakeActor) CallEmitsEvent(ctx exec.VMContext, target address.Address) (uint8, error) {
+	if err := ctx.Emit(FakeEventName, "boom"); err != nil {
+		return errors.CodeError(err), err
+	}
+	_, code, err := ctx.Send(target, "emitsEvent", types.ZeroAttoFIL, []interface{}{})
+	return code, err
+}
+
 // MustConvertParams encodes the given params and panics if it fails to do so.
 func MustConvertParams(params ...interface{}) []byte {
 	vals, err := abi.ToValues(params)
@@ -35,6 +35,11 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	InitAddress, err = NewActorAddress([]byte("init"))
+	if err != nil {
+		panic(err)
+	}
 }
 
 var (
@@ -49,6 +54,8 @@ var (
 	StorageMarketAddress Address
 	// PaymentBrokerAddress is the hard-coded address of the filecoin storage market.
 	PaymentBrokerAddress Address
+	// InitAddress is the hard-coded address of the init actor, which assigns ID addresses.
+	InitAddress Address
 )
 
 var (
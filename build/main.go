@@ -251,6 +251,7 @@ func build() {
 	buildGengen()
 	buildFaucet()
 	buildGenesisFileServer()
+	buildReplay()
 	generateGenesis()
 }
 
@@ -259,6 +260,7 @@ func forcebuild() {
 	buildGengen()
 	buildFaucet()
 	buildGenesisFileServer()
+	buildReplay()
 	generateGenesis()
 }
 
@@ -321,6 +323,12 @@ func buildGenesisFileServer() {
 	runCmd(cmd([]string{"go", "build", "-o", "./tools/genesis-file-server/genesis-file-server", "./tools/genesis-file-server/"}...))
 }
 
+func buildReplay() {
+	log.Println("Building replay...")
+
+	runCmd(cmd([]string{"go", "build", "-o", "./tools/replay/replay", "./tools/replay/"}...))
+}
+
 func install() {
 	log.Println("Installing...")
 
@@ -0,0 +1,168 @@
+package chain
+
+import (
+	"context"
+	"io"
+
+	"gx/ipfs/QmNRAuGmvnVw8urHkUZQirhu42VTiZjVWASa2aTznEMmpP/go-merkledag"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	"gx/ipfs/QmUGpiTCKct5s1F7jaAnY9KJmoo7Qm1R2uhSjq5iHDSUMn/go-car"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// Export writes the blocks of head and its ancestors down to fromHeight,
+// along with their messages, to w as a CAR file whose roots are the blocks of
+// head. If includeState is set, the parent state of each block, including
+// the storage of its actors, is written as well. The states of the tipsets
+// below the checkpoint of a chain synced from a snapshot are not known and
+// are left out.
+func Export(ctx context.Context, store ReadStore, bs bstore.Blockstore, head types.TipSet, fromHeight uint64, includeState bool, w io.Writer) error {
+	blocks := cid.NewSet()
+	states := cid.NewSet()
+	for it := IterAncestors(ctx, store, head); !it.Complete(); {
+		h, err := it.Value().Height()
+		if err != nil {
+			return err
+		}
+		if h < fromHeight {
+			break
+		}
+		withState := includeState
+		if withState {
+			withState, err = blockStatesKnown(ctx, store, it.Value())
+			if err != nil {
+				return err
+			}
+		}
+		for _, blk := range it.Value() {
+			blocks.Add(blk.Cid())
+			if withState {
+				states.Add(blk.Cid())
+			}
+		}
+		if err := it.Next(); err != nil {
+			return err
+		}
+	}
+
+	return writeCar(ctx, bs, head.ToSortedCidSet().ToSlice(), blocks, states, w)
+}
+
+// blockStatesKnown returns true if the states the blocks of ts commit to were
+// computed when validating ts. The tipsets below the checkpoint of a chain
+// synced from a snapshot were not validated: either the state after them or
+// the state after their parent is unknown.
+func blockStatesKnown(ctx context.Context, store ReadStore, ts types.TipSet) (bool, error) {
+	tsas, err := store.GetTipSetAndState(ctx, ts.String())
+	if err != nil {
+		return false, err
+	}
+	if !tsas.TipSetStateRoot.Defined() {
+		return false, nil
+	}
+	parentIDs, err := ts.Parents()
+	if err != nil {
+		return false, err
+	}
+	if parentIDs.Empty() {
+		return true, nil
+	}
+	parent, err := store.GetTipSetAndState(ctx, parentIDs.String())
+	if err != nil {
+		return false, err
+	}
+	return parent.TipSetStateRoot.Defined(), nil
+}
+
+// writeCar writes the DAG under roots to w as a CAR file, following only the
+// links of the given blocks to their parents, and to the rest of their
+// contents for those in states.
+func writeCar(ctx context.Context, bs bstore.Blockstore, roots []cid.Cid, blocks, states *cid.Set, w io.Writer) error {
+	dserv := &exportDAGService{
+		DAGService: merkledag.NewDAGService(bserv.New(bs, offline.Exchange(bs))),
+		blocks:     blocks,
+		states:     states,
+	}
+	return car.WriteCar(ctx, dserv, roots, w)
+}
+
+// Import loads the CAR file read from r into bs and hands the tipset made of
+// its roots to syncer, which validates it and the ancestors it includes. The
+// chain in the CAR file must extend a tipset the syncer already knows about,
+// e.g. genesis. Import returns the key of the imported tipset.
+func Import(ctx context.Context, bs bstore.Blockstore, syncer Syncer, r io.Reader) (types.SortedCidSet, error) {
+	header, err := car.LoadCar(bs, r)
+	if err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "failed to load CAR file")
+	}
+	if len(header.Roots) == 0 {
+		return types.SortedCidSet{}, errors.New("CAR file has no roots")
+	}
+
+	if err := syncer.HandleNewBlocks(ctx, header.Roots); err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "failed to sync imported chain")
+	}
+	return types.NewSortedCidSet(header.Roots...), nil
+}
+
+// exportDAGService limits the links followed while writing a CAR file to the
+// exported blocks and the states of some of them.
+type exportDAGService struct {
+	ipld.DAGService
+
+	blocks *cid.Set
+	states *cid.Set
+}
+
+// Get returns the node with the given CID, stripped of the links that must
+// not be followed.
+func (ds *exportDAGService) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	nd, err := ds.DAGService.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*ipld.Link
+	if ds.blocks.Has(c) {
+		blk, err := types.DecodeBlock(nd.RawData())
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range nd.Links() {
+			if blk.Parents.Has(l.Cid) {
+				// parents below the exported range are left out
+				if ds.blocks.Has(l.Cid) {
+					links = append(links, l)
+				}
+			} else if ds.states.Has(c) {
+				links = append(links, l)
+			}
+		}
+	} else {
+		for _, l := range nd.Links() {
+			// actor code CIDs are raw nodes that are not stored
+			if l.Cid.Prefix().Codec != cid.Raw {
+				links = append(links, l)
+			}
+		}
+	}
+	return &exportNode{Node: nd, links: links}, nil
+}
+
+// exportNode is a node with some of its links removed.
+type exportNode struct {
+	ipld.Node
+
+	links []*ipld.Link
+}
+
+// Links returns the links of the node that are followed.
+func (n *exportNode) Links() []*ipld.Link {
+	return n.links
+}
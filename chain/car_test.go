@@ -0,0 +1,120 @@
+package chain_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// recordingSyncer records the blocks and snapshots it is asked to sync.
+type recordingSyncer struct {
+	synced          []cid.Cid
+	snapshot        []types.TipSet
+	parentStateRoot cid.Cid
+}
+
+func (rs *recordingSyncer) HandleNewBlocks(ctx context.Context, blkCids []cid.Cid) error {
+	rs.synced = append(rs.synced, blkCids...)
+	return nil
+}
+
+func (rs *recordingSyncer) SyncSnapshot(ctx context.Context, chain []types.TipSet, parentStateRoot cid.Cid) error {
+	rs.snapshot = chain
+	rs.parentStateRoot = parentStateRoot
+	return nil
+}
+
+func (rs *recordingSyncer) Pause() func() {
+	return func() {}
+}
+
+func TestExportImport(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+	chainStore, err := chain.Init(ctx, r, bs, cst, consensus.DefaultGenesis)
+	require.NoError(err)
+
+	mockSigner, _ := types.NewMockSignersAndKeyInfo(1)
+	newSignedMessage := types.NewSignedMessageForTestGetter(mockSigner)
+
+	genesis := chainStore.Head()
+	stateRoot := genesis.ToSlice()[0].StateRoot
+	tipsets := []types.TipSet{genesis}
+	for h := uint64(1); h <= 3; h++ {
+		blk := &types.Block{
+			Parents:   tipsets[len(tipsets)-1].ToSortedCidSet(),
+			Height:    types.Uint64(h),
+			StateRoot: stateRoot,
+			Messages:  []*types.SignedMessage{newSignedMessage()},
+		}
+		_, err := cst.Put(ctx, blk)
+		require.NoError(err)
+		ts := th.RequireNewTipSet(require, blk)
+		th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: stateRoot})
+		tipsets = append(tipsets, ts)
+	}
+	head := tipsets[3]
+
+	// loadExport exports the chain and loads the result into a new blockstore
+	loadExport := func(fromHeight uint64, includeState bool) bstore.Blockstore {
+		var buf bytes.Buffer
+		require.NoError(chain.Export(ctx, chainStore, bs, head, fromHeight, includeState, &buf))
+
+		importBs := bstore.NewBlockstore(datastore.NewMapDatastore())
+		syncer := &recordingSyncer{}
+		key, err := chain.Import(ctx, importBs, syncer, &buf)
+		require.NoError(err)
+		assert.True(head.ToSortedCidSet().Equals(key))
+		assert.Equal(head.ToSortedCidSet().ToSlice(), syncer.synced)
+		return importBs
+	}
+
+	hasBlock := func(bs bstore.Blockstore, ts types.TipSet) bool {
+		has, err := bs.Has(ts.ToSlice()[0].Cid())
+		require.NoError(err)
+		return has
+	}
+
+	t.Run("the whole chain without state", func(t *testing.T) {
+		importBs := loadExport(0, false)
+		for _, ts := range tipsets {
+			assert.True(hasBlock(importBs, ts))
+		}
+		has, err := importBs.Has(stateRoot)
+		require.NoError(err)
+		assert.False(has)
+	})
+
+	t.Run("part of the chain with state", func(t *testing.T) {
+		importBs := loadExport(2, true)
+		assert.False(hasBlock(importBs, tipsets[0]))
+		assert.False(hasBlock(importBs, tipsets[1]))
+		assert.True(hasBlock(importBs, tipsets[2]))
+		assert.True(hasBlock(importBs, tipsets[3]))
+
+		// the state can be loaded from the imported blocks alone
+		importCst := &hamt.CborIpldStore{Blocks: bserv.New(importBs, offline.Exchange(importBs))}
+		_, err := hamt.LoadNode(ctx, importCst, stateRoot)
+		assert.NoError(err)
+	})
+}
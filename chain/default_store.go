@@ -14,7 +14,7 @@ import (
 	logging "gx/ipfs/QmbkT7eMTyXfpeyB3ZMxxcxg7XH8t6uXp49jqzz4HB7BGF/go-log"
 	"gx/ipfs/QmdbxjQWogRCHRaxhhGnYdT1oQJzL9GdqSKzCdqWr85AP2/pubsub"
 
-	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -24,6 +24,11 @@ var logStore = logging.Logger("chain.store")
 
 var headKey = datastore.NewKey("/chain/heaviestTipSet")
 
+// ErrStateBelowCheckpoint is returned when asking for the state after a
+// tipset older than the parent of the checkpoint of a chain synced from a
+// snapshot. Snapshots do not hold those states.
+var ErrStateBelowCheckpoint = errors.New("the state after tipsets below the checkpoint the chain was synced from is unknown")
+
 // DefaultStore is a generic implementation of the Store interface.
 // It works(tm) for now.
 type DefaultStore struct {
@@ -46,7 +51,11 @@ type DefaultStore struct {
 	genesis cid.Cid
 	// head is the tipset at the head of the best known chain.
 	head types.TipSet
-	// Protects head and genesisCid.
+	// checkpoint is the key of a tipset trusted to be part of the chain.
+	// The tipsets below it are not validated, they are imported from a
+	// snapshot instead.
+	checkpoint types.SortedCidSet
+	// Protects head, checkpoint and genesisCid.
 	mu sync.RWMutex
 
 	// headEvents is a pubsub channel that publishes an event every time the head changes.
@@ -59,13 +68,17 @@ type DefaultStore struct {
 
 	// Tracks tipsets by height/parentset for use by expected consensus.
 	tipIndex *TipIndex
+
+	// forks tells which actors are available at the height of a tipset.
+	forks consensus.ForkSchedule
 }
 
 // Ensure DefaultStore satisfies the Store interface at compile time.
 var _ Store = (*DefaultStore)(nil)
 
-// NewDefaultStore constructs a new default store.
-func NewDefaultStore(ds repo.Datastore, stateStore *hamt.CborIpldStore, genesisCid cid.Cid) *DefaultStore {
+// NewDefaultStore constructs a new default store. The fork schedule must be
+// the one the chain is validated with.
+func NewDefaultStore(ds repo.Datastore, stateStore *hamt.CborIpldStore, genesisCid cid.Cid, forks consensus.ForkSchedule) *DefaultStore {
 	priv := bstore.NewBlockstore(ds)
 	return &DefaultStore{
 		bsPriv:     priv,
@@ -74,6 +87,7 @@ func NewDefaultStore(ds repo.Datastore, stateStore *hamt.CborIpldStore, genesisC
 		headEvents: pubsub.New(128),
 		tipIndex:   NewTipIndex(),
 		genesis:    genesisCid,
+		forks:      forks,
 	}
 }
 
@@ -367,6 +381,25 @@ func (store *DefaultStore) Head() types.TipSet {
 	return store.head
 }
 
+// Checkpoint returns the key of the trusted checkpoint of the chain, or an
+// empty key if there is none.
+func (store *DefaultStore) Checkpoint() types.SortedCidSet {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.checkpoint
+}
+
+// SetCheckpoint sets the trusted checkpoint of the chain. Chains that do
+// not include the checkpoint are rejected by the syncer, and the tipsets
+// below it must be imported from a snapshot rather than synced.
+func (store *DefaultStore) SetCheckpoint(key types.SortedCidSet) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.checkpoint = key
+}
+
 // BlockHeight returns the chain height of the head tipset.
 // Strictly speaking, the block height is the number of tip sets that appear on chain plus
 // the number of "null blocks" that occur when a mining round fails to produce a block.
@@ -374,7 +407,8 @@ func (store *DefaultStore) BlockHeight() (uint64, error) {
 	return store.Head().Height()
 }
 
-// LatestState returns the state associated with the latest chain head.
+// LatestState returns the state associated with the latest chain head,
+// loaded with the actors available at the height of the head.
 func (store *DefaultStore) LatestState(ctx context.Context) (state.Tree, error) {
 	h := store.Head()
 	if h == nil {
@@ -384,7 +418,29 @@ func (store *DefaultStore) LatestState(ctx context.Context) (state.Tree, error)
 	if err != nil {
 		return nil, err
 	}
-	return state.LoadStateTree(ctx, store.stateStore, tsas.TipSetStateRoot, builtin.Actors)
+	height, err := h.Height()
+	if err != nil {
+		return nil, err
+	}
+	return state.LoadStateTree(ctx, store.stateStore, tsas.TipSetStateRoot, store.forks.ActorsAt(height))
+}
+
+// TipSetState returns the state after the tipset with the provided tipset
+// key, loaded with the actors available at the height of the tipset. It
+// returns ErrStateBelowCheckpoint if that state is not known.
+func (store *DefaultStore) TipSetState(ctx context.Context, tsKey string) (state.Tree, error) {
+	tsas, err := store.GetTipSetAndState(ctx, tsKey)
+	if err != nil {
+		return nil, err
+	}
+	if !tsas.TipSetStateRoot.Defined() {
+		return nil, ErrStateBelowCheckpoint
+	}
+	h, err := tsas.TipSet.Height()
+	if err != nil {
+		return nil, err
+	}
+	return state.LoadStateTree(ctx, store.stateStore, tsas.TipSetStateRoot, store.forks.ActorsAt(h))
 }
 
 // BlockHistory returns a channel of block pointers (or errors), starting with the input tipset
@@ -12,8 +12,10 @@ import (
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
 
+	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/repo"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
@@ -34,7 +36,7 @@ func initStoreTest(ctx context.Context, require *require.Assertions) {
 func newChainStore() chain.Store {
 	r := repo.NewInMemoryRepo()
 	ds := r.Datastore()
-	return chain.NewDefaultStore(ds, hamt.NewCborStore(), genCid)
+	return chain.NewDefaultStore(ds, hamt.NewCborStore(), genCid, consensus.DefaultForkSchedule)
 }
 
 // requirePutTestChain adds all test chain tipsets to the passed in chain store.
@@ -304,7 +306,7 @@ func TestLatestState(t *testing.T) {
 	ds := r.Datastore()
 	bs := bstore.NewBlockstore(ds)
 	cst := hamt.NewCborStore()
-	chain := chain.NewDefaultStore(ds, cst, genCid)
+	chain := chain.NewDefaultStore(ds, cst, genCid, consensus.DefaultForkSchedule)
 
 	requirePutTestChain(require, chain)
 
@@ -325,6 +327,61 @@ func TestLatestState(t *testing.T) {
 	assert.Equal(genStateRoot, c)
 }
 
+// LatestState loads the head state with the actors of the upgrades active at
+// the height of the head.
+func TestLatestStateUsesUpgradedActors(t *testing.T) {
+	ctx := context.Background()
+	initStoreTest(ctx, require.New(t))
+	require := require.New(t)
+	assert := assert.New(t)
+	r := repo.NewInMemoryRepo()
+	ds := r.Datastore()
+	bs := bstore.NewBlockstore(ds)
+	cst := hamt.NewCborStore()
+	_, err := initGenesis(cst, bs)
+	require.NoError(err)
+
+	upgradedCode := types.NewCidForTestGetter()()
+	forks := consensus.ForkSchedule{
+		{Height: 0, Actors: map[cid.Cid]exec.ExecutableActor{upgradedCode: &actor.FakeActor{}}},
+	}
+	chain := chain.NewDefaultStore(ds, cst, genCid, forks)
+	requirePutTestChain(require, chain)
+	assertSetHead(assert, chain, genTS)
+
+	st, err := chain.LatestState(ctx)
+	require.NoError(err)
+	_, err = st.GetBuiltinActorCode(upgradedCode)
+	assert.NoError(err)
+}
+
+// TipSetState returns the state of any stored tipset.
+func TestTipSetState(t *testing.T) {
+	ctx := context.Background()
+	initStoreTest(ctx, require.New(t))
+	require := require.New(t)
+	assert := assert.New(t)
+	r := repo.NewInMemoryRepo()
+	ds := r.Datastore()
+	bs := bstore.NewBlockstore(ds)
+	cst := hamt.NewCborStore()
+	chain := chain.NewDefaultStore(ds, cst, genCid, consensus.DefaultForkSchedule)
+
+	requirePutTestChain(require, chain)
+	_, err := initGenesis(cst, bs)
+	require.NoError(err)
+
+	// the head does not need to be set
+	st, err := chain.TipSetState(ctx, genTS.String())
+	require.NoError(err)
+	c, err := st.Flush(ctx)
+	require.NoError(err)
+	assert.Equal(genStateRoot, c)
+
+	_, err = chain.TipSetState(ctx, types.NewSortedCidSet(types.SomeCid()).String())
+	assert.Error(err)
+}
+
 func assertEmptyCh(assert *assert.Assertions, ch <-chan interface{}) {
 	select {
 	case <-ch:
@@ -467,7 +524,7 @@ func TestLoadAndReboot(t *testing.T) {
 
 	r := repo.NewInMemoryRepo()
 	ds := r.Datastore()
-	chainStore := chain.NewDefaultStore(ds, hamt.NewCborStore(), genCid)
+	chainStore := chain.NewDefaultStore(ds, hamt.NewCborStore(), genCid, consensus.DefaultForkSchedule)
 	requirePutTestChain(require, chainStore)
 	assertSetHead(assert, chainStore, genTS) // set the genesis block
 
@@ -475,7 +532,7 @@ func TestLoadAndReboot(t *testing.T) {
 	chainStore.Stop()
 
 	// rebuild chain with same datastore
-	rebootChain := chain.NewDefaultStore(ds, hamt.NewCborStore(), genCid)
+	rebootChain := chain.NewDefaultStore(ds, hamt.NewCborStore(), genCid, consensus.DefaultForkSchedule)
 	err := rebootChain.Load(ctx)
 	assert.NoError(err)
 
@@ -27,6 +27,12 @@ var (
 	ErrNewChainTooLong = errors.New("input chain forked from best chain too far in the past")
 	// ErrUnexpectedStoreState indicates that the syncer's chain store is violating expected invariants.
 	ErrUnexpectedStoreState = errors.New("the chain store is in an unexpected state")
+	// ErrChainMissesCheckpoint is returned when processing a chain that does not include the checkpoint of the store.
+	ErrChainMissesCheckpoint = errors.New("input chain does not include the checkpoint")
+	// ErrCheckpointNotImported is returned when processing a chain through the checkpoint before its snapshot has been imported.
+	ErrCheckpointNotImported = errors.New("the snapshot of the checkpoint must be imported before syncing through it")
+	// ErrSnapshotNotOfCheckpoint is returned when syncing a snapshot of a tipset other than the checkpoint of the store.
+	ErrSnapshotNotOfCheckpoint = errors.New("snapshot is not of the checkpoint")
 )
 
 var logSyncer = logging.Logger("chain.syncer")
@@ -349,6 +355,9 @@ func (syncer *DefaultSyncer) HandleNewBlocks(ctx context.Context, blkCids []cid.
 		return err
 	}
 	parent := parentTsas.TipSet
+	if err := syncer.checkCheckpoint(ctx, parent, chain); err != nil {
+		return err
+	}
 
 	// Try adding the tipsets of the chain to the store, checking for new
 	// heaviest tipsets.
@@ -381,3 +390,105 @@ func (syncer *DefaultSyncer) HandleNewBlocks(ctx context.Context, blkCids []cid.
 	}
 	return nil
 }
+
+// checkCheckpoint errors unless chain, which extends parent, either starts at
+// the checkpoint of the store or extends a tipset at or above it. Tipsets
+// below the checkpoint are never validated by the syncer.
+func (syncer *DefaultSyncer) checkCheckpoint(ctx context.Context, parent types.TipSet, chain []types.TipSet) error {
+	checkpoint := syncer.chainStore.Checkpoint()
+	if checkpoint.Empty() {
+		return nil
+	}
+
+	for i, ts := range chain {
+		if ts.String() == checkpoint.String() {
+			if i > 0 {
+				return ErrCheckpointNotImported
+			}
+			return nil
+		}
+	}
+
+	if !syncer.chainStore.HasTipSetAndState(ctx, checkpoint.String()) {
+		return ErrChainMissesCheckpoint
+	}
+	checkpointTsas, err := syncer.chainStore.GetTipSetAndState(ctx, checkpoint.String())
+	if err != nil {
+		return err
+	}
+	checkpointHeight, err := checkpointTsas.TipSet.Height()
+	if err != nil {
+		return err
+	}
+	parentHeight, err := parent.Height()
+	if err != nil {
+		return err
+	}
+	if parentHeight < checkpointHeight {
+		return ErrChainMissesCheckpoint
+	}
+	return nil
+}
+
+// SyncSnapshot syncs chain, the tipsets of a snapshot of the checkpoint of
+// the store in order of height starting with genesis, and parentStateRoot,
+// the state after the parent of the checkpoint.  The tipsets below the
+// checkpoint are added to the store without being validated.  Only the state
+// after the parent of the checkpoint is known, the states after the older
+// tipsets are recorded as undefined.  The checkpoint itself is then validated
+// against its parent state like any other tipset, which fails unless
+// parentStateRoot is the state the checkpoint was mined on.
+func (syncer *DefaultSyncer) SyncSnapshot(ctx context.Context, chain []types.TipSet, parentStateRoot cid.Cid) error {
+	syncer.mu.Lock()
+	defer syncer.mu.Unlock()
+
+	checkpoint := syncer.chainStore.Checkpoint()
+	if checkpoint.Empty() {
+		return errors.New("no checkpoint is set")
+	}
+	if len(chain) == 0 || chain[len(chain)-1].String() != checkpoint.String() {
+		return ErrSnapshotNotOfCheckpoint
+	}
+	if chain[0].String() != types.NewSortedCidSet(syncer.chainStore.GenesisCid()).String() {
+		return errors.New("snapshot does not start at genesis")
+	}
+	for i := 1; i < len(chain); i++ {
+		parents, err := chain[i].Parents()
+		if err != nil {
+			return err
+		}
+		if parents.String() != chain[i-1].String() {
+			return errors.Errorf("tipset %s of the snapshot does not extend %s", chain[i].String(), chain[i-1].String())
+		}
+	}
+
+	for i := 1; i < len(chain)-1; i++ {
+		if syncer.chainStore.HasTipSetAndState(ctx, chain[i].String()) {
+			continue
+		}
+		stateRoot := cid.Undef
+		if i == len(chain)-2 {
+			stateRoot = parentStateRoot
+		}
+		err := syncer.chainStore.PutTipSetAndState(ctx, &TipSetAndState{
+			TipSet:          chain[i],
+			TipSetStateRoot: stateRoot,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if syncer.chainStore.HasTipSetAndState(ctx, checkpoint.String()) {
+		return nil
+	}
+	logSyncer.Infof("syncing checkpoint %s", checkpoint.String())
+	return syncer.syncOne(ctx, chain[len(chain)-2], chain[len(chain)-1])
+}
+
+// Pause stops the syncer from writing to the store and the blockstore until
+// resume is called.  It waits for the blocks being handled to be synced.
+func (syncer *DefaultSyncer) Pause() (resume func()) {
+	syncer.mu.Lock()
+	return syncer.mu.Unlock
+}
@@ -189,7 +189,7 @@ func loadSyncerFromRepo(require *require.Assertions, r repo.Repo) (*chain.Defaul
 	require.NoError(err)
 	calcGenBlk.StateRoot = genStateRoot
 	chainDS := r.ChainDatastore()
-	chainStore := chain.NewDefaultStore(chainDS, cst, calcGenBlk.Cid())
+	chainStore := chain.NewDefaultStore(chainDS, cst, calcGenBlk.Cid(), consensus.DefaultForkSchedule)
 
 	blockSource := th.NewTestFetcher()
 	syncer := chain.NewDefaultSyncer(cst, con, chainStore, blockSource) // note we use same cst for on and offline for tests
@@ -235,7 +235,7 @@ func initSyncTest(require *require.Assertions, con consensus.Protocol, genFunc f
 	require.NoError(err)
 	calcGenBlk.StateRoot = genStateRoot
 	chainDS := r.ChainDatastore()
-	chainStore := chain.NewDefaultStore(chainDS, cst, calcGenBlk.Cid())
+	chainStore := chain.NewDefaultStore(chainDS, cst, calcGenBlk.Cid(), consensus.DefaultForkSchedule)
 
 	fetcher := th.NewTestFetcher()
 	syncer := chain.NewDefaultSyncer(cst, con, chainStore, fetcher) // note we use same cst for on and offline for tests
@@ -446,6 +446,60 @@ func TestSyncChainHead(t *testing.T) {
 	assertHead(assert, chainStore, link4)
 }
 
+// Syncer starts from a snapshot of its checkpoint.
+func TestSyncFromSnapshot(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	syncer, chainStore, _, blockSource := initSyncTestDefault(require)
+	ctx := context.Background()
+
+	chainStore.SetCheckpoint(link3.ToSortedCidSet())
+
+	cids1 := requirePutBlocks(require, blockSource, link1.ToSlice()...)
+	_ = requirePutBlocks(require, blockSource, link2.ToSlice()...)
+	_ = requirePutBlocks(require, blockSource, link3.ToSlice()...)
+	cids4 := requirePutBlocks(require, blockSource, link4.ToSlice()...)
+
+	// the syncer does not validate the chain below the checkpoint
+	err := syncer.HandleNewBlocks(ctx, cids4)
+	assert.Equal(chain.ErrCheckpointNotImported, err)
+	assertNoAdd(assert, chainStore, cids1)
+
+	err = syncer.SyncSnapshot(ctx, []types.TipSet{genTS, link1, link2}, link2State)
+	assert.Equal(chain.ErrSnapshotNotOfCheckpoint, err)
+
+	require.NoError(syncer.SyncSnapshot(ctx, []types.TipSet{genTS, link1, link2, link3}, link2State))
+	assertTsAdded(assert, chainStore, link1)
+	assertTsAdded(assert, chainStore, link2)
+	assertTsAdded(assert, chainStore, link3)
+	assertHead(assert, chainStore, link3)
+
+	// only the parent state of the checkpoint is known below it
+	assert.Equal(link2State, requireGetTsas(ctx, require, chainStore, link2.String()).TipSetStateRoot)
+	assert.False(requireGetTsas(ctx, require, chainStore, link1.String()).TipSetStateRoot.Defined())
+
+	// the chain is validated from the checkpoint onwards
+	require.NoError(syncer.HandleNewBlocks(ctx, cids4))
+	assertTsAdded(assert, chainStore, link4)
+	assertHead(assert, chainStore, link4)
+
+	// forks below the checkpoint are rejected
+	signer, ki := types.NewMockSignersAndKeyInfo(1)
+	forkBlk := th.RequireMkFakeChild(require, th.FakeChildParams{
+		Parent:      link2,
+		GenesisCid:  genCid,
+		StateRoot:   genStateRoot,
+		MinerAddr:   minerAddress,
+		Signer:      signer,
+		MinerPubKey: ki[0].PublicKey(),
+		Nonce:       uint64(5),
+	})
+	forkCids := requirePutBlocks(require, blockSource, forkBlk)
+	err = syncer.HandleNewBlocks(ctx, forkCids)
+	assert.Equal(chain.ErrChainMissesCheckpoint, err)
+	assertNoAdd(assert, chainStore, forkCids)
+}
+
 // Syncer determines the heavier fork.
 func TestSyncIgnoreLightFork(t *testing.T) {
 	assert := assert.New(t)
@@ -928,7 +982,7 @@ func TestTipSetWeightDeep(t *testing.T) {
 	var calcGenBlk types.Block
 	require.NoError(cst.Get(ctx, info.GenesisCid, &calcGenBlk))
 
-	chainStore := chain.NewDefaultStore(r.ChainDatastore(), cst, calcGenBlk.Cid())
+	chainStore := chain.NewDefaultStore(r.ChainDatastore(), cst, calcGenBlk.Cid(), consensus.DefaultForkSchedule)
 
 	verifier := proofs.NewFakeVerifier(true, nil)
 	con := consensus.NewExpected(cst, bs, th.NewTestProcessor(), &th.TestView{}, calcGenBlk.Cid(), verifier)
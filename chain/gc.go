@@ -0,0 +1,152 @@
+package chain
+
+import (
+	"context"
+
+	"gx/ipfs/QmNRAuGmvnVw8urHkUZQirhu42VTiZjVWASa2aTznEMmpP/go-merkledag"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+)
+
+// GCResult describes the outcome of a garbage collection.
+type GCResult struct {
+	// Kept is the number of chain blocks that are still reachable.
+	Kept int `json:"kept"`
+	// Removed is the number of chain blocks that were removed, or would
+	// have been in a dry run.
+	Removed int `json:"removed"`
+	// RemovedBytes is the total size of the removed blocks.
+	RemovedBytes uint64 `json:"removedBytes"`
+	// DryRun is set if nothing was actually removed.
+	DryRun bool `json:"dryRun"`
+}
+
+// CollectGarbage removes the blocks of bs holding chain data that are not
+// reachable from the heaviest chain of store: the blocks of every tipset of
+// the chain and the states before and after the last keepTipSets tipsets,
+// including the storage of their actors. Older states, states of forks and
+// actor storage that was replaced are removed. Only dag-cbor blocks are
+// considered, other data such as the pieces of storage deals is left alone.
+// If dryRun is set the result is computed without removing anything.
+//
+// The caller must make sure nothing writes chain data to bs while the
+// collection runs, e.g. by pausing the syncer. Blocks written after the
+// collection started are never removed.
+func CollectGarbage(ctx context.Context, store ReadStore, bs bstore.Blockstore, keepTipSets uint64, dryRun bool) (*GCResult, error) {
+	if keepTipSets == 0 {
+		return nil, errors.New("the state of at least one tipset must be kept")
+	}
+
+	// list the candidates first so blocks written while marking are left
+	// alone
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []cid.Cid
+	for c := range keys {
+		if c.Prefix().Codec == cid.DagCBOR {
+			candidates = append(candidates, c)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	live, err := markChain(ctx, store, bs, keepTipSets)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GCResult{DryRun: dryRun}
+	for _, c := range candidates {
+		if live.Has(c) {
+			result.Kept++
+			continue
+		}
+
+		blk, err := bs.Get(c)
+		if err == bstore.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			if err := bs.DeleteBlock(c); err != nil {
+				return nil, errors.Wrapf(err, "failed to remove block %s", c)
+			}
+		}
+		result.Removed++
+		result.RemovedBytes += uint64(len(blk.RawData()))
+	}
+	return result, nil
+}
+
+// markChain returns the CIDs of the blocks of the heaviest chain of store and
+// of the nodes of the states of its last keepTipSets tipsets.
+func markChain(ctx context.Context, store ReadStore, bs bstore.Blockstore, keepTipSets uint64) (*cid.Set, error) {
+	live := cid.NewSet()
+	dserv := merkledag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+
+	var kept uint64
+	for it := IterAncestors(ctx, store, store.Head()); !it.Complete(); {
+		ts := it.Value()
+		for _, blk := range ts {
+			live.Add(blk.Cid())
+		}
+
+		if kept < keepTipSets {
+			tsas, err := store.GetTipSetAndState(ctx, ts.String())
+			if err != nil {
+				return nil, err
+			}
+			if err := markDAG(ctx, dserv, bs, tsas.TipSetStateRoot, live); err != nil {
+				return nil, err
+			}
+			for _, blk := range ts {
+				if err := markDAG(ctx, dserv, bs, blk.StateRoot, live); err != nil {
+					return nil, err
+				}
+			}
+			kept++
+		}
+
+		if err := it.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+// markDAG adds the CIDs of the nodes of the DAG rooted at root to live. Nodes
+// that are not in bs, such as actor code or the states below a checkpoint,
+// are skipped.
+func markDAG(ctx context.Context, dserv ipld.DAGService, bs bstore.Blockstore, root cid.Cid, live *cid.Set) error {
+	if !root.Defined() || live.Has(root) {
+		return nil
+	}
+	has, err := bs.Has(root)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return nil
+	}
+	live.Add(root)
+
+	nd, err := dserv.Get(ctx, root)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get node %s", root)
+	}
+	for _, l := range nd.Links() {
+		if err := markDAG(ctx, dserv, bs, l.Cid, live); err != nil {
+			return err
+		}
+	}
+	return nil
+}
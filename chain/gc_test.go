@@ -0,0 +1,124 @@
+package chain_test
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+	chainStore, err := chain.Init(ctx, r, bs, cst, consensus.DefaultGenesis)
+	require.NoError(err)
+
+	// every tipset changes the balance of an account, giving each tipset a
+	// state of its own
+	addr := address.NewForTestGetter()()
+	genesis := chainStore.Head()
+	tipsets := []types.TipSet{genesis}
+	stateRoots := []cid.Cid{genesis.ToSlice()[0].StateRoot}
+	for h := uint64(1); h <= 4; h++ {
+		parentRoot := stateRoots[len(stateRoots)-1]
+		st, err := state.LoadStateTree(ctx, cst, parentRoot, builtin.Actors)
+		require.NoError(err)
+		require.NoError(st.SetActor(ctx, addr, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(h))))
+		root, err := st.Flush(ctx)
+		require.NoError(err)
+
+		blk := &types.Block{
+			Parents:   tipsets[len(tipsets)-1].ToSortedCidSet(),
+			Height:    types.Uint64(h),
+			StateRoot: parentRoot,
+		}
+		_, err = cst.Put(ctx, blk)
+		require.NoError(err)
+		ts := th.RequireNewTipSet(require, blk)
+		th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: root})
+		require.NoError(chainStore.SetHead(ctx, ts))
+
+		tipsets = append(tipsets, ts)
+		stateRoots = append(stateRoots, root)
+	}
+
+	// data that is not chain data is never collected
+	piece := blocks.NewBlock([]byte("piece data"))
+	require.NoError(bs.Put(piece))
+
+	has := func(c cid.Cid) bool {
+		has, err := bs.Has(c)
+		require.NoError(err)
+		return has
+	}
+
+	_, err = chain.CollectGarbage(ctx, chainStore, bs, 0, false)
+	assert.Error(err)
+
+	dryRun, err := chain.CollectGarbage(ctx, chainStore, bs, 1, true)
+	require.NoError(err)
+	assert.True(dryRun.DryRun)
+	assert.True(dryRun.Removed > 0)
+	assert.True(dryRun.RemovedBytes > 0)
+	for _, root := range stateRoots {
+		assert.True(has(root))
+	}
+
+	result, err := chain.CollectGarbage(ctx, chainStore, bs, 1, false)
+	require.NoError(err)
+	assert.False(result.DryRun)
+	assert.Equal(dryRun.Removed, result.Removed)
+	assert.Equal(dryRun.RemovedBytes, result.RemovedBytes)
+	assert.Equal(dryRun.Kept, result.Kept)
+
+	// only the states before and after the head are left
+	assert.False(has(stateRoots[0]))
+	assert.False(has(stateRoots[1]))
+	assert.False(has(stateRoots[2]))
+	assert.True(has(stateRoots[3]))
+	assert.True(has(stateRoots[4]))
+	for _, ts := range tipsets {
+		assert.True(has(ts.ToSlice()[0].Cid()))
+	}
+	assert.True(has(piece.Cid()))
+
+	// the kept state is complete
+	st, err := chainStore.LatestState(ctx)
+	require.NoError(err)
+	act, err := st.GetActor(ctx, addr)
+	require.NoError(err)
+	assert.True(types.NewAttoFILFromFIL(4).Equal(act.Balance))
+	require.NoError(st.ForEachActor(ctx, func(addr address.Address, act *actor.Actor) error {
+		if act.Head.Defined() {
+			assert.True(has(act.Head))
+		}
+		return nil
+	}))
+
+	// nothing is left to collect
+	again, err := chain.CollectGarbage(ctx, chainStore, bs, 1, false)
+	require.NoError(err)
+	assert.Equal(0, again.Removed)
+}
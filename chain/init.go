@@ -13,7 +13,8 @@ import (
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
-// Init initializes a DefaultSycner in the given repo.
+// Init initializes a DefaultSycner in the given repo. The returned store
+// loads states with the actors of the default fork schedule.
 func Init(ctx context.Context, r repo.Repo, bs bstore.Blockstore, cst *hamt.CborIpldStore, gen consensus.GenesisInitFunc) (*DefaultStore, error) {
 	// TODO the following should be wrapped in the chain.Store or a sub
 	// interface.
@@ -26,7 +27,7 @@ func Init(ctx context.Context, r repo.Repo, bs bstore.Blockstore, cst *hamt.Cbor
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate genesis block")
 	}
-	chainStore := NewDefaultStore(r.ChainDatastore(), cst, genesis.Cid())
+	chainStore := NewDefaultStore(r.ChainDatastore(), cst, genesis.Cid(), consensus.DefaultForkSchedule)
 
 	// Persist the genesis tipset to the repo.
 	genTsas := &TipSetAndState{
@@ -0,0 +1,271 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/sampling"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// ReplayConsensus is the part of the consensus protocol the Replayer runs
+// tipsets through. It is implemented by consensus.Expected.
+type ReplayConsensus interface {
+	RunStateTransition(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) (state.Tree, error)
+	BlockStates(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) ([]state.Tree, error)
+}
+
+// BlockMismatch describes a block whose StateRoot is not the state computed
+// by replaying its messages.
+type BlockMismatch struct {
+	Block    cid.Cid `json:"block"`
+	Expected cid.Cid `json:"expected"`
+	Computed cid.Cid `json:"computed"`
+
+	// Actors are the actors that differ between the state the block commits
+	// to (Before) and the computed state (After).
+	Actors []*state.ActorDiff `json:"actors"`
+}
+
+// ReplayResult is the outcome of replaying a single tipset.
+type ReplayResult struct {
+	Height uint64 `json:"height"`
+	TipSet string `json:"tipset"`
+
+	// StateRoot is the state computed for the tipset, if the state
+	// transition succeeded.
+	StateRoot cid.Cid `json:"stateRoot,omitempty"`
+
+	// Mismatches lists the blocks of the tipset whose state root differs from
+	// the computed one.
+	Mismatches []*BlockMismatch `json:"mismatches,omitempty"`
+
+	// Error is set if the state transition failed for another reason than a
+	// state root mismatch.
+	Error string `json:"error,omitempty"`
+}
+
+// Replayer re-runs the state transitions of the tipsets in a chain store to
+// find where the states it computes diverge from the ones the chain commits
+// to.
+type Replayer struct {
+	chainReader ReadStore
+	stateStore  *hamt.CborIpldStore
+	consensus   ReplayConsensus
+	forks       consensus.ForkSchedule
+}
+
+// NewReplayer returns a Replayer replaying the heaviest chain of
+// chainReader. The fork schedule must be the one c runs state transitions
+// with.
+func NewReplayer(chainReader ReadStore, stateStore *hamt.CborIpldStore, c ReplayConsensus, forks consensus.ForkSchedule) *Replayer {
+	return &Replayer{
+		chainReader: chainReader,
+		stateStore:  stateStore,
+		consensus:   c,
+		forks:       forks,
+	}
+}
+
+// HeadHeight returns the height of the head of the chain being replayed.
+func (r *Replayer) HeadHeight() (uint64, error) {
+	return r.chainReader.Head().Height()
+}
+
+// Replay replays the tipsets of the heaviest chain with heights from from to
+// to inclusive, in order, and calls cb with the result of each. The genesis
+// tipset has no state transition and is skipped. Replaying a tipset whose
+// parent state is not known fails with ErrStateBelowCheckpoint.
+func (r *Replayer) Replay(ctx context.Context, from, to uint64, cb func(*ReplayResult) error) error {
+	if from > to {
+		return fmt.Errorf("invalid height range %d to %d", from, to)
+	}
+	if from == 0 {
+		from = 1
+	}
+
+	tipSets, err := r.tipSetsBetween(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, ts := range tipSets {
+		res, err := r.replayTipSet(ctx, ts)
+		if err != nil {
+			return err
+		}
+		if err := cb(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tipSetsBetween returns the tipsets of the heaviest chain with heights from
+// from to to inclusive, oldest first.
+func (r *Replayer) tipSetsBetween(ctx context.Context, from, to uint64) ([]types.TipSet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var tipSets []types.TipSet
+	for raw := range r.chainReader.BlockHistory(ctx, r.chainReader.Head()) {
+		switch v := raw.(type) {
+		case error:
+			return nil, v
+		case types.TipSet:
+			h, err := v.Height()
+			if err != nil {
+				return nil, err
+			}
+			if h < from {
+				return tipSets, nil
+			}
+			if h <= to {
+				tipSets = append([]types.TipSet{v}, tipSets...)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected type in channel: %T", raw)
+		}
+	}
+	return tipSets, nil
+}
+
+// replayTipSet runs the state transition of ts on top of its parent state.
+// Errors of the state transition itself are reported in the result.
+func (r *Replayer) replayTipSet(ctx context.Context, ts types.TipSet) (*ReplayResult, error) {
+	h, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	res := &ReplayResult{Height: h, TipSet: ts.String()}
+
+	parentIDs, err := ts.Parents()
+	if err != nil {
+		return nil, err
+	}
+	parent, err := r.chainReader.GetTipSetAndState(ctx, parentIDs.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get parent of tipset at height %d", h)
+	}
+	if !parent.TipSetStateRoot.Defined() {
+		return nil, errors.Wrapf(ErrStateBelowCheckpoint, "cannot replay tipset at height %d", h)
+	}
+	parentHeight, err := parent.TipSet.Height()
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := GetRecentAncestors(ctx, parent.TipSet, r.chainReader, types.NewBlockHeight(h), consensus.AncestorRoundsNeeded, sampling.LookbackParameter)
+	if err != nil {
+		return nil, err
+	}
+
+	loadParentState := func() (state.Tree, error) {
+		return state.LoadStateTree(ctx, r.stateStore, parent.TipSetStateRoot, r.forks.ActorsAt(parentHeight))
+	}
+
+	pSt, err := loadParentState()
+	if err != nil {
+		return nil, err
+	}
+	st, err := r.consensus.RunStateTransition(ctx, ts, ancestors, pSt)
+	if err == consensus.ErrStateRootMismatch {
+		// running the state transition modified the parent state
+		pSt, err = loadParentState()
+		if err != nil {
+			return nil, err
+		}
+		res.Mismatches, err = r.blockMismatches(ctx, ts, ancestors, pSt)
+		return res, err
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res, nil
+	}
+
+	res.StateRoot, err = st.Flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// blockMismatches computes the state of each block of ts and diffs it with
+// the state the block commits to when they differ.
+func (r *Replayer) blockMismatches(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) ([]*BlockMismatch, error) {
+	states, err := r.consensus.BlockStates(ctx, ts, ancestors, pSt)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute block states")
+	}
+
+	var mismatches []*BlockMismatch
+	for i, blk := range ts.ToSlice() {
+		computed, err := states[i].Flush(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if computed.Equals(blk.StateRoot) {
+			continue
+		}
+
+		diff, err := state.DiffActors(ctx, r.stateStore, blk.StateRoot, computed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to diff state of block %s", blk.Cid())
+		}
+
+		mismatches = append(mismatches, &BlockMismatch{
+			Block:    blk.Cid(),
+			Expected: blk.StateRoot,
+			Computed: computed,
+			Actors:   diff,
+		})
+	}
+	return mismatches, nil
+}
+
+// PrintReplayResult writes a human readable description of res to w.
+func PrintReplayResult(w io.Writer, res *ReplayResult) error {
+	var err error
+	switch {
+	case res.Error != "":
+		_, err = fmt.Fprintf(w, "%d %s: error: %s\n", res.Height, res.TipSet, res.Error)
+	case len(res.Mismatches) == 0:
+		_, err = fmt.Fprintf(w, "%d %s: ok %s\n", res.Height, res.TipSet, res.StateRoot)
+	default:
+		_, err = fmt.Fprintf(w, "%d %s: state root mismatch\n", res.Height, res.TipSet)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, m := range res.Mismatches {
+		if _, err := fmt.Fprintf(w, "  block %s: expected %s, computed %s\n", m.Block, m.Expected, m.Computed); err != nil {
+			return err
+		}
+		for _, d := range m.Actors {
+			if _, err := fmt.Fprintf(w, "    %s: %s\n", d.Address, describeActorDiff(d)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describeActorDiff lists the fields that differ between the state a block
+// commits to and the state computed for it.
+func describeActorDiff(d *state.ActorDiff) string {
+	if d.Added() {
+		return "only in computed state"
+	}
+	if d.Removed() {
+		return "only in block state"
+	}
+	return strings.Join(d.Changes(), ", ")
+}
@@ -0,0 +1,117 @@
+package chain_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// divergingConsensus accepts every tipset except those at badHeight, whose
+// blocks it computes a state for that has an extra actor.
+type divergingConsensus struct {
+	badHeight uint64
+	extra     address.Address
+}
+
+func (dc *divergingConsensus) RunStateTransition(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) (state.Tree, error) {
+	h, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	if h == dc.badHeight {
+		return nil, consensus.ErrStateRootMismatch
+	}
+	return pSt, nil
+}
+
+func (dc *divergingConsensus) BlockStates(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) ([]state.Tree, error) {
+	if err := pSt.SetActor(ctx, dc.extra, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))); err != nil {
+		return nil, err
+	}
+	return []state.Tree{pSt}, nil
+}
+
+func TestReplay(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := hamt.NewCborStore()
+	chainStore, err := chain.Init(ctx, r, bs, cst, consensus.DefaultGenesis)
+	require.NoError(err)
+
+	// every block commits to the genesis state
+	parent := chainStore.Head()
+	stateRoot := parent.ToSlice()[0].StateRoot
+	for h := uint64(1); h <= 3; h++ {
+		blk := &types.Block{
+			Parents:   parent.ToSortedCidSet(),
+			Height:    types.Uint64(h),
+			StateRoot: stateRoot,
+		}
+		_, err := cst.Put(ctx, blk)
+		require.NoError(err)
+		parent = th.RequireNewTipSet(require, blk)
+		th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{TipSet: parent, TipSetStateRoot: stateRoot})
+	}
+	require.NoError(chainStore.SetHead(ctx, parent))
+
+	extra := address.NewForTestGetter()()
+	replayer := chain.NewReplayer(chainStore, cst, &divergingConsensus{badHeight: 2, extra: extra}, consensus.DefaultForkSchedule)
+
+	var results []*chain.ReplayResult
+	err = replayer.Replay(ctx, 0, 3, func(res *chain.ReplayResult) error {
+		results = append(results, res)
+		return nil
+	})
+	require.NoError(err)
+
+	// genesis is skipped
+	require.Len(results, 3)
+	assert.Equal(uint64(1), results[0].Height)
+	assert.Equal(stateRoot, results[0].StateRoot)
+	assert.Empty(results[0].Mismatches)
+	assert.Equal(uint64(3), results[2].Height)
+	assert.Empty(results[2].Mismatches)
+
+	mismatched := results[1]
+	assert.Equal(uint64(2), mismatched.Height)
+	require.Len(mismatched.Mismatches, 1)
+	m := mismatched.Mismatches[0]
+	assert.Equal(stateRoot, m.Expected)
+	assert.NotEqual(stateRoot, m.Computed)
+	require.Len(m.Actors, 1)
+	assert.Equal(extra, m.Actors[0].Address)
+	assert.Nil(m.Actors[0].Before)
+
+	var out bytes.Buffer
+	require.NoError(chain.PrintReplayResult(&out, mismatched))
+	assert.Contains(out.String(), "state root mismatch")
+	assert.Contains(out.String(), extra.String()+": only in computed state")
+
+	t.Run("only the requested heights are replayed", func(t *testing.T) {
+		var heights []uint64
+		err := replayer.Replay(ctx, 2, 2, func(res *chain.ReplayResult) error {
+			heights = append(heights, res.Height)
+			return nil
+		})
+		require.NoError(err)
+		assert.Equal([]uint64{2}, heights)
+	})
+}
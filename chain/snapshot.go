@@ -0,0 +1,181 @@
+package chain
+
+import (
+	"context"
+	"io"
+
+	"gx/ipfs/QmNRAuGmvnVw8urHkUZQirhu42VTiZjVWASa2aTznEMmpP/go-merkledag"
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	"gx/ipfs/QmUGpiTCKct5s1F7jaAnY9KJmoo7Qm1R2uhSjq5iHDSUMn/go-car"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func init() {
+	cbor.RegisterCborType(snapshotHeader{})
+}
+
+// snapshotHeader is the root of a snapshot. Besides the checkpoint it names
+// the parent state of the checkpoint, which the blocks of the checkpoint do
+// not link to: each of them links to the state after its own messages only.
+type snapshotHeader struct {
+	Checkpoint      types.SortedCidSet
+	ParentStateRoot cid.Cid
+}
+
+// ExportSnapshot writes a snapshot of the chain at checkpoint to w as a CAR
+// file whose root is a header naming checkpoint and its parent state. The
+// snapshot holds the blocks of checkpoint and all of its ancestors, along
+// with the parent state of checkpoint, which is all a node needs to sync from
+// checkpoint onwards. The header is written to bs.
+func ExportSnapshot(ctx context.Context, store ReadStore, bs bstore.Blockstore, checkpoint types.TipSet, w io.Writer) error {
+	parentIDs, err := checkpoint.Parents()
+	if err != nil {
+		return err
+	}
+	if parentIDs.Empty() {
+		return errors.New("cannot take a snapshot at genesis")
+	}
+	parent, err := store.GetTipSetAndState(ctx, parentIDs.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to get the parent of the checkpoint")
+	}
+	if !parent.TipSetStateRoot.Defined() {
+		return errors.New("the parent state of the checkpoint is unknown")
+	}
+
+	blocks := cid.NewSet()
+	for it := IterAncestors(ctx, store, checkpoint); !it.Complete(); {
+		for _, blk := range it.Value() {
+			blocks.Add(blk.Cid())
+		}
+		if err := it.Next(); err != nil {
+			return err
+		}
+	}
+
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+	root, err := cst.Put(ctx, &snapshotHeader{
+		Checkpoint:      checkpoint.ToSortedCidSet(),
+		ParentStateRoot: parent.TipSetStateRoot,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to store snapshot header")
+	}
+
+	return writeCar(ctx, bs, []cid.Cid{root}, blocks, cid.NewSet(), w)
+}
+
+// ImportSnapshot loads the snapshot read from r into bs and hands the chain
+// and parent state of the checkpoint it holds to syncer, which checks that
+// the snapshot is of its checkpoint. ImportSnapshot returns the key of the
+// checkpoint.
+func ImportSnapshot(ctx context.Context, bs bstore.Blockstore, syncer Syncer, r io.Reader) (types.SortedCidSet, error) {
+	carHeader, err := car.LoadCar(bs, r)
+	if err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "failed to load snapshot")
+	}
+	if len(carHeader.Roots) != 1 {
+		return types.SortedCidSet{}, errors.New("not a snapshot: expected a single root")
+	}
+
+	raw, err := bs.Get(carHeader.Roots[0])
+	if err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "snapshot is missing its header")
+	}
+	var header snapshotHeader
+	if err := cbor.DecodeInto(raw.RawData(), &header); err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "not a snapshot")
+	}
+	if header.Checkpoint.Empty() || !header.ParentStateRoot.Defined() {
+		return types.SortedCidSet{}, errors.New("not a snapshot: incomplete header")
+	}
+
+	chain, err := snapshotChain(bs, header.Checkpoint.ToSlice())
+	if err != nil {
+		return types.SortedCidSet{}, err
+	}
+
+	// the parent state of the checkpoint must be complete since the
+	// syncer has no way to fetch what is missing
+	if err := requireDAG(ctx, bs, header.ParentStateRoot); err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "snapshot is missing the parent state of the checkpoint")
+	}
+
+	if err := syncer.SyncSnapshot(ctx, chain, header.ParentStateRoot); err != nil {
+		return types.SortedCidSet{}, errors.Wrap(err, "failed to sync snapshot")
+	}
+	return header.Checkpoint, nil
+}
+
+// snapshotChain reads the tipset made of roots and all of its ancestors from
+// bs and returns them in order of height, starting with genesis.
+func snapshotChain(bs bstore.Blockstore, roots []cid.Cid) ([]types.TipSet, error) {
+	var chain []types.TipSet
+	for cids := roots; len(cids) > 0; {
+		var blks []*types.Block
+		for _, c := range cids {
+			raw, err := bs.Get(c)
+			if err != nil {
+				return nil, errors.Wrapf(err, "snapshot is missing block %s", c)
+			}
+			blk, err := types.DecodeBlock(raw.RawData())
+			if err != nil {
+				return nil, err
+			}
+			blks = append(blks, blk)
+		}
+
+		ts, err := types.NewTipSet(blks...)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, ts)
+
+		parents, err := ts.Parents()
+		if err != nil {
+			return nil, err
+		}
+		cids = parents.ToSlice()
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// requireDAG errors unless every node of the DAG rooted at root, apart from
+// actor code, is in bs.
+func requireDAG(ctx context.Context, bs bstore.Blockstore, root cid.Cid) error {
+	dserv := &exportDAGService{
+		DAGService: merkledag.NewDAGService(bserv.New(bs, offline.Exchange(bs))),
+		blocks:     cid.NewSet(),
+		states:     cid.NewSet(),
+	}
+	seen := cid.NewSet()
+
+	var walk func(c cid.Cid) error
+	walk = func(c cid.Cid) error {
+		if !seen.Visit(c) {
+			return nil
+		}
+		nd, err := dserv.Get(ctx, c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get node %s", c)
+		}
+		for _, l := range nd.Links() {
+			if err := walk(l.Cid); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
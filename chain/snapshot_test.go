@@ -0,0 +1,286 @@
+package chain_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/sampling"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// droppingBlockstore silently drops the block with CID drop instead of
+// storing it.
+type droppingBlockstore struct {
+	bstore.Blockstore
+
+	drop cid.Cid
+}
+
+func (bs *droppingBlockstore) Put(blk blocks.Block) error {
+	if blk.Cid().Equals(bs.drop) {
+		return nil
+	}
+	return bs.Blockstore.Put(blk)
+}
+
+func (bs *droppingBlockstore) PutMany(blks []blocks.Block) error {
+	for _, blk := range blks {
+		if err := bs.Put(blk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportImportSnapshot(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+	chainStore, err := chain.Init(ctx, r, bs, cst, consensus.DefaultGenesis)
+	require.NoError(err)
+
+	genesis := chainStore.Head()
+	stateRoot := genesis.ToSlice()[0].StateRoot
+	tipsets := []types.TipSet{genesis}
+	for h := uint64(1); h <= 3; h++ {
+		blk := &types.Block{
+			Parents:   tipsets[len(tipsets)-1].ToSortedCidSet(),
+			Height:    types.Uint64(h),
+			StateRoot: stateRoot,
+		}
+		_, err := cst.Put(ctx, blk)
+		require.NoError(err)
+		ts := th.RequireNewTipSet(require, blk)
+		th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: stateRoot})
+		tipsets = append(tipsets, ts)
+	}
+	checkpoint := tipsets[2]
+
+	t.Run("the snapshot holds the chain up to the checkpoint", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(chain.ExportSnapshot(ctx, chainStore, bs, checkpoint, &buf))
+
+		importBs := bstore.NewBlockstore(datastore.NewMapDatastore())
+		syncer := &recordingSyncer{}
+		key, err := chain.ImportSnapshot(ctx, importBs, syncer, &buf)
+		require.NoError(err)
+		assert.True(checkpoint.ToSortedCidSet().Equals(key))
+
+		require.Len(syncer.snapshot, 3)
+		for i, ts := range syncer.snapshot {
+			assert.Equal(tipsets[i].String(), ts.String())
+		}
+		assert.Equal(stateRoot, syncer.parentStateRoot)
+
+		has, err := importBs.Has(tipsets[3].ToSlice()[0].Cid())
+		require.NoError(err)
+		assert.False(has)
+	})
+
+	t.Run("the snapshot must include the parent state of the checkpoint", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(chain.ExportSnapshot(ctx, chainStore, bs, checkpoint, &buf))
+
+		importBs := &droppingBlockstore{
+			Blockstore: bstore.NewBlockstore(datastore.NewMapDatastore()),
+			drop:       stateRoot,
+		}
+		syncer := &recordingSyncer{}
+		_, err := chain.ImportSnapshot(ctx, importBs, syncer, &buf)
+		assert.Error(err)
+		assert.Contains(err.Error(), "missing the parent state of the checkpoint")
+		assert.Nil(syncer.snapshot)
+	})
+
+	t.Run("an export of the chain is not a snapshot", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(chain.Export(ctx, chainStore, bs, checkpoint, 0, true, &buf))
+
+		importBs := bstore.NewBlockstore(datastore.NewMapDatastore())
+		syncer := &recordingSyncer{}
+		_, err := chain.ImportSnapshot(ctx, importBs, syncer, &buf)
+		assert.Error(err)
+		assert.Contains(err.Error(), "not a snapshot")
+		assert.Nil(syncer.snapshot)
+	})
+
+	t.Run("there is no snapshot at genesis", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(chain.ExportSnapshot(ctx, chainStore, bs, genesis, &buf))
+	})
+}
+
+// A node syncs from a snapshot of a checkpoint whose parent is a tipset of
+// several blocks. Every block pays a block reward, so the state after that
+// tipset differs from the state roots of its blocks and of the checkpoint.
+func TestSyncFromSnapshotWithExpectedConsensus(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	type testNode struct {
+		bs    bstore.Blockstore
+		cst   *hamt.CborIpldStore
+		store *chain.DefaultStore
+		con   *consensus.Expected
+	}
+	newNode := func() *testNode {
+		r := repo.NewInMemoryRepo()
+		bs := bstore.NewBlockstore(r.Datastore())
+		cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+		genBlk, err := initGenesis(cst, bs)
+		require.NoError(err)
+		require.True(genCid.Equals(genBlk.Cid()))
+
+		store := chain.NewDefaultStore(r.ChainDatastore(), cst, genCid, consensus.DefaultForkSchedule)
+		th.RequirePutTsas(ctx, require, store, &chain.TipSetAndState{TipSet: genTS, TipSetStateRoot: genStateRoot})
+		require.NoError(store.SetHead(ctx, genTS))
+
+		con := consensus.NewExpected(cst, bs, consensus.NewDefaultProcessor(), &th.TestView{}, genCid, proofs.NewFakeVerifier(true, nil))
+		return &testNode{bs: bs, cst: cst, store: store, con: con.(*consensus.Expected)}
+	}
+
+	mockSigner, _ := types.NewMockSignersAndKeyInfo(1)
+	pubKey := mockSigner.PubKeys[0]
+
+	// mine adds a tipset of n blocks on top of parent to the store of nd,
+	// with the states consensus computes for them.
+	mine := func(nd *testNode, parent types.TipSet, n int) types.TipSet {
+		parentTsas, err := nd.store.GetTipSetAndState(ctx, parent.String())
+		require.NoError(err)
+		h, err := parent.Height()
+		require.NoError(err)
+		ancestors, err := chain.GetRecentAncestors(ctx, parent, nd.store, types.NewBlockHeight(h+1), consensus.AncestorRoundsNeeded, sampling.LookbackParameter)
+		require.NoError(err)
+		loadParentState := func() state.Tree {
+			st, err := state.LoadStateTree(ctx, nd.cst, parentTsas.TipSetStateRoot, builtin.Actors)
+			require.NoError(err)
+			return st
+		}
+
+		var blks []*types.Block
+		for i := 0; i < n; i++ {
+			blk := th.RequireMkFakeChildWithCon(require, th.FakeChildParams{
+				Parent:      parent,
+				GenesisCid:  genCid,
+				StateRoot:   parentTsas.TipSetStateRoot,
+				Consensus:   nd.con,
+				MinerAddr:   minerAddress,
+				MinerPubKey: pubKey,
+				Signer:      mockSigner,
+				Nonce:       uint64(i),
+			})
+			blk.Proof, blk.Ticket, err = th.MakeProofAndWinningTicket(pubKey, 25, 100, mockSigner)
+			require.NoError(err)
+			blks = append(blks, blk)
+		}
+
+		// each block commits to the state after its own messages
+		unsealed := th.RequireNewTipSet(require, blks...)
+		states, err := nd.con.BlockStates(ctx, unsealed, ancestors, loadParentState())
+		require.NoError(err)
+		for i, blk := range unsealed.ToSlice() {
+			blk.StateRoot, err = states[i].Flush(ctx)
+			require.NoError(err)
+		}
+
+		ts := th.RequireNewTipSet(require, blks...)
+		st, err := nd.con.RunStateTransition(ctx, ts, ancestors, loadParentState())
+		require.NoError(err)
+		root, err := st.Flush(ctx)
+		require.NoError(err)
+
+		for _, blk := range blks {
+			_, err := nd.cst.Put(ctx, blk)
+			require.NoError(err)
+		}
+		th.RequirePutTsas(ctx, require, nd.store, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: root})
+		require.NoError(nd.store.SetHead(ctx, ts))
+		return ts
+	}
+
+	src := newNode()
+	ts1 := mine(src, genTS, 2)
+	ts2 := mine(src, ts1, 2)
+	checkpoint := mine(src, ts2, 1)
+	ts4 := mine(src, checkpoint, 1)
+
+	parentStateRoot := requireGetTsas(ctx, require, src.store, ts2.String()).TipSetStateRoot
+	for _, blk := range append(ts2.ToSlice(), checkpoint.ToSlice()...) {
+		require.False(parentStateRoot.Equals(blk.StateRoot))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(chain.ExportSnapshot(ctx, src.store, src.bs, checkpoint, &buf))
+
+	dst := newNode()
+	dst.store.SetCheckpoint(checkpoint.ToSortedCidSet())
+	fetcher := th.NewTestFetcher()
+	syncer := chain.NewDefaultSyncer(dst.cst, dst.con, dst.store, fetcher)
+
+	key, err := chain.ImportSnapshot(ctx, dst.bs, syncer, &buf)
+	require.NoError(err)
+	assert.True(checkpoint.ToSortedCidSet().Equals(key))
+	assertHead(assert, dst.store, checkpoint)
+
+	// the checkpoint was validated on top of the parent state in the
+	// snapshot, the only state known below it
+	assert.Equal(parentStateRoot, requireGetTsas(ctx, require, dst.store, ts2.String()).TipSetStateRoot)
+	assert.False(requireGetTsas(ctx, require, dst.store, ts1.String()).TipSetStateRoot.Defined())
+	assert.Equal(
+		requireGetTsas(ctx, require, src.store, checkpoint.String()).TipSetStateRoot,
+		requireGetTsas(ctx, require, dst.store, checkpoint.String()).TipSetStateRoot,
+	)
+
+	// only the states from the parent of the checkpoint onwards can be read
+	_, err = dst.store.TipSetState(ctx, ts1.String())
+	assert.Equal(chain.ErrStateBelowCheckpoint, err)
+	_, err = dst.store.TipSetState(ctx, ts2.String())
+	assert.NoError(err)
+
+	replayer := chain.NewReplayer(dst.store, dst.cst, dst.con, consensus.DefaultForkSchedule)
+	err = replayer.Replay(ctx, 2, 2, func(*chain.ReplayResult) error { return nil })
+	assert.Equal(chain.ErrStateBelowCheckpoint, errors.Cause(err))
+	var replayed []*chain.ReplayResult
+	require.NoError(replayer.Replay(ctx, 3, 3, func(res *chain.ReplayResult) error {
+		replayed = append(replayed, res)
+		return nil
+	}))
+	require.Len(replayed, 1)
+	assert.Empty(replayed[0].Error)
+	assert.Empty(replayed[0].Mismatches)
+
+	var export bytes.Buffer
+	require.NoError(chain.Export(ctx, dst.store, dst.bs, checkpoint, 0, true, &export))
+
+	// the chain is validated from the checkpoint onwards
+	require.NoError(syncer.HandleNewBlocks(ctx, requirePutBlocks(require, fetcher, ts4.ToSlice()...)))
+	assertHead(assert, dst.store, ts4)
+	assert.Equal(
+		requireGetTsas(ctx, require, src.store, ts4.String()).TipSetStateRoot,
+		requireGetTsas(ctx, require, dst.store, ts4.String()).TipSetStateRoot,
+	)
+}
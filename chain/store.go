@@ -39,6 +39,9 @@ type ReadStore interface {
 	Head() types.TipSet
 	// LatestState returns the latest state of the head
 	LatestState(ctx context.Context) (state.Tree, error)
+	// TipSetState returns the state after the tipset with the provided
+	// tipset key, or ErrStateBelowCheckpoint if it is not known.
+	TipSetState(ctx context.Context, tsKey string) (state.Tree, error)
 
 	BlockHistory(ctx context.Context, tips types.TipSet) <-chan interface{}
 
@@ -74,4 +77,10 @@ type Store interface {
 
 	// SetHead sets the internally tracked  head to the provided tipset.
 	SetHead(ctx context.Context, s types.TipSet) error
+
+	// Checkpoint returns the key of the trusted checkpoint of the chain, or
+	// an empty key if there is none.
+	Checkpoint() types.SortedCidSet
+	// SetCheckpoint sets the trusted checkpoint of the chain.
+	SetCheckpoint(key types.SortedCidSet)
 }
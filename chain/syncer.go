@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/types"
 )
 
 // Syncer handles new blocks, either from the network or the local node's
@@ -19,4 +21,12 @@ import (
 // after too many blocks.
 type Syncer interface {
 	HandleNewBlocks(ctx context.Context, blkCids []cid.Cid) error
+	// SyncSnapshot syncs the chain of a snapshot of the checkpoint of the
+	// store, from genesis up to and including the checkpoint, along with the
+	// parent state of the checkpoint.  Only the checkpoint is validated, the
+	// tipsets below it are trusted.
+	SyncSnapshot(ctx context.Context, chain []types.TipSet, parentStateRoot cid.Cid) error
+	// Pause stops the syncer from writing to the store and the blockstore
+	// until resume is called.  Blocks handled in the meantime wait for it.
+	Pause() (resume func())
 }
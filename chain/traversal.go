@@ -2,6 +2,7 @@ package chain
 
 import (
 	"context"
+	"fmt"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 
@@ -62,3 +63,30 @@ func (it *TipsetIterator) Next() error {
 	it.value, it.err = GetParentTipSet(it.ctx, it.store, it.value)
 	return it.err
 }
+
+// FindTipSetAtHeight returns the tipset at the given height among start and
+// its ancestors. When there is no tipset at that height because it was a null
+// round, the closest tipset below it is returned.
+func FindTipSetAtHeight(ctx context.Context, store BlockProvider, start types.TipSet, height uint64) (types.TipSet, error) {
+	startHeight, err := start.Height()
+	if err != nil {
+		return nil, err
+	}
+	if height > startHeight {
+		return nil, fmt.Errorf("height %d is above the height of tipset %s (%d)", height, start, startHeight)
+	}
+
+	for it := IterAncestors(ctx, store, start); !it.Complete(); {
+		h, err := it.Value().Height()
+		if err != nil {
+			return nil, err
+		}
+		if h <= height {
+			return it.Value(), nil
+		}
+		if err := it.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("no tipset at or below height %d", height)
+}
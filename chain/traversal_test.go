@@ -80,3 +80,33 @@ func requireTipset(t *testing.T, blocks ...*types.Block) types.TipSet {
 	require.NoError(t, err)
 	return set
 }
+
+func TestFindTipSetAtHeight(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	store := th.NewFakeBlockProvider()
+
+	root := store.NewBlock(0)
+	b1 := store.NewBlock(1, root)
+	// b3 follows a null round at height 2
+	b3 := store.NewBlock(2, b1)
+	b3.Height = 3
+	head := requireTipset(t, b3)
+
+	ts, err := chain.FindTipSetAtHeight(ctx, store, head, 3)
+	require.NoError(err)
+	assert.True(head.Equals(ts))
+
+	ts, err = chain.FindTipSetAtHeight(ctx, store, head, 2)
+	require.NoError(err)
+	assert.True(requireTipset(t, b1).Equals(ts))
+
+	ts, err = chain.FindTipSetAtHeight(ctx, store, head, 0)
+	require.NoError(err)
+	assert.True(requireTipset(t, root).Equals(ts))
+
+	_, err = chain.FindTipSetAtHeight(ctx, store, head, 4)
+	assert.Error(err)
+}
@@ -0,0 +1,47 @@
+// Package clients contains the support code of the typed clients of the
+// builtin actors. The clients themselves are generated from the exports of
+// each actor by clients/gen into one package per actor, e.g. minerclient.
+package clients
+
+//go:generate go run ./gen -root ..
+
+import (
+	"context"
+	"fmt"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// API is the part of the plumbing API the generated clients call actors
+// through.
+type API interface {
+	MessageSend(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+}
+
+// SendOpts are the parts of a message sent by a generated client that are
+// not parameters of the actor method it calls.
+type SendOpts struct {
+	From     address.Address
+	Value    *types.AttoFIL
+	GasPrice types.AttoFIL
+	GasLimit types.GasUnits
+}
+
+// Return decodes the i-th value returned by a query, which is expected to be
+// of type t.
+func Return(rets [][]byte, i int, t abi.Type) (interface{}, error) {
+	if i >= len(rets) {
+		return nil, fmt.Errorf("expected at least %d return values, got %d", i+1, len(rets))
+	}
+	val, err := abi.Deserialize(rets[i], t)
+	if err != nil {
+		return nil, err
+	}
+	return val.Val, nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/vesting"
+	"github.com/filecoin-project/go-filecoin/exec"
+)
+
+// actorSpec describes a builtin actor to generate a client for.
+type actorSpec struct {
+	// Name is the name of the actor's package in actor/builtin. The client
+	// package is named after it.
+	Name    string
+	Exports exec.Exports
+
+	// Address is the expression of the address of the actor if there is
+	// only one instance of it. Clients of other actors take the address of
+	// the actor to call.
+	Address string
+}
+
+var actorSpecs = []actorSpec{
+	{Name: "initactor", Exports: (&initactor.Actor{}).Exports(), Address: "address.InitAddress"},
+	{Name: "miner", Exports: (&miner.Actor{}).Exports()},
+	{Name: "multisig", Exports: (&multisig.Actor{}).Exports()},
+	{Name: "paymentbroker", Exports: (&paymentbroker.Actor{}).Exports(), Address: "address.PaymentBrokerAddress"},
+	{Name: "storagemarket", Exports: (&storagemarket.Actor{}).Exports(), Address: "address.StorageMarketAddress"},
+	{Name: "vesting", Exports: (&vesting.Actor{}).Exports()},
+}
+
+// abiTypeNames are the names of the abi.Type constants.
+var abiTypeNames = map[abi.Type]string{
+	abi.Address:        "Address",
+	abi.AttoFIL:        "AttoFIL",
+	abi.BytesAmount:    "BytesAmount",
+	abi.ChannelID:      "ChannelID",
+	abi.BlockHeight:    "BlockHeight",
+	abi.Integer:        "Integer",
+	abi.Bytes:          "Bytes",
+	abi.String:         "String",
+	abi.UintArray:      "UintArray",
+	abi.PeerID:         "PeerID",
+	abi.SectorID:       "SectorID",
+	abi.CommitmentsMap: "CommitmentsMap",
+	abi.PoStProofs:     "PoStProofs",
+	abi.Boolean:        "Boolean",
+	abi.Uint64:         "Uint64",
+}
+
+// imports are the packages generated code may refer to, by name.
+var imports = []struct{ name, path string }{
+	{"context", "context"},
+	{"big", "math/big"},
+	{"", ""},
+	{"cid", "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"},
+	{"peer", "gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"},
+	{"", ""},
+	{"abi", "github.com/filecoin-project/go-filecoin/abi"},
+	{"address", "github.com/filecoin-project/go-filecoin/address"},
+	{"clients", "github.com/filecoin-project/go-filecoin/clients"},
+	{"proofs", "github.com/filecoin-project/go-filecoin/proofs"},
+	{"types", "github.com/filecoin-project/go-filecoin/types"},
+}
+
+// reservedNames may not be used as parameter names in generated code.
+var reservedNames = map[string]bool{
+	"ctx": true, "api": true, "opts": true, "from": true, "to": true,
+	"rets": true, "err": true,
+}
+
+func init() {
+	for _, imp := range imports {
+		if imp.name != "" {
+			reservedNames[imp.name] = true
+		}
+	}
+}
+
+// clientPackage returns the name of the client package of the actor.
+func clientPackage(spec actorSpec) string {
+	return spec.Name + "client"
+}
+
+// clientPath returns the path of the generated client of the actor relative
+// to the root of the repository.
+func clientPath(root string, spec actorSpec) string {
+	return filepath.Join(root, "clients", clientPackage(spec), "client_gen.go")
+}
+
+// generate returns the source of the client of the actor described by spec.
+// Parameters are named after the parameters of the actor's methods, which
+// are read from the actor's source in root.
+func generate(root string, spec actorSpec) ([]byte, error) {
+	paramNames, err := methodParamNames(filepath.Join(root, "actor", "builtin", spec.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []string
+	for method := range spec.Exports {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var body bytes.Buffer
+	for _, method := range methods {
+		sig := spec.Exports[method]
+		funcName := strings.Title(method)
+		params, err := paramList(sig.Params, paramNames[funcName])
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %s", spec.Name, method, err)
+		}
+		if err := writeSend(&body, spec, method, params); err != nil {
+			return nil, err
+		}
+		if err := writeQuery(&body, spec, method, params, sig.Return); err != nil {
+			return nil, fmt.Errorf("%s.%s: %s", spec.Name, method, err)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clients/gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "// Package %s sends messages to and queries the %s actor.\n", clientPackage(spec), spec.Name)
+	fmt.Fprintf(&out, "package %s\n\n", clientPackage(spec))
+	fmt.Fprintf(&out, "import (\n")
+	for _, imp := range imports {
+		if imp.name == "" {
+			fmt.Fprintf(&out, "\n")
+			continue
+		}
+		if regexp.MustCompile(`\b` + imp.name + `\.`).Match(body.Bytes()) {
+			fmt.Fprintf(&out, "\t%q\n", imp.path)
+		}
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(body.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+type param struct {
+	name string
+	typ  abi.Type
+}
+
+// paramList pairs the types of the parameters of a method with their names.
+func paramList(types []abi.Type, names []string) ([]param, error) {
+	params := make([]param, len(types))
+	for i, t := range types {
+		if _, ok := abiTypeNames[t]; !ok {
+			return nil, fmt.Errorf("unsupported abi type %d", t)
+		}
+		name := fmt.Sprintf("p%d", i)
+		if len(names) == len(types) && names[i] != "_" {
+			name = names[i]
+		}
+		if reservedNames[name] {
+			name += "Param"
+		}
+		params[i] = param{name: name, typ: t}
+	}
+	return params, nil
+}
+
+// writeSend writes a function sending a message that calls method.
+func writeSend(w *bytes.Buffer, spec actorSpec, method string, params []param) error {
+	to, toParam := actorAddress(spec)
+	fmt.Fprintf(w, "\n// %s sends a message calling %s on the %s actor.\n", strings.Title(method), method, spec.Name)
+	fmt.Fprintf(w, "func %s(ctx context.Context, api clients.API, opts clients.SendOpts%s%s) (cid.Cid, error) {\n",
+		strings.Title(method), toParam, paramDecls(params))
+	fmt.Fprintf(w, "\treturn api.MessageSend(ctx, opts.From, %s, opts.Value, opts.GasPrice, opts.GasLimit, %q%s)\n", to, method, paramArgs(params))
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+// writeQuery writes a function querying method and decoding its return
+// values.
+func writeQuery(w *bytes.Buffer, spec actorSpec, method string, params []param, rets []abi.Type) error {
+	to, toParam := actorAddress(spec)
+
+	var retDecls []string
+	for i, t := range rets {
+		if _, ok := abiTypeNames[t]; !ok {
+			return fmt.Errorf("unsupported abi type %d", t)
+		}
+		retDecls = append(retDecls, fmt.Sprintf("ret%d %s", i, t))
+	}
+	retDecls = append(retDecls, "err error")
+
+	fmt.Fprintf(w, "\n// Query%s returns the result of calling %s on the %s actor without\n// sending a message.\n", strings.Title(method), method, spec.Name)
+	fmt.Fprintf(w, "func Query%s(ctx context.Context, api clients.API, from address.Address%s%s) (%s) {\n",
+		strings.Title(method), toParam, paramDecls(params), strings.Join(retDecls, ", "))
+
+	zeros := make([]string, 0, len(rets)+1)
+	for i := range rets {
+		zeros = append(zeros, fmt.Sprintf("ret%d", i))
+	}
+	zeros = append(zeros, "err")
+
+	if len(rets) == 0 {
+		fmt.Fprintf(w, "\t_, _, err = api.MessageQuery(ctx, from, %s, %q%s)\n", to, method, paramArgs(params))
+		fmt.Fprintf(w, "\treturn err\n")
+		fmt.Fprintf(w, "}\n")
+		return nil
+	}
+
+	fmt.Fprintf(w, "\trets, _, err := api.MessageQuery(ctx, from, %s, %q%s)\n", to, method, paramArgs(params))
+	fmt.Fprintf(w, "\tif err != nil {\n\t\treturn %s\n\t}\n", strings.Join(zeros, ", "))
+
+	results := make([]string, 0, len(rets)+1)
+	for i, t := range rets {
+		fmt.Fprintf(w, "\tv%d, err := clients.Return(rets, %d, abi.%s)\n", i, i, abiTypeNames[t])
+		fmt.Fprintf(w, "\tif err != nil {\n\t\treturn %s\n\t}\n", strings.Join(zeros, ", "))
+		results = append(results, fmt.Sprintf("v%d.(%s)", i, t))
+	}
+	results = append(results, "nil")
+	fmt.Fprintf(w, "\treturn %s\n", strings.Join(results, ", "))
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+// actorAddress returns the expression of the address of the actor to call
+// and the declaration of the parameter holding it, if any.
+func actorAddress(spec actorSpec) (string, string) {
+	if spec.Address != "" {
+		return spec.Address, ""
+	}
+	return "to", ", to address.Address"
+}
+
+func paramDecls(params []param) string {
+	var decls string
+	for _, p := range params {
+		decls += fmt.Sprintf(", %s %s", p.name, p.typ)
+	}
+	return decls
+}
+
+func paramArgs(params []param) string {
+	var args string
+	for _, p := range params {
+		args += ", " + p.name
+	}
+	return args
+}
+
+// methodParamNames parses the actor package in dir and returns the names of
+// the parameters of the methods of its Actor type, without the leading
+// VMContext.
+func methodParamNames(dir string) (map[string][]string, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string][]string)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv == nil || !isActorReceiver(fn.Recv) {
+					continue
+				}
+
+				var params []string
+				for _, field := range fn.Type.Params.List {
+					for _, name := range field.Names {
+						params = append(params, name.Name)
+					}
+				}
+				if len(params) > 0 {
+					names[fn.Name.Name] = params[1:]
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// isActorReceiver returns true if recv is a receiver of type *Actor.
+func isActorReceiver(recv *ast.FieldList) bool {
+	if len(recv.List) != 1 {
+		return false
+	}
+	star, ok := recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := star.X.(*ast.Ident)
+	return ok && ident.Name == "Actor"
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+)
+
+func TestGeneratedClientsAreUpToDate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	for _, spec := range actorSpecs {
+		expected, err := generate("../..", spec)
+		require.NoError(err)
+
+		actual, err := ioutil.ReadFile(clientPath("../..", spec))
+		require.NoError(err, "client of %s is missing, run go generate in clients", spec.Name)
+		assert.Equal(string(expected), string(actual), "client of %s is out of date, run go generate in clients", spec.Name)
+	}
+}
+
+func TestGenerateNamesParametersAfterActorMethods(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	for _, spec := range actorSpecs {
+		if spec.Name != "miner" {
+			continue
+		}
+		src, err := generate("../..", spec)
+		require.NoError(err)
+		assert.Contains(string(src), "func AddAsk(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, price *types.AttoFIL, expiry *big.Int) (cid.Cid, error)")
+	}
+}
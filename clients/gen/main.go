@@ -0,0 +1,40 @@
+// gen generates the typed clients of the builtin actors in clients from the
+// exports of each actor. Run it through go generate in clients after
+// changing the exports of a builtin actor.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	root := flag.String("root", ".", "path to the root of the go-filecoin repository")
+	flag.Parse()
+
+	if err := run(*root); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err) // nolint: errcheck
+		os.Exit(1)
+	}
+}
+
+func run(root string) error {
+	for _, spec := range actorSpecs {
+		src, err := generate(root, spec)
+		if err != nil {
+			return err
+		}
+
+		path := clientPath(root, spec)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
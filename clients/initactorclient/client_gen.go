@@ -0,0 +1,52 @@
+// Code generated by clients/gen. DO NOT EDIT.
+
+// Package initactorclient sends messages to and queries the initactor actor.
+package initactorclient
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/clients"
+)
+
+// GetActorAddress sends a message calling getActorAddress on the initactor actor.
+func GetActorAddress(ctx context.Context, api clients.API, opts clients.SendOpts, idAddr address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.InitAddress, opts.Value, opts.GasPrice, opts.GasLimit, "getActorAddress", idAddr)
+}
+
+// QueryGetActorAddress returns the result of calling getActorAddress on the initactor actor without
+// sending a message.
+func QueryGetActorAddress(ctx context.Context, api clients.API, from address.Address, idAddr address.Address) (ret0 address.Address, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.InitAddress, "getActorAddress", idAddr)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Address)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(address.Address), nil
+}
+
+// GetIDAddress sends a message calling getIDAddress on the initactor actor.
+func GetIDAddress(ctx context.Context, api clients.API, opts clients.SendOpts, addr address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.InitAddress, opts.Value, opts.GasPrice, opts.GasLimit, "getIDAddress", addr)
+}
+
+// QueryGetIDAddress returns the result of calling getIDAddress on the initactor actor without
+// sending a message.
+func QueryGetIDAddress(ctx context.Context, api clients.API, from address.Address, addr address.Address) (ret0 address.Address, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.InitAddress, "getIDAddress", addr)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Address)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(address.Address), nil
+}
@@ -0,0 +1,492 @@
+// Code generated by clients/gen. DO NOT EDIT.
+
+// Package minerclient sends messages to and queries the miner actor.
+package minerclient
+
+import (
+	"context"
+	"math/big"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/clients"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// AcceptOwner sends a message calling acceptOwner on the miner actor.
+func AcceptOwner(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "acceptOwner")
+}
+
+// QueryAcceptOwner returns the result of calling acceptOwner on the miner actor without
+// sending a message.
+func QueryAcceptOwner(ctx context.Context, api clients.API, from address.Address, to address.Address) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "acceptOwner")
+	return err
+}
+
+// AddAsk sends a message calling addAsk on the miner actor.
+func AddAsk(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, price *types.AttoFIL, expiry *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "addAsk", price, expiry)
+}
+
+// QueryAddAsk returns the result of calling addAsk on the miner actor without
+// sending a message.
+func QueryAddAsk(ctx context.Context, api clients.API, from address.Address, to address.Address, price *types.AttoFIL, expiry *big.Int) (ret0 *big.Int, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "addAsk", price, expiry)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Integer)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*big.Int), nil
+}
+
+// AddCollateral sends a message calling addCollateral on the miner actor.
+func AddCollateral(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, pledge *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "addCollateral", pledge)
+}
+
+// QueryAddCollateral returns the result of calling addCollateral on the miner actor without
+// sending a message.
+func QueryAddCollateral(ctx context.Context, api clients.API, from address.Address, to address.Address, pledge *big.Int) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "addCollateral", pledge)
+	return err
+}
+
+// CancelAsk sends a message calling cancelAsk on the miner actor.
+func CancelAsk(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, askid *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "cancelAsk", askid)
+}
+
+// QueryCancelAsk returns the result of calling cancelAsk on the miner actor without
+// sending a message.
+func QueryCancelAsk(ctx context.Context, api clients.API, from address.Address, to address.Address, askid *big.Int) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "cancelAsk", askid)
+	return err
+}
+
+// ChangeWorker sends a message calling changeWorker on the miner actor.
+func ChangeWorker(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, worker address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "changeWorker", worker)
+}
+
+// QueryChangeWorker returns the result of calling changeWorker on the miner actor without
+// sending a message.
+func QueryChangeWorker(ctx context.Context, api clients.API, from address.Address, to address.Address, worker address.Address) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "changeWorker", worker)
+	return err
+}
+
+// CommitSector sends a message calling commitSector on the miner actor.
+func CommitSector(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, sectorID uint64, commD []byte, commR []byte, commRStar []byte, proof []byte, dealIDs []uint64) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "commitSector", sectorID, commD, commR, commRStar, proof, dealIDs)
+}
+
+// QueryCommitSector returns the result of calling commitSector on the miner actor without
+// sending a message.
+func QueryCommitSector(ctx context.Context, api clients.API, from address.Address, to address.Address, sectorID uint64, commD []byte, commR []byte, commRStar []byte, proof []byte, dealIDs []uint64) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "commitSector", sectorID, commD, commR, commRStar, proof, dealIDs)
+	return err
+}
+
+// GetAsk sends a message calling getAsk on the miner actor.
+func GetAsk(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, askid *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getAsk", askid)
+}
+
+// QueryGetAsk returns the result of calling getAsk on the miner actor without
+// sending a message.
+func QueryGetAsk(ctx context.Context, api clients.API, from address.Address, to address.Address, askid *big.Int) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getAsk", askid)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// GetAsks sends a message calling getAsks on the miner actor.
+func GetAsks(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getAsks")
+}
+
+// QueryGetAsks returns the result of calling getAsks on the miner actor without
+// sending a message.
+func QueryGetAsks(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 []uint64, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getAsks")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.UintArray)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]uint64), nil
+}
+
+// GetCollateral sends a message calling getCollateral on the miner actor.
+func GetCollateral(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getCollateral")
+}
+
+// QueryGetCollateral returns the result of calling getCollateral on the miner actor without
+// sending a message.
+func QueryGetCollateral(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *types.AttoFIL, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getCollateral")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.AttoFIL)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.AttoFIL), nil
+}
+
+// GetKey sends a message calling getKey on the miner actor.
+func GetKey(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getKey")
+}
+
+// QueryGetKey returns the result of calling getKey on the miner actor without
+// sending a message.
+func QueryGetKey(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getKey")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// GetLastPoSt sends a message calling getLastPoSt on the miner actor.
+func GetLastPoSt(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getLastPoSt")
+}
+
+// QueryGetLastPoSt returns the result of calling getLastPoSt on the miner actor without
+// sending a message.
+func QueryGetLastPoSt(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *types.BlockHeight, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getLastPoSt")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.BlockHeight)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.BlockHeight), nil
+}
+
+// GetLastUsedSectorID sends a message calling getLastUsedSectorID on the miner actor.
+func GetLastUsedSectorID(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getLastUsedSectorID")
+}
+
+// QueryGetLastUsedSectorID returns the result of calling getLastUsedSectorID on the miner actor without
+// sending a message.
+func QueryGetLastUsedSectorID(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 uint64, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getLastUsedSectorID")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.SectorID)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(uint64), nil
+}
+
+// GetOwner sends a message calling getOwner on the miner actor.
+func GetOwner(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getOwner")
+}
+
+// QueryGetOwner returns the result of calling getOwner on the miner actor without
+// sending a message.
+func QueryGetOwner(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 address.Address, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getOwner")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Address)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(address.Address), nil
+}
+
+// GetPeerID sends a message calling getPeerID on the miner actor.
+func GetPeerID(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getPeerID")
+}
+
+// QueryGetPeerID returns the result of calling getPeerID on the miner actor without
+// sending a message.
+func QueryGetPeerID(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 peer.ID, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getPeerID")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.PeerID)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(peer.ID), nil
+}
+
+// GetPendingOwner sends a message calling getPendingOwner on the miner actor.
+func GetPendingOwner(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getPendingOwner")
+}
+
+// QueryGetPendingOwner returns the result of calling getPendingOwner on the miner actor without
+// sending a message.
+func QueryGetPendingOwner(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 address.Address, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getPendingOwner")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Address)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(address.Address), nil
+}
+
+// GetPledge sends a message calling getPledge on the miner actor.
+func GetPledge(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getPledge")
+}
+
+// QueryGetPledge returns the result of calling getPledge on the miner actor without
+// sending a message.
+func QueryGetPledge(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *big.Int, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getPledge")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Integer)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*big.Int), nil
+}
+
+// GetPower sends a message calling getPower on the miner actor.
+func GetPower(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getPower")
+}
+
+// QueryGetPower returns the result of calling getPower on the miner actor without
+// sending a message.
+func QueryGetPower(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *big.Int, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getPower")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Integer)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*big.Int), nil
+}
+
+// GetProvingPeriodStart sends a message calling getProvingPeriodStart on the miner actor.
+func GetProvingPeriodStart(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getProvingPeriodStart")
+}
+
+// QueryGetProvingPeriodStart returns the result of calling getProvingPeriodStart on the miner actor without
+// sending a message.
+func QueryGetProvingPeriodStart(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *types.BlockHeight, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getProvingPeriodStart")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.BlockHeight)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.BlockHeight), nil
+}
+
+// GetSectorCommitments sends a message calling getSectorCommitments on the miner actor.
+func GetSectorCommitments(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, start uint64, limit *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getSectorCommitments", start, limit)
+}
+
+// QueryGetSectorCommitments returns the result of calling getSectorCommitments on the miner actor without
+// sending a message.
+func QueryGetSectorCommitments(ctx context.Context, api clients.API, from address.Address, to address.Address, start uint64, limit *big.Int) (ret0 map[string]types.Commitments, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getSectorCommitments", start, limit)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.CommitmentsMap)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(map[string]types.Commitments), nil
+}
+
+// GetSectorInfo sends a message calling getSectorInfo on the miner actor.
+func GetSectorInfo(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, sectorID uint64) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getSectorInfo", sectorID)
+}
+
+// QueryGetSectorInfo returns the result of calling getSectorInfo on the miner actor without
+// sending a message.
+func QueryGetSectorInfo(ctx context.Context, api clients.API, from address.Address, to address.Address, sectorID uint64) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getSectorInfo", sectorID)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// GetWorker sends a message calling getWorker on the miner actor.
+func GetWorker(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getWorker")
+}
+
+// QueryGetWorker returns the result of calling getWorker on the miner actor without
+// sending a message.
+func QueryGetWorker(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 address.Address, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getWorker")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Address)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(address.Address), nil
+}
+
+// IsBootstrapMiner sends a message calling isBootstrapMiner on the miner actor.
+func IsBootstrapMiner(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "isBootstrapMiner")
+}
+
+// QueryIsBootstrapMiner returns the result of calling isBootstrapMiner on the miner actor without
+// sending a message.
+func QueryIsBootstrapMiner(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 bool, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "isBootstrapMiner")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Boolean)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(bool), nil
+}
+
+// ListSectors sends a message calling listSectors on the miner actor.
+func ListSectors(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "listSectors")
+}
+
+// QueryListSectors returns the result of calling listSectors on the miner actor without
+// sending a message.
+func QueryListSectors(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "listSectors")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// ProposeOwner sends a message calling proposeOwner on the miner actor.
+func ProposeOwner(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, newOwner address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "proposeOwner", newOwner)
+}
+
+// QueryProposeOwner returns the result of calling proposeOwner on the miner actor without
+// sending a message.
+func QueryProposeOwner(ctx context.Context, api clients.API, from address.Address, to address.Address, newOwner address.Address) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "proposeOwner", newOwner)
+	return err
+}
+
+// SlashStorageFault sends a message calling slashStorageFault on the miner actor.
+func SlashStorageFault(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "slashStorageFault")
+}
+
+// QuerySlashStorageFault returns the result of calling slashStorageFault on the miner actor without
+// sending a message.
+func QuerySlashStorageFault(ctx context.Context, api clients.API, from address.Address, to address.Address) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "slashStorageFault")
+	return err
+}
+
+// SubmitPoSt sends a message calling submitPoSt on the miner actor.
+func SubmitPoSt(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, postProofs []proofs.PoStProof) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "submitPoSt", postProofs)
+}
+
+// QuerySubmitPoSt returns the result of calling submitPoSt on the miner actor without
+// sending a message.
+func QuerySubmitPoSt(ctx context.Context, api clients.API, from address.Address, to address.Address, postProofs []proofs.PoStProof) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "submitPoSt", postProofs)
+	return err
+}
+
+// UpdateAsk sends a message calling updateAsk on the miner actor.
+func UpdateAsk(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, askid *big.Int, price *types.AttoFIL, expiry *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "updateAsk", askid, price, expiry)
+}
+
+// QueryUpdateAsk returns the result of calling updateAsk on the miner actor without
+// sending a message.
+func QueryUpdateAsk(ctx context.Context, api clients.API, from address.Address, to address.Address, askid *big.Int, price *types.AttoFIL, expiry *big.Int) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "updateAsk", askid, price, expiry)
+	return err
+}
+
+// UpdatePeerID sends a message calling updatePeerID on the miner actor.
+func UpdatePeerID(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, pid peer.ID) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "updatePeerID", pid)
+}
+
+// QueryUpdatePeerID returns the result of calling updatePeerID on the miner actor without
+// sending a message.
+func QueryUpdatePeerID(ctx context.Context, api clients.API, from address.Address, to address.Address, pid peer.ID) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "updatePeerID", pid)
+	return err
+}
+
+// WithdrawCollateral sends a message calling withdrawCollateral on the miner actor.
+func WithdrawCollateral(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, amount *types.AttoFIL) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "withdrawCollateral", amount)
+}
+
+// QueryWithdrawCollateral returns the result of calling withdrawCollateral on the miner actor without
+// sending a message.
+func QueryWithdrawCollateral(ctx context.Context, api clients.API, from address.Address, to address.Address, amount *types.AttoFIL) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "withdrawCollateral", amount)
+	return err
+}
@@ -0,0 +1,116 @@
+// Code generated by clients/gen. DO NOT EDIT.
+
+// Package multisigclient sends messages to and queries the multisig actor.
+package multisigclient
+
+import (
+	"context"
+	"math/big"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/clients"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// Approve sends a message calling approve on the multisig actor.
+func Approve(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, txID *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "approve", txID)
+}
+
+// QueryApprove returns the result of calling approve on the multisig actor without
+// sending a message.
+func QueryApprove(ctx context.Context, api clients.API, from address.Address, to address.Address, txID *big.Int) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "approve", txID)
+	return err
+}
+
+// Cancel sends a message calling cancel on the multisig actor.
+func Cancel(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, txID *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "cancel", txID)
+}
+
+// QueryCancel returns the result of calling cancel on the multisig actor without
+// sending a message.
+func QueryCancel(ctx context.Context, api clients.API, from address.Address, to address.Address, txID *big.Int) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, to, "cancel", txID)
+	return err
+}
+
+// GetPending sends a message calling getPending on the multisig actor.
+func GetPending(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getPending")
+}
+
+// QueryGetPending returns the result of calling getPending on the multisig actor without
+// sending a message.
+func QueryGetPending(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getPending")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// GetSigners sends a message calling getSigners on the multisig actor.
+func GetSigners(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getSigners")
+}
+
+// QueryGetSigners returns the result of calling getSigners on the multisig actor without
+// sending a message.
+func QueryGetSigners(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getSigners")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// GetThreshold sends a message calling getThreshold on the multisig actor.
+func GetThreshold(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getThreshold")
+}
+
+// QueryGetThreshold returns the result of calling getThreshold on the multisig actor without
+// sending a message.
+func QueryGetThreshold(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *big.Int, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getThreshold")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Integer)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*big.Int), nil
+}
+
+// Propose sends a message calling propose on the multisig actor.
+func Propose(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address, toParam address.Address, value *types.AttoFIL, method string, params []byte) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "propose", toParam, value, method, params)
+}
+
+// QueryPropose returns the result of calling propose on the multisig actor without
+// sending a message.
+func QueryPropose(ctx context.Context, api clients.API, from address.Address, to address.Address, toParam address.Address, value *types.AttoFIL, method string, params []byte) (ret0 *big.Int, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "propose", toParam, value, method, params)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Integer)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*big.Int), nil
+}
@@ -0,0 +1,120 @@
+// Code generated by clients/gen. DO NOT EDIT.
+
+// Package paymentbrokerclient sends messages to and queries the paymentbroker actor.
+package paymentbrokerclient
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/clients"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// Close sends a message calling close on the paymentbroker actor.
+func Close(ctx context.Context, api clients.API, opts clients.SendOpts, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "close", payer, chid, amt, validAt, lane, nonce, condition, sig)
+}
+
+// QueryClose returns the result of calling close on the paymentbroker actor without
+// sending a message.
+func QueryClose(ctx context.Context, api clients.API, from address.Address, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "close", payer, chid, amt, validAt, lane, nonce, condition, sig)
+	return err
+}
+
+// CreateChannel sends a message calling createChannel on the paymentbroker actor.
+func CreateChannel(ctx context.Context, api clients.API, opts clients.SendOpts, target address.Address, eol *types.BlockHeight) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "createChannel", target, eol)
+}
+
+// QueryCreateChannel returns the result of calling createChannel on the paymentbroker actor without
+// sending a message.
+func QueryCreateChannel(ctx context.Context, api clients.API, from address.Address, target address.Address, eol *types.BlockHeight) (ret0 *types.ChannelID, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "createChannel", target, eol)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.ChannelID)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.ChannelID), nil
+}
+
+// Extend sends a message calling extend on the paymentbroker actor.
+func Extend(ctx context.Context, api clients.API, opts clients.SendOpts, chid *types.ChannelID, eol *types.BlockHeight) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "extend", chid, eol)
+}
+
+// QueryExtend returns the result of calling extend on the paymentbroker actor without
+// sending a message.
+func QueryExtend(ctx context.Context, api clients.API, from address.Address, chid *types.ChannelID, eol *types.BlockHeight) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "extend", chid, eol)
+	return err
+}
+
+// Ls sends a message calling ls on the paymentbroker actor.
+func Ls(ctx context.Context, api clients.API, opts clients.SendOpts, payer address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "ls", payer)
+}
+
+// QueryLs returns the result of calling ls on the paymentbroker actor without
+// sending a message.
+func QueryLs(ctx context.Context, api clients.API, from address.Address, payer address.Address) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "ls", payer)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// Reclaim sends a message calling reclaim on the paymentbroker actor.
+func Reclaim(ctx context.Context, api clients.API, opts clients.SendOpts, chid *types.ChannelID) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "reclaim", chid)
+}
+
+// QueryReclaim returns the result of calling reclaim on the paymentbroker actor without
+// sending a message.
+func QueryReclaim(ctx context.Context, api clients.API, from address.Address, chid *types.ChannelID) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "reclaim", chid)
+	return err
+}
+
+// Redeem sends a message calling redeem on the paymentbroker actor.
+func Redeem(ctx context.Context, api clients.API, opts clients.SendOpts, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "redeem", payer, chid, amt, validAt, lane, nonce, condition, sig)
+}
+
+// QueryRedeem returns the result of calling redeem on the paymentbroker actor without
+// sending a message.
+func QueryRedeem(ctx context.Context, api clients.API, from address.Address, payer address.Address, chid *types.ChannelID, amt *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64, condition []byte, sig []byte) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "redeem", payer, chid, amt, validAt, lane, nonce, condition, sig)
+	return err
+}
+
+// Voucher sends a message calling voucher on the paymentbroker actor.
+func Voucher(ctx context.Context, api clients.API, opts clients.SendOpts, chid *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.PaymentBrokerAddress, opts.Value, opts.GasPrice, opts.GasLimit, "voucher", chid, amount, validAt, lane, nonce)
+}
+
+// QueryVoucher returns the result of calling voucher on the paymentbroker actor without
+// sending a message.
+func QueryVoucher(ctx context.Context, api clients.API, from address.Address, chid *types.ChannelID, amount *types.AttoFIL, validAt *types.BlockHeight, lane uint64, nonce uint64) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.PaymentBrokerAddress, "voucher", chid, amount, validAt, lane, nonce)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
@@ -0,0 +1,174 @@
+// Code generated by clients/gen. DO NOT EDIT.
+
+// Package storagemarketclient sends messages to and queries the storagemarket actor.
+package storagemarketclient
+
+import (
+	"context"
+	"math/big"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/clients"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// CommitDeals sends a message calling commitDeals on the storagemarket actor.
+func CommitDeals(ctx context.Context, api clients.API, opts clients.SendOpts, sectorID uint64, dealIDs []uint64) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "commitDeals", sectorID, dealIDs)
+}
+
+// QueryCommitDeals returns the result of calling commitDeals on the storagemarket actor without
+// sending a message.
+func QueryCommitDeals(ctx context.Context, api clients.API, from address.Address, sectorID uint64, dealIDs []uint64) (ret0 *types.BlockHeight, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "commitDeals", sectorID, dealIDs)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.BlockHeight)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.BlockHeight), nil
+}
+
+// CreateMiner sends a message calling createMiner on the storagemarket actor.
+func CreateMiner(ctx context.Context, api clients.API, opts clients.SendOpts, pledge *big.Int, publicKey []byte, pid peer.ID) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "createMiner", pledge, publicKey, pid)
+}
+
+// QueryCreateMiner returns the result of calling createMiner on the storagemarket actor without
+// sending a message.
+func QueryCreateMiner(ctx context.Context, api clients.API, from address.Address, pledge *big.Int, publicKey []byte, pid peer.ID) (ret0 address.Address, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "createMiner", pledge, publicKey, pid)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Address)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(address.Address), nil
+}
+
+// GetDeal sends a message calling getDeal on the storagemarket actor.
+func GetDeal(ctx context.Context, api clients.API, opts clients.SendOpts, dealID uint64) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "getDeal", dealID)
+}
+
+// QueryGetDeal returns the result of calling getDeal on the storagemarket actor without
+// sending a message.
+func QueryGetDeal(ctx context.Context, api clients.API, from address.Address, dealID uint64) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "getDeal", dealID)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// GetTotalStorage sends a message calling getTotalStorage on the storagemarket actor.
+func GetTotalStorage(ctx context.Context, api clients.API, opts clients.SendOpts) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "getTotalStorage")
+}
+
+// QueryGetTotalStorage returns the result of calling getTotalStorage on the storagemarket actor without
+// sending a message.
+func QueryGetTotalStorage(ctx context.Context, api clients.API, from address.Address) (ret0 *big.Int, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "getTotalStorage")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Integer)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*big.Int), nil
+}
+
+// IsPieceCommitted sends a message calling isPieceCommitted on the storagemarket actor.
+func IsPieceCommitted(ctx context.Context, api clients.API, opts clients.SendOpts, dealID uint64, minerAddr address.Address, pieceRef []byte) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "isPieceCommitted", dealID, minerAddr, pieceRef)
+}
+
+// QueryIsPieceCommitted returns the result of calling isPieceCommitted on the storagemarket actor without
+// sending a message.
+func QueryIsPieceCommitted(ctx context.Context, api clients.API, from address.Address, dealID uint64, minerAddr address.Address, pieceRef []byte) (ret0 bool, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "isPieceCommitted", dealID, minerAddr, pieceRef)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Boolean)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(bool), nil
+}
+
+// ListDealsForMiner sends a message calling listDealsForMiner on the storagemarket actor.
+func ListDealsForMiner(ctx context.Context, api clients.API, opts clients.SendOpts, minerAddr address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "listDealsForMiner", minerAddr)
+}
+
+// QueryListDealsForMiner returns the result of calling listDealsForMiner on the storagemarket actor without
+// sending a message.
+func QueryListDealsForMiner(ctx context.Context, api clients.API, from address.Address, minerAddr address.Address) (ret0 []byte, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "listDealsForMiner", minerAddr)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.Bytes)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]byte), nil
+}
+
+// PublishStorageDeals sends a message calling publishStorageDeals on the storagemarket actor.
+func PublishStorageDeals(ctx context.Context, api clients.API, opts clients.SendOpts, deals []byte) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "publishStorageDeals", deals)
+}
+
+// QueryPublishStorageDeals returns the result of calling publishStorageDeals on the storagemarket actor without
+// sending a message.
+func QueryPublishStorageDeals(ctx context.Context, api clients.API, from address.Address, deals []byte) (ret0 []uint64, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, address.StorageMarketAddress, "publishStorageDeals", deals)
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.UintArray)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.([]uint64), nil
+}
+
+// UpdatePower sends a message calling updatePower on the storagemarket actor.
+func UpdatePower(ctx context.Context, api clients.API, opts clients.SendOpts, delta *big.Int) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "updatePower", delta)
+}
+
+// QueryUpdatePower returns the result of calling updatePower on the storagemarket actor without
+// sending a message.
+func QueryUpdatePower(ctx context.Context, api clients.API, from address.Address, delta *big.Int) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, address.StorageMarketAddress, "updatePower", delta)
+	return err
+}
+
+// UpdateProvingDeadline sends a message calling updateProvingDeadline on the storagemarket actor.
+func UpdateProvingDeadline(ctx context.Context, api clients.API, opts clients.SendOpts, oldDeadline *types.BlockHeight, newDeadline *types.BlockHeight) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, address.StorageMarketAddress, opts.Value, opts.GasPrice, opts.GasLimit, "updateProvingDeadline", oldDeadline, newDeadline)
+}
+
+// QueryUpdateProvingDeadline returns the result of calling updateProvingDeadline on the storagemarket actor without
+// sending a message.
+func QueryUpdateProvingDeadline(ctx context.Context, api clients.API, from address.Address, oldDeadline *types.BlockHeight, newDeadline *types.BlockHeight) (err error) {
+	_, _, err = api.MessageQuery(ctx, from, address.StorageMarketAddress, "updateProvingDeadline", oldDeadline, newDeadline)
+	return err
+}
@@ -0,0 +1,53 @@
+// Code generated by clients/gen. DO NOT EDIT.
+
+// Package vestingclient sends messages to and queries the vesting actor.
+package vestingclient
+
+import (
+	"context"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/clients"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// GetWithdrawable sends a message calling getWithdrawable on the vesting actor.
+func GetWithdrawable(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "getWithdrawable")
+}
+
+// QueryGetWithdrawable returns the result of calling getWithdrawable on the vesting actor without
+// sending a message.
+func QueryGetWithdrawable(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *types.AttoFIL, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "getWithdrawable")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.AttoFIL)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.AttoFIL), nil
+}
+
+// Withdraw sends a message calling withdraw on the vesting actor.
+func Withdraw(ctx context.Context, api clients.API, opts clients.SendOpts, to address.Address) (cid.Cid, error) {
+	return api.MessageSend(ctx, opts.From, to, opts.Value, opts.GasPrice, opts.GasLimit, "withdraw")
+}
+
+// QueryWithdraw returns the result of calling withdraw on the vesting actor without
+// sending a message.
+func QueryWithdraw(ctx context.Context, api clients.API, from address.Address, to address.Address) (ret0 *types.AttoFIL, err error) {
+	rets, _, err := api.MessageQuery(ctx, from, to, "withdraw")
+	if err != nil {
+		return ret0, err
+	}
+	v0, err := clients.Return(rets, 0, abi.AttoFIL)
+	if err != nil {
+		return ret0, err
+	}
+	return v0.(*types.AttoFIL), nil
+}
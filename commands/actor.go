@@ -8,9 +8,13 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/vesting"
+	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
 
@@ -23,6 +27,7 @@ import (
 type ActorView struct {
 	ActorType string          `json:"actorType"`
 	Address   string          `json:"address"`
+	IDAddress string          `json:"idAddress,omitempty"`
 	Code      cid.Cid         `json:"code,omitempty"`
 	Nonce     uint64          `json:"nonce"`
 	Balance   *types.AttoFIL  `json:"balance"`
@@ -51,8 +56,16 @@ var actorCmd = &cmds.Command{
 }
 
 var actorLsCmd = &cmds.Command{
+	Options: []cmdkit.Option{
+		heightOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		results, err := GetPorcelainAPI(env).ActorLs(req.Context)
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		results, err := GetPorcelainAPI(env).ActorLsAt(req.Context, tsKey)
 		if err != nil {
 			return err
 		}
@@ -77,10 +90,28 @@ var actorLsCmd = &cmds.Command{
 				output = makeActorView(result.Actor, result.Address, &miner.Actor{})
 			case result.Actor.Code.Equals(types.BootstrapMinerActorCodeCid):
 				output = makeActorView(result.Actor, result.Address, &miner.Actor{})
+			case result.Actor.Code.Equals(types.MultisigActorCodeCid):
+				output = makeActorView(result.Actor, result.Address, &multisig.Actor{})
+			case result.Actor.Code.Equals(types.InitActorCodeCid):
+				output = makeActorView(result.Actor, result.Address, &initactor.Actor{})
+			case result.Actor.Code.Equals(types.VestingActorCodeCid):
+				output = makeActorView(result.Actor, result.Address, &vesting.Actor{})
 			default:
 				output = makeActorView(result.Actor, result.Address, nil)
 			}
 
+			addr, err := address.NewFromString(result.Address)
+			if err != nil {
+				return err
+			}
+			_, idAddr, err := GetPorcelainAPI(env).AddressLookup(req.Context, addr)
+			if err != nil {
+				return err
+			}
+			if !idAddr.Empty() {
+				output.IDAddress = idAddr.String()
+			}
+
 			if err := re.Emit(output); err != nil {
 				return err
 			}
@@ -12,6 +12,7 @@ import (
 	"gx/ipfs/Qmf46mr235gtyxizkKUkTH5fo62Thza2zwXR4DWC7rkoqF/go-ipfs-cmds"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -89,9 +90,23 @@ var addrsLsCmd = &cmds.Command{
 	},
 }
 
+// AddressLookupResult is the result of running the address lookup command.
+type AddressLookupResult struct {
+	Address   string
+	IDAddress string `json:",omitempty"`
+	PeerID    string `json:",omitempty"`
+}
+
 var addrsLookupCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the actor address and ID address of an actor",
+		ShortDescription: `
+Given either the actor address or the ID address of an actor, prints both
+forms. The peer ID is printed as well if the actor is a miner.
+`,
+	},
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("address", true, false, "Miner address to find peerId for"),
+		cmdkit.StringArg("address", true, false, "Actor or ID address to look up"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addr, err := address.NewFromString(req.Arguments[0])
@@ -99,17 +114,45 @@ var addrsLookupCmd = &cmds.Command{
 			return err
 		}
 
-		v, err := GetPorcelainAPI(env).MinerGetPeerID(req.Context, addr)
+		actorAddr, idAddr, err := GetPorcelainAPI(env).AddressLookup(req.Context, addr)
 		if err != nil {
-			return errors.Wrapf(err, "failed to find miner with address %s", addr.String())
+			return err
+		}
+
+		result := &AddressLookupResult{Address: actorAddr.String()}
+		if !idAddr.Empty() {
+			result.IDAddress = idAddr.String()
+		}
+
+		act, err := GetPorcelainAPI(env).ActorGet(req.Context, actorAddr)
+		if err != nil && !state.IsActorNotFoundError(err) {
+			return err
+		}
+		if act != nil && (act.Code.Equals(types.MinerActorCodeCid) || act.Code.Equals(types.BootstrapMinerActorCodeCid)) {
+			pid, err := GetPorcelainAPI(env).MinerGetPeerID(req.Context, actorAddr)
+			if err != nil {
+				return errors.Wrapf(err, "failed to find miner with address %s", actorAddr.String())
+			}
+			result.PeerID = pid.Pretty()
 		}
-		return re.Emit(v.Pretty())
+
+		return re.Emit(result)
 	},
-	Type: string(""),
+	Type: &AddressLookupResult{},
 	Encoders: cmds.EncoderMap{
-		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, pid string) error {
-			_, err := fmt.Fprintln(w, pid)
-			return err
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *AddressLookupResult) error {
+			idAddr := res.IDAddress
+			if idAddr == "" {
+				idAddr = "none"
+			}
+			if _, err := fmt.Fprintf(w, "address: %s\nid: %s\n", res.Address, idAddr); err != nil {
+				return err
+			}
+			if res.PeerID != "" {
+				_, err := fmt.Fprintf(w, "peer id: %s\n", res.PeerID)
+				return err
+			}
+			return nil
 		}),
 	},
 }
@@ -136,13 +179,21 @@ var balanceCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("address", true, false, "Address to get balance for"),
 	},
+	Options: []cmdkit.Option{
+		heightOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addr, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
 			return err
 		}
 
-		balance, err := GetPorcelainAPI(env).WalletBalance(req.Context, addr)
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		balance, err := GetPorcelainAPI(env).WalletBalanceAt(req.Context, addr, tsKey)
 		if err != nil {
 			return err
 		}
@@ -55,6 +55,29 @@ func TestWalletBalance(t *testing.T) {
 	assert.Equal("0", balance.ReadStdoutTrimNewlines())
 }
 
+func TestWalletBalanceAtHeight(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	networkAddr := address.NetworkAddress.String()
+	genesisBalance := d.RunSuccess("wallet", "balance", networkAddr).ReadStdoutTrimNewlines()
+
+	// the block reward is paid by the network actor
+	d.RunSuccess("mining", "once")
+	balance := d.RunSuccess("wallet", "balance", networkAddr).ReadStdoutTrimNewlines()
+	assert.NotEqual(genesisBalance, balance)
+
+	balanceAt0 := d.RunSuccess("wallet", "balance", networkAddr, "--height", "0").ReadStdoutTrimNewlines()
+	assert.Equal(genesisBalance, balanceAt0)
+	balanceAt1 := d.RunSuccess("wallet", "balance", networkAddr, "--height", "1").ReadStdoutTrimNewlines()
+	assert.Equal(balance, balanceAt1)
+
+	d.RunFail("above the height", "wallet", "balance", networkAddr, "--height", "100")
+}
+
 func TestAddrLookupAndUpdate(t *testing.T) {
 	assert := assert.New(t)
 
@@ -74,10 +97,24 @@ func TestAddrLookupAndUpdate(t *testing.T) {
 	minerPidForUpdate := th.RequireRandomPeerID(require.New(t))
 
 	// capture original, pre-update miner pid
-	lookupOutA := th.RunSuccessFirstLine(d, "address", "lookup", minerAddr)
+	lookupOutA := d.RunSuccess("address", "lookup", minerAddr).ReadStdout()
+	assert.Contains(lookupOutA, "address: "+minerAddr)
+	assert.Contains(lookupOutA, "peer id: ")
+
+	// Miners are assigned an ID when they are created, so looking up the ID
+	// address finds the miner.
+	var idAddr string
+	for _, line := range strings.Split(lookupOutA, "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			idAddr = strings.TrimPrefix(line, "id: ")
+		}
+	}
+	require.NotEqual(t, "none", idAddr)
+	byID := d.RunSuccess("address", "lookup", idAddr).ReadStdout()
+	assert.Equal(lookupOutA, byID)
 
-	// Not a miner address, should fail.
-	d.RunFail("failed to find", "address", "lookup", addr)
+	// Not a miner address, no peer id.
+	assert.NotContains(d.RunSuccess("address", "lookup", addr).ReadStdout(), "peer id")
 
 	// update the miner's peer ID
 	updateMsg := th.RunSuccessFirstLine(d,
@@ -96,8 +133,8 @@ func TestAddrLookupAndUpdate(t *testing.T) {
 	d.WaitForMessageRequireSuccess(core.MustDecodeCid(updateMsg))
 
 	// use the address lookup command to ensure update happened
-	lookupOutB := th.RunSuccessFirstLine(d, "address", "lookup", minerAddr)
-	assert.Equal(minerPidForUpdate.Pretty(), lookupOutB)
+	lookupOutB := d.RunSuccess("address", "lookup", minerAddr).ReadStdout()
+	assert.Contains(lookupOutB, "peer id: "+minerPidForUpdate.Pretty())
 	assert.NotEqual(lookupOutA, lookupOutB)
 }
 
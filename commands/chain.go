@@ -7,10 +7,14 @@ import (
 	"strconv"
 	"strings"
 
+	"gx/ipfs/QmQmhotPUzVrMEWNK3x1R5jQ5ZHWyL7tVUrmRPjrBrvyCb/go-ipfs-files"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 	"gx/ipfs/Qmf46mr235gtyxizkKUkTH5fo62Thza2zwXR4DWC7rkoqF/go-ipfs-cmds"
 
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/node"
+	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -19,8 +23,14 @@ var chainCmd = &cmds.Command{
 		Tagline: "Inspect the filecoin blockchain",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"head": chainHeadCmd,
-		"ls":   chainLsCmd,
+		"export":          chainExportCmd,
+		"head":            chainHeadCmd,
+		"import":          chainImportCmd,
+		"import-snapshot": chainImportSnapshotCmd,
+		"ls":              chainLsCmd,
+		"replay":          chainReplayCmd,
+		"snapshot":        chainSnapshotCmd,
+		"state-diff":      chainStateDiffCmd,
 	},
 }
 
@@ -45,6 +55,169 @@ var chainHeadCmd = &cmds.Command{
 	},
 }
 
+var chainExportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export the blockchain to a CAR file",
+		ShortDescription: `
+Writes the blocks and messages of the tipsets of the heaviest chain between two
+heights to stdout as a CAR file whose roots are the blocks of the last tipset.
+With --include-state the parent state of each block, including actor storage,
+is written as well, except below the checkpoint of a node synced from a
+snapshot, where it is not known. The file can be loaded into another node with
+chain import.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("from-height", "Height of the first tipset to export").WithDefault(uint(0)),
+		cmdkit.UintOption("to-height", "Height of the last tipset to export, defaults to the head"),
+		cmdkit.BoolOption("include-state", "Include the state trees of the exported blocks"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+
+		fromHeight, _ := req.Options["from-height"].(uint)
+		toHeight, ok := req.Options["to-height"].(uint)
+		if !ok {
+			head, err := api.ChainHead(req.Context).Height()
+			if err != nil {
+				return err
+			}
+			toHeight = uint(head)
+		}
+		if fromHeight > toHeight {
+			return fmt.Errorf("from-height %d is above to-height %d", fromHeight, toHeight)
+		}
+		includeState, _ := req.Options["include-state"].(bool)
+
+		r, w := io.Pipe()
+		go func() {
+			err := api.ChainExport(req.Context, uint64(fromHeight), uint64(toHeight), includeState, w)
+			w.CloseWithError(err) // nolint: errcheck
+		}()
+		return re.Emit(r)
+	},
+}
+
+var chainImportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import a blockchain from a CAR file",
+		ShortDescription: `
+Loads a CAR file written by chain export and syncs the chain it contains, which
+is fully validated. The exported chain must extend a tipset the node already
+has, so it should either start at genesis or at a tipset the node has synced.
+Prints the CIDs of the blocks of the imported head.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("file", true, false, "Path to the CAR file to import").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		iter := req.Files.Entries()
+		if !iter.Next() {
+			return fmt.Errorf("no file given: %s", iter.Err())
+		}
+
+		fi, ok := iter.Node().(files.File)
+		if !ok {
+			return fmt.Errorf("given file was not a files.File")
+		}
+
+		key, err := GetPorcelainAPI(env).ChainImport(req.Context, fi)
+		if err != nil {
+			return err
+		}
+		return re.Emit(key.ToSlice())
+	},
+	Type: []cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res []cid.Cid) error {
+			for _, c := range res {
+				if _, err := fmt.Fprintln(w, c.String()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var chainSnapshotCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export a snapshot of the blockchain to a CAR file",
+		ShortDescription: `
+Writes the tipset of the heaviest chain at the given height, all of its
+ancestors and its parent state to stdout as a CAR file. New nodes whose
+sync.checkpoint config is set to the CIDs of the blocks of that tipset can load
+the snapshot with chain import-snapshot instead of syncing from genesis.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("height", "Height of the checkpoint, defaults to the head"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+
+		height, ok := req.Options["height"].(uint)
+		if !ok {
+			head, err := api.ChainHead(req.Context).Height()
+			if err != nil {
+				return err
+			}
+			height = uint(head)
+		}
+
+		r, w := io.Pipe()
+		go func() {
+			err := api.ChainExportSnapshot(req.Context, uint64(height), w)
+			w.CloseWithError(err) // nolint: errcheck
+		}()
+		return re.Emit(r)
+	},
+}
+
+var chainImportSnapshotCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Start syncing the blockchain from a snapshot",
+		ShortDescription: `
+Loads a snapshot written by chain snapshot. The snapshot must be of the tipset
+set as sync.checkpoint in the config, which the node must have been started
+with. The chain below the checkpoint is trusted, only the checkpoint and the
+tipsets after it are validated. Prints the CIDs of the blocks of the checkpoint.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("file", true, false, "Path to the snapshot to import").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		iter := req.Files.Entries()
+		if !iter.Next() {
+			return fmt.Errorf("no file given: %s", iter.Err())
+		}
+
+		fi, ok := iter.Node().(files.File)
+		if !ok {
+			return fmt.Errorf("given file was not a files.File")
+		}
+
+		key, err := GetPorcelainAPI(env).ChainImportSnapshot(req.Context, fi)
+		if err != nil {
+			return err
+		}
+		return re.Emit(key.ToSlice())
+	},
+	Type: []cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res []cid.Cid) error {
+			for _, c := range res {
+				if _, err := fmt.Fprintln(w, c.String()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
 var chainLsCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "List blocks in the blockchain",
@@ -104,3 +277,139 @@ var chainLsCmd = &cmds.Command{
 		}),
 	},
 }
+
+var chainReplayCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Re-run the state transitions of stored tipsets and check their state roots",
+		ShortDescription: `
+Replays the tipsets of the heaviest chain between two heights on top of their
+parent states and compares the state computed for each block with the block's
+StateRoot. For every block that does not match, the actors whose balance,
+nonce, code or storage head differ are printed.
+
+The command reads the repo directly and does not need a running daemon. It
+cannot be used while a daemon has the repo open.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("from", "Height of the first tipset to replay").WithDefault(uint(1)),
+		cmdkit.UintOption("to", "Height of the last tipset to replay, defaults to the head"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		rep, err := getRepo(req)
+		if err != nil {
+			return err
+		}
+		defer rep.Close() // nolint: errcheck
+
+		replayer, err := node.NewReplayer(req.Context, rep)
+		if err != nil {
+			return err
+		}
+
+		from, _ := req.Options["from"].(uint)
+		to, ok := req.Options["to"].(uint)
+		if !ok {
+			head, err := replayer.HeadHeight()
+			if err != nil {
+				return err
+			}
+			to = uint(head)
+		}
+
+		return replayer.Replay(req.Context, uint64(from), uint64(to), func(res *chain.ReplayResult) error {
+			return re.Emit(res)
+		})
+	},
+	Type: chain.ReplayResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *chain.ReplayResult) error {
+			return chain.PrintReplayResult(w, res)
+		}),
+	},
+}
+
+var chainStateDiffCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the actors that differ between the states of two tipsets",
+		ShortDescription: `
+Compares the state after tipset-a with the state after tipset-b and lists the
+actors that were added (+), removed (-) or modified (~) between them, along
+with the changes to the balance, nonce, code and storage head of modified
+actors. Tipsets are given as comma separated lists of block CIDs.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("tipset-a", true, false, "Block CIDs of the first tipset, separated by commas"),
+		cmdkit.StringArg("tipset-b", true, false, "Block CIDs of the second tipset, separated by commas"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		a, err := parseTipSetKey(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		b, err := parseTipSetKey(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		diffs, err := GetPorcelainAPI(env).ChainStateDiff(req.Context, a, b)
+		if err != nil {
+			return err
+		}
+		for _, d := range diffs {
+			if err := re.Emit(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: state.ActorDiff{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, d *state.ActorDiff) error {
+			var err error
+			switch {
+			case d.Added():
+				_, err = fmt.Fprintf(w, "+ %s balance %s\n", d.Address, d.After.Balance)
+			case d.Removed():
+				_, err = fmt.Fprintf(w, "- %s balance %s\n", d.Address, d.Before.Balance)
+			default:
+				_, err = fmt.Fprintf(w, "~ %s %s\n", d.Address, strings.Join(d.Changes(), ", "))
+			}
+			return err
+		}),
+	},
+}
+
+// parseTipSetKey parses a comma separated list of block CIDs identifying a
+// tipset.
+func parseTipSetKey(s string) (types.SortedCidSet, error) {
+	var cids []cid.Cid
+	for _, str := range strings.Split(s, ",") {
+		c, err := cid.Decode(strings.TrimSpace(str))
+		if err != nil {
+			return types.SortedCidSet{}, fmt.Errorf("invalid block CID %q: %s", str, err)
+		}
+		cids = append(cids, c)
+	}
+	return types.NewSortedCidSet(cids...), nil
+}
+
+// heightOption lets commands read the state of the chain as of a past height.
+var heightOption = cmdkit.UintOption("height", "Use the state after the tipset at this height instead of the head")
+
+// stateTipSetKey returns the key of the tipset whose state a command taking
+// the height option reads: the tipset of the heaviest chain at that height or
+// the head if the option is not set.
+func stateTipSetKey(req *cmds.Request, env cmds.Environment) (types.SortedCidSet, error) {
+	height, ok := req.Options["height"].(uint)
+	if !ok {
+		return GetPorcelainAPI(env).ChainHead(req.Context).ToSortedCidSet(), nil
+	}
+
+	ts, err := GetPorcelainAPI(env).ChainTipSetAtHeight(req.Context, uint64(height))
+	if err != nil {
+		return types.SortedCidSet{}, err
+	}
+	return ts.ToSortedCidSet(), nil
+}
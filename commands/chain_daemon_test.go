@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -142,3 +145,87 @@ func TestChainDaemon(t *testing.T) {
 		assert.Contains(chainLsResult, `"nonce":"0"`)
 	})
 }
+
+func TestChainStateDiff(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	before := d.RunSuccess("chain", "head").ReadStdoutTrimNewlines()
+	d.RunSuccess("mining", "once")
+	after := d.RunSuccess("chain", "head").ReadStdoutTrimNewlines()
+
+	// the block reward changes the balance of the miner owner
+	out := d.RunSuccess("chain", "state-diff", before, after).ReadStdoutTrimNewlines()
+	assert.Contains(out, "~ ")
+	assert.Contains(out, "balance")
+
+	out = d.RunSuccess("chain", "state-diff", after, after).ReadStdoutTrimNewlines()
+	assert.Empty(out)
+
+	d.RunFail("invalid block CID", "chain", "state-diff", "notacid", after)
+}
+
+func TestChainExportImport(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	exporter := makeTestDaemonWithMinerAndStart(t)
+	defer exporter.ShutdownSuccess()
+	importer := th.NewDaemon(t).Start()
+	defer importer.ShutdownSuccess()
+
+	exporter.RunSuccess("mining", "once")
+	exporter.RunSuccess("mining", "once")
+	head := exporter.RunSuccess("chain", "head").ReadStdoutTrimNewlines()
+
+	carFile, err := ioutil.TempFile("", "chain.*.car")
+	require.NoError(err)
+	defer os.Remove(carFile.Name()) // nolint: errcheck
+
+	exported := exporter.RunSuccess("chain", "export").ReadStdout()
+	_, err = carFile.WriteString(exported)
+	require.NoError(err)
+	require.NoError(carFile.Close())
+
+	imported := importer.RunSuccess("chain", "import", carFile.Name()).ReadStdoutTrimNewlines()
+	assert.Equal(head, imported)
+	assert.Equal(head, importer.RunSuccess("chain", "head").ReadStdoutTrimNewlines())
+
+	exporter.RunFail("above to-height", "chain", "export", "--from-height", "2", "--to-height", "1")
+}
+
+func TestChainSnapshot(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	exporter := makeTestDaemonWithMinerAndStart(t)
+	defer exporter.ShutdownSuccess()
+
+	exporter.RunSuccess("mining", "once")
+	exporter.RunSuccess("mining", "once")
+	checkpoint := exporter.RunSuccess("chain", "head").ReadStdoutTrimNewlines()
+
+	snapshotFile, err := ioutil.TempFile("", "snapshot.*.car")
+	require.NoError(err)
+	defer os.Remove(snapshotFile.Name()) // nolint: errcheck
+
+	snapshot := exporter.RunSuccess("chain", "snapshot").ReadStdout()
+	_, err = snapshotFile.WriteString(snapshot)
+	require.NoError(err)
+	require.NoError(snapshotFile.Close())
+
+	importer := th.NewDaemon(t, th.Checkpoint(strings.Replace(checkpoint, "\n", ",", -1))).Start()
+	defer importer.ShutdownSuccess()
+
+	imported := importer.RunSuccess("chain", "import-snapshot", snapshotFile.Name()).ReadStdoutTrimNewlines()
+	assert.Equal(checkpoint, imported)
+	assert.Equal(checkpoint, importer.RunSuccess("chain", "head").ReadStdoutTrimNewlines())
+
+	// a node without a checkpoint cannot start from a snapshot
+	exporter.RunFail("no checkpoint is set", "chain", "import-snapshot", snapshotFile.Name())
+}
@@ -204,11 +204,16 @@ var clientListAsksCmd = &cmds.Command{
 		ShortDescription: `
 Lists all asks in the storage market. This command takes no arguments. Results
 will be returned as a space separated table with miner, id, price and expiration
-respectively.
+respectively. Asks that have expired are not listed unless --include-expired
+is given.
 `,
 	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("include-expired", "Also list asks that have expired"),
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		asksCh := GetPorcelainAPI(env).ClientListAsks(req.Context)
+		includeExpired, _ := req.Options["include-expired"].(bool)
+		asksCh := GetPorcelainAPI(env).ClientListAsks(req.Context, includeExpired)
 
 		for a := range asksCh {
 			if a.Error != nil {
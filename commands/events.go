@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+	"gx/ipfs/Qmf46mr235gtyxizkKUkTH5fo62Thza2zwXR4DWC7rkoqF/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/evts"
+)
+
+var eventsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Find and follow events emitted by actors",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls":        eventsLsCmd,
+		"subscribe": eventsSubscribeCmd,
+	},
+}
+
+var eventsFilterOptions = []cmdkit.Option{
+	cmdkit.StringOption("actor", "Only show events emitted by the actor with this address"),
+	cmdkit.StringOption("name", "Only show events with this name"),
+}
+
+var eventsLsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "List events recorded on chain",
+		ShortDescription: `Lists the events the node has indexed for the heaviest chain, newest first.`,
+	},
+	Options: eventsFilterOptions,
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		filter, err := eventsFilterFromOptions(req)
+		if err != nil {
+			return err
+		}
+
+		found, err := GetPorcelainAPI(env).EventsFind(req.Context, filter)
+		if err != nil {
+			return err
+		}
+		for _, ev := range found {
+			if err := re.Emit(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: evts.ChainEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(printChainEvent),
+	},
+}
+
+var eventsSubscribeCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "Print events as they are recorded on chain",
+		ShortDescription: `Prints the events in every new tipset of the heaviest chain until interrupted.`,
+	},
+	Options: eventsFilterOptions,
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		filter, err := eventsFilterFromOptions(req)
+		if err != nil {
+			return err
+		}
+
+		evCh, err := GetPorcelainAPI(env).EventsSubscribe(req.Context, filter)
+		if err != nil {
+			return err
+		}
+		for ev := range evCh {
+			if err := re.Emit(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: evts.ChainEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(printChainEvent),
+	},
+}
+
+func eventsFilterFromOptions(req *cmds.Request) (evts.Filter, error) {
+	var filter evts.Filter
+	if o, ok := req.Options["actor"]; ok {
+		addr, err := address.NewFromString(o.(string))
+		if err != nil {
+			return filter, err
+		}
+		filter.Actor = addr
+	}
+	if o, ok := req.Options["name"]; ok {
+		filter.Name = o.(string)
+	}
+	return filter, nil
+}
+
+func printChainEvent(req *cmds.Request, w io.Writer, ev *evts.ChainEvent) error {
+	_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", ev.Height, ev.Actor, ev.Name, ev.Message)
+	return err
+}
@@ -35,6 +35,7 @@ var initCmd = &cmds.Command{
 		cmdkit.StringOption(WithMiner, "when set, creates a custom genesis block with a pre generated miner account, requires running the daemon using dev mode (--dev)"),
 		cmdkit.StringOption(DefaultAddress, "when set, sets the daemons's default address to the provided address"),
 		cmdkit.UintOption(AutoSealIntervalSeconds, "when set to a number > 0, configures the daemon to check for and seal any staged sectors on an interval.").WithDefault(uint(120)),
+		cmdkit.StringOption(Checkpoint, "when set, only syncs chains including the tipset made of these comma separated block CIDs, starting from a snapshot of it loaded with chain import-snapshot"),
 		cmdkit.BoolOption(DevnetTest, "when set, populates config bootstrap addrs with the dns multiaddrs of the test devnet and other test devnet specific bootstrap parameters."),
 		cmdkit.BoolOption(DevnetNightly, "when set, populates config bootstrap addrs with the dns multiaddrs of the nightly devnet and other nightly devnet specific bootstrap parameters"),
 		cmdkit.BoolOption(DevnetUser, "when set, populates config bootstrap addrs with the dns multiaddrs of the user devnet and other user devnet specific bootstrap parameters"),
@@ -95,6 +96,14 @@ func getConfigFromOptions(options cmdkit.OptMap) (*config.Config, error) {
 		}
 	}
 
+	if c, ok := options[Checkpoint].(string); ok {
+		var err error
+		newConfig.Sync.Checkpoint, err = parseTipSetKey(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	devnetTest, _ := options[DevnetTest].(bool)
 	devnetNightly, _ := options[DevnetNightly].(bool)
 	devnetUser, _ := options[DevnetUser].(bool)
@@ -67,6 +67,9 @@ const (
 	// GenesisFile is the path of file containing archive of genesis block DAG data
 	GenesisFile = "genesisfile"
 
+	// Checkpoint is the comma separated list of the CIDs of the blocks of a tipset the node trusts to be part of the chain
+	Checkpoint = "checkpoint"
+
 	// DevnetTest populates config bootstrap addrs with the dns multiaddrs of the test devnet and other test devnet specific bootstrap parameters
 	DevnetTest = "devnet-test"
 
@@ -104,6 +107,7 @@ MINE
 VIEW DATA STRUCTURES
   go-filecoin chain                  - Inspect the filecoin blockchain
   go-filecoin dag                    - Interact with IPLD DAG objects
+  go-filecoin events                 - Find and follow events emitted by actors
   go-filecoin show                   - Get human-readable representations of filecoin objects
 
 NETWORK COMMANDS
@@ -116,6 +120,7 @@ NETWORK COMMANDS
 
 ACTOR COMMANDS
   go-filecoin actor                  - Interact with actors. Actors are built-in smart contracts.
+  go-filecoin multisig               - Manage multisig wallets
   go-filecoin paych                  - Payment channel operations
 
 MESSAGE COMMANDS
@@ -124,6 +129,7 @@ MESSAGE COMMANDS
 
 TOOL COMMANDS
   go-filecoin log                    - Interact with the daemon event log output.
+  go-filecoin repo                   - Manage the filecoin repo
   go-filecoin version                - Show go-filecoin version information
 `,
 	},
@@ -158,15 +164,18 @@ var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"client":           clientCmd,
 	"dag":              dagCmd,
 	"dht":              dhtCmd,
+	"events":           eventsCmd,
 	"id":               idCmd,
 	"log":              logCmd,
 	"message":          msgCmd,
 	"miner":            minerCmd,
 	"mining":           miningCmd,
 	"mpool":            mpoolCmd,
+	"multisig":         multisigCmd,
 	"outbox":           outboxCmd,
 	"paych":            paymentChannelCmd,
 	"ping":             pingCmd,
+	"repo":             repoCmd,
 	"retrieval-client": retrievalClientCmd,
 	"show":             showCmd,
 	"stats":            statsCmd,
@@ -302,6 +311,11 @@ func requiresDaemon(req *cmds.Request) bool {
 		return false
 	}
 
+	// replay reads the repo directly so that it works offline
+	if req.Command == chainReplayCmd {
+		return false
+	}
+
 	return true
 }
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
@@ -18,6 +19,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/mthdsig"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
 )
 
 var msgCmd = &cmds.Command{
@@ -27,6 +29,7 @@ var msgCmd = &cmds.Command{
 	Subcommands: map[string]*cmds.Command{
 		"send":   msgSendCmd,
 		"status": msgStatusCmd,
+		"trace":  msgTraceCmd,
 		"wait":   msgWaitCmd,
 	},
 }
@@ -308,3 +311,62 @@ func appendJSON(val interface{}, out []byte) ([]byte, error) {
 	out = append(out, byte('\n'))
 	return out, nil
 }
+
+var msgTraceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show how a message on chain was executed",
+		ShortDescription: `Replays a message on the parent state of the tipset that includes it and prints
+every message it sent along the way, with the gas each one used and the exit
+code and error it returned.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the message to trace"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msgCid, err := cid.Parse(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid cid "+req.Arguments[0])
+		}
+
+		trace, err := GetPorcelainAPI(env).MessageTrace(req.Context, msgCid)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(trace)
+	},
+	Type: vm.Trace{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, trace *vm.Trace) error {
+			return printTrace(w, trace, 0)
+		}),
+	},
+}
+
+// printTrace writes one line per message of the trace, indenting the messages
+// sent by another one below it.
+func printTrace(w io.Writer, trace *vm.Trace, depth int) error {
+	method := trace.Method
+	if method == "" {
+		method = "<transfer>"
+	}
+	value := types.ZeroAttoFIL
+	if trace.Value != nil {
+		value = trace.Value
+	}
+
+	line := fmt.Sprintf("%s%s -> %s %s value=%s gas=%d exit=%d", strings.Repeat("  ", depth), trace.From, trace.To, method, value, trace.GasUsed, trace.ExitCode)
+	if trace.Error != "" {
+		line += fmt.Sprintf(" error=%q", trace.Error)
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+
+	for _, sub := range trace.Subcalls {
+		if err := printTrace(w, sub, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
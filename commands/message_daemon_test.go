@@ -178,3 +178,27 @@ func TestMessageStatus(t *testing.T) {
 		assert.NotContains(status, "On chain")
 	})
 }
+
+func TestMessageTrace(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	msg := d.RunSuccess(
+		"message", "send",
+		"--from", fixtures.TestAddresses[0],
+		"--gas-price", "0", "--gas-limit", "300",
+		"--value=10",
+		fixtures.TestAddresses[1],
+	)
+	msgcid := strings.Trim(msg.ReadStdout(), "\n")
+
+	d.RunSuccess("mining once")
+
+	trace := d.RunSuccess("message", "trace", msgcid).ReadStdoutTrimNewlines()
+	assert.Contains(trace, fixtures.TestAddresses[0]+" -> "+fixtures.TestAddresses[1]+" <transfer>")
+	assert.Contains(trace, "exit=0")
+
+	d.RunFail("not found on chain", "message", "trace", "QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS")
+}
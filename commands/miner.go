@@ -12,6 +12,7 @@ import (
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 	"gx/ipfs/Qmf46mr235gtyxizkKUkTH5fo62Thza2zwXR4DWC7rkoqF/go-ipfs-cmds"
 
+	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -22,12 +23,16 @@ var minerCmd = &cmds.Command{
 		Tagline: "Manage a single miner actor",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"create":        minerCreateCmd,
-		"owner":         minerOwnerCmd,
-		"pledge":        minerPledgeCmd,
-		"power":         minerPowerCmd,
-		"set-price":     minerSetPriceCmd,
-		"update-peerid": minerUpdatePeerIDCmd,
+		"collateral":         minerCollateralCmd,
+		"create":             minerCreateCmd,
+		"owner":              minerOwnerCmd,
+		"pledge":             minerPledgeCmd,
+		"power":              minerPowerCmd,
+		"sectors":            minerSectorsCmd,
+		"set-price":          minerSetPriceCmd,
+		"status":             minerStatusCmd,
+		"transfer-ownership": minerTransferOwnershipCmd,
+		"update-peerid":      minerUpdatePeerIDCmd,
 	},
 }
 
@@ -423,16 +428,25 @@ var minerPowerCmd = &cmds.Command{
 		ShortDescription: `Check the current power of a given miner and total power of the storage market.
 Values will be output as a ratio where the first number is the miner power and second is the total market power.`,
 	},
+	Options: []cmdkit.Option{
+		heightOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		minerAddr, err := optionalAddr(req.Arguments[0])
 		if err != nil {
 			return err
 		}
 
-		bytes, _, err := GetPorcelainAPI(env).MessageQuery(
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		bytes, _, err := GetPorcelainAPI(env).MessageQueryAt(
 			req.Context,
 			address.Undef,
 			minerAddr,
+			tsKey,
 			"getPower",
 		)
 		if err != nil {
@@ -440,10 +454,11 @@ Values will be output as a ratio where the first number is the miner power and s
 		}
 		power := big.NewInt(0).SetBytes(bytes[0])
 
-		bytes, _, err = GetPorcelainAPI(env).MessageQuery(
+		bytes, _, err = GetPorcelainAPI(env).MessageQueryAt(
 			req.Context,
 			address.Undef,
 			address.StorageMarketAddress,
+			tsKey,
 			"getTotalStorage",
 		)
 		if err != nil {
@@ -464,3 +479,435 @@ Values will be output as a ratio where the first number is the miner power and s
 		}),
 	},
 }
+
+var minerStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the proving period status of a miner",
+		ShortDescription: `Shows the power and collateral of the given miner along with its current
+proving period. A miner that submits its PoSt after the end of the proving period
+pays a fee out of its collateral; a miner that has not submitted it by the end of
+the grace period loses its power and collateral.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := optionalAddr(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		status, err := GetPorcelainAPI(env).MinerGetStatus(req.Context, minerAddr)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&status)
+	},
+	Type: porcelain.MinerStatus{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, status *porcelain.MinerStatus) error {
+			provingPeriod, gracePeriodEnd := "none", "none"
+			if status.ProvingPeriodStart != nil {
+				provingPeriod = fmt.Sprintf("%s - %s", status.ProvingPeriodStart, status.ProvingPeriodEnd)
+				gracePeriodEnd = status.GracePeriodEnd.String()
+			}
+
+			lastPoSt := "none"
+			if status.LastPoSt != nil {
+				lastPoSt = status.LastPoSt.String()
+			}
+
+			state := "ok"
+			if status.Late {
+				state = "late, PoSt is overdue"
+			}
+
+			_, err := fmt.Fprintf(w, `Power:          %s
+Collateral:     %s FIL
+Proving period: %s
+Grace ends:     %s
+Last PoSt:      %s
+Status:         %s
+`, status.Power, status.Collateral, provingPeriod, gracePeriodEnd, lastPoSt, state)
+			return err
+		}),
+	},
+}
+
+var minerSectorsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the sectors committed by a miner",
+		ShortDescription: `Lists the sectors committed by the given miner along with the block height at
+which each one expires. A sector expires when all of the deals it contains have
+ended and is removed when the miner submits its next PoSt. Sectors that contain
+no deals never expire.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := optionalAddr(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		sectors, err := GetPorcelainAPI(env).MinerListSectors(req.Context, minerAddr)
+		if err != nil {
+			return err
+		}
+
+		for _, sector := range sectors {
+			if err := re.Emit(sector); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: minerActor.SectorInfo{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sector *minerActor.SectorInfo) error {
+			expiration := "never"
+			if sector.Expiration != nil {
+				expiration = sector.Expiration.String()
+			}
+			_, err := fmt.Fprintf(w, "%d %s\n", sector.SectorID, expiration)
+			return err
+		}),
+	},
+}
+
+var minerCollateralCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the collateral of a miner",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add":      minerCollateralAddCmd,
+		"show":     minerCollateralShowCmd,
+		"withdraw": minerCollateralWithdrawCmd,
+	},
+}
+
+// MinerCollateralResult is the return type for the miner collateral add and
+// withdraw commands
+type MinerCollateralResult struct {
+	Cid     cid.Cid
+	GasUsed types.GasUnits
+	Preview bool
+}
+
+var minerCollateralResultEncoders = cmds.EncoderMap{
+	cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MinerCollateralResult) error {
+		if res.Preview {
+			output := strconv.FormatUint(uint64(res.GasUsed), 10)
+			_, err := w.Write([]byte(output))
+			return err
+		}
+		return PrintString(w, res.Cid)
+	}),
+}
+
+var minerCollateralAddCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Add <amount> FIL of collateral to <miner>",
+		ShortDescription: `Issues a new message to the network adding collateral to the miner. The pledge
+of the miner can be raised at the same time with --pledge; the collateral must
+then cover at least 0.001 FIL per pledged sector.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+		cmdkit.StringArg("amount", true, false, "The amount of collateral in FIL to add"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send from"),
+		cmdkit.Uint64Option("pledge", "Number of sectors to add to the pledge").WithDefault(uint64(0)),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		amount, ok := types.NewAttoFILFromFILString(req.Arguments[1])
+		if !ok {
+			return ErrInvalidCollateral
+		}
+
+		fromAddr, err := optionalAddr(req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		pledge, ok := req.Options["pledge"].(uint64)
+		if !ok {
+			return ErrInvalidPledge
+		}
+
+		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		if preview {
+			usedGas, err := GetPorcelainAPI(env).MessagePreview(
+				req.Context,
+				fromAddr,
+				minerAddr,
+				"addCollateral",
+				big.NewInt(0).SetUint64(pledge),
+			)
+			if err != nil {
+				return err
+			}
+
+			return re.Emit(&MinerCollateralResult{
+				Cid:     cid.Cid{},
+				GasUsed: usedGas,
+				Preview: true,
+			})
+		}
+
+		c, err := GetPorcelainAPI(env).MinerAddCollateral(
+			req.Context,
+			fromAddr,
+			minerAddr,
+			gasPrice,
+			gasLimit,
+			amount,
+			big.NewInt(0).SetUint64(pledge),
+		)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&MinerCollateralResult{
+			Cid:     c,
+			GasUsed: types.NewGasUnits(0),
+			Preview: false,
+		})
+	},
+	Type:     &MinerCollateralResult{},
+	Encoders: minerCollateralResultEncoders,
+}
+
+var minerCollateralWithdrawCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Withdraw <amount> FIL of collateral from <miner>",
+		ShortDescription: `Issues a new message to the network sending collateral back to the owner of the
+miner. Collateral backing committed sectors cannot be withdrawn.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+		cmdkit.StringArg("amount", true, false, "The amount of collateral in FIL to withdraw"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send from"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		amount, ok := types.NewAttoFILFromFILString(req.Arguments[1])
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		fromAddr, err := optionalAddr(req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		if preview {
+			usedGas, err := GetPorcelainAPI(env).MessagePreview(
+				req.Context,
+				fromAddr,
+				minerAddr,
+				"withdrawCollateral",
+				amount,
+			)
+			if err != nil {
+				return err
+			}
+
+			return re.Emit(&MinerCollateralResult{
+				Cid:     cid.Cid{},
+				GasUsed: usedGas,
+				Preview: true,
+			})
+		}
+
+		c, err := GetPorcelainAPI(env).MinerWithdrawCollateral(
+			req.Context,
+			fromAddr,
+			minerAddr,
+			gasPrice,
+			gasLimit,
+			amount,
+		)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&MinerCollateralResult{
+			Cid:     c,
+			GasUsed: types.NewGasUnits(0),
+			Preview: false,
+		})
+	},
+	Type:     &MinerCollateralResult{},
+	Encoders: minerCollateralResultEncoders,
+}
+
+var minerCollateralShowCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "Show the collateral and pledge of <miner>",
+		ShortDescription: `Shows the total collateral of the miner, how much of it backs committed sectors and how much can be withdrawn.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		collateral, err := GetPorcelainAPI(env).MinerGetCollateral(req.Context, minerAddr)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&collateral)
+	},
+	Type: porcelain.MinerCollateral{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c *porcelain.MinerCollateral) error {
+			_, err := fmt.Fprintf(w, `Collateral:   %s FIL
+Locked:       %s FIL
+Withdrawable: %s FIL
+Pledge:       %s sectors
+`, c.Collateral, c.Locked, c.Withdrawable, c.PledgeSectors)
+			return err
+		}),
+	},
+}
+
+// MinerTransferOwnershipResult is the return type for miner transfer-ownership command
+type MinerTransferOwnershipResult struct {
+	Cid     cid.Cid
+	GasUsed types.GasUnits
+	Preview bool
+}
+
+var minerTransferOwnershipCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Transfer <miner> to <new-owner>",
+		ShortDescription: `Transferring a miner takes two steps. First the current owner proposes the
+new owner by running this command. Then the new owner accepts the miner by
+running this command again with --accept, which sends the message from
+<new-owner>. The transfer is complete once the second message is mined.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "The address of the miner"),
+		cmdkit.StringArg("new-owner", true, false, "The address of the new owner"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send the proposal from"),
+		cmdkit.BoolOption("accept", "Accept the transfer as <new-owner>"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		newOwner, err := address.NewFromString(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		fromAddr, err := optionalAddr(req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		method := "proposeOwner"
+		params := []interface{}{newOwner}
+		if accept, _ := req.Options["accept"].(bool); accept {
+			fromAddr = newOwner
+			method = "acceptOwner"
+			params = nil
+		}
+
+		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		if preview {
+			usedGas, err := GetPorcelainAPI(env).MessagePreview(
+				req.Context,
+				fromAddr,
+				minerAddr,
+				method,
+				params...,
+			)
+			if err != nil {
+				return err
+			}
+
+			return re.Emit(&MinerTransferOwnershipResult{
+				Cid:     cid.Cid{},
+				GasUsed: usedGas,
+				Preview: true,
+			})
+		}
+
+		c, err := GetPorcelainAPI(env).MessageSendWithDefaultAddress(
+			req.Context,
+			fromAddr,
+			minerAddr,
+			nil,
+			gasPrice,
+			gasLimit,
+			method,
+			params...,
+		)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&MinerTransferOwnershipResult{
+			Cid:     c,
+			GasUsed: types.NewGasUnits(0),
+			Preview: false,
+		})
+	},
+	Type: &MinerTransferOwnershipResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MinerTransferOwnershipResult) error {
+			if res.Preview {
+				output := strconv.FormatUint(uint64(res.GasUsed), 10)
+				_, err := w.Write([]byte(output))
+				return err
+			}
+			return PrintString(w, res.Cid)
+		}),
+	},
+}
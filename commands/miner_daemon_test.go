@@ -34,12 +34,16 @@ func TestMinerHelp(t *testing.T) {
 		t.Parallel()
 
 		expected := []string{
-			"miner create <pledge> <collateral>      - Create a new file miner with <pledge> sectors and <collateral> FIL",
-			"miner owner <miner>                     - Show the actor address of <miner>",
-			"miner pledge <miner>                    - View number of pledged sectors for <miner>",
-			"miner power <miner>                     - Get the power of a miner versus the total storage market power",
-			"miner set-price <storageprice> <expiry> - Set the minimum price for storage",
-			"miner update-peerid <address> <peerid>  - Change the libp2p identity that a miner is operating",
+			"miner collateral                             - Manage the collateral of a miner",
+			"miner create <pledge> <collateral>           - Create a new file miner with <pledge> sectors and <collateral> FIL",
+			"miner owner <miner>                          - Show the actor address of <miner>",
+			"miner pledge <miner>                         - View number of pledged sectors for <miner>",
+			"miner power <miner>                          - Get the power of a miner versus the total storage market power",
+			"miner sectors <miner>                        - List the sectors committed by a miner",
+			"miner set-price <storageprice> <expiry>      - Set the minimum price for storage",
+			"miner status <miner>                         - Show the proving period status of a miner",
+			"miner transfer-ownership <miner> <new-owner> - Transfer <miner> to <new-owner>",
+			"miner update-peerid <address> <peerid>       - Change the libp2p identity that a miner is operating",
 		}
 
 		result := runHelpSuccess(t, "miner", "--help")
@@ -48,6 +52,21 @@ func TestMinerHelp(t *testing.T) {
 		}
 	})
 
+	t.Run("collateral --help shows collateral subcommands", func(t *testing.T) {
+		t.Parallel()
+
+		expected := []string{
+			"miner collateral add <miner> <amount>      - Add <amount> FIL of collateral to <miner>",
+			"miner collateral show <miner>              - Show the collateral and pledge of <miner>",
+			"miner collateral withdraw <miner> <amount> - Withdraw <amount> FIL of collateral from <miner>",
+		}
+
+		result := runHelpSuccess(t, "miner", "collateral", "--help")
+		for _, elem := range expected {
+			assert.Contains(result, elem)
+		}
+	})
+
 	t.Run("pledge --help shows pledge help", func(t *testing.T) {
 		t.Parallel()
 		result := runHelpSuccess(t, "miner", "pledge", "--help")
@@ -191,7 +210,7 @@ func TestMinerCreate(t *testing.T) {
 
 			d1.ConnectSuccess(d)
 
-			args := []string{"miner", "create", "--from", fromAddress.String(), "--gas-price", "0", "--gas-limit", "300"}
+			args := []string{"miner", "create", "--from", fromAddress.String(), "--gas-price", "0", "--gas-limit", "1000"}
 
 			if pid.Pretty() != peer.ID("").Pretty() {
 				args = append(args, "--peerid", pid.Pretty())
@@ -234,23 +253,23 @@ func TestMinerCreate(t *testing.T) {
 
 		d.RunFail("invalid peer id",
 			"miner", "create",
-			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "300", "--peerid", "flarp", "1000000", "20",
+			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "1000", "--peerid", "flarp", "1000000", "20",
 		)
 		d.RunFail("invalid from address",
 			"miner", "create",
-			"--from", "hello", "--gas-price", "0", "--gas-limit", "300", "1000000", "20",
+			"--from", "hello", "--gas-price", "0", "--gas-limit", "1000", "1000000", "20",
 		)
 		d.RunFail("invalid pledge",
 			"miner", "create",
-			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "300", "'-123'", "20",
+			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "1000", "'-123'", "20",
 		)
 		d.RunFail("invalid pledge",
 			"miner", "create",
-			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "300", "1f", "20",
+			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "1000", "1f", "20",
 		)
 		d.RunFail("invalid collateral",
 			"miner", "create",
-			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "300", "100", "2f",
+			"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "1000", "100", "2f",
 		)
 	})
 
@@ -270,7 +289,7 @@ func TestMinerCreate(t *testing.T) {
 		go func() {
 			d.RunFail("pledge must be at least",
 				"miner", "create",
-				"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "300", "1", "10",
+				"--from", testAddr.String(), "--gas-price", "0", "--gas-limit", "1000", "1", "10",
 			)
 			wg.Done()
 		}()
@@ -313,7 +332,7 @@ func TestMinerCreateSuccess(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "0", "--gas-limit", "300", "100", "200")
+		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "0", "--gas-limit", "1000", "100", "200")
 		addr, err := address.NewFromString(strings.Trim(miner.ReadStdout(), "\n"))
 		assert.NoError(err)
 		assert.NotEqual(addr, address.Undef)
@@ -342,9 +361,14 @@ func TestMinerCreateChargesGas(t *testing.T) {
 	// make sure the FIL shows up in the MinerOwnerAccount
 	startingBalance := queryBalance(t, d, miningMinerOwnerAddr)
 
+	// the message is charged the gas a preview of it reports
+	preview := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "333", "--gas-limit", "1000", "--preview", "100", "200")
+	expectedGasCost, ok := big.NewInt(0).SetString(preview.ReadStdoutTrimNewlines(), 10)
+	require.True(ok)
+
 	wg.Add(1)
 	go func() {
-		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "333", "--gas-limit", "300", "100", "200")
+		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "333", "--gas-limit", "1000", "100", "200")
 		addr, err := address.NewFromString(strings.Trim(miner.ReadStdout(), "\n"))
 		assert.NoError(err)
 		assert.NotEqual(addr, address.Undef)
@@ -356,7 +380,6 @@ func TestMinerCreateChargesGas(t *testing.T) {
 
 	expectedBlockReward := consensus.NewDefaultBlockRewarder().BlockRewardAmount()
 	expectedPrice := types.NewAttoFILFromFIL(333)
-	expectedGasCost := big.NewInt(100)
 	expectedBalance := expectedBlockReward.Add(expectedPrice.MulBigInt(expectedGasCost))
 	newBalance := queryBalance(t, d, miningMinerOwnerAddr)
 	assert.Equal(expectedBalance.String(), newBalance.Sub(startingBalance).String())
@@ -454,6 +477,87 @@ func TestMinerPower(t *testing.T) {
 	assert.Equal("3 / 6", power)
 }
 
+func TestMinerStatus(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	fi, err := ioutil.TempFile("", "gengentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := gengen.GenGenesisCar(testConfig, fi, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = fi.Close()
+
+	d := th.NewDaemon(t, th.GenesisFile(fi.Name())).Start()
+	defer d.ShutdownSuccess()
+
+	status := d.RunSuccess("miner", "status", info.Miners[0].Address.String()).ReadStdout()
+
+	assert.Contains(status, "Power:          3")
+	assert.Contains(status, "Collateral:     100000 FIL")
+	assert.Contains(status, "Proving period: 0 - 20000")
+	assert.Contains(status, "Grace ends:     20100")
+	assert.Contains(status, "Last PoSt:      none")
+	assert.Contains(status, "Status:         ok")
+}
+
+func TestMinerSectors(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	fi, err := ioutil.TempFile("", "gengentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := gengen.GenGenesisCar(testConfig, fi, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = fi.Close()
+
+	d := th.NewDaemon(t, th.GenesisFile(fi.Name())).Start()
+	defer d.ShutdownSuccess()
+
+	sectors := d.RunSuccess("miner", "sectors", info.Miners[0].Address.String()).ReadStdoutTrimNewlines()
+
+	// genesis sectors contain no deals
+	assert.Equal("1 never\n2 never\n3 never", sectors)
+}
+
+func TestMinerCollateralShow(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	fi, err := ioutil.TempFile("", "gengentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := gengen.GenGenesisCar(testConfig, fi, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = fi.Close()
+
+	d := th.NewDaemon(t, th.GenesisFile(fi.Name())).Start()
+	defer d.ShutdownSuccess()
+
+	collateral := d.RunSuccess("miner", "collateral", "show", info.Miners[0].Address.String()).ReadStdout()
+
+	assert.Contains(collateral, "Collateral:   100000 FIL")
+	assert.Contains(collateral, "Locked:       0.003 FIL")
+	assert.Contains(collateral, "Withdrawable: 99999.997 FIL")
+	assert.Contains(collateral, "Pledge:       10000 sectors")
+}
+
 var testConfig = &gengen.GenesisCfg{
 	Keys: 4,
 	PreAlloc: []string{
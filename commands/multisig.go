@@ -0,0 +1,425 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+	"gx/ipfs/Qmf46mr235gtyxizkKUkTH5fo62Thza2zwXR4DWC7rkoqF/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var multisigCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage multisig wallets",
+		ShortDescription: `Multisig wallets are actors that only spend their funds once a threshold of
+their signers have approved a transaction. Any signer may propose a transaction,
+which is executed as soon as enough signers approve it.`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add-signer":       multisigAddSignerCmd,
+		"approve":          multisigApproveCmd,
+		"cancel":           multisigCancelCmd,
+		"change-threshold": multisigChangeThresholdCmd,
+		"pending":          multisigPendingCmd,
+		"propose":          multisigProposeCmd,
+		"signers":          multisigSignersCmd,
+	},
+}
+
+// MultisigSendResult is the return type for the multisig commands that send
+// a message to a multisig wallet.
+type MultisigSendResult struct {
+	Cid     cid.Cid
+	GasUsed types.GasUnits
+	Preview bool
+}
+
+var multisigSendEncoders = cmds.EncoderMap{
+	cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MultisigSendResult) error {
+		if res.Preview {
+			output := strconv.FormatUint(uint64(res.GasUsed), 10)
+			_, err := w.Write([]byte(output))
+			return err
+		}
+		return PrintString(w, res.Cid)
+	}),
+}
+
+var multisigProposeCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Propose a transfer from a multisig wallet",
+		ShortDescription: `Issues a new message proposing that <multisig> send <value> FIL to <target>.
+The proposal counts as approved by the sender. If the wallet's threshold is one it
+executes immediately, otherwise it waits for other signers to approve it. The ID of
+the proposed transaction is returned in the message receipt and listed by
+'go-filecoin multisig pending'.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("target", true, false, "Address to send funds to"),
+		cmdkit.StringArg("value", true, false, "Amount in FIL to send"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Signer address to propose from"),
+		cmdkit.StringOption("method", "Method to invoke on the target"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := address.NewFromString(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		value, ok := types.NewAttoFILFromFILString(req.Arguments[2])
+		if !ok {
+			return ErrInvalidAmount
+		}
+
+		method, _ := req.Options["method"].(string)
+
+		return runMultisigPropose(req, re, env, target, value, method)
+	},
+	Type:     &MultisigSendResult{},
+	Encoders: multisigSendEncoders,
+}
+
+var multisigChangeThresholdCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Propose changing the number of approvals a multisig wallet requires",
+		ShortDescription: `Issues a new message proposing that <multisig> require <threshold> approvals
+for future transactions. The change takes effect once the proposal itself has been
+approved under the current threshold.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("threshold", true, false, "Number of approvals to require"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Signer address to propose from"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msigAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		threshold, ok := big.NewInt(0).SetString(req.Arguments[1], 10)
+		if !ok {
+			return fmt.Errorf("invalid threshold: %s", req.Arguments[1])
+		}
+
+		return runMultisigPropose(req, re, env, msigAddr, types.NewZeroAttoFIL(), "changeThreshold", threshold)
+	},
+	Type:     &MultisigSendResult{},
+	Encoders: multisigSendEncoders,
+}
+
+var multisigAddSignerCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Propose adding a signer to a multisig wallet",
+		ShortDescription: `Issues a new message proposing that <signer> be added to the signers of <multisig>.
+The signer is added once the proposal has been approved under the current threshold.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("signer", true, false, "Address of the new signer"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Signer address to propose from"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msigAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		signer, err := address.NewFromString(req.Arguments[1])
+		if err != nil {
+			return err
+		}
+
+		return runMultisigPropose(req, re, env, msigAddr, types.NewZeroAttoFIL(), "addSigner", signer)
+	},
+	Type:     &MultisigSendResult{},
+	Encoders: multisigSendEncoders,
+}
+
+// runMultisigPropose proposes a transaction from the multisig wallet given as
+// the command's first argument, honoring the from and gas options.
+func runMultisigPropose(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment, target address.Address, value *types.AttoFIL, method string, params ...interface{}) error {
+	msigAddr, err := address.NewFromString(req.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	fromAddr, err := optionalAddr(req.Options["from"])
+	if err != nil {
+		return err
+	}
+
+	gasPrice, gasLimit, preview, err := parseGasOptions(req)
+	if err != nil {
+		return err
+	}
+
+	if preview {
+		usedGas, err := GetPorcelainAPI(env).MultisigPreviewPropose(
+			req.Context,
+			fromAddr,
+			msigAddr,
+			target,
+			value,
+			method,
+			params...,
+		)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&MultisigSendResult{
+			Cid:     cid.Cid{},
+			GasUsed: usedGas,
+			Preview: true,
+		})
+	}
+
+	c, err := GetPorcelainAPI(env).MultisigPropose(
+		req.Context,
+		fromAddr,
+		msigAddr,
+		gasPrice,
+		gasLimit,
+		target,
+		value,
+		method,
+		params...,
+	)
+	if err != nil {
+		return err
+	}
+
+	return re.Emit(&MultisigSendResult{
+		Cid:     c,
+		GasUsed: types.NewGasUnits(0),
+		Preview: false,
+	})
+}
+
+var multisigApproveCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "Approve a pending multisig transaction",
+		ShortDescription: `Issues a new message approving transaction <id> of <multisig>. The transaction is executed if this approval meets the wallet's threshold.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("id", true, false, "ID of the pending transaction"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Signer address to approve from"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return runMultisigTxCmd(req, re, env, "approve")
+	},
+	Type:     &MultisigSendResult{},
+	Encoders: multisigSendEncoders,
+}
+
+var multisigCancelCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "Cancel a pending multisig transaction",
+		ShortDescription: `Issues a new message removing transaction <id> from <multisig>. Only the signer that proposed the transaction may cancel it.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+		cmdkit.StringArg("id", true, false, "ID of the pending transaction"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address of the signer that proposed the transaction"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return runMultisigTxCmd(req, re, env, "cancel")
+	},
+	Type:     &MultisigSendResult{},
+	Encoders: multisigSendEncoders,
+}
+
+// runMultisigTxCmd sends method to the multisig wallet given as the command's
+// first argument with the transaction ID given as the second.
+func runMultisigTxCmd(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment, method string) error {
+	msigAddr, err := address.NewFromString(req.Arguments[0])
+	if err != nil {
+		return err
+	}
+
+	txID, ok := big.NewInt(0).SetString(req.Arguments[1], 10)
+	if !ok {
+		return fmt.Errorf("invalid transaction id: %s", req.Arguments[1])
+	}
+
+	fromAddr, err := optionalAddr(req.Options["from"])
+	if err != nil {
+		return err
+	}
+
+	gasPrice, gasLimit, preview, err := parseGasOptions(req)
+	if err != nil {
+		return err
+	}
+
+	if preview {
+		usedGas, err := GetPorcelainAPI(env).MessagePreview(
+			req.Context,
+			fromAddr,
+			msigAddr,
+			method,
+			txID,
+		)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&MultisigSendResult{
+			Cid:     cid.Cid{},
+			GasUsed: usedGas,
+			Preview: true,
+		})
+	}
+
+	c, err := GetPorcelainAPI(env).MessageSendWithDefaultAddress(
+		req.Context,
+		fromAddr,
+		msigAddr,
+		nil,
+		gasPrice,
+		gasLimit,
+		method,
+		txID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return re.Emit(&MultisigSendResult{
+		Cid:     c,
+		GasUsed: types.NewGasUnits(0),
+		Preview: false,
+	})
+}
+
+// MultisigSignersResult is the return type for the multisig signers command.
+type MultisigSignersResult struct {
+	Signers  []address.Address
+	Required uint64
+}
+
+var multisigSignersCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "Show the signers of a multisig wallet",
+		ShortDescription: `Shows the addresses that may approve transactions of <multisig> and how many approvals it requires.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msigAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		signers, err := GetPorcelainAPI(env).MultisigGetSigners(req.Context, msigAddr)
+		if err != nil {
+			return err
+		}
+
+		required, err := GetPorcelainAPI(env).MultisigGetThreshold(req.Context, msigAddr)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&MultisigSignersResult{
+			Signers:  signers,
+			Required: required,
+		})
+	},
+	Type: &MultisigSignersResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MultisigSignersResult) error {
+			if _, err := fmt.Fprintf(w, "required: %d of %d\n", res.Required, len(res.Signers)); err != nil {
+				return err
+			}
+			for _, s := range res.Signers {
+				if err := PrintString(w, s); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var multisigPendingCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline:          "List the pending transactions of a multisig wallet",
+		ShortDescription: `Lists the transactions of <multisig> that are waiting for approval.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("multisig", true, false, "Address of the multisig wallet"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msigAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		pending, err := GetPorcelainAPI(env).MultisigListPending(req.Context, msigAddr)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(pending)
+	},
+	Type: map[string]*multisig.Transaction{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, txs *map[string]*multisig.Transaction) error {
+			if len(*txs) == 0 {
+				fmt.Fprintln(w, "no pending transactions") // nolint: errcheck
+				return nil
+			}
+
+			var ids []uint64
+			for _, tx := range *txs {
+				ids = append(ids, tx.ID)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+			for _, id := range ids {
+				tx := (*txs)[strconv.FormatUint(id, 10)]
+				_, err := fmt.Fprintf(w, "%d: to: %s, value: %s, method: %q, proposer: %s, approvals: %d\n", tx.ID, tx.To, tx.Value, tx.Method, tx.Proposer, len(tx.Approved))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
@@ -0,0 +1,75 @@
+package commands_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/gengen/util"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+)
+
+func TestMultisigHelp(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	expected := []string{
+		"multisig add-signer <multisig> <signer>          - Propose adding a signer to a multisig wallet",
+		"multisig approve <multisig> <id>                 - Approve a pending multisig transaction",
+		"multisig cancel <multisig> <id>                  - Cancel a pending multisig transaction",
+		"multisig change-threshold <multisig> <threshold> - Propose changing the number of approvals a multisig wallet requires",
+		"multisig pending <multisig>                      - List the pending transactions of a multisig wallet",
+		"multisig propose <multisig> <target> <value>     - Propose a transfer from a multisig wallet",
+		"multisig signers <multisig>                      - Show the signers of a multisig wallet",
+	}
+
+	result := runHelpSuccess(t, "multisig", "--help")
+	for _, elem := range expected {
+		assert.Contains(result, elem)
+	}
+}
+
+func TestMultisigSignersAndPending(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cfg := &gengen.GenesisCfg{
+		Keys:     2,
+		PreAlloc: []string{"10", "10"},
+		Multisigs: []gengen.Multisig{
+			{
+				Signers:  []int{0, 1},
+				Required: 2,
+				Balance:  "100",
+			},
+		},
+	}
+
+	fi, err := ioutil.TempFile("", "gengentest")
+	require.NoError(err)
+
+	info, err := gengen.GenGenesisCar(cfg, fi, 0)
+	require.NoError(err)
+	require.NoError(fi.Close())
+
+	d := th.NewDaemon(t, th.GenesisFile(fi.Name())).Start()
+	defer d.ShutdownSuccess()
+
+	msigAddr := info.Multisigs[0].Address.String()
+
+	signer0, err := info.Keys[0].Address()
+	require.NoError(err)
+	signer1, err := info.Keys[1].Address()
+	require.NoError(err)
+
+	signers := d.RunSuccess("multisig", "signers", msigAddr).ReadStdout()
+	assert.Contains(signers, "required: 2 of 2")
+	assert.Contains(signers, signer0.String())
+	assert.Contains(signers, signer1.String())
+
+	pending := d.RunSuccess("multisig", "pending", msigAddr).ReadStdoutTrimNewlines()
+	assert.Equal("no pending transactions", pending)
+}
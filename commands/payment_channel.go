@@ -169,7 +169,12 @@ var lsCmd = &cmds.Command{
 			}
 
 			for chid, pc := range *pcs {
-				_, err := fmt.Fprintf(w, "%s: target: %v, amt: %v, amt redeemed: %v, eol: %v\n", chid, pc.Target.String(), pc.Amount, pc.AmountRedeemed, pc.Eol)
+				state := "open"
+				if pc.Settling() {
+					state = fmt.Sprintf("settling, settle height: %v", pc.SettleHeight)
+				}
+
+				_, err := fmt.Fprintf(w, "%s: target: %v, amt: %v, amt redeemed: %v, eol: %v, state: %s\n", chid, pc.Target.String(), pc.Amount, pc.AmountRedeemed, pc.Eol, state)
 				if err != nil {
 					return err
 				}
@@ -191,6 +196,8 @@ var voucherCmd = &cmds.Command{
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("from", "Address for which to retrieve channels"),
 		cmdkit.StringOption("validat", "Smallest block height at which target can redeem"),
+		cmdkit.Uint64Option("lane", "Lane of the channel the voucher pays out of").WithDefault(uint64(0)),
+		cmdkit.Uint64Option("nonce", "Nonce of the voucher within its lane, must be greater than any nonce issued on the lane before. Defaults to the next nonce of the lane"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		fromAddr, err := optionalAddr(req.Options["from"])
@@ -213,7 +220,10 @@ var voucherCmd = &cmds.Command{
 			return err
 		}
 
-		voucher, err := GetPorcelainAPI(env).PaymentChannelVoucher(req.Context, fromAddr, channel, amount, validAt)
+		lane, _ := req.Options["lane"].(uint64)
+		nonce, _ := req.Options["nonce"].(uint64)
+
+		voucher, err := GetPorcelainAPI(env).PaymentChannelVoucher(req.Context, fromAddr, channel, amount, validAt, lane, nonce)
 		if err != nil {
 			return err
 		}
@@ -275,12 +285,17 @@ var redeemCmd = &cmds.Command{
 				return err
 			}
 
+			condition, err := paymentbroker.EncodeCondition(voucher.Condition)
+			if err != nil {
+				return err
+			}
+
 			usedGas, err := GetPorcelainAPI(env).MessagePreview(
 				req.Context,
 				fromAddr,
 				address.PaymentBrokerAddress,
 				"redeem",
-				voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, []byte(voucher.Signature),
+				voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, uint64(voucher.Lane), uint64(voucher.Nonce), condition, []byte(voucher.Signature),
 			)
 			if err != nil {
 				return err
@@ -297,6 +312,11 @@ var redeemCmd = &cmds.Command{
 			return err
 		}
 
+		condition, err := paymentbroker.EncodeCondition(voucher.Condition)
+		if err != nil {
+			return err
+		}
+
 		c, err := GetPorcelainAPI(env).MessageSendWithDefaultAddress(
 			req.Context,
 			fromAddr,
@@ -305,7 +325,7 @@ var redeemCmd = &cmds.Command{
 			gasPrice,
 			gasLimit,
 			"redeem",
-			voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, []byte(voucher.Signature),
+			voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, uint64(voucher.Lane), uint64(voucher.Nonce), condition, []byte(voucher.Signature),
 		)
 		if err != nil {
 			return err
@@ -458,12 +478,17 @@ var closeCmd = &cmds.Command{
 				return err
 			}
 
+			condition, err := paymentbroker.EncodeCondition(voucher.Condition)
+			if err != nil {
+				return err
+			}
+
 			usedGas, err := GetPorcelainAPI(env).MessagePreview(
 				req.Context,
 				fromAddr,
 				address.PaymentBrokerAddress,
 				"close",
-				voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, []byte(voucher.Signature),
+				voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, uint64(voucher.Lane), uint64(voucher.Nonce), condition, []byte(voucher.Signature),
 			)
 			if err != nil {
 				return err
@@ -480,6 +505,11 @@ var closeCmd = &cmds.Command{
 			return err
 		}
 
+		condition, err := paymentbroker.EncodeCondition(voucher.Condition)
+		if err != nil {
+			return err
+		}
+
 		c, err := GetPorcelainAPI(env).MessageSendWithDefaultAddress(
 			req.Context,
 			fromAddr,
@@ -488,7 +518,7 @@ var closeCmd = &cmds.Command{
 			gasPrice,
 			gasLimit,
 			"close",
-			voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, []byte(voucher.Signature),
+			voucher.Payer, &voucher.Channel, &voucher.Amount, &voucher.ValidAt, uint64(voucher.Lane), uint64(voucher.Nonce), condition, []byte(voucher.Signature),
 		)
 		if err != nil {
 			return err
@@ -75,7 +75,7 @@ func TestPaymentChannelLs(t *testing.T) {
 		daemonTestWithPaymentChannel(t, &payer, &target, amt, eol, func(d *th.TestDaemon, channelID *types.ChannelID) {
 			ls := listChannelsAsStrs(d, &payer)[0]
 
-			assert.Equal(fmt.Sprintf("%s: target: %s, amt: 10000, amt redeemed: 0, eol: 20", channelID, target.String()), ls)
+			assert.Equal(fmt.Sprintf("%s: target: %s, amt: 10000, amt redeemed: 0, eol: 20, state: open", channelID, target.String()), ls)
 		})
 	})
 
@@ -97,7 +97,7 @@ func TestPaymentChannelLs(t *testing.T) {
 
 			ls := th.RunSuccessLines(d, args...)[0]
 
-			assert.Equal(fmt.Sprintf("%s: target: %s, amt: 10000, amt redeemed: 0, eol: 20", channelID, target.String()), ls)
+			assert.Equal(fmt.Sprintf("%s: target: %s, amt: 10000, amt redeemed: 0, eol: 20, state: open", channelID, target.String()), ls)
 		})
 	})
 
@@ -141,6 +141,41 @@ func TestPaymentChannelVoucherSuccess(t *testing.T) {
 	})
 }
 
+func TestPaymentChannelVoucherLane(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	payer, err := address.NewFromString(fixtures.TestAddresses[2])
+	require.NoError(err)
+	target, err := address.NewFromString(fixtures.TestAddresses[1])
+	require.NoError(err)
+
+	eol := types.NewBlockHeight(20)
+	amt := types.NewAttoFILFromFIL(10000)
+
+	daemonTestWithPaymentChannel(t, &payer, &target, amt, eol, func(d *th.TestDaemon, channelID *types.ChannelID) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		args := []string{"paych", "voucher", channelID.String(), "100", "--from", payer.String(), "--lane", "3", "--nonce", "2"}
+		voucher, err := paymentbroker.DecodeVoucher(th.RunSuccessFirstLine(d, args...))
+		require.NoError(err)
+
+		assert.Equal(types.Uint64(3), voucher.Lane)
+		assert.Equal(types.Uint64(2), voucher.Nonce)
+
+		// without a nonce the voucher gets the next nonce of the lane
+		args = []string{"paych", "voucher", channelID.String(), "200", "--from", payer.String(), "--lane", "3"}
+		voucher, err = paymentbroker.DecodeVoucher(th.RunSuccessFirstLine(d, args...))
+		require.NoError(err)
+		assert.Equal(types.Uint64(3), voucher.Nonce)
+
+		// and a nonce that was issued before is refused
+		args = []string{"paych", "voucher", channelID.String(), "300", "--from", payer.String(), "--lane", "3", "--nonce", "2"}
+		d.RunFail("not greater than the nonce", args...)
+	})
+}
+
 func TestPaymentChannelRedeemSuccess(t *testing.T) {
 	t.Parallel()
 	require := require.New(t)
@@ -172,7 +207,7 @@ func TestPaymentChannelRedeemSuccess(t *testing.T) {
 		mustRedeemVoucher(t, targetDaemon, voucher, &target)
 
 		ls := listChannelsAsStrs(targetDaemon, &payer)[0]
-		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 10000, amt redeemed: 111, eol: 20", channelID.String(), target.String()), ls)
+		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 10000, amt redeemed: 111, eol: 20, state: open", channelID.String(), target.String()), ls)
 	})
 }
 
@@ -208,7 +243,7 @@ func TestPaymentChannelRedeemTooEarlyFails(t *testing.T) {
 		mustRedeemVoucher(t, targetDaemon, voucher, &target)
 
 		ls := listChannelsAsStrs(targetDaemon, &payer)[0]
-		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 10000, amt redeemed: 0, eol: 20", channelID.String(), target.String()), ls)
+		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 10000, amt redeemed: 0, eol: 20, state: open", channelID.String(), target.String()), ls)
 	})
 }
 
@@ -246,7 +281,7 @@ func TestPaymentChannelReclaimSuccess(t *testing.T) {
 		mustRedeemVoucher(t, targetDaemon, voucher, &target)
 
 		lsStr := listChannelsAsStrs(targetDaemon, &payer)[0]
-		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 1000, amt redeemed: 10, eol: %s", channelID, target.String(), eol.String()), lsStr)
+		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 1000, amt redeemed: 10, eol: %s, state: open", channelID, target.String(), eol.String()), lsStr)
 
 		d.RunSuccess("mining once")
 		d.RunSuccess("mining once")
@@ -299,19 +334,13 @@ func TestPaymentChannelCloseSuccess(t *testing.T) {
 		// target redeems the voucher (on-chain) and simultaneously closes the channel
 		mustCloseChannel(t, targetDaemon, voucher, target)
 
-		// channel has been closed
+		// channel is settling, its remaining funds stay locked until the payer reclaims them
 		lsStr := listChannelsAsStrs(targetDaemon, payer)[0]
-		assert.Contains(lsStr, "no channels")
-
-		// channel's original locked funds minus the redeemed voucher amount
-		// are returned to the payer
-		args := []string{"wallet", "balance", payer.String()}
-		balStr := th.RunSuccessFirstLine(targetDaemon, args...)
-		assert.Equal("999999999990", balStr)
+		assert.Contains(lsStr, "amt redeemed: 10, eol: 100, state: settling, settle height: ")
 
 		// target's balance reflects redeemed voucher
-		args = []string{"wallet", "balance", target.String()}
-		balStr = th.RunSuccessFirstLine(targetDaemon, args...)
+		args := []string{"wallet", "balance", target.String()}
+		balStr := th.RunSuccessFirstLine(targetDaemon, args...)
 		assert.Equal("1000000000010", balStr)
 	})
 }
@@ -335,12 +364,12 @@ func TestPaymentChannelExtendSuccess(t *testing.T) {
 		extendedAmt := types.NewAttoFILFromFIL(3001)
 
 		lsStr := listChannelsAsStrs(d, &payer)[0]
-		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 2000, amt redeemed: 0, eol: %s", channelID.String(), target.String(), eol.String()), lsStr)
+		assert.Equal(fmt.Sprintf("%v: target: %s, amt: 2000, amt redeemed: 0, eol: %s, state: open", channelID.String(), target.String(), eol.String()), lsStr)
 
 		mustExtendChannel(t, d, channelID, extendedAmt, extendedEOL, &payer)
 
 		lsStr = listChannelsAsStrs(d, &payer)[0]
-		assert.Equal(fmt.Sprintf("%v: target: %s, amt: %s, amt redeemed: 0, eol: %s", channelID.String(), target.String(), extendedAmt.Add(amt), extendedEOL), lsStr)
+		assert.Equal(fmt.Sprintf("%v: target: %s, amt: %s, amt redeemed: 0, eol: %s, state: open", channelID.String(), target.String(), extendedAmt.Add(amt), extendedEOL), lsStr)
 	})
 }
 
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"io"
+
+	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+	"gx/ipfs/Qmf46mr235gtyxizkKUkTH5fo62Thza2zwXR4DWC7rkoqF/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+)
+
+var repoCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the filecoin repo",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"gc": repoGCCmd,
+	},
+}
+
+var repoGCCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Remove chain data that is no longer needed",
+		ShortDescription: `
+Removes the states of all but the last tipsets of the heaviest chain from the
+blockstore, along with any other chain data they no longer reference, such as
+replaced actor storage and the states of forks. The blocks and messages of the
+whole chain are kept, as is data that is not chain data, e.g. the pieces of
+storage deals. The node stops syncing while blocks are removed. Forks from
+tipsets whose state has been removed can no longer be synced.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("keep", "Number of tipsets, counting back from the head, whose state is kept").WithDefault(uint(1000)),
+		cmdkit.BoolOption("dry-run", "Report what would be removed without removing anything"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		keep, _ := req.Options["keep"].(uint)
+		dryRun, _ := req.Options["dry-run"].(bool)
+
+		result, err := GetPorcelainAPI(env).RepoGC(req.Context, uint64(keep), dryRun)
+		if err != nil {
+			return err
+		}
+		return re.Emit(result)
+	},
+	Type: chain.GCResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, result *chain.GCResult) error {
+			sw := NewSilentWriter(w)
+			if result.DryRun {
+				sw.Printf("would remove %d blocks (%d bytes), keeping %d\n", result.Removed, result.RemovedBytes, result.Kept)
+			} else {
+				sw.Printf("removed %d blocks (%d bytes), kept %d\n", result.Removed, result.RemovedBytes, result.Kept)
+			}
+			return sw.Error()
+		}),
+	},
+}
@@ -0,0 +1,44 @@
+package commands_test
+
+import (
+	"fmt"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/fixtures"
+)
+
+func TestRepoGC(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	d.RunSuccess("mining", "once")
+	d.RunSuccess("mining", "once")
+	d.RunSuccess("mining", "once")
+
+	var wouldRemove, removed int
+	out := d.RunSuccess("repo", "gc", "--keep", "1", "--dry-run").ReadStdoutTrimNewlines()
+	_, err := fmt.Sscanf(out, "would remove %d blocks", &wouldRemove)
+	require.NoError(err)
+	assert.True(wouldRemove > 0)
+
+	out = d.RunSuccess("repo", "gc", "--keep", "1").ReadStdoutTrimNewlines()
+	_, err = fmt.Sscanf(out, "removed %d blocks", &removed)
+	require.NoError(err)
+	assert.Equal(wouldRemove, removed)
+
+	out = d.RunSuccess("repo", "gc", "--keep", "1", "--dry-run").ReadStdoutTrimNewlines()
+	assert.Contains(out, "would remove 0 blocks")
+
+	// the node keeps working on top of the remaining state
+	d.RunSuccess("mining", "once")
+	d.RunSuccess("wallet", "balance", fixtures.TestAddresses[0])
+
+	d.RunFail("at least one tipset", "repo", "gc", "--keep", "0")
+}
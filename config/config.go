@@ -26,6 +26,7 @@ type Config struct {
 	Heartbeat *HeartbeatConfig `json:"heartbeat"`
 	Net       string           `json:"net"`
 	Metrics   *MetricsConfig   `json:"metrics"`
+	Sync      *SyncConfig      `json:"sync"`
 }
 
 // APIConfig holds all configuration options related to the api.
@@ -167,6 +168,20 @@ func newDefaultMetricsConfig() *MetricsConfig {
 	}
 }
 
+// SyncConfig holds all configuration options related to syncing the chain.
+type SyncConfig struct {
+	// Checkpoint is the key of a tipset trusted to be part of the chain.
+	// When set, the node only syncs chains that include it and starts
+	// from a snapshot of it instead of validating the chain below it.
+	Checkpoint types.SortedCidSet `json:"checkpoint"`
+}
+
+func newDefaultSyncConfig() *SyncConfig {
+	return &SyncConfig{
+		Checkpoint: types.SortedCidSet{},
+	}
+}
+
 // NewDefaultConfig returns a config object with all the fields filled out to
 // their default values
 func NewDefaultConfig() *Config {
@@ -180,6 +195,7 @@ func NewDefaultConfig() *Config {
 		Heartbeat: newDefaultHeartbeatConfig(),
 		Net:       "",
 		Metrics:   newDefaultMetricsConfig(),
+		Sync:      newDefaultSyncConfig(),
 	}
 }
 
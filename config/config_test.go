@@ -84,6 +84,9 @@ func TestWriteFile(t *testing.T) {
 		"prometheusEnabled": false,
 		"reportInterval": "5s",
 		"prometheusEndpoint": "/ip4/0.0.0.0/tcp/9400"
+	},
+	"sync": {
+		"checkpoint": null
 	}
 }`,
 		string(content),
@@ -15,9 +15,9 @@ import (
 	logging "gx/ipfs/QmbkT7eMTyXfpeyB3ZMxxcxg7XH8t6uXp49jqzz4HB7BGF/go-log"
 	"gx/ipfs/QmcTzQXRcU2vf8yX5EEboz1BSvWC7wWmeYAKVQmhp8WZYU/sha256-simd"
 
-	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -95,6 +95,9 @@ type Expected struct {
 	genesisCid cid.Cid
 
 	verifier proofs.Verifier
+
+	// forks is the schedule of upgrades applied during state transitions.
+	forks ForkSchedule
 }
 
 // Ensure Expected satisfies the Protocol interface at compile time.
@@ -102,6 +105,13 @@ var _ Protocol = (*Expected)(nil)
 
 // NewExpected is the constructor for the Expected consenus.Protocol module.
 func NewExpected(cs *hamt.CborIpldStore, bs blockstore.Blockstore, processor Processor, pt PowerTableView, gCid cid.Cid, verifier proofs.Verifier) Protocol {
+	return NewExpectedWithForks(cs, bs, processor, pt, gCid, verifier, DefaultForkSchedule)
+}
+
+// NewExpectedWithForks creates an Expected consensus.Protocol module that
+// upgrades the chain according to the given fork schedule rather than the
+// default one.
+func NewExpectedWithForks(cs *hamt.CborIpldStore, bs blockstore.Blockstore, processor Processor, pt PowerTableView, gCid cid.Cid, verifier proofs.Verifier, forks ForkSchedule) Protocol {
 	return &Expected{
 		cstore:       cs,
 		bstore:       bs,
@@ -109,6 +119,7 @@ func NewExpected(cs *hamt.CborIpldStore, bs blockstore.Blockstore, processor Pro
 		PwrTableView: pt,
 		genesisCid:   gCid,
 		verifier:     verifier,
+		forks:        forks,
 	}
 }
 
@@ -254,8 +265,22 @@ func (c *Expected) RunStateTransition(ctx context.Context, ts types.TipSet, ance
 		}
 	}
 
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	parentHeight, err := ancestors[0].Height()
+	if err != nil {
+		return nil, err
+	}
+
 	vms := vm.NewStorageMap(c.bstore)
-	st, err := c.runMessages(ctx, pSt, vms, ts, ancestors)
+	pSt, err = c.forks.UpgradeState(ctx, c.cstore, pSt, vms, parentHeight, height)
+	if err != nil {
+		return nil, errors.Wrap(err, "error upgrading parent state")
+	}
+
+	st, err := c.runMessages(ctx, pSt, vms, ts, ancestors, c.forks.ActorsAt(height))
 	if err != nil {
 		return nil, err
 	}
@@ -350,29 +375,16 @@ func CreateChallengeSeed(parents types.TipSet, nullBlkCount uint64) (proofs.PoSt
 // An error is returned if individual blocks contain messages that do not
 // lead to successful state transitions.  An error is also returned if the node
 // faults while running aggregate state computation.
-func (c *Expected) runMessages(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet) (state.Tree, error) {
+func (c *Expected) runMessages(ctx context.Context, st state.Tree, vms vm.StorageMap, ts types.TipSet, ancestors []types.TipSet, actors map[cid.Cid]exec.ExecutableActor) (state.Tree, error) {
 	var cpySt state.Tree
 
 	// TODO: order blocks in the tipset by ticket
 	// TODO: don't process messages twice
 	for _, blk := range ts.ToSlice() {
-		cpyCid, err := st.Flush(ctx)
+		var err error
+		cpySt, err = c.blockState(ctx, st, vms, blk, ancestors, actors)
 		if err != nil {
-			return nil, errors.Wrap(err, "error validating block state")
-		}
-		// state copied so changes don't propagate between block validations
-		cpySt, err = state.LoadStateTree(ctx, c.cstore, cpyCid, builtin.Actors)
-		if err != nil {
-			return nil, errors.Wrap(err, "error validating block state")
-		}
-
-		receipts, err := c.processor.ProcessBlock(ctx, cpySt, vms, blk, ancestors)
-		if err != nil {
-			return nil, errors.Wrap(err, "error validating block state")
-		}
-		// TODO: check that receipts actually match
-		if len(receipts) != len(blk.MessageReceipts) {
-			return nil, fmt.Errorf("found invalid message receipts: %v %v", receipts, blk.MessageReceipts)
+			return nil, err
 		}
 
 		outCid, err := cpySt.Flush(ctx)
@@ -397,6 +409,64 @@ func (c *Expected) runMessages(ctx context.Context, st state.Tree, vms vm.Storag
 	return st, nil
 }
 
+// blockState applies the messages of blk to a copy of st and returns the
+// copy.
+func (c *Expected) blockState(ctx context.Context, st state.Tree, vms vm.StorageMap, blk *types.Block, ancestors []types.TipSet, actors map[cid.Cid]exec.ExecutableActor) (state.Tree, error) {
+	cpyCid, err := st.Flush(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error validating block state")
+	}
+	// state copied so changes don't propagate between block validations
+	cpySt, err := state.LoadStateTree(ctx, c.cstore, cpyCid, actors)
+	if err != nil {
+		return nil, errors.Wrap(err, "error validating block state")
+	}
+
+	receipts, err := c.processor.ProcessBlock(ctx, cpySt, vms, blk, ancestors)
+	if err != nil {
+		return nil, errors.Wrap(err, "error validating block state")
+	}
+	// TODO: check that receipts actually match
+	if len(receipts) != len(blk.MessageReceipts) {
+		return nil, fmt.Errorf("found invalid message receipts: %v %v", receipts, blk.MessageReceipts)
+	}
+	return cpySt, nil
+}
+
+// BlockStates computes the state each block of ts commits to in its
+// StateRoot, the way RunStateTransition does to validate them, without
+// checking the results against the blocks. The states are returned in the
+// order of ts.ToSlice(). It is used to debug state root mismatches.
+func (c *Expected) BlockStates(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) ([]state.Tree, error) {
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	parentHeight, err := ancestors[0].Height()
+	if err != nil {
+		return nil, err
+	}
+
+	vms := vm.NewStorageMap(c.bstore)
+	pSt, err = c.forks.UpgradeState(ctx, c.cstore, pSt, vms, parentHeight, height)
+	if err != nil {
+		return nil, errors.Wrap(err, "error upgrading parent state")
+	}
+
+	var states []state.Tree
+	for _, blk := range ts.ToSlice() {
+		blkSt, err := c.blockState(ctx, pSt, vms, blk, ancestors, c.forks.ActorsAt(height))
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, blkSt)
+	}
+	if err := vms.Flush(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
 // CreateTicket computes a valid ticket.
 // 	params:  proof  []byte, the proof to sign
 // 			 signerPubKey []byte, the public key for the signer. Must exist in the signer
@@ -0,0 +1,179 @@
+package consensus
+
+import (
+	"context"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+// Migration transforms the state of the chain when an upgrade activates.
+// Changes to actor storage must be made through vms so that they are
+// flushed along with the rest of the state transition.
+type Migration func(ctx context.Context, st state.Tree, vms vm.StorageMap) error
+
+// Upgrade is a change to the protocol that activates at a fixed block height.
+type Upgrade struct {
+	// Height is the height of the first tipset processed under the upgrade.
+	Height uint64
+
+	// Actors are the actor implementations the upgrade introduces, keyed by
+	// code CID. They are available to every tipset at or above Height and
+	// take precedence over builtin actors with the same code CID.
+	Actors map[cid.Cid]exec.ExecutableActor
+
+	// Migrate, if set, is run over the parent state of the first tipset at
+	// or above Height, before any of that tipset's messages are applied.
+	Migrate Migration
+
+	// GasSchedule, if set, replaces the gas schedule for messages at or
+	// above Height.
+	GasSchedule *vm.GasSchedule
+}
+
+// ForkSchedule is the list of upgrades a chain goes through, ordered by
+// activation height.
+type ForkSchedule []*Upgrade
+
+// MinerSectorsUpgradeHeight is the height at which miners still keeping
+// their sector commitments in the legacy State.SectorCommitments map are
+// migrated to the Sectors lookup.
+const MinerSectorsUpgradeHeight = 1
+
+// DefaultForkSchedule is the schedule of upgrades of the network.
+var DefaultForkSchedule = ForkSchedule{
+	{Height: MinerSectorsUpgradeHeight, Migrate: MigrateMinerSectors},
+}
+
+// MigrateMinerSectors moves the sector commitments of every miner that still
+// uses the legacy State.SectorCommitments map into its Sectors lookup.
+func MigrateMinerSectors(ctx context.Context, st state.Tree, vms vm.StorageMap) error {
+	var miners []address.Address
+	err := st.ForEachActor(ctx, func(addr address.Address, act *actor.Actor) error {
+		if act.Code.Equals(types.MinerActorCodeCid) {
+			miners = append(miners, addr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range miners {
+		act, err := st.GetActor(ctx, addr)
+		if err != nil {
+			return err
+		}
+
+		storage := vms.NewStorage(addr, act)
+		chunk, err := storage.Get(storage.Head())
+		if err != nil {
+			return errors.Wrapf(err, "failed to read state of miner %s", addr)
+		}
+		var minerState miner.State
+		if err := actor.UnmarshalStorage(chunk, &minerState); err != nil {
+			return errors.Wrapf(err, "failed to decode state of miner %s", addr)
+		}
+		if len(minerState.SectorCommitments) == 0 {
+			continue
+		}
+
+		if err := miner.MigrateSectorCommitments(ctx, storage, &minerState); err != nil {
+			return errors.Wrapf(err, "failed to migrate sectors of miner %s", addr)
+		}
+		stateBytes, err := actor.MarshalStorage(&minerState)
+		if err != nil {
+			return err
+		}
+		id, err := storage.Put(stateBytes)
+		if err != nil {
+			return err
+		}
+		if err := storage.Commit(id, storage.Head()); err != nil {
+			return err
+		}
+		if err := st.SetActor(ctx, addr, act); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ActorsAt returns the actor implementations that messages at the given
+// height run against.
+func (fs ForkSchedule) ActorsAt(height uint64) map[cid.Cid]exec.ExecutableActor {
+	actors := builtin.Actors
+	copied := false
+	for _, upgrade := range fs {
+		if upgrade.Height > height || len(upgrade.Actors) == 0 {
+			continue
+		}
+
+		// never modify builtin.Actors itself
+		if !copied {
+			actors = make(map[cid.Cid]exec.ExecutableActor, len(builtin.Actors)+len(upgrade.Actors))
+			for c, a := range builtin.Actors {
+				actors[c] = a
+			}
+			copied = true
+		}
+		for c, a := range upgrade.Actors {
+			actors[c] = a
+		}
+	}
+	return actors
+}
+
+// GasScheduleAt returns the gas schedule that messages at the given height
+// are charged by.
+func (fs ForkSchedule) GasScheduleAt(height uint64) *vm.GasSchedule {
+	schedule := vm.GasScheduleV0
+	for _, upgrade := range fs {
+		if upgrade.Height > height || upgrade.GasSchedule == nil {
+			continue
+		}
+		schedule = upgrade.GasSchedule
+	}
+	return schedule
+}
+
+// Migrate runs the migrations of every upgrade that activates after
+// parentHeight and at or before height, in order. Null rounds between the
+// two heights do not cause upgrades scheduled during them to be skipped.
+func (fs ForkSchedule) Migrate(ctx context.Context, st state.Tree, vms vm.StorageMap, parentHeight, height uint64) error {
+	for _, upgrade := range fs {
+		if upgrade.Height <= parentHeight || upgrade.Height > height || upgrade.Migrate == nil {
+			continue
+		}
+
+		if err := upgrade.Migrate(ctx, st, vms); err != nil {
+			return errors.Wrapf(err, "failed to run migration at height %d", upgrade.Height)
+		}
+	}
+	return nil
+}
+
+// UpgradeState migrates st from parentHeight to height and returns the
+// resulting state, loaded with the actors available at height.
+func (fs ForkSchedule) UpgradeState(ctx context.Context, cst *hamt.CborIpldStore, st state.Tree, vms vm.StorageMap, parentHeight, height uint64) (state.Tree, error) {
+	if err := fs.Migrate(ctx, st, vms, parentHeight, height); err != nil {
+		return nil, err
+	}
+
+	root, err := st.Flush(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to flush migrated state")
+	}
+
+	return state.LoadStateTree(ctx, cst, root, fs.ActorsAt(height))
+}
@@ -0,0 +1,220 @@
+package consensus_test
+
+import (
+	"context"
+	"testing"
+
+	dag "gx/ipfs/QmNRAuGmvnVw8urHkUZQirhu42VTiZjVWASa2aTznEMmpP/go-merkledag"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+var testMinerV2CodeCid = dag.NewRawNode([]byte("mineractorv2")).Cid()
+
+// migrateMinersToV2 moves the sector commitments of every miner into their
+// Sectors lookup and switches them over to the v2 miner code.
+func migrateMinersToV2(ctx context.Context, st state.Tree, vms vm.StorageMap) error {
+	if err := consensus.MigrateMinerSectors(ctx, st, vms); err != nil {
+		return err
+	}
+
+	var miners []address.Address
+	err := st.ForEachActor(ctx, func(addr address.Address, act *actor.Actor) error {
+		if act.Code.Equals(types.MinerActorCodeCid) {
+			miners = append(miners, addr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range miners {
+		act, err := st.GetActor(ctx, addr)
+		if err != nil {
+			return err
+		}
+		act.Code = testMinerV2CodeCid
+		if err := st.SetActor(ctx, addr, act); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readActorState(storage exec.Storage, out interface{}) error {
+	chunk, err := storage.Get(storage.Head())
+	if err != nil {
+		return err
+	}
+	return actor.UnmarshalStorage(chunk, out)
+}
+
+func writeActorState(storage exec.Storage, in interface{}) error {
+	stateBytes, err := cbor.DumpObject(in)
+	if err != nil {
+		return err
+	}
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+	return storage.Commit(id, storage.Head())
+}
+
+func requireMinerState(ctx context.Context, require *require.Assertions, st state.Tree, bs blockstore.Blockstore, addr address.Address) (*actor.Actor, *miner.State) {
+	act, err := st.GetActor(ctx, addr)
+	require.NoError(err)
+
+	var minerState miner.State
+	require.NoError(readActorState(vm.NewStorageMap(bs).NewStorage(addr, act), &minerState))
+	return act, &minerState
+}
+
+func TestForkScheduleActorsAt(t *testing.T) {
+	assert := assert.New(t)
+
+	forks := consensus.ForkSchedule{
+		{Height: 10, Actors: map[cid.Cid]exec.ExecutableActor{testMinerV2CodeCid: &miner.Actor{}}},
+	}
+
+	assert.Equal(builtin.Actors, forks.ActorsAt(9))
+	assert.NotContains(forks.ActorsAt(9), testMinerV2CodeCid)
+
+	actors := forks.ActorsAt(10)
+	assert.Contains(actors, testMinerV2CodeCid)
+	assert.Contains(actors, types.MinerActorCodeCid)
+	assert.NotContains(builtin.Actors, testMinerV2CodeCid)
+}
+
+func TestForkScheduleGasScheduleAt(t *testing.T) {
+	assert := assert.New(t)
+
+	v1 := &vm.GasSchedule{Call: 1000}
+	forks := consensus.ForkSchedule{
+		{Height: 10, GasSchedule: v1},
+		{Height: 20, Actors: map[cid.Cid]exec.ExecutableActor{testMinerV2CodeCid: &miner.Actor{}}},
+	}
+
+	assert.Equal(vm.GasScheduleV0, consensus.DefaultForkSchedule.GasScheduleAt(500))
+	assert.Equal(vm.GasScheduleV0, forks.GasScheduleAt(9))
+	assert.Equal(v1, forks.GasScheduleAt(10))
+	assert.Equal(v1, forks.GasScheduleAt(500))
+}
+
+func TestForkScheduleMigratesMinerState(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cst, bs, verifier := setupCborBlockstoreProofs()
+	genesisBlock, err := consensus.DefaultGenesis(cst, bs)
+	require.NoError(err)
+
+	// add a miner whose commitments still use the legacy layout
+	mockSigner, kis := types.NewMockSignersAndKeyInfo(1)
+	ownerAddr, err := kis[0].Address()
+	require.NoError(err)
+	minerAddr, err := address.NewActorAddress([]byte("legacy miner"))
+	require.NoError(err)
+
+	st, err := state.LoadStateTree(ctx, cst, genesisBlock.StateRoot, builtin.Actors)
+	require.NoError(err)
+	vms := vm.NewStorageMap(bs)
+
+	require.NoError(st.SetActor(ctx, ownerAddr, testhelpers.RequireNewAccountActor(require, types.NewZeroAttoFIL())))
+	minerActor := testhelpers.RequireNewMinerActor(require, vms, minerAddr, ownerAddr, kis[0].PublicKey(), 10000, testhelpers.RequireRandomPeerID(require), types.NewZeroAttoFIL())
+
+	comms := types.Commitments{
+		CommD:     proofs.CommD{1},
+		CommR:     proofs.CommR{2},
+		CommRStar: proofs.CommRStar{3},
+	}
+	storage := vms.NewStorage(minerAddr, minerActor)
+	var legacyState miner.State
+	require.NoError(readActorState(storage, &legacyState))
+	legacyState.SectorCommitments = map[string]types.Commitments{"1": comms}
+	require.NoError(writeActorState(storage, &legacyState))
+	require.NoError(vms.Flush())
+	require.NoError(st.SetActor(ctx, minerAddr, minerActor))
+
+	baseRoot, err := st.Flush(ctx)
+	require.NoError(err)
+
+	forks := consensus.ForkSchedule{
+		{
+			Height:  2,
+			Actors:  map[cid.Cid]exec.ExecutableActor{testMinerV2CodeCid: &miner.Actor{}},
+			Migrate: migrateMinersToV2,
+		},
+	}
+	exp := consensus.NewExpectedWithForks(cst, bs, testhelpers.NewTestProcessor(), testhelpers.NewTestPowerTableView(1, 1), genesisBlock.Cid(), verifier, forks)
+
+	genesisTs := testhelpers.RequireNewTipSet(require, genesisBlock)
+	loadBase := func() state.Tree {
+		baseSt, err := state.LoadStateTree(ctx, cst, baseRoot, builtin.Actors)
+		require.NoError(err)
+		return baseSt
+	}
+
+	// before the activation height the state is left alone
+	ts1 := testhelpers.RequireNewTipSet(require, testhelpers.NewValidTestBlockFromTipSet(genesisTs, baseRoot, 1, minerAddr, kis[0].PublicKey(), mockSigner))
+	st1, err := exp.RunStateTransition(ctx, ts1, []types.TipSet{genesisTs}, loadBase())
+	require.NoError(err)
+
+	act, minerState := requireMinerState(ctx, require, st1, bs, minerAddr)
+	assert.Equal(types.MinerActorCodeCid, act.Code)
+	assert.Equal(map[string]types.Commitments{"1": comms}, minerState.SectorCommitments)
+
+	ancestors := []types.TipSet{ts1, genesisTs}
+
+	t.Run("blocks at the activation height must include the migration", func(t *testing.T) {
+		ts2 := testhelpers.RequireNewTipSet(require, testhelpers.NewValidTestBlockFromTipSet(ts1, baseRoot, 2, minerAddr, kis[0].PublicKey(), mockSigner))
+		_, err := exp.RunStateTransition(ctx, ts2, ancestors, loadBase())
+		assert.Equal(consensus.ErrStateRootMismatch, err)
+	})
+
+	t.Run("the migration runs at the activation height", func(t *testing.T) {
+		migrationVms := vm.NewStorageMap(bs)
+		migrated, err := forks.UpgradeState(ctx, cst, loadBase(), migrationVms, 1, 2)
+		require.NoError(err)
+		require.NoError(migrationVms.Flush())
+		migratedRoot, err := migrated.Flush(ctx)
+		require.NoError(err)
+		require.False(migratedRoot.Equals(baseRoot))
+
+		ts2 := testhelpers.RequireNewTipSet(require, testhelpers.NewValidTestBlockFromTipSet(ts1, migratedRoot, 2, minerAddr, kis[0].PublicKey(), mockSigner))
+		st2, err := exp.RunStateTransition(ctx, ts2, ancestors, loadBase())
+		require.NoError(err)
+
+		act, minerState := requireMinerState(ctx, require, st2, bs, minerAddr)
+		assert.Equal(testMinerV2CodeCid, act.Code)
+		assert.Nil(minerState.SectorCommitments)
+
+		sectors, err := actor.LoadTypedLookup(ctx, vm.NewStorageMap(bs).NewStorage(minerAddr, act), minerState.Sectors, types.Commitments{})
+		require.NoError(err)
+		value, err := sectors.Find(ctx, "1")
+		require.NoError(err)
+		assert.Equal(comms, value)
+
+		// the new code can be called after the upgrade
+		owner, err := consensus.MinerOwnerAddress(ctx, st2, vm.NewStorageMap(bs), minerAddr)
+		require.NoError(err)
+		assert.Equal(ownerAddr, owner)
+	})
+}
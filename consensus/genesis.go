@@ -3,6 +3,7 @@ package consensus
 import (
 	"context"
 	"math/big"
+	"sort"
 
 	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
 	"gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
@@ -11,9 +12,12 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/vesting"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -37,10 +41,18 @@ func init() {
 
 // Config is used to configure values in the GenesisInitFunction.
 type Config struct {
-	accounts map[address.Address]*types.AttoFIL
-	nonces   map[address.Address]uint64
-	actors   map[address.Address]*actor.Actor
-	miners   map[address.Address]*miner.State
+	accounts  map[address.Address]*types.AttoFIL
+	nonces    map[address.Address]uint64
+	actors    map[address.Address]*actor.Actor
+	miners    map[address.Address]*miner.State
+	multisigs map[address.Address]*multisigAlloc
+	vestings  map[address.Address]*vesting.State
+}
+
+// multisigAlloc describes a multisig actor preallocated in genesis.
+type multisigAlloc struct {
+	balance *types.AttoFIL
+	state   *multisig.State
 }
 
 // GenOption is a configuration option for the GenesisInitFunction.
@@ -62,6 +74,27 @@ func MinerActor(addr address.Address, owner address.Address, key []byte, pledge
 	}
 }
 
+// MultisigActor returns a config option that sets up a multisig actor owned
+// by the given signers.
+func MultisigActor(addr address.Address, signers []address.Address, required uint64, balance *types.AttoFIL) GenOption {
+	return func(gc *Config) error {
+		gc.multisigs[addr] = &multisigAlloc{
+			balance: balance,
+			state:   multisig.NewState(signers, required),
+		}
+		return nil
+	}
+}
+
+// VestingActor returns a config option that sets up a vesting actor holding
+// total, which vests to the beneficiary over duration blocks from startHeight.
+func VestingActor(addr address.Address, beneficiary address.Address, total *types.AttoFIL, startHeight, duration uint64) GenOption {
+	return func(gc *Config) error {
+		gc.vestings[addr] = vesting.NewState(beneficiary, total, types.NewBlockHeight(startHeight), types.NewBlockHeight(duration))
+		return nil
+	}
+}
+
 // ActorNonce returns a config option that sets the nonce of an existing actor.
 func ActorNonce(addr address.Address, nonce uint64) GenOption {
 	return func(gc *Config) error {
@@ -83,10 +116,12 @@ func AddActor(addr address.Address, actor *actor.Actor) GenOption {
 // NewEmptyConfig inits and returns an empty config
 func NewEmptyConfig() *Config {
 	return &Config{
-		accounts: make(map[address.Address]*types.AttoFIL),
-		nonces:   make(map[address.Address]uint64),
-		actors:   make(map[address.Address]*actor.Actor),
-		miners:   make(map[address.Address]*miner.State),
+		accounts:  make(map[address.Address]*types.AttoFIL),
+		nonces:    make(map[address.Address]uint64),
+		actors:    make(map[address.Address]*actor.Actor),
+		miners:    make(map[address.Address]*miner.State),
+		multisigs: make(map[address.Address]*multisigAlloc),
+		vestings:  make(map[address.Address]*vesting.State),
 	}
 }
 
@@ -132,6 +167,28 @@ func MakeGenesisFunc(opts ...GenOption) GenesisInitFunc {
 				return nil, err
 			}
 		}
+		// Initialize multisig actors
+		for addr, val := range genCfg.multisigs {
+			a := multisig.NewActor(val.balance)
+
+			if err := (&multisig.Actor{}).InitializeState(storageMap.NewStorage(addr, a), val.state); err != nil {
+				return nil, err
+			}
+			if err := st.SetActor(ctx, addr, a); err != nil {
+				return nil, err
+			}
+		}
+		// Initialize vesting actors
+		for addr, val := range genCfg.vestings {
+			a := vesting.NewActor(val.Total)
+
+			if err := (&vesting.Actor{}).InitializeState(storageMap.NewStorage(addr, a), val); err != nil {
+				return nil, err
+			}
+			if err := st.SetActor(ctx, addr, a); err != nil {
+				return nil, err
+			}
+		}
 		for addr, nonce := range genCfg.nonces {
 			a, err := st.GetActor(ctx, addr)
 			if err != nil {
@@ -151,6 +208,9 @@ func MakeGenesisFunc(opts ...GenOption) GenesisInitFunc {
 				return nil, err
 			}
 		}
+		if err := AssignGenesisIDs(ctx, st, storageMap); err != nil {
+			return nil, err
+		}
 
 		c, err := st.Flush(ctx)
 		if err != nil {
@@ -213,5 +273,56 @@ func SetupDefaultActors(ctx context.Context, st state.Tree, storageMap vm.Storag
 
 	pbAct.Balance = types.NewAttoFILFromFIL(0)
 
-	return st.SetActor(ctx, address.PaymentBrokerAddress, pbAct)
+	if err := st.SetActor(ctx, address.PaymentBrokerAddress, pbAct); err != nil {
+		return err
+	}
+
+	initAct := initactor.NewActor()
+	initStorage := storageMap.NewStorage(address.InitAddress, initAct)
+	if err := (&initactor.Actor{}).InitializeState(initStorage, nil); err != nil {
+		return err
+	}
+	// Builtin actors get the first IDs, in this order.
+	for _, addr := range []address.Address{address.InitAddress, address.StorageMarketAddress, address.PaymentBrokerAddress} {
+		if _, err := initactor.AssignID(ctx, initStorage, addr); err != nil {
+			return err
+		}
+	}
+
+	return st.SetActor(ctx, address.InitAddress, initAct)
+}
+
+// AssignGenesisIDs has the init actor assign IDs to all actors of the genesis
+// state st that don't have one yet. Genesis actors are set directly rather
+// than created through the VM, so they would otherwise never get an ID. IDs
+// are assigned in the order of the actors' addresses, which keeps genesis
+// deterministic. It must be called after SetupDefaultActors.
+func AssignGenesisIDs(ctx context.Context, st state.Tree, storageMap vm.StorageMap) error {
+	// ForEachActor only visits actors that have been flushed
+	if _, err := st.Flush(ctx); err != nil {
+		return err
+	}
+
+	var addrs []address.Address
+	err := st.ForEachActor(ctx, func(addr address.Address, _ *actor.Actor) error {
+		addrs = append(addrs, addr)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].String() < addrs[j].String() })
+
+	initAct, err := st.GetActor(ctx, address.InitAddress)
+	if err != nil {
+		return err
+	}
+	initStorage := storageMap.NewStorage(address.InitAddress, initAct)
+	for _, addr := range addrs {
+		if _, err := initactor.AssignID(ctx, initStorage, addr); err != nil {
+			return err
+		}
+	}
+
+	return st.SetActor(ctx, address.InitAddress, initAct)
 }
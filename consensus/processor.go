@@ -6,7 +6,9 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -27,8 +29,9 @@ type BlockRewarder interface {
 	// BlockReward pays out the mining reward
 	BlockReward(ctx context.Context, st state.Tree, minerOwnerAddr address.Address) error
 
-	// GasReward pays gas from the sender to the miner
-	GasReward(ctx context.Context, st state.Tree, minerOwnerAddr address.Address, msg *types.SignedMessage, cost *types.AttoFIL) error
+	// GasReward pays gas from the sender to the miner. The sender is given by
+	// its actor address, never by ID.
+	GasReward(ctx context.Context, st state.Tree, minerOwnerAddr address.Address, from address.Address, cost *types.AttoFIL) error
 }
 
 // ApplicationResult contains the result of successfully applying one message.
@@ -37,6 +40,10 @@ type BlockRewarder interface {
 type ApplicationResult struct {
 	Receipt        *types.MessageReceipt
 	ExecutionError error
+
+	// Trace records the execution of the message and every message it sent.
+	// It is nil if the message was rejected before it reached the VM.
+	Trace *vm.Trace
 }
 
 // ProcessTipSetResponse records the results of successfully applied messages,
@@ -53,23 +60,29 @@ type ProcessTipSetResponse struct {
 type DefaultProcessor struct {
 	signedMessageValidator SignedMessageValidator
 	blockRewarder          BlockRewarder
+	// forks decides the gas schedule messages are charged by.
+	forks ForkSchedule
 }
 
 var _ Processor = (*DefaultProcessor)(nil)
 
 // NewDefaultProcessor creates a default processor from the given state tree and vms.
 func NewDefaultProcessor() *DefaultProcessor {
-	return &DefaultProcessor{
-		signedMessageValidator: NewDefaultMessageValidator(),
-		blockRewarder:          NewDefaultBlockRewarder(),
-	}
+	return NewDefaultProcessorWithForks(DefaultForkSchedule)
+}
+
+// NewDefaultProcessorWithForks creates a default processor that charges
+// messages by the gas schedules of the given upgrades.
+func NewDefaultProcessorWithForks(forks ForkSchedule) *DefaultProcessor {
+	return NewConfiguredProcessor(NewDefaultMessageValidator(), NewDefaultBlockRewarder(), forks)
 }
 
-// NewConfiguredProcessor creates a default processor with custom validation and rewards.
-func NewConfiguredProcessor(validator SignedMessageValidator, rewarder BlockRewarder) *DefaultProcessor {
+// NewConfiguredProcessor creates a default processor with custom validation, rewards and upgrades.
+func NewConfiguredProcessor(validator SignedMessageValidator, rewarder BlockRewarder, forks ForkSchedule) *DefaultProcessor {
 	return &DefaultProcessor{
 		signedMessageValidator: validator,
 		blockRewarder:          rewarder,
+		forks:                  forks,
 	}
 }
 
@@ -110,7 +123,7 @@ func (p *DefaultProcessor) ProcessBlock(ctx context.Context, st state.Tree, vms
 	}()
 
 	// find miner's owner address
-	minerOwnerAddr, err := minerOwnerAddress(ctx, st, vms, blk.Miner)
+	minerOwnerAddr, err := MinerOwnerAddress(ctx, st, vms, blk.Miner)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +169,7 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 	// consensus functions).
 	for _, blk := range tips {
 		// find miner's owner address
-		minerOwnerAddr, err := minerOwnerAddress(ctx, st, vms, blk.Miner)
+		minerOwnerAddr, err := MinerOwnerAddress(ctx, st, vms, blk.Miner)
 		if err != nil {
 			return &emptyRes, err
 		}
@@ -262,6 +275,8 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 //       keep in pool). There could be an account-creating message forthcoming.
 //   - send to self: permanently unapplyable (don't include in a block, revert changes,
 //       discard)
+//   - send to an ID address that has not been assigned: permanently unapplyable
+//       (as above)
 //   - transfer negative value: permanently unapplyable (as above)
 //   - all other vmerrors: successfully applied! Include in the block and
 //       revert changes. Necessarily all vm errors that are not faults are
@@ -282,9 +297,18 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 		log.Infof("[TIMER] DefaultProcessor.ApplyMessage CID: %s - elapsed time: %s", msgCid.String(), dur)
 	}()
 
+	// Messages may address their sender by ID. The VM only deals with actor
+	// addresses, and the sender is also needed below to charge it for gas.
+	from, err := vm.ResolveAddress(ctx, st, vms, msg.From)
+	if errors.IsFault(err) {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.ApplyErrorTemporaryWrapf(errFromAccountNotFound, "apply message failed")
+	}
+
 	cachedStateTree := state.NewCachedStateTree(st)
 
-	r, err := p.attemptApplyMessage(ctx, cachedStateTree, vms, msg, bh, gasTracker, ancestors)
+	r, trace, err := p.attemptApplyMessage(ctx, cachedStateTree, vms, msg, from, bh, gasTracker, ancestors)
 	if err == nil {
 		err = cachedStateTree.Commit(ctx)
 		if err != nil {
@@ -297,7 +321,7 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 	}
 
 	if r.GasAttoFIL.IsPositive() {
-		gasError := p.blockRewarder.GasReward(ctx, st, minerOwnerAddr, msg, r.GasAttoFIL)
+		gasError := p.blockRewarder.GasReward(ctx, st, minerOwnerAddr, from, r.GasAttoFIL)
 		if gasError != nil {
 			return nil, errors.NewFaultError("failed to transfer gas reward to owner of miner")
 		}
@@ -321,16 +345,16 @@ func (p *DefaultProcessor) ApplyMessage(ctx context.Context, st state.Tree, vms
 
 	// At this point we consider the message successfully applied so inc
 	// the nonce.
-	fromActor, err := st.GetActor(ctx, msg.From)
+	fromActor, err := st.GetActor(ctx, from)
 	if err != nil {
 		return nil, errors.FaultErrorWrap(err, "couldn't load from actor")
 	}
 	fromActor.IncNonce()
-	if err := st.SetActor(ctx, msg.From, fromActor); err != nil {
+	if err := st.SetActor(ctx, from, fromActor); err != nil {
 		return nil, errors.FaultErrorWrap(err, "could not set from actor after inc nonce")
 	}
 
-	return &ApplicationResult{Receipt: r, ExecutionError: executionError}, nil
+	return &ApplicationResult{Receipt: r, ExecutionError: executionError, Trace: trace}, nil
 }
 
 var (
@@ -346,13 +370,23 @@ var (
 	errInsufficientGas           = errors.NewRevertError("balance insufficient to cover transfer+gas")
 	errInvalidSignature          = errors.NewRevertError("invalid signature by sender over message data")
 	// TODO we'll eventually handle sending to self.
-	errSelfSend = errors.NewRevertError("cannot send to self")
+	errSelfSend             = errors.NewRevertError("cannot send to self")
+	errToAddressNotAssigned = errors.NewRevertError("recipient ID address has not been assigned")
 )
 
 // CallQueryMethod calls a method on an actor in the given state tree. It does
 // not make any changes to the state/blockchain and is useful for interrogating
 // actor state. Block height bh is optional; some methods will ignore it.
 func CallQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) ([][]byte, uint8, error) {
+	to, err := vm.ResolveAddress(ctx, st, vms, to)
+	if err != nil {
+		return nil, 1, errors.ApplyErrorPermanentWrapf(err, "failed to resolve To address")
+	}
+	from, err = vm.ResolveAddress(ctx, st, vms, from)
+	if err != nil {
+		return nil, 1, errors.ApplyErrorPermanentWrapf(err, "failed to resolve From address")
+	}
+
 	toActor, err := st.GetActor(ctx, to)
 	if err != nil {
 		return nil, 1, errors.ApplyErrorPermanentWrapf(err, "failed to get To actor")
@@ -389,8 +423,18 @@ func CallQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to a
 }
 
 // PreviewQueryMethod estimates the amount of gas that will be used by a method
-// call. It accepts all the same arguments as CallQueryMethod.
-func PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight) (types.GasUnits, error) {
+// call charged by the given gas schedule. It accepts all the same arguments as
+// CallQueryMethod.
+func PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, to address.Address, method string, params []byte, from address.Address, optBh *types.BlockHeight, gasSchedule *vm.GasSchedule) (types.GasUnits, error) {
+	to, err := vm.ResolveAddress(ctx, st, vms, to)
+	if err != nil {
+		return types.NewGasUnits(0), errors.ApplyErrorPermanentWrapf(err, "failed to resolve To address")
+	}
+	from, err = vm.ResolveAddress(ctx, st, vms, from)
+	if err != nil {
+		return types.NewGasUnits(0), errors.ApplyErrorPermanentWrapf(err, "failed to resolve From address")
+	}
+
 	toActor, err := st.GetActor(ctx, to)
 	if err != nil {
 		return types.NewGasUnits(0), errors.ApplyErrorPermanentWrapf(err, "failed to get To actor")
@@ -418,9 +462,15 @@ func PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, t
 		State:       cachedSt,
 		StorageMap:  vms,
 		GasTracker:  gasTracker,
+		GasSchedule: gasSchedule,
 		BlockHeight: optBh,
 	}
 	vmCtx := vm.NewVMContext(vmCtxParams)
+
+	// a real message also pays for checking its signature
+	if err := vmCtx.Charge(vmCtx.GasSchedule().VerifySignature); err != nil {
+		return types.NewGasUnits(0), err
+	}
 	_, _, err = vm.Send(ctx, vmCtx)
 
 	return vmCtx.GasUnits(), err
@@ -430,24 +480,30 @@ func PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, t
 // to make ApplyMessage more readable. The distinction is that attemptApplyMessage
 // should deal with trying to apply the message to the state tree whereas
 // ApplyMessage should deal with any side effects and how it should be presented
-// to the caller. attemptApplyMessage should only be called from ApplyMessage.
-func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.CachedTree, store vm.StorageMap, msg *types.SignedMessage, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet) (*types.MessageReceipt, error) {
+// to the caller. attemptApplyMessage should only be called from ApplyMessage,
+// with from the actor address of the sender of msg.
+func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.CachedTree, store vm.StorageMap, msg *types.SignedMessage, from address.Address, bh *types.BlockHeight, gasTracker *vm.GasTracker, ancestors []types.TipSet) (*types.MessageReceipt, *vm.Trace, error) {
 	gasTracker.ResetForNewMessage(msg.MeteredMessage)
 	if err := blockGasLimitError(gasTracker); err != nil {
 		return &types.MessageReceipt{
 			ExitCode:   errors.CodeError(err),
 			GasAttoFIL: types.ZeroAttoFIL,
-		}, err
+		}, nil, err
 	}
 
-	fromActor, err := st.GetActor(ctx, msg.From)
+	// Messages may address their recipient by ID too. The VM only deals with
+	// actor addresses, so resolve it before the message is sent.
+	vmMsg := msg.Message
+	vmMsg.From = from
+
+	fromActor, err := st.GetActor(ctx, vmMsg.From)
 	if state.IsActorNotFoundError(err) {
 		return &types.MessageReceipt{
 			ExitCode:   errors.CodeError(err),
 			GasAttoFIL: types.ZeroAttoFIL,
-		}, errFromAccountNotFound
+		}, nil, errFromAccountNotFound
 	} else if err != nil {
-		return nil, errors.FaultErrorWrapf(err, "failed to get From actor %s", msg.From)
+		return nil, nil, errors.FaultErrorWrapf(err, "failed to get From actor %s", msg.From)
 	}
 
 	err = p.signedMessageValidator.Validate(ctx, msg, fromActor)
@@ -455,42 +511,71 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 		return &types.MessageReceipt{
 			ExitCode:   errors.CodeError(err),
 			GasAttoFIL: types.ZeroAttoFIL,
-		}, err
+		}, nil, err
 	}
 
 	// Processing an external message from an empty actor upgrades it to an account actor.
 	if fromActor.Empty() {
 		err := account.UpgradeActor(fromActor)
 		if err != nil {
-			return nil, errors.FaultErrorWrap(err, "failed to upgrade empty actor")
+			return nil, nil, errors.FaultErrorWrap(err, "failed to upgrade empty actor")
 		}
 	}
 
-	toActor, err := st.GetOrCreateActor(ctx, msg.To, func() (*actor.Actor, error) {
+	vmMsg.To, err = vm.ResolveAddress(ctx, st, store, msg.To)
+	if errors.IsFault(err) {
+		return nil, nil, err
+	} else if err != nil {
+		return &types.MessageReceipt{
+			ExitCode:   errors.CodeError(err),
+			GasAttoFIL: types.ZeroAttoFIL,
+		}, nil, errToAddressNotAssigned
+	}
+	if vmMsg.To == vmMsg.From {
+		return &types.MessageReceipt{
+			ExitCode:   errors.CodeError(errSelfSend),
+			GasAttoFIL: types.ZeroAttoFIL,
+		}, nil, errSelfSend
+	}
+
+	toActor, err := st.GetOrCreateActor(ctx, vmMsg.To, func() (*actor.Actor, error) {
 		// Addresses are deterministic so sending a message to a non-existent address must not install an actor,
 		// else actors could be installed ahead of address activation. So here we create the empty, upgradable
-		// actor to collect any balance that may be transferred.
+		// actor to collect any balance that may be transferred. It gets an ID like any other actor.
+		if err := vm.AssignID(ctx, st, store, vmMsg.To); err != nil {
+			return nil, err
+		}
 		return &actor.Actor{}, nil
 	})
 	if err != nil {
-		return nil, errors.FaultErrorWrap(err, "failed to get To actor")
+		return nil, nil, errors.FaultErrorWrap(err, "failed to get To actor")
 	}
 
 	vmCtxParams := vm.NewContextParams{
 		From:        fromActor,
 		To:          toActor,
-		Message:     &msg.Message,
+		Message:     &vmMsg,
 		State:       st,
 		StorageMap:  store,
 		GasTracker:  gasTracker,
+		GasSchedule: p.forks.GasScheduleAt(bh.AsBigInt().Uint64()),
 		BlockHeight: bh,
 		Ancestors:   ancestors,
 	}
 	vmCtx := vm.NewVMContext(vmCtxParams)
 
-	ret, exitCode, vmErr := vm.Send(ctx, vmCtx)
+	// The message's signature was verified on its way here, which costs gas
+	// like everything else done on the message's behalf.
+	var ret [][]byte
+	var exitCode uint8
+	vmErr := vmCtx.Charge(vmCtx.GasSchedule().VerifySignature)
+	if vmErr != nil {
+		exitCode = exec.ErrInsufficientGas
+	} else {
+		ret, exitCode, vmErr = vm.Send(ctx, vmCtx)
+	}
 	if errors.IsFault(vmErr) {
-		return nil, vmErr
+		return nil, nil, vmErr
 	}
 
 	// compute gas charge
@@ -503,7 +588,17 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 
 	receipt.Return = append(receipt.Return, ret...)
 
-	return receipt, vmErr
+	// events of a failed message describe changes that were reverted
+	if vmErr == nil {
+		receipt.Events = vmCtx.Events()
+	}
+
+	// the trace of the message accounts for all the gas it paid for, including
+	// the signature check.
+	trace := vmCtx.Trace()
+	trace.Finish(vmCtx.GasUnits(), exitCode, vmErr)
+
+	return receipt, trace, vmErr
 }
 
 // ApplyMessagesResponse is the output struct of ApplyMessages.  It exists to
@@ -534,6 +629,12 @@ func (p *DefaultProcessor) ApplyMessagesAndPayRewards(ctx context.Context, st st
 		return ApplyMessagesResponse{}, err
 	}
 
+	// penalize miners that have missed their proving period before applying
+	// any messages, so the penalty does not depend on what the block contains.
+	if err := p.slashStorageFaults(ctx, st, vms, bh, ancestors); err != nil {
+		return ApplyMessagesResponse{}, err
+	}
+
 	gasTracker := vm.NewGasTracker()
 
 	// process all messages
@@ -559,6 +660,84 @@ func (p *DefaultProcessor) ApplyMessagesAndPayRewards(ctx context.Context, st st
 	return ret, nil
 }
 
+// slashStorageFaults sends a slashStorageFault message from the network to
+// each miner whose proving deadline passed at bh, that is, since the parent
+// tipset, so that heights skipped by null blocks are not missed. Miners that
+// have not submitted a PoSt by the end of their grace period lose their power,
+// sectors and collateral. Only fault errors are returned, a miner that fails
+// to process the message is logged and skipped.
+func (p *DefaultProcessor) slashStorageFaults(ctx context.Context, st state.Tree, vms vm.StorageMap, bh *types.BlockHeight, ancestors []types.TipSet) error {
+	marketActor, err := st.GetActor(ctx, address.StorageMarketAddress)
+	if state.IsActorNotFoundError(err) {
+		return nil
+	} else if err != nil {
+		return errors.FaultErrorWrap(err, "could not get storage market actor")
+	}
+
+	height := bh.AsBigInt().Uint64()
+	if height == 0 {
+		return nil
+	}
+	from := height - 1
+	if len(ancestors) > 0 {
+		parentHeight, err := ancestors[0].Height()
+		if err != nil {
+			return errors.FaultErrorWrap(err, "could not get parent height")
+		}
+		if parentHeight < from {
+			from = parentHeight
+		}
+	}
+
+	miners, err := storagemarket.MinersWithProvingDeadlines(ctx, vms.NewStorage(address.StorageMarketAddress, marketActor), types.NewBlockHeight(from), bh)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "could not list miners past their proving deadline")
+	}
+
+	for _, minerAddr := range miners {
+		cachedSt := state.NewCachedStateTree(st)
+
+		minerActor, err := cachedSt.GetActor(ctx, minerAddr)
+		if err != nil {
+			return errors.FaultErrorWrapf(err, "could not get miner %s", minerAddr)
+		}
+
+		msg := &types.Message{
+			From:   address.NetworkAddress,
+			To:     minerAddr,
+			Method: "slashStorageFault",
+		}
+
+		// This message is sent by the system and never costs gas.
+		gasTracker := vm.NewGasTracker()
+		gasTracker.MsgGasLimit = types.BlockGasLimit
+
+		vmCtxParams := vm.NewContextParams{
+			To:          minerActor,
+			Message:     msg,
+			State:       cachedSt,
+			StorageMap:  vms,
+			GasTracker:  gasTracker,
+			BlockHeight: bh,
+			Ancestors:   ancestors,
+		}
+
+		_, _, err = vm.Send(ctx, vm.NewVMContext(vmCtxParams))
+		if errors.IsFault(err) {
+			return err
+		} else if err != nil {
+			log.Warningf("failed to check miner %s for storage faults: %s", minerAddr, err)
+			continue
+		}
+
+		if err := cachedSt.Commit(ctx); err != nil {
+			return errors.FaultErrorWrap(err, "could not commit state tree")
+		}
+	}
+
+	return nil
+}
+
 // DefaultBlockRewarder pays the block reward from the network actor to the miner's owner.
 type DefaultBlockRewarder struct{}
 
@@ -579,9 +758,9 @@ func (br *DefaultBlockRewarder) BlockReward(ctx context.Context, st state.Tree,
 }
 
 // GasReward transfers the gas cost reward from the sender actor to the minerOwnerAddr
-func (br *DefaultBlockRewarder) GasReward(ctx context.Context, st state.Tree, minerOwnerAddr address.Address, msg *types.SignedMessage, gas *types.AttoFIL) error {
+func (br *DefaultBlockRewarder) GasReward(ctx context.Context, st state.Tree, minerOwnerAddr address.Address, from address.Address, gas *types.AttoFIL) error {
 	cachedTree := state.NewCachedStateTree(st)
-	if err := rewardTransfer(ctx, msg.From, minerOwnerAddr, gas, cachedTree); err != nil {
+	if err := rewardTransfer(ctx, from, minerOwnerAddr, gas, cachedTree); err != nil {
 		return errors.FaultErrorWrap(err, "Error attempting to pay gas reward")
 	}
 	return cachedTree.Commit(ctx)
@@ -629,6 +808,7 @@ func isTemporaryError(err error) bool {
 func isPermanentError(err error) bool {
 	return err == errInsufficientGas ||
 		err == errSelfSend ||
+		err == errToAddressNotAssigned ||
 		err == errInvalidSignature ||
 		err == errNonceTooLow ||
 		err == errNonAccountActor ||
@@ -637,8 +817,8 @@ func isPermanentError(err error) bool {
 		err == errGasAboveBlockLimit
 }
 
-// minerOwnerAddress finds the address of the owner of the given miner
-func minerOwnerAddress(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address) (address.Address, error) {
+// MinerOwnerAddress finds the address of the owner of the given miner
+func MinerOwnerAddress(ctx context.Context, st state.Tree, vms vm.StorageMap, minerAddr address.Address) (address.Address, error) {
 	ret, code, err := CallQueryMethod(ctx, st, vms, minerAddr, "getOwner", []byte{}, address.Undef, types.NewBlockHeight(0))
 	if err != nil {
 		return address.Undef, errors.FaultErrorWrap(err, "could not get miner owner")
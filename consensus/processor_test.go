@@ -10,6 +10,7 @@ import (
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	"gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
 	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
@@ -17,6 +18,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
 	"github.com/filecoin-project/go-filecoin/address"
 	. "github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/state"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -65,7 +67,7 @@ func TestProcessBlockSuccess(t *testing.T) {
 	})
 
 	msg := types.NewMessage(fromAddr, toAddr, 0, types.NewAttoFILFromFIL(550), "", nil)
-	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 
 	blk := &types.Block{
@@ -126,7 +128,7 @@ func TestProcessTipSetSuccess(t *testing.T) {
 	stCid, miner := mustCreateMiner(ctx, require, st, vms, minerAddr, minerOwner)
 
 	msg1 := types.NewMessage(fromAddr1, toAddr, 0, types.NewAttoFILFromFIL(550), "", nil)
-	smsg1, err := types.NewSignedMessage(*msg1, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg1, err := types.NewSignedMessage(*msg1, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	blk1 := &types.Block{
 		Height:    20,
@@ -136,7 +138,7 @@ func TestProcessTipSetSuccess(t *testing.T) {
 	}
 
 	msg2 := types.NewMessage(fromAddr2, toAddr, 0, types.NewAttoFILFromFIL(50), "", nil)
-	smsg2, err := types.NewSignedMessage(*msg2, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg2, err := types.NewSignedMessage(*msg2, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	blk2 := &types.Block{
 		Height:    20,
@@ -194,7 +196,7 @@ func TestProcessTipsConflicts(t *testing.T) {
 	stCid, miner := mustCreateMiner(ctx, require, st, vms, minerAddr, minerOwner)
 
 	msg1 := types.NewMessage(fromAddr, toAddr, 0, types.NewAttoFILFromFIL(501), "", nil)
-	smsg1, err := types.NewSignedMessage(*msg1, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg1, err := types.NewSignedMessage(*msg1, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	blk1 := &types.Block{
 		Height:    20,
@@ -205,7 +207,7 @@ func TestProcessTipsConflicts(t *testing.T) {
 	}
 
 	msg2 := types.NewMessage(fromAddr, toAddr, 0, types.NewAttoFILFromFIL(502), "", nil)
-	smsg2, err := types.NewSignedMessage(*msg2, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg2, err := types.NewSignedMessage(*msg2, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	blk2 := &types.Block{
 		Height:    20,
@@ -352,7 +354,7 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	stCid, miner := mustCreateMiner(ctx, require, st, vms, minerAddr, minerOwnerAddr)
 
 	msg := types.NewMessage(fromAddr, toAddr, 0, nil, "returnRevertError", nil)
-	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	blk := &types.Block{
 		Height:    20,
@@ -733,14 +735,14 @@ func TestSendToNonexistentAddressThenSpendFromIt(t *testing.T) {
 
 	// send 500 from addr1 to addr2
 	msg := types.NewMessage(addr1, addr2, 0, types.NewAttoFILFromFIL(500), "", []byte{})
-	smsg, err := types.NewSignedMessage(*msg, mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg, err := types.NewSignedMessage(*msg, mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	_, err = NewDefaultProcessor().ApplyMessage(ctx, st, th.VMStorage(), smsg, addr4, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 	require.NoError(err)
 
 	// send 250 along from addr2 to addr3
 	msg = types.NewMessage(addr2, addr3, 0, types.NewAttoFILFromFIL(300), "", []byte{})
-	smsg, err = types.NewSignedMessage(*msg, mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	smsg, err = types.NewSignedMessage(*msg, mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 	_, err = NewDefaultProcessor().ApplyMessage(ctx, st, th.VMStorage(), smsg, addr4, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 	require.NoError(err)
@@ -814,6 +816,10 @@ func TestApplyMessageChargesGas(t *testing.T) {
 	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
 	defer delete(builtin.Actors, fakeActorCodeCid)
 
+	// every message pays for its signature and call on top of what the
+	// actor charges
+	overhead := uint64(vm.GasScheduleV0.VerifySignature + vm.GasScheduleV0.Call)
+
 	t.Run("ApplyMessage charges gas on success", func(t *testing.T) {
 		addresses, st, mockSigner := setupActorsForGasTest(t, vms, fakeActorCodeCid, 1000)
 		addr0 := addresses[0]
@@ -831,12 +837,12 @@ func TestApplyMessageChargesGas(t *testing.T) {
 
 		minerActor, err := st.GetActor(ctx, minerAddr)
 		require.NoError(err)
-		// miner receives (3 FIL/gasUnit * 50 gasUnits) FIL from the sender
-		assert.Equal(types.NewAttoFILFromFIL(1300), minerActor.Balance)
+		// miner receives (3 FIL/gasUnit * 115 gasUnits) FIL from the sender
+		assert.Equal(types.NewAttoFILFromFIL(1000+3*(100+overhead)), minerActor.Balance)
 		accountActor, err := st.GetActor(ctx, addr0)
 		require.NoError(err)
 		// sender's resulting balance of FIL
-		assert.Equal(types.NewAttoFILFromFIL(700), accountActor.Balance)
+		assert.Equal(types.NewAttoFILFromFIL(1000-3*(100+overhead)), accountActor.Balance)
 	})
 
 	t.Run("ApplyMessage charges gas on message execution failure", func(t *testing.T) {
@@ -858,11 +864,11 @@ func TestApplyMessageChargesGas(t *testing.T) {
 		minerActor, err := st.GetActor(ctx, minerAddr)
 		require.NoError(err)
 
-		// miner receives (3 FIL/gasUnit * 100 gasUnits) FIL from the sender
-		assert.Equal(types.NewAttoFILFromFIL(1300), minerActor.Balance)
+		// miner receives (3 FIL/gasUnit * 115 gasUnits) FIL from the sender
+		assert.Equal(types.NewAttoFILFromFIL(1000+3*(100+overhead)), minerActor.Balance)
 		accountActor, err := st.GetActor(ctx, addr0)
 		require.NoError(err)
-		assert.Equal(types.NewAttoFILFromFIL(700), accountActor.Balance)
+		assert.Equal(types.NewAttoFILFromFIL(1000-3*(100+overhead)), accountActor.Balance)
 	})
 
 	t.Run("ApplyMessage charges the gas limit when limit is exceeded", func(t *testing.T) {
@@ -916,13 +922,15 @@ func TestApplyMessageChargesGas(t *testing.T) {
 		minerActor, err := st.GetActor(ctx, minerAddr)
 		require.NoError(err)
 
-		// miner receives (3 FIL/gas * 100 gas * 2 messages)
-		assert.Equal(types.NewAttoFILFromFIL(1600), minerActor.Balance)
+		// miner receives (3 FIL/gas * 100 gas * 2 messages) plus the overhead
+		// of the signature and both calls
+		nestedGas := 2*100 + overhead + uint64(vm.GasScheduleV0.Call)
+		assert.Equal(types.NewAttoFILFromFIL(1000+3*nestedGas), minerActor.Balance)
 
 		accountActor, err := st.GetActor(ctx, addr0)
 		require.NoError(err)
 		// sender's resulting balance of FIL
-		assert.Equal(types.NewAttoFILFromFIL(1400), accountActor.Balance)
+		assert.Equal(types.NewAttoFILFromFIL(2000-3*nestedGas), accountActor.Balance)
 	})
 
 	t.Run("ApplyMessage when it sends another message with insufficient gas fails with correct message", func(t *testing.T) {
@@ -960,6 +968,106 @@ func TestApplyMessageChargesGas(t *testing.T) {
 	})
 }
 
+func TestApplyMessageTracesNestedSends(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	vms := th.VMStorage()
+
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer delete(builtin.Actors, fakeActorCodeCid)
+
+	addresses, st, mockSigner := setupActorsForGasTest(t, vms, fakeActorCodeCid, 1000)
+	addr0 := addresses[0]
+	addr1 := addresses[1]
+	addr2 := addresses[2]
+	minerAddr := addresses[3]
+
+	params, err := abi.ToEncodedValues(addr2)
+	require.NoError(err)
+	msg := types.NewMessage(addr0, addr1, 0, types.ZeroAttoFIL, "runsAnotherMessage", params)
+
+	// enough gas for the outer call but not for the method it sends
+	outerGas := vm.GasScheduleV0.VerifySignature + vm.GasScheduleV0.Call + types.NewGasUnits(100)
+	gasLimit := outerGas + vm.GasScheduleV0.Call + types.NewGasUnits(50)
+
+	appResult, err := th.ApplyTestMessageWithGas(st, th.VMStorage(), msg, types.NewBlockHeight(0), mockSigner,
+		*types.NewGasPrice(0), gasLimit, minerAddr)
+	require.NoError(err)
+	require.Error(appResult.ExecutionError)
+
+	trace := appResult.Trace
+	require.NotNil(trace)
+	assert.Equal(addr0, trace.From)
+	assert.Equal(addr1, trace.To)
+	assert.Equal("runsAnotherMessage", trace.Method)
+	assert.Equal(gasLimit, trace.GasUsed)
+	assert.Equal(uint8(exec.ErrInsufficientGas), trace.ExitCode)
+
+	// the failure is recorded on the nested send it happened in
+	require.Len(trace.Subcalls, 1)
+	inner := trace.Subcalls[0]
+	assert.Equal(addr1, inner.From)
+	assert.Equal(addr2, inner.To)
+	assert.Equal("hasReturnValue", inner.Method)
+	assert.Equal(gasLimit-outerGas, inner.GasUsed)
+	assert.Equal(uint8(exec.ErrInsufficientGas), inner.ExitCode)
+	assert.Contains(inner.Error, "gas cost exceeds gas limit")
+	assert.Empty(inner.Subcalls)
+}
+
+func TestApplyMessageRecordsEvents(t *testing.T) {
+	vms := th.VMStorage()
+
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer delete(builtin.Actors, fakeActorCodeCid)
+
+	addresses, st, mockSigner := setupActorsForGasTest(t, vms, fakeActorCodeCid, 1000)
+	addr0 := addresses[0]
+	addr1 := addresses[1]
+	addr2 := addresses[2]
+	minerAddr := addresses[3]
+
+	t.Run("events of a successful message and its sends are recorded in order", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		params, err := abi.ToEncodedValues(addr2)
+		require.NoError(err)
+		msg := types.NewMessage(addr0, addr1, 0, types.ZeroAttoFIL, "callEmitsEvent", params)
+
+		appResult, err := th.ApplyTestMessageWithGas(st, vms, msg, types.NewBlockHeight(0), mockSigner,
+			*types.NewGasPrice(0), types.NewGasUnits(1000), minerAddr)
+		require.NoError(err)
+		require.NoError(appResult.ExecutionError)
+
+		events := appResult.Receipt.Events
+		require.Len(events, 2)
+		assert.Equal(addr1, events[0].Actor)
+		assert.Equal(actor.FakeEventName, events[0].Name)
+		assert.Equal(addr2, events[1].Actor)
+		assert.Equal(actor.FakeEventName, events[1].Name)
+
+		var data string
+		require.NoError(cbor.DecodeInto(events[0].Data, &data))
+		assert.Equal("boom", data)
+	})
+
+	t.Run("events of a reverted message are dropped", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		msg := types.NewMessage(addr0, addr1, 1, types.ZeroAttoFIL, "emitsEventAndReverts", nil)
+
+		appResult, err := th.ApplyTestMessageWithGas(st, vms, msg, types.NewBlockHeight(0), mockSigner,
+			*types.NewGasPrice(0), types.NewGasUnits(1000), minerAddr)
+		require.NoError(err)
+		require.Error(appResult.ExecutionError)
+		assert.Empty(appResult.Receipt.Events)
+	})
+}
+
 func TestBlockGasLimitBehavior(t *testing.T) {
 	fakeActorCodeCid := types.NewCidForTestGetter()()
 	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm/errors"
 )
@@ -25,7 +26,7 @@ const (
 	// ErrStaleHead indicates that an actor attempted to commit over a stale chunk
 	ErrStaleHead = 35
 	// ErrInsufficientGas indicates that an actor did not have sufficient gas to run a message
-	ErrInsufficientGas = 36
+	ErrInsufficientGas = errors.ErrInsufficientGas
 )
 
 // Errors map error codes to revert errors this actor may return
@@ -78,6 +79,14 @@ type VMContext interface {
 
 	CreateNewActor(addr address.Address, code cid.Cid, initalizationParams interface{}) error
 
+	VerifySeal(req proofs.VerifySealRequest) (proofs.VerifySealResponse, error)
+	VerifyPoST(req proofs.VerifyPoSTRequest) (proofs.VerifyPoSTResponse, error)
+
+	// Emit records an event with the given name and cbor encodable data in
+	// the receipt of the message being processed. Events are discarded if the
+	// message fails.
+	Emit(name string, data interface{}) error
+
 	// TODO: Remove these when Storage above is completely implemented
 	ReadStorage() ([]byte, error)
 	WriteStorage(interface{}) error
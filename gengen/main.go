@@ -52,6 +52,21 @@ $ cat setup.json
 			"owner": 1,
 			"power": 1000
 		}
+	],
+	"multisigs": [
+		{
+			"signers": [2, 3],
+			"required": 2,
+			"balance": "100"
+		}
+	],
+	"vestings": [
+		{
+			"beneficiary": 3,
+			"total": "1000",
+			"startHeight": 0,
+			"duration": 10000
+		}
 	]
 }
 $ cat setup.json | gengen > genesis.car
@@ -124,6 +139,12 @@ func main() {
 	for _, m := range info.Miners {
 		fmt.Fprintf(os.Stderr, "created miner %s, owned by %d, power = %d\n", m.Address, m.Owner, m.Power) // nolint: errcheck
 	}
+	for _, m := range info.Multisigs {
+		fmt.Fprintf(os.Stderr, "created multisig %s, signers = %v, required = %d\n", m.Address, m.Signers, m.Required) // nolint: errcheck
+	}
+	for _, v := range info.Vestings {
+		fmt.Fprintf(os.Stderr, "created vesting actor %s, beneficiary = %d, total = %s\n", v.Address, v.Beneficiary, v.Total) // nolint: errcheck
+	}
 }
 
 func readConfig(filePath string) (*gengen.GenesisCfg, error) {
@@ -11,6 +11,8 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/vesting"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/crypto"
@@ -59,6 +61,42 @@ type GenesisCfg struct {
 
 	// Miners is a list of miners that should be set up at the start of the network
 	Miners []Miner
+
+	// Multisigs is a list of multisig wallets that should be set up at the
+	// start of the network
+	Multisigs []Multisig
+
+	// Vestings is a list of allocations that unlock linearly over time
+	Vestings []Vesting
+}
+
+// Multisig describes a multisig wallet to create in the genesis block
+type Multisig struct {
+	// Signers are the names of the keys that may approve transactions.
+	// Each must be a name of a key from the configs 'Keys' list
+	Signers []int
+
+	// Required is the number of signers that must approve a transaction
+	Required uint64
+
+	// Balance is the string value of whole filecoin held by the wallet
+	Balance string
+}
+
+// Vesting describes a vesting allocation to create in the genesis block
+type Vesting struct {
+	// Beneficiary is the name of the key that may withdraw vested funds.
+	// It must be a name of a key from the configs 'Keys' list
+	Beneficiary int
+
+	// Total is the string value of whole filecoin that vests
+	Total string
+
+	// StartHeight is the block height at which vesting begins
+	StartHeight uint64
+
+	// Duration is the number of blocks over which the total vests
+	Duration uint64
 }
 
 // RenderedGenInfo contains information about a genesis block creation
@@ -69,6 +107,12 @@ type RenderedGenInfo struct {
 	// Miners is the list of addresses of miners created
 	Miners []RenderedMinerInfo
 
+	// Multisigs is the list of multisig wallets created
+	Multisigs []RenderedMultisigInfo
+
+	// Vestings is the list of vesting actors created
+	Vestings []RenderedVestingInfo
+
 	// GenesisCid is the cid of the created genesis block
 	GenesisCid cid.Cid
 }
@@ -85,6 +129,30 @@ type RenderedMinerInfo struct {
 	Power uint64
 }
 
+// RenderedMultisigInfo contains info about a created multisig wallet
+type RenderedMultisigInfo struct {
+	// Address is the address of the multisig actor
+	Address address.Address
+
+	// Signers are the key names of the signers of this wallet
+	Signers []int
+
+	// Required is the number of approvals a transaction needs
+	Required uint64
+}
+
+// RenderedVestingInfo contains info about a created vesting actor
+type RenderedVestingInfo struct {
+	// Address is the address of the vesting actor
+	Address address.Address
+
+	// Beneficiary is the key name of the beneficiary
+	Beneficiary int
+
+	// Total is the amount of filecoin that vests
+	Total string
+}
+
 // GenGen takes the genesis configuration and creates a genesis block that
 // matches the description. It writes all chunks to the dagservice, and returns
 // the final genesis block.
@@ -113,6 +181,20 @@ func GenGen(ctx context.Context, cfg *GenesisCfg, cst *hamt.CborIpldStore, bs bl
 		return nil, err
 	}
 
+	multisigs, err := setupMultisigs(st, storageMap, keys, cfg.Multisigs)
+	if err != nil {
+		return nil, err
+	}
+
+	vestings, err := setupVestings(st, storageMap, keys, cfg.Vestings)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := consensus.AssignGenesisIDs(ctx, st, storageMap); err != nil {
+		return nil, err
+	}
+
 	if err := cst.Blocks.AddBlock(types.StorageMarketActorCodeObj); err != nil {
 		return nil, err
 	}
@@ -128,6 +210,15 @@ func GenGen(ctx context.Context, cfg *GenesisCfg, cst *hamt.CborIpldStore, bs bl
 	if err := cst.Blocks.AddBlock(types.PaymentBrokerActorCodeObj); err != nil {
 		return nil, err
 	}
+	if err := cst.Blocks.AddBlock(types.MultisigActorCodeObj); err != nil {
+		return nil, err
+	}
+	if err := cst.Blocks.AddBlock(types.InitActorCodeObj); err != nil {
+		return nil, err
+	}
+	if err := cst.Blocks.AddBlock(types.VestingActorCodeObj); err != nil {
+		return nil, err
+	}
 
 	stateRoot, err := st.Flush(ctx)
 	if err != nil {
@@ -152,6 +243,8 @@ func GenGen(ctx context.Context, cfg *GenesisCfg, cst *hamt.CborIpldStore, bs bl
 		Keys:       keys,
 		GenesisCid: c,
 		Miners:     miners,
+		Multisigs:  multisigs,
+		Vestings:   vestings,
 	}, nil
 }
 
@@ -283,7 +376,7 @@ func setupMiners(st state.Tree, sm vm.StorageMap, keys []*types.KeyInfo, miners
 			if _, err := pnrg.Read(sealProof[:]); err != nil {
 				return nil, err
 			}
-			_, err := applyMessageDirect(ctx, st, sm, addr, maddr, types.NewAttoFILFromFIL(0), "commitSector", sectorID, commD, commR, commRStar, sealProof)
+			_, err := applyMessageDirect(ctx, st, sm, addr, maddr, types.NewAttoFILFromFIL(0), "commitSector", sectorID, commD, commR, commRStar, sealProof, []uint64{})
 			if err != nil {
 				return nil, err
 			}
@@ -293,6 +386,98 @@ func setupMiners(st state.Tree, sm vm.StorageMap, keys []*types.KeyInfo, miners
 	return minfos, nil
 }
 
+func setupMultisigs(st state.Tree, sm vm.StorageMap, keys []*types.KeyInfo, multisigs []Multisig) ([]RenderedMultisigInfo, error) {
+	var msinfos []RenderedMultisigInfo
+	ctx := context.Background()
+
+	for i, m := range multisigs {
+		signers := make([]address.Address, len(m.Signers))
+		for j, k := range m.Signers {
+			if k < 0 || k >= len(keys) {
+				return nil, fmt.Errorf("multisig %d: no key at index %d", i, k)
+			}
+			addr, err := keys[k].Address()
+			if err != nil {
+				return nil, err
+			}
+			signers[j] = addr
+		}
+
+		valint, err := strconv.ParseUint(m.Balance, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		// multisig addresses are derived from their position in the config
+		// so that the same config always produces the same genesis
+		addr, err := address.NewActorAddress([]byte(fmt.Sprintf("multisig-%d", i)))
+		if err != nil {
+			return nil, err
+		}
+
+		act := multisig.NewActor(types.NewAttoFILFromFIL(valint))
+		if err := (&multisig.Actor{}).InitializeState(sm.NewStorage(addr, act), multisig.NewState(signers, m.Required)); err != nil {
+			return nil, err
+		}
+		if err := st.SetActor(ctx, addr, act); err != nil {
+			return nil, err
+		}
+
+		msinfos = append(msinfos, RenderedMultisigInfo{
+			Address:  addr,
+			Signers:  m.Signers,
+			Required: m.Required,
+		})
+	}
+
+	return msinfos, nil
+}
+
+func setupVestings(st state.Tree, sm vm.StorageMap, keys []*types.KeyInfo, vestings []Vesting) ([]RenderedVestingInfo, error) {
+	var vinfos []RenderedVestingInfo
+	ctx := context.Background()
+
+	for i, v := range vestings {
+		if v.Beneficiary < 0 || v.Beneficiary >= len(keys) {
+			return nil, fmt.Errorf("vesting %d: no key at index %d", i, v.Beneficiary)
+		}
+		beneficiary, err := keys[v.Beneficiary].Address()
+		if err != nil {
+			return nil, err
+		}
+
+		valint, err := strconv.ParseUint(v.Total, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		total := types.NewAttoFILFromFIL(valint)
+
+		// vesting addresses are derived from their position in the config
+		// so that the same config always produces the same genesis
+		addr, err := address.NewActorAddress([]byte(fmt.Sprintf("vesting-%d", i)))
+		if err != nil {
+			return nil, err
+		}
+
+		act := vesting.NewActor(total)
+		vstate := vesting.NewState(beneficiary, total, types.NewBlockHeight(v.StartHeight), types.NewBlockHeight(v.Duration))
+		if err := (&vesting.Actor{}).InitializeState(sm.NewStorage(addr, act), vstate); err != nil {
+			return nil, err
+		}
+		if err := st.SetActor(ctx, addr, act); err != nil {
+			return nil, err
+		}
+
+		vinfos = append(vinfos, RenderedVestingInfo{
+			Address:     addr,
+			Beneficiary: v.Beneficiary,
+			Total:       v.Total,
+		})
+	}
+
+	return vinfos, nil
+}
+
 // GenGenesisCar generates a car for the given genesis configuration
 func GenGenesisCar(cfg *GenesisCfg, out io.Writer, seed int64) (*RenderedGenInfo, error) {
 	// TODO: these six lines are ugly. We can do better...
@@ -327,7 +512,7 @@ func applyMessageDirect(ctx context.Context, st state.Tree, vms vm.StorageMap, f
 	}
 
 	// create new processor that doesn't reward and doesn't validate
-	applier := consensus.NewConfiguredProcessor(&messageValidator{}, &blockRewarder{})
+	applier := consensus.NewConfiguredProcessor(&messageValidator{}, &blockRewarder{}, consensus.DefaultForkSchedule)
 
 	res, err := applier.ApplyMessagesAndPayRewards(ctx, st, vms, []*types.SignedMessage{smsg}, address.Undef, types.NewBlockHeight(0), nil)
 	if err != nil {
@@ -366,7 +551,7 @@ func (gbr *blockRewarder) BlockReward(ctx context.Context, st state.Tree, minerA
 }
 
 // GasReward is a noop
-func (gbr *blockRewarder) GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, msg *types.SignedMessage, cost *types.AttoFIL) error {
+func (gbr *blockRewarder) GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, from address.Address, cost *types.AttoFIL) error {
 	return nil
 }
 
@@ -30,6 +30,21 @@ var testConfig = &GenesisCfg{
 			Power: 10,
 		},
 	},
+	Multisigs: []Multisig{
+		{
+			Signers:  []int{2, 3},
+			Required: 2,
+			Balance:  "100",
+		},
+	},
+	Vestings: []Vesting{
+		{
+			Beneficiary: 3,
+			Total:       "1000",
+			StartHeight: 0,
+			Duration:    100,
+		},
+	},
 }
 
 func TestGenGenLoading(t *testing.T) {
@@ -49,6 +64,8 @@ func TestGenGenLoading(t *testing.T) {
 	stdout := o.ReadStdout()
 	assert.Contains(stdout, `"MinerActor"`)
 	assert.Contains(stdout, `"StoragemarketActor"`)
+	assert.Contains(stdout, `"MultisigActor"`)
+	assert.Contains(stdout, `"VestingActor"`)
 }
 
 func TestGenGenDeterministicBetweenBuilds(t *testing.T) {
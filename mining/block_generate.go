@@ -10,6 +10,7 @@ import (
 
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 
+	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
@@ -58,7 +59,21 @@ func (w *DefaultWorker) Generate(ctx context.Context,
 	messages := mq.Drain()
 
 	vms := vm.NewStorageMap(w.blockstore)
-	res, err := w.processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, messages, w.minerOwnerAddr, types.NewBlockHeight(blockHeight), ancestors)
+
+	stateTree, err = w.forks.UpgradeState(ctx, w.cstore, stateTree, vms, baseHeight, blockHeight)
+	if err != nil {
+		return nil, errors.Wrap(err, "upgrade state")
+	}
+
+	// The block reward goes to the owner in the base state, which may have
+	// changed since this worker was created.
+	minerOwnerAddr, err := consensus.MinerOwnerAddress(ctx, stateTree, vms, w.minerAddr)
+	if err != nil {
+		log.Warningf("could not look up owner of miner %s, using %s: %s", w.minerAddr, w.minerOwnerAddr, err)
+		minerOwnerAddr = w.minerOwnerAddr
+	}
+
+	res, err := w.processor.ApplyMessagesAndPayRewards(ctx, stateTree, vms, messages, minerOwnerAddr, types.NewBlockHeight(blockHeight), ancestors)
 	if err != nil {
 		return nil, errors.Wrap(err, "generate apply messages")
 	}
@@ -92,6 +92,7 @@ type DefaultWorker struct {
 	powerTable    consensus.PowerTableView
 	blockstore    blockstore.Blockstore
 	cstore        *hamt.CborIpldStore
+	forks         consensus.ForkSchedule
 	blockTime     time.Duration
 }
 
@@ -104,6 +105,7 @@ func NewDefaultWorker(messageSource MessageSource,
 	powerTable consensus.PowerTableView,
 	bs blockstore.Blockstore,
 	cst *hamt.CborIpldStore,
+	forks consensus.ForkSchedule,
 	miner address.Address,
 	minerOwner address.Address,
 	minerPubKey []byte,
@@ -118,6 +120,7 @@ func NewDefaultWorker(messageSource MessageSource,
 		powerTable,
 		bs,
 		cst,
+		forks,
 		miner,
 		minerOwner,
 		minerPubKey,
@@ -141,6 +144,7 @@ func NewDefaultWorkerWithDeps(messageSource MessageSource,
 	powerTable consensus.PowerTableView,
 	bs blockstore.Blockstore,
 	cst *hamt.CborIpldStore,
+	forks consensus.ForkSchedule,
 	miner address.Address,
 	minerOwner address.Address,
 	minerPubKey []byte,
@@ -156,6 +160,7 @@ func NewDefaultWorkerWithDeps(messageSource MessageSource,
 		powerTable:     powerTable,
 		blockstore:     bs,
 		cstore:         cst,
+		forks:          forks,
 		createPoSTFunc: createPoST,
 		minerAddr:      miner,
 		minerOwnerAddr: minerOwner,
@@ -135,7 +135,7 @@ func (r *ZeroRewarder) BlockReward(ctx context.Context, st state.Tree, minerAddr
 	return nil
 }
 
-func (r *ZeroRewarder) GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, msg *types.SignedMessage, cost *types.AttoFIL) error {
+func (r *ZeroRewarder) GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, from address.Address, cost *types.AttoFIL) error {
 	return nil
 }
 
@@ -149,7 +149,7 @@ func makeNodes(t *testing.T, assertions *assert.Assertions, numNodes int) (addre
 	)
 	seed.GiveKey(t, minerNode, 0)
 	mineraddr, minerOwnerAddr := seed.GiveMiner(t, minerNode, 0)
-	_, err := storage.NewMiner(mineraddr, minerOwnerAddr, minerNode, minerNode.Repo.DealsDatastore(), minerNode.PorcelainAPI)
+	_, err := storage.NewMiner(mineraddr, minerOwnerAddr, minerOwnerAddr, minerNode, minerNode.Repo.DealsDatastore(), minerNode.PorcelainAPI)
 	assertions.NoError(err)
 
 	nodes := []*Node{minerNode}
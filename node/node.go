@@ -51,9 +51,12 @@ import (
 	"github.com/filecoin-project/go-filecoin/plumbing"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/plumbing/dag"
+	"github.com/filecoin-project/go-filecoin/plumbing/evts"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/mthdsig"
+	"github.com/filecoin-project/go-filecoin/plumbing/stdiff"
 	"github.com/filecoin-project/go-filecoin/plumbing/strgdls"
+	"github.com/filecoin-project/go-filecoin/plumbing/vchrs"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
@@ -111,9 +114,13 @@ type Node struct {
 
 	Wallet *wallet.Wallet
 
+	// events indexes the events emitted on the heaviest chain.
+	events *evts.Index
+
 	// Mining stuff.
 	AddNewlyMinedBlock newBlockFunc
 	blockTime          time.Duration
+	forks              consensus.ForkSchedule
 	cancelMining       context.CancelFunc
 	GetAncestorsFunc   mining.GetAncestors
 	GetStateTreeFunc   mining.GetStateTree
@@ -182,6 +189,7 @@ type Config struct {
 	Rewarder    consensus.BlockRewarder
 	Repo        repo.Repo
 	IsRelay     bool
+	Forks       consensus.ForkSchedule
 }
 
 // ConfigOpt is a configuration option for a filecoin node.
@@ -239,6 +247,24 @@ func RewarderConfigOption(rewarder consensus.BlockRewarder) ConfigOpt {
 	}
 }
 
+// ForkScheduleConfigOption returns a function that sets the schedule of upgrades
+// the node validates and mines the chain with. Nodes default to
+// consensus.DefaultForkSchedule.
+func ForkScheduleConfigOption(forks consensus.ForkSchedule) ConfigOpt {
+	return func(c *Config) error {
+		c.Forks = forks
+		return nil
+	}
+}
+
+// forkSchedule returns the configured fork schedule, or the default one.
+func (nc *Config) forkSchedule() consensus.ForkSchedule {
+	if nc.Forks == nil {
+		return consensus.DefaultForkSchedule
+	}
+	return nc.Forks
+}
+
 // New creates a new node.
 func New(ctx context.Context, opts ...ConfigOpt) (*Node, error) {
 	n := &Config{}
@@ -381,24 +407,27 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		return nil, err
 	}
 
+	forks := nc.forkSchedule()
+
 	// set up chainstore
-	chainStore := chain.NewDefaultStore(nc.Repo.ChainDatastore(), &cstOffline, genCid)
+	chainStore := chain.NewDefaultStore(nc.Repo.ChainDatastore(), &cstOffline, genCid, forks)
+	chainStore.SetCheckpoint(nc.Repo.Config().Sync.Checkpoint)
 	powerTable := &consensus.MarketView{}
 
 	// set up processor
 	var processor consensus.Processor
 	if nc.Rewarder == nil {
-		processor = consensus.NewDefaultProcessor()
+		processor = consensus.NewDefaultProcessorWithForks(forks)
 	} else {
-		processor = consensus.NewConfiguredProcessor(consensus.NewDefaultMessageValidator(), nc.Rewarder)
+		processor = consensus.NewConfiguredProcessor(consensus.NewDefaultMessageValidator(), nc.Rewarder, forks)
 	}
 
 	// set up consensus
 	var nodeConsensus consensus.Protocol
 	if nc.Verifier == nil {
-		nodeConsensus = consensus.NewExpected(&cstOffline, bs, processor, powerTable, genCid, &proofs.RustVerifier{})
+		nodeConsensus = consensus.NewExpectedWithForks(&cstOffline, bs, processor, powerTable, genCid, &proofs.RustVerifier{}, forks)
 	} else {
-		nodeConsensus = consensus.NewExpected(&cstOffline, bs, processor, powerTable, genCid, nc.Verifier)
+		nodeConsensus = consensus.NewExpectedWithForks(&cstOffline, bs, processor, powerTable, genCid, nc.Verifier, forks)
 	}
 
 	// only the syncer gets the storage which is online connected
@@ -417,19 +446,27 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 	}
 	fcWallet := wallet.New(backend)
 
+	msgWaiter := msg.NewWaiter(chainStore, bs, &cstOffline, forks)
+	eventIndex := evts.NewIndex(chainStore, msgWaiter, nc.Repo.Datastore())
+
 	PorcelainAPI := porcelain.New(plumbing.New(&plumbing.APIDeps{
+		Blockstore:   bs,
 		Chain:        chainStore,
 		Config:       cfg.NewConfig(nc.Repo),
 		DAG:          dag.NewDAG(merkledag.NewDAGService(bservice)),
 		Deals:        strgdls.New(nc.Repo.DealsDatastore()),
+		Events:       eventIndex,
 		MsgPool:      msgPool,
-		MsgPreviewer: msg.NewPreviewer(fcWallet, chainStore, &cstOffline, bs),
-		MsgQueryer:   msg.NewQueryer(nc.Repo, fcWallet, chainStore, &cstOffline, bs),
+		MsgPreviewer: msg.NewPreviewer(fcWallet, chainStore, &cstOffline, bs, forks),
+		MsgQueryer:   msg.NewQueryer(nc.Repo, fcWallet, chainStore, &cstOffline, bs, forks),
 		MsgSender:    msg.NewSender(fcWallet, chainStore, chainStore, outbox, msgPool, consensus.NewOutboundMessageValidator(), fsub.Publish),
-		MsgWaiter:    msg.NewWaiter(chainStore, bs, &cstOffline),
+		MsgWaiter:    msgWaiter,
 		Network:      net.New(peerHost, pubsub.NewPublisher(fsub), pubsub.NewSubscriber(fsub), net.NewRouter(router), bandwidthTracker, pinger),
 		Outbox:       outbox,
 		SigGetter:    mthdsig.NewGetter(chainStore),
+		StateDiffer:  stdiff.NewDiffer(chainStore, &cstOffline),
+		Syncer:       chainSyncer,
+		Vouchers:     vchrs.New(nc.Repo.Datastore()),
 		Wallet:       fcWallet,
 	}))
 
@@ -451,7 +488,9 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		PeerHost:     peerHost,
 		Repo:         nc.Repo,
 		Wallet:       fcWallet,
+		events:       eventIndex,
 		blockTime:    nc.BlockTime,
+		forks:        forks,
 		Router:       router,
 	}
 
@@ -536,6 +575,8 @@ func (node *Node) Start(ctx context.Context) error {
 	go node.handleSubscription(cctx, node.processBlock, "processBlock", node.BlockSub, "BlockSub")
 	go node.handleSubscription(cctx, node.processMessage, "processMessage", node.MessageSub, "MessageSub")
 
+	node.events.Start(cctx)
+
 	outboxPolicy := core.NewMessageQueuePolicy(node.Outbox, node.ChainReadStore(), core.OutboxMaxAgeRounds)
 
 	node.HeaviestTipSetHandled = func() {}
@@ -773,9 +814,9 @@ func (node *Node) StartMining(ctx context.Context) error {
 		}
 	}
 
-	minerOwnerAddr, err := node.miningOwnerAddress(ctx, minerAddr)
+	minerWorkerAddr, err := node.miningWorkerAddress(ctx, minerAddr)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get mining owner address for miner %s", minerAddr)
+		return errors.Wrapf(err, "failed to get mining worker address for miner %s", minerAddr)
 	}
 
 	_, mineDelay := node.MiningTimes()
@@ -819,14 +860,19 @@ func (node *Node) StartMining(ctx context.Context) error {
 
 					// TODO: determine these algorithmically by simulating call and querying historical prices
 					gasPrice := types.NewGasPrice(0)
-					gasUnits := types.NewGasUnits(300)
+					gasUnits := types.NewGasUnits(1000)
+
+					// the worker may have changed since mining started
+					if workerAddr, err := node.miningWorkerAddress(node.miningCtx, minerAddr); err == nil {
+						minerWorkerAddr = workerAddr
+					}
 
 					val := result.SealingResult
 					// This call can fail due to, e.g. nonce collisions. Our miners existence depends on this.
 					// We should deal with this, but MessageSendWithRetry is problematic.
 					_, err := node.PorcelainAPI.MessageSend(
 						node.miningCtx,
-						minerOwnerAddr,
+						minerWorkerAddr,
 						minerAddr,
 						nil,
 						gasPrice,
@@ -837,9 +883,10 @@ func (node *Node) StartMining(ctx context.Context) error {
 						val.CommR[:],
 						val.CommRStar[:],
 						val.Proof[:],
+						node.StorageMiner.DealIDsForSector(val.SectorID),
 					)
 					if err != nil {
-						log.Errorf("failed to send commitSector message from %s to %s for sector with id %d: %s", minerOwnerAddr, minerAddr, val.SectorID, err)
+						log.Errorf("failed to send commitSector message from %s to %s for sector with id %d: %s", minerWorkerAddr, minerAddr, val.SectorID, err)
 						continue
 					}
 
@@ -942,7 +989,12 @@ func initStorageMinerForNode(ctx context.Context, node *Node) (*storage.Miner, e
 		return nil, errors.Wrap(err, "no mining owner available, skipping storage miner setup")
 	}
 
-	miner, err := storage.NewMiner(minerAddr, miningOwnerAddr, node, node.Repo.DealsDatastore(), node.PorcelainAPI)
+	miningWorkerAddr, err := node.miningWorkerAddress(ctx, minerAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "no mining worker available, skipping storage miner setup")
+	}
+
+	miner, err := storage.NewMiner(minerAddr, miningOwnerAddr, miningWorkerAddr, node, node.Repo.DealsDatastore(), node.PorcelainAPI)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to instantiate storage miner")
 	}
@@ -980,6 +1032,19 @@ func (node *Node) miningOwnerAddress(ctx context.Context, miningAddr address.Add
 	return ownerAddr, nil
 }
 
+// miningWorkerAddress returns the worker of miningAddr, the address that sends
+// commitSector and submitPoSt messages. Miners that do not know about workers
+// are operated by their owner.
+func (node *Node) miningWorkerAddress(ctx context.Context, miningAddr address.Address) (address.Address, error) {
+	workerAddr, err := node.PorcelainAPI.MinerGetWorkerAddress(ctx, miningAddr)
+	if err == nil {
+		return workerAddr, nil
+	}
+
+	log.Warningf("failed to get miner worker address, falling back to owner: %s", err)
+	return node.miningOwnerAddress(ctx, miningAddr)
+}
+
 // BlockHeight returns the current block height of the chain.
 func (node *Node) BlockHeight() (*types.BlockHeight, error) {
 	head := node.ChainReader.Head()
@@ -1041,7 +1106,7 @@ func (node *Node) setupProtocols() error {
 // CreateMiningWorker creates a mining.Worker for the node using the configured
 // getStateTree, getWeight, and getAncestors functions for the node
 func (node *Node) CreateMiningWorker(ctx context.Context) (mining.Worker, error) {
-	processor := consensus.NewDefaultProcessor()
+	processor := consensus.NewDefaultProcessorWithForks(node.forks)
 
 	minerAddr, err := node.miningAddress()
 	if err != nil {
@@ -1060,7 +1125,7 @@ func (node *Node) CreateMiningWorker(ctx context.Context) (mining.Worker, error)
 	}
 	return mining.NewDefaultWorker(
 		node.MsgPool, node.getStateTree, node.getWeight, node.getAncestors, processor, node.PowerTable,
-		node.Blockstore, node.CborStore(), minerAddr, minerOwnerAddr, minerPubKey,
+		node.Blockstore, node.CborStore(), node.forks, minerAddr, minerOwnerAddr, minerPubKey,
 		node.Wallet, node.blockTime), nil
 }
 
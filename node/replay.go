@@ -0,0 +1,46 @@
+package node
+
+import (
+	"context"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+// NewReplayer returns a chain.Replayer over the chain stored in rep. It reads
+// the repo directly without starting a node, so it works offline and must
+// not be used on a repo a running node has open. Of the node config options,
+// only the fork schedule is used.
+func NewReplayer(ctx context.Context, rep repo.Repo, opts ...ConfigOpt) (*chain.Replayer, error) {
+	nc := &Config{}
+	for _, o := range opts {
+		if err := o(nc); err != nil {
+			return nil, err
+		}
+	}
+	forks := nc.forkSchedule()
+
+	bs := bstore.NewBlockstore(rep.Datastore())
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+
+	genCid, err := readGenesisCid(rep.Datastore())
+	if err != nil {
+		return nil, err
+	}
+
+	chainStore := chain.NewDefaultStore(rep.ChainDatastore(), cst, genCid, forks)
+	if err := chainStore.Load(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to load chain")
+	}
+
+	exp := consensus.NewExpectedWithForks(cst, bs, consensus.NewDefaultProcessorWithForks(forks), &consensus.MarketView{}, genCid, &proofs.RustVerifier{}, forks)
+	return chain.NewReplayer(chainStore, cst, exp.(*consensus.Expected), forks), nil
+}
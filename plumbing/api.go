@@ -10,6 +10,7 @@ import (
 	uio "gx/ipfs/QmRDWTzVdbHXdtat7tVJ7YC7kRaW7rTZTEF79yykcLYa49/go-unixfs/io"
 	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
 	pstore "gx/ipfs/QmRhFARzTHcFh8wUxwN5KvyTGq73FLC65EfFAhz8Ng7aGb/go-libp2p-peerstore"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
 	"gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"
 	"gx/ipfs/QmZZseAa9xcK6tT3YpaShNUAEpyRAoWmUL5ojH3uGNepAc/go-libp2p-metrics"
 	logging "gx/ipfs/QmbkT7eMTyXfpeyB3ZMxxcxg7XH8t6uXp49jqzz4HB7BGF/go-log"
@@ -23,12 +24,16 @@ import (
 	"github.com/filecoin-project/go-filecoin/net/pubsub"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/plumbing/dag"
+	"github.com/filecoin-project/go-filecoin/plumbing/evts"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/plumbing/mthdsig"
+	"github.com/filecoin-project/go-filecoin/plumbing/stdiff"
 	"github.com/filecoin-project/go-filecoin/plumbing/strgdls"
+	"github.com/filecoin-project/go-filecoin/plumbing/vchrs"
 	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
 	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
@@ -40,9 +45,11 @@ import (
 type API struct {
 	logger logging.EventLogger
 
+	blockstore   bstore.Blockstore
 	chain        chain.ReadStore
 	config       *cfg.Config
 	dag          *dag.DAG
+	events       *evts.Index
 	msgPool      *core.MessagePool
 	msgPreviewer *msg.Previewer
 	msgQueryer   *msg.Queryer
@@ -51,16 +58,21 @@ type API struct {
 	msgWaiter    *msg.Waiter
 	network      *net.Network
 	sigGetter    *mthdsig.Getter
+	stateDiffer  *stdiff.Differ
 	storagedeals *strgdls.Store
+	syncer       chain.Syncer
+	vouchers     *vchrs.Store
 	wallet       *wallet.Wallet
 }
 
 // APIDeps contains all the API's dependencies
 type APIDeps struct {
+	Blockstore   bstore.Blockstore
 	Chain        chain.ReadStore
 	Config       *cfg.Config
 	DAG          *dag.DAG
 	Deals        *strgdls.Store
+	Events       *evts.Index
 	MsgPool      *core.MessagePool
 	MsgPreviewer *msg.Previewer
 	MsgQueryer   *msg.Queryer
@@ -69,6 +81,9 @@ type APIDeps struct {
 	Network      *net.Network
 	Outbox       *core.MessageQueue
 	SigGetter    *mthdsig.Getter
+	StateDiffer  *stdiff.Differ
+	Syncer       chain.Syncer
+	Vouchers     *vchrs.Store
 	Wallet       *wallet.Wallet
 }
 
@@ -77,9 +92,11 @@ func New(deps *APIDeps) *API {
 	return &API{
 		logger: logging.Logger("porcelain"),
 
+		blockstore:   deps.Blockstore,
 		chain:        deps.Chain,
 		config:       deps.Config,
 		dag:          deps.DAG,
+		events:       deps.Events,
 		msgPool:      deps.MsgPool,
 		msgPreviewer: deps.MsgPreviewer,
 		msgQueryer:   deps.MsgQueryer,
@@ -88,7 +105,10 @@ func New(deps *APIDeps) *API {
 		network:      deps.Network,
 		outbox:       deps.Outbox,
 		sigGetter:    deps.SigGetter,
+		stateDiffer:  deps.StateDiffer,
 		storagedeals: deps.Deals,
+		syncer:       deps.Syncer,
+		vouchers:     deps.Vouchers,
 		wallet:       deps.Wallet,
 	}
 }
@@ -127,11 +147,68 @@ func (api *API) GetRecentAncestorsOfHeaviestChain(ctx context.Context, descendan
 	return chain.GetRecentAncestorsOfHeaviestChain(ctx, api.chain, descendantBlockHeight)
 }
 
+// ChainTipSetAtHeight returns the tipset of the heaviest chain at the given
+// height, or the closest tipset below it if the height is a null round
+func (api *API) ChainTipSetAtHeight(ctx context.Context, height uint64) (types.TipSet, error) {
+	return chain.FindTipSetAtHeight(ctx, api.chain, api.chain.Head(), height)
+}
+
+// ChainExport writes the tipsets of the heaviest chain between fromHeight and
+// toHeight to w as a CAR file, optionally along with their parent states.
+func (api *API) ChainExport(ctx context.Context, fromHeight, toHeight uint64, includeState bool, w io.Writer) error {
+	head, err := chain.FindTipSetAtHeight(ctx, api.chain, api.chain.Head(), toHeight)
+	if err != nil {
+		return err
+	}
+	return chain.Export(ctx, api.chain, api.blockstore, head, fromHeight, includeState, w)
+}
+
+// ChainExportSnapshot writes a snapshot of the heaviest chain at the given
+// height to w as a CAR file. Nodes whose checkpoint is the tipset at that
+// height can start syncing from the snapshot.
+func (api *API) ChainExportSnapshot(ctx context.Context, height uint64, w io.Writer) error {
+	checkpoint, err := chain.FindTipSetAtHeight(ctx, api.chain, api.chain.Head(), height)
+	if err != nil {
+		return err
+	}
+	return chain.ExportSnapshot(ctx, api.chain, api.blockstore, checkpoint, w)
+}
+
+// ChainImport loads a chain exported with ChainExport from r and syncs it,
+// returning the key of its head tipset.
+func (api *API) ChainImport(ctx context.Context, r io.Reader) (types.SortedCidSet, error) {
+	return chain.Import(ctx, api.blockstore, api.syncer, r)
+}
+
+// ChainImportSnapshot loads a snapshot exported with ChainExportSnapshot
+// from r and syncs the chain from it, returning the key of its checkpoint.
+// The snapshot must be of the checkpoint set in the config.
+func (api *API) ChainImportSnapshot(ctx context.Context, r io.Reader) (types.SortedCidSet, error) {
+	return chain.ImportSnapshot(ctx, api.blockstore, api.syncer, r)
+}
+
 // ChainLs returns a channel of tipsets from head to genesis
 func (api *API) ChainLs(ctx context.Context) <-chan interface{} {
 	return api.chain.BlockHistory(ctx, api.chain.Head())
 }
 
+// ChainStateDiff returns the actors that differ between the states after the
+// tipsets identified by a and b, ordered by address.
+func (api *API) ChainStateDiff(ctx context.Context, a, b types.SortedCidSet) ([]*state.ActorDiff, error) {
+	return api.stateDiffer.Diff(ctx, a, b)
+}
+
+// RepoGC removes the chain data of the repo that is no longer needed: the
+// states of all but the last keepTipSets tipsets of the heaviest chain. The
+// syncer is paused while blocks are removed. With dryRun nothing is removed.
+func (api *API) RepoGC(ctx context.Context, keepTipSets uint64, dryRun bool) (*chain.GCResult, error) {
+	if !dryRun {
+		resume := api.syncer.Pause()
+		defer resume()
+	}
+	return chain.CollectGarbage(ctx, api.chain, api.blockstore, keepTipSets, dryRun)
+}
+
 // ActorGet returns an actor from the latest state on the chain
 func (api *API) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
 	state, err := api.chain.LatestState(ctx)
@@ -150,6 +227,26 @@ func (api *API) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, e
 	return state.GetAllActors(ctx, st), nil
 }
 
+// ActorGetAt returns an actor from the state after the tipset identified by
+// tsKey
+func (api *API) ActorGetAt(ctx context.Context, addr address.Address, tsKey types.SortedCidSet) (*actor.Actor, error) {
+	st, err := api.chain.TipSetState(ctx, tsKey.String())
+	if err != nil {
+		return nil, err
+	}
+	return st.GetActor(ctx, addr)
+}
+
+// ActorLsAt returns a slice of actors from the state after the tipset
+// identified by tsKey
+func (api *API) ActorLsAt(ctx context.Context, tsKey types.SortedCidSet) (<-chan state.GetAllActorsResult, error) {
+	st, err := api.chain.TipSetState(ctx, tsKey.String())
+	if err != nil {
+		return nil, err
+	}
+	return state.GetAllActors(ctx, st), nil
+}
+
 // BlockGet gets a block by CID
 func (api *API) BlockGet(ctx context.Context, id cid.Cid) (*types.Block, error) {
 	return api.chain.GetBlock(ctx, id)
@@ -165,6 +262,26 @@ func (api *API) DealPut(storageDeal *storagedeal.Deal) error {
 	return api.storagedeals.Put(storageDeal)
 }
 
+// VoucherNonceReserve records and returns the nonce of a new voucher of payer
+// on the given lane of the given channel. A zero nonce reserves the next nonce
+// of the lane.
+func (api *API) VoucherNonceReserve(payer address.Address, channel *types.ChannelID, lane, nonce uint64) (uint64, error) {
+	return api.vouchers.Reserve(payer, channel, lane, nonce)
+}
+
+// EventsFind returns the events indexed for the heaviest chain that match
+// the filter, newest first.
+func (api *API) EventsFind(ctx context.Context, filter evts.Filter) ([]*evts.ChainEvent, error) {
+	return api.events.Find(ctx, filter)
+}
+
+// EventsSubscribe returns a channel of the events matching the filter in
+// tipsets that become part of the heaviest chain after the call. The channel
+// is closed when ctx is done.
+func (api *API) EventsSubscribe(ctx context.Context, filter evts.Filter) (<-chan *evts.ChainEvent, error) {
+	return api.events.Subscribe(ctx, filter)
+}
+
 // OutboxQueues lists addresses with non-empty outbox queues (in no particular order).
 func (api *API) OutboxQueues() []address.Address {
 	return api.outbox.Queues()
@@ -208,6 +325,12 @@ func (api *API) MessageQuery(ctx context.Context, optFrom, to address.Address, m
 	return api.msgQueryer.Query(ctx, optFrom, to, method, params...)
 }
 
+// MessageQueryAt calls an actor's method like MessageQuery, but using the
+// state after the tipset identified by tsKey.
+func (api *API) MessageQueryAt(ctx context.Context, optFrom, to address.Address, tsKey types.SortedCidSet, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	return api.msgQueryer.QueryAt(ctx, optFrom, to, tsKey, method, params...)
+}
+
 // MessageSend sends a message. It uses the default from address if none is given and signs the
 // message using the wallet. This call "sends" in the sense that it enqueues the
 // message in the msg pool and broadcasts it to the network; it does not wait for the
@@ -222,6 +345,12 @@ func (api *API) MessageFind(ctx context.Context, msgCid cid.Cid) (*msg.ChainMess
 	return api.msgWaiter.Find(ctx, msgCid)
 }
 
+// MessageTrace replays a message found on chain and returns the trace of its
+// execution, including the messages it sent to other actors.
+func (api *API) MessageTrace(ctx context.Context, msgCid cid.Cid) (*vm.Trace, error) {
+	return api.msgWaiter.Trace(ctx, msgCid)
+}
+
 // MessageWait invokes the callback when a message with the given cid appears on chain.
 // It will find the message in both the case that it is already on chain and
 // the case that it appears in a newly mined block. An error is returned if one is
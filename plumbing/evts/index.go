@@ -0,0 +1,508 @@
+package evts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore"
+	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore/query"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	logging "gx/ipfs/QmbkT7eMTyXfpeyB3ZMxxcxg7XH8t6uXp49jqzz4HB7BGF/go-log"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+	"gx/ipfs/QmdbxjQWogRCHRaxhhGnYdT1oQJzL9GdqSKzCdqWr85AP2/pubsub"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var log = logging.Logger("evts")
+
+func init() {
+	cbor.RegisterCborType(eventRecord{})
+	cbor.RegisterCborType(heightRecord{})
+}
+
+// EventsPrefix is the datastore prefix of the event index.
+const EventsPrefix = "events"
+
+// eventsTopic is the topic the index publishes newly indexed events on.
+const eventsTopic = "events"
+
+// ChainEvent is an event emitted by an actor along with the message that
+// caused it and the block that included the message.
+type ChainEvent struct {
+	*types.Event
+	Message cid.Cid `json:"message"`
+	Block   cid.Cid `json:"block"`
+	Height  uint64  `json:"height"`
+}
+
+// Filter selects events by the actor that emitted them and their name. An
+// empty actor or name matches any.
+type Filter struct {
+	Actor address.Address
+	Name  string
+}
+
+// Matches returns true if the event is selected by the filter.
+func (f Filter) Matches(ev *types.Event) bool {
+	if !f.Actor.Empty() && f.Actor != ev.Actor {
+		return false
+	}
+	return f.Name == "" || f.Name == ev.Name
+}
+
+// TipSetProcessor applies the messages of a tipset to the state of its
+// parent. It is implemented by msg.Waiter.
+type TipSetProcessor interface {
+	ProcessTipSet(ctx context.Context, ts types.TipSet) (*consensus.ProcessTipSetResponse, error)
+}
+
+// eventRecord is how an event is stored in the index. Seq is the position
+// of the event among the events of its tipset.
+type eventRecord struct {
+	Event   *types.Event
+	Message cid.Cid
+	Block   cid.Cid
+	Height  uint64
+	Seq     uint64
+}
+
+// heightRecord lists the events the index holds for the tipset of the
+// heaviest chain at a height.
+type heightRecord struct {
+	TipSet string
+	Keys   []string
+}
+
+// Index keeps the events emitted by the messages of the heaviest chain in a
+// datastore, keyed by the actor that emitted them, their name and the height
+// of the tipset that applied their message. Events are taken from the results
+// of applying each tipset to its parent state, since the receipts a block
+// carries do not account for the other blocks of its tipset. The index
+// follows the head of the chain once started, replacing the events of the
+// tipsets that leave the heaviest chain on a reorg.
+type Index struct {
+	chainReader chain.ReadStore
+	processor   TipSetProcessor
+	ds          repo.Datastore
+
+	// lk serializes updates of the index.
+	lk     sync.Mutex
+	events *pubsub.PubSub
+}
+
+// NewIndex returns a new event index over the given chain, stored in ds.
+func NewIndex(chainReader chain.ReadStore, processor TipSetProcessor, ds repo.Datastore) *Index {
+	return &Index{
+		chainReader: chainReader,
+		processor:   processor,
+		ds:          ds,
+		events:      pubsub.New(128),
+	}
+}
+
+// Start indexes the heaviest chain and keeps indexing every new head until
+// ctx is done. Indexing happens in the background, so events of tipsets that
+// have not been indexed yet are not found.
+func (idx *Index) Start(ctx context.Context) {
+	headCh := idx.chainReader.HeadEvents().Sub(chain.NewHeadTopic)
+
+	go func() {
+		defer idx.chainReader.HeadEvents().Unsub(headCh, chain.NewHeadTopic)
+
+		if head := idx.chainReader.Head(); head != nil {
+			if err := idx.update(ctx, head); err != nil {
+				log.Errorf("failed to index events of head %s: %s", head.String(), err)
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok := <-headCh:
+				if !ok {
+					return
+				}
+				ts, ok := raw.(types.TipSet)
+				if !ok {
+					continue
+				}
+				if err := idx.update(ctx, ts); err != nil {
+					log.Errorf("failed to index events of head %s: %s", ts.String(), err)
+				}
+			}
+		}
+	}()
+}
+
+// Find returns the indexed events matching the filter, newest first. Events
+// within a tipset stay in the order they were emitted.
+func (idx *Index) Find(ctx context.Context, filter Filter) ([]*ChainEvent, error) {
+	prefix := datastore.NewKey(EventsPrefix).ChildString("ev")
+	if !filter.Actor.Empty() {
+		prefix = prefix.ChildString(filter.Actor.String())
+		if filter.Name != "" {
+			prefix = prefix.ChildString(filter.Name)
+		}
+	}
+
+	results, err := idx.ds.Query(query.Query{Prefix: prefix.String() + "/"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query events from datastore")
+	}
+	defer results.Close() // nolint: errcheck
+
+	var records []*eventRecord
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			return nil, errors.Wrap(entry.Error, "failed to read events from datastore")
+		}
+		var rec eventRecord
+		if err := cbor.DecodeInto(entry.Value, &rec); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal event")
+		}
+		if filter.Matches(rec.Event) {
+			records = append(records, &rec)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Height != records[j].Height {
+			return records[i].Height > records[j].Height
+		}
+		return records[i].Seq < records[j].Seq
+	})
+
+	found := make([]*ChainEvent, len(records))
+	for i, rec := range records {
+		found[i] = rec.chainEvent()
+	}
+	return found, ctx.Err()
+}
+
+// Subscribe returns a channel of the events matching the filter in every
+// tipset the index adds from now on. When the head moves by more than one
+// tipset the events of the tipsets in between are delivered oldest first.
+// The channel is closed when ctx is done.
+func (idx *Index) Subscribe(ctx context.Context, filter Filter) (<-chan *ChainEvent, error) {
+	evCh := idx.events.Sub(eventsTopic)
+	out := make(chan *ChainEvent)
+
+	go func() {
+		defer close(out)
+		defer func() {
+			// keep receiving until unsubscribed so publishing never blocks
+			go idx.events.Unsub(evCh, eventsTopic)
+			for range evCh {
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok := <-evCh:
+				if !ok {
+					return
+				}
+				ev, ok := raw.(*ChainEvent)
+				if !ok || !filter.Matches(ev.Event) {
+					continue
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// update makes the index hold the events of the chain ending in head. It
+// walks back from head until it reaches a tipset that is already indexed,
+// dropping whatever the index holds for the heights it passes, and publishes
+// the events it adds oldest first.
+func (idx *Index) update(ctx context.Context, head types.TipSet) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+
+	headHeight, err := head.Height()
+	if err != nil {
+		return err
+	}
+
+	// forget the tipsets of the previous chain above the new head
+	indexedHeight, err := idx.indexedHeight()
+	if err != nil {
+		return err
+	}
+	for h := headHeight + 1; h <= indexedHeight; h++ {
+		if err := idx.removeHeight(h); err != nil {
+			return err
+		}
+	}
+	// recorded before walking so that an interrupted update never leaves
+	// tipsets indexed above the recorded head
+	data, err := cbor.DumpObject(headHeight)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal indexed height")
+	}
+	if err := idx.ds.Put(headKey(), data); err != nil {
+		return errors.Wrap(err, "failed to save indexed height")
+	}
+
+	var added [][]*ChainEvent
+	prevHeight := headHeight + 1
+	for raw := range idx.chainReader.BlockHistory(ctx, head) {
+		var ts types.TipSet
+		switch v := raw.(type) {
+		case error:
+			return v
+		case types.TipSet:
+			ts = v
+		default:
+			return fmt.Errorf("unexpected type in channel: %T", raw)
+		}
+
+		h, err := ts.Height()
+		if err != nil {
+			return err
+		}
+		// the heights skipped by null rounds hold no tipset on this chain
+		for skipped := h + 1; skipped < prevHeight; skipped++ {
+			if err := idx.removeHeight(skipped); err != nil {
+				return err
+			}
+		}
+		prevHeight = h
+
+		rec, err := idx.getHeight(h)
+		if err != nil {
+			return err
+		}
+		if rec != nil && rec.TipSet == ts.String() {
+			break
+		}
+		if err := idx.removeHeight(h); err != nil {
+			return err
+		}
+
+		evs, err := idx.addTipSet(ctx, ts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to index tipset %s", ts.String())
+		}
+		added = append(added, evs)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i := len(added) - 1; i >= 0; i-- {
+		for _, ev := range added[i] {
+			idx.events.Pub(ev, eventsTopic)
+		}
+	}
+	return nil
+}
+
+// addTipSet stores the events emitted by the messages of ts and returns
+// them. Tipsets that cannot be applied have no events: the genesis tipset,
+// and the tipsets whose parent state is unknown because the chain was synced
+// from a snapshot of a later checkpoint.
+func (idx *Index) addTipSet(ctx context.Context, ts types.TipSet) ([]*ChainEvent, error) {
+	h, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*eventRecord
+	applicable, err := idx.hasParentState(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	if applicable {
+		records, err = idx.eventsInTipSet(ctx, ts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	heightRec := heightRecord{TipSet: ts.String()}
+	evs := make([]*ChainEvent, len(records))
+	for i, rec := range records {
+		key := eventKey(rec)
+		data, err := cbor.DumpObject(rec)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal event")
+		}
+		if err := idx.ds.Put(key, data); err != nil {
+			return nil, errors.Wrap(err, "could not save event")
+		}
+		heightRec.Keys = append(heightRec.Keys, key.String())
+		evs[i] = rec.chainEvent()
+	}
+
+	data, err := cbor.DumpObject(heightRec)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal height record")
+	}
+	if err := idx.ds.Put(heightKey(h), data); err != nil {
+		return nil, errors.Wrap(err, "could not save height record")
+	}
+	return evs, nil
+}
+
+// hasParentState returns true if ts has a parent whose state is known.
+func (idx *Index) hasParentState(ctx context.Context, ts types.TipSet) (bool, error) {
+	parentIDs, err := ts.Parents()
+	if err != nil {
+		return false, err
+	}
+	if parentIDs.Empty() {
+		return false, nil
+	}
+	parent, err := idx.chainReader.GetTipSetAndState(ctx, parentIDs.String())
+	if err != nil {
+		return false, err
+	}
+	return parent.TipSetStateRoot.Defined(), nil
+}
+
+// eventsInTipSet applies ts and returns the events of its messages in the
+// order they were emitted. Results are matched with messages in the
+// canonical message order of the tipset, which skips duplicate messages and
+// the messages that failed to apply.
+func (idx *Index) eventsInTipSet(ctx context.Context, ts types.TipSet) ([]*eventRecord, error) {
+	res, err := idx.processor.ProcessTipSet(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	blks := ts.ToSlice()
+	types.SortBlocks(blks)
+
+	var records []*eventRecord
+	var applied types.SortedCidSet
+	for _, blk := range blks {
+		for _, msg := range blk.Messages {
+			msgCid, err := msg.Cid()
+			if err != nil {
+				return nil, err
+			}
+			if res.Failures.Has(msgCid) || applied.Has(msgCid) {
+				continue
+			}
+			j := applied.Len()
+			(&applied).Add(msgCid)
+
+			if j >= len(res.Results) {
+				return nil, fmt.Errorf("tipset %s has more messages than results", ts.String())
+			}
+			receipt := res.Results[j].Receipt
+			if receipt == nil {
+				continue
+			}
+			for _, ev := range receipt.Events {
+				records = append(records, &eventRecord{
+					Event:   ev,
+					Message: msgCid,
+					Block:   blk.Cid(),
+					Height:  uint64(blk.Height),
+					Seq:     uint64(len(records)),
+				})
+			}
+		}
+	}
+	return records, nil
+}
+
+// indexedHeight returns the height of the head the index was last updated
+// to, or 0 if it has never been updated.
+func (idx *Index) indexedHeight() (uint64, error) {
+	data, err := idx.ds.Get(headKey())
+	if err == datastore.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read indexed height")
+	}
+	var h uint64
+	if err := cbor.DecodeInto(data, &h); err != nil {
+		return 0, errors.Wrap(err, "failed to unmarshal indexed height")
+	}
+	return h, nil
+}
+
+// getHeight returns the record of the tipset indexed at height h, or nil if
+// there is none.
+func (idx *Index) getHeight(h uint64) (*heightRecord, error) {
+	data, err := idx.ds.Get(heightKey(h))
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read height record %d", h)
+	}
+	var rec heightRecord
+	if err := cbor.DecodeInto(data, &rec); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal height record %d", h)
+	}
+	return &rec, nil
+}
+
+// removeHeight deletes the tipset indexed at height h and its events.
+func (idx *Index) removeHeight(h uint64) error {
+	rec, err := idx.getHeight(h)
+	if err != nil || rec == nil {
+		return err
+	}
+	for _, key := range rec.Keys {
+		if err := idx.ds.Delete(datastore.NewKey(key)); err != nil {
+			return errors.Wrap(err, "failed to delete event")
+		}
+	}
+	return idx.ds.Delete(heightKey(h))
+}
+
+func (rec *eventRecord) chainEvent() *ChainEvent {
+	return &ChainEvent{
+		Event:   rec.Event,
+		Message: rec.Message,
+		Block:   rec.Block,
+		Height:  rec.Height,
+	}
+}
+
+// eventKey is /events/ev/<actor>/<name>/<height>/<seq>. Heights and
+// sequence numbers are zero padded so keys sort in chain order.
+func eventKey(rec *eventRecord) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{
+		EventsPrefix,
+		"ev",
+		rec.Event.Actor.String(),
+		rec.Event.Name,
+		fmt.Sprintf("%020d", rec.Height),
+		fmt.Sprintf("%010d", rec.Seq),
+	})
+}
+
+func heightKey(h uint64) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{EventsPrefix, "height", fmt.Sprintf("%020d", h)})
+}
+
+func headKey() datastore.Key {
+	return datastore.KeyWithNamespaces([]string{EventsPrefix, "head"})
+}
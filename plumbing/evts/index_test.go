@@ -0,0 +1,327 @@
+package evts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	hamt "gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var mockSigner, _ = types.NewMockSignersAndKeyInfo(2)
+
+var newSignedMessage = types.NewSignedMessageForTestGetter(mockSigner)
+
+// fakeProcessor returns the results registered for each tipset instead of
+// applying it.
+type fakeProcessor struct {
+	results map[string]*consensus.ProcessTipSetResponse
+}
+
+func newFakeProcessor() *fakeProcessor {
+	return &fakeProcessor{results: make(map[string]*consensus.ProcessTipSetResponse)}
+}
+
+func (fp *fakeProcessor) ProcessTipSet(ctx context.Context, ts types.TipSet) (*consensus.ProcessTipSetResponse, error) {
+	res, ok := fp.results[ts.String()]
+	if !ok {
+		return nil, fmt.Errorf("unexpected tipset %s", ts.String())
+	}
+	return res, nil
+}
+
+func setupTest(require *require.Assertions) (*hamt.CborIpldStore, *chain.DefaultStore, repo.Datastore) {
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+	chainStore, err := chain.Init(context.Background(), r, bs, cst, consensus.DefaultGenesis)
+	require.NoError(err)
+	return cst, chainStore, r.Datastore()
+}
+
+// requireAddTipSet puts a tipset of the given blocks on top of parent into
+// the chain store.
+func requireAddTipSet(ctx context.Context, require *require.Assertions, cst *hamt.CborIpldStore, chainStore *chain.DefaultStore, parent types.TipSet, blks ...*types.Block) types.TipSet {
+	height, err := parent.Height()
+	require.NoError(err)
+
+	for _, blk := range blks {
+		blk.Parents = parent.ToSortedCidSet()
+		blk.Height = types.Uint64(height + 1)
+		blk.StateRoot = parent.ToSlice()[0].StateRoot
+		_, err = cst.Put(ctx, blk)
+		require.NoError(err)
+	}
+
+	ts := th.RequireNewTipSet(require, blks...)
+	th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{
+		TipSet:          ts,
+		TipSetStateRoot: blks[0].StateRoot,
+	})
+	return ts
+}
+
+// requireAddSimpleTipSet puts a single block tipset on top of parent with a
+// message for each of the given receipts, and registers the receipts as the
+// results of applying the tipset.
+func requireAddSimpleTipSet(ctx context.Context, require *require.Assertions, cst *hamt.CborIpldStore, chainStore *chain.DefaultStore, fp *fakeProcessor, parent types.TipSet, nonce uint64, receipts ...*types.MessageReceipt) types.TipSet {
+	blk := &types.Block{Nonce: types.Uint64(nonce)}
+	res := &consensus.ProcessTipSetResponse{}
+	for _, receipt := range receipts {
+		blk.Messages = append(blk.Messages, newSignedMessage())
+		res.Results = append(res.Results, &consensus.ApplicationResult{Receipt: receipt})
+	}
+
+	ts := requireAddTipSet(ctx, require, cst, chainStore, parent, blk)
+	fp.results[ts.String()] = res
+	return ts
+}
+
+func receiptWithEvents(events ...*types.Event) *types.MessageReceipt {
+	return &types.MessageReceipt{Events: events}
+}
+
+func TestIndexFind(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cst, chainStore, ds := setupTest(require)
+	fp := newFakeProcessor()
+	addrGetter := address.NewForTestGetter()
+	addrA, addrB := addrGetter(), addrGetter()
+
+	ts1 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, chainStore.Head(), 0,
+		receiptWithEvents(&types.Event{Actor: addrA, Name: "added"}),
+		receiptWithEvents(),
+		receiptWithEvents(&types.Event{Actor: addrB, Name: "redeemed"}, &types.Event{Actor: addrB, Name: "added"}))
+	ts2 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts1, 0,
+		receiptWithEvents(&types.Event{Actor: addrA, Name: "added"}))
+
+	idx := NewIndex(chainStore, fp, ds)
+	require.NoError(idx.update(ctx, ts2))
+
+	all, err := idx.Find(ctx, Filter{})
+	require.NoError(err)
+	require.Len(all, 4)
+	assert.Equal(uint64(2), all[0].Height)
+	assert.Equal(addrA, all[0].Actor)
+	assert.Equal("added", all[1].Name)
+	assert.Equal("redeemed", all[2].Name)
+	assert.Equal("added", all[3].Name)
+
+	// the events point at the message whose result they are in
+	blk1 := ts1.ToSlice()[0]
+	msgCid, err := blk1.Messages[2].Cid()
+	require.NoError(err)
+	assert.Equal(msgCid, all[2].Message)
+	assert.Equal(blk1.Cid(), all[2].Block)
+	assert.Equal(uint64(1), all[2].Height)
+
+	added, err := idx.Find(ctx, Filter{Name: "added"})
+	require.NoError(err)
+	assert.Len(added, 3)
+
+	byB, err := idx.Find(ctx, Filter{Actor: addrB, Name: "added"})
+	require.NoError(err)
+	require.Len(byB, 1)
+	assert.Equal(addrB, byB[0].Actor)
+	assert.Equal(uint64(1), byB[0].Height)
+
+	byA, err := idx.Find(ctx, Filter{Actor: addrA})
+	require.NoError(err)
+	assert.Len(byA, 2)
+
+	// the index is persisted, so a new index over the same datastore finds
+	// the events without applying any tipset again
+	idx = NewIndex(chainStore, newFakeProcessor(), ds)
+	require.NoError(idx.update(ctx, ts2))
+	all, err = idx.Find(ctx, Filter{})
+	require.NoError(err)
+	assert.Len(all, 4)
+}
+
+func TestIndexEventsFromTipSetResults(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cst, chainStore, ds := setupTest(require)
+	fp := newFakeProcessor()
+	addr := address.NewForTestGetter()()
+
+	// both blocks include msg2, and msg3 conflicts with another message of
+	// the tipset
+	msg1, msg2, msg3 := newSignedMessage(), newSignedMessage(), newSignedMessage()
+	blkA := &types.Block{Ticket: []byte{1}, Messages: []*types.SignedMessage{msg1, msg2}}
+	blkB := &types.Block{
+		Ticket:   []byte{2},
+		Messages: []*types.SignedMessage{msg2, msg3},
+		// events in the receipts of a block are ignored
+		MessageReceipts: []*types.MessageReceipt{
+			receiptWithEvents(&types.Event{Actor: addr, Name: "ignored"}),
+			receiptWithEvents(&types.Event{Actor: addr, Name: "ignored"}),
+		},
+	}
+	ts := requireAddTipSet(ctx, require, cst, chainStore, chainStore.Head(), blkA, blkB)
+
+	msg3Cid, err := msg3.Cid()
+	require.NoError(err)
+	fp.results[ts.String()] = &consensus.ProcessTipSetResponse{
+		Results: []*consensus.ApplicationResult{
+			{Receipt: receiptWithEvents(&types.Event{Actor: addr, Name: "first"})},
+			{Receipt: receiptWithEvents(&types.Event{Actor: addr, Name: "second"})},
+		},
+		Failures: types.NewSortedCidSet(msg3Cid),
+	}
+
+	idx := NewIndex(chainStore, fp, ds)
+	require.NoError(idx.update(ctx, ts))
+
+	all, err := idx.Find(ctx, Filter{Actor: addr})
+	require.NoError(err)
+	require.Len(all, 2)
+	assert.Equal("first", all[0].Name)
+	assert.Equal("second", all[1].Name)
+
+	msg2Cid, err := msg2.Cid()
+	require.NoError(err)
+	assert.Equal(msg2Cid, all[1].Message)
+	assert.Equal(blkA.Cid(), all[1].Block)
+}
+
+func TestIndexReorg(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cst, chainStore, ds := setupTest(require)
+	fp := newFakeProcessor()
+	addr := address.NewForTestGetter()()
+
+	ts1 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, chainStore.Head(), 0,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "common"}))
+	ts2a := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts1, 0,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "forkA"}))
+	ts3a := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts2a, 0,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "forkA"}))
+	ts2b := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts1, 1,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "forkB"}))
+
+	idx := NewIndex(chainStore, fp, ds)
+	require.NoError(idx.update(ctx, ts3a))
+
+	names := func() []string {
+		evs, err := idx.Find(ctx, Filter{Actor: addr})
+		require.NoError(err)
+		var names []string
+		for _, ev := range evs {
+			names = append(names, ev.Name)
+		}
+		return names
+	}
+	assert.Equal([]string{"forkA", "forkA", "common"}, names())
+
+	// the events of the tipsets that left the heaviest chain are dropped,
+	// including the ones above the new head
+	require.NoError(idx.update(ctx, ts2b))
+	assert.Equal([]string{"forkB", "common"}, names())
+
+	require.NoError(idx.update(ctx, ts3a))
+	assert.Equal([]string{"forkA", "forkA", "common"}, names())
+}
+
+func TestIndexSkipsTipSetsWithUnknownParentState(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cst, chainStore, ds := setupTest(require)
+	fp := newFakeProcessor()
+	addr := address.NewForTestGetter()()
+
+	ts1 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, chainStore.Head(), 0,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "first"}))
+	ts2 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts1, 0,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "skipped"}))
+	ts3 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts2, 0,
+		receiptWithEvents(&types.Event{Actor: addr, Name: "checkpoint"}))
+
+	// as if synced from a snapshot of ts3, which holds the state after ts2
+	// but not the one after ts1
+	th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{TipSet: ts1, TipSetStateRoot: cid.Undef})
+
+	idx := NewIndex(chainStore, fp, ds)
+	require.NoError(idx.update(ctx, ts3))
+
+	evs, err := idx.Find(ctx, Filter{Actor: addr})
+	require.NoError(err)
+	var names []string
+	for _, ev := range evs {
+		names = append(names, ev.Name)
+	}
+	assert.Equal([]string{"checkpoint", "first"}, names)
+}
+
+func TestIndexSubscribe(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cst, chainStore, ds := setupTest(require)
+	fp := newFakeProcessor()
+	addrGetter := address.NewForTestGetter()
+	addrA, addrB := addrGetter(), addrGetter()
+
+	ts1 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, chainStore.Head(), 0,
+		receiptWithEvents(&types.Event{Actor: addrA, Name: "added"}))
+
+	idx := NewIndex(chainStore, fp, ds)
+	require.NoError(idx.update(ctx, ts1))
+
+	evCh, err := idx.Subscribe(ctx, Filter{Name: "added"})
+	require.NoError(err)
+
+	// the head moves by two tipsets at once
+	ts2 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts1, 0,
+		receiptWithEvents(&types.Event{Actor: addrA, Name: "added"}))
+	ts3 := requireAddSimpleTipSet(ctx, require, cst, chainStore, fp, ts2, 0,
+		receiptWithEvents(&types.Event{Actor: addrB, Name: "redeemed"}),
+		receiptWithEvents(&types.Event{Actor: addrB, Name: "added"}))
+
+	done := make(chan error)
+	go func() { done <- idx.update(ctx, ts3) }()
+
+	// events in tipsets that were already indexed are not delivered and the
+	// new ones arrive oldest first
+	ev := <-evCh
+	assert.Equal(uint64(2), ev.Height)
+	assert.Equal(addrA, ev.Actor)
+	ev = <-evCh
+	assert.Equal(uint64(3), ev.Height)
+	assert.Equal(addrB, ev.Actor)
+	require.NoError(<-done)
+
+	cancel()
+	for range evCh {
+	}
+}
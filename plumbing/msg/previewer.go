@@ -8,7 +8,6 @@ import (
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
-	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
@@ -27,11 +26,14 @@ type Previewer struct {
 	cst *hamt.CborIpldStore
 	// For vm storage.
 	bs bstore.Blockstore
+	// To know which actors are available and which gas schedule is in
+	// effect at the height of the head.
+	forks consensus.ForkSchedule
 }
 
 // NewPreviewer constructs a Previewer.
-func NewPreviewer(wallet *wallet.Wallet, chainReader chain.ReadStore, cst *hamt.CborIpldStore, bs bstore.Blockstore) *Previewer {
-	return &Previewer{wallet, chainReader, cst, bs}
+func NewPreviewer(wallet *wallet.Wallet, chainReader chain.ReadStore, cst *hamt.CborIpldStore, bs bstore.Blockstore, forks consensus.ForkSchedule) *Previewer {
+	return &Previewer{wallet, chainReader, cst, bs, forks}
 }
 
 // Preview sends a read-only message to an actor.
@@ -46,17 +48,17 @@ func (p *Previewer) Preview(ctx context.Context, optFrom, to address.Address, me
 	if err != nil {
 		return types.NewGasUnits(0), errors.Wrap(err, "couldnt get latest state root")
 	}
-	st, err := state.LoadStateTree(ctx, p.cst, tsas.TipSetStateRoot, builtin.Actors)
-	if err != nil {
-		return types.NewGasUnits(0), errors.Wrap(err, "could load tree for latest state root")
-	}
 	h, err := headTs.Height()
 	if err != nil {
 		return types.NewGasUnits(0), errors.Wrap(err, "couldnt get base tipset height")
 	}
+	st, err := state.LoadStateTree(ctx, p.cst, tsas.TipSetStateRoot, p.forks.ActorsAt(h))
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrap(err, "could load tree for latest state root")
+	}
 
 	vms := vm.NewStorageMap(p.bs)
-	usedGas, err := consensus.PreviewQueryMethod(ctx, st, vms, to, method, encodedParams, optFrom, types.NewBlockHeight(h))
+	usedGas, err := consensus.PreviewQueryMethod(ctx, st, vms, to, method, encodedParams, optFrom, types.NewBlockHeight(h), p.forks.GasScheduleAt(h))
 	if err != nil {
 		return types.NewGasUnits(0), errors.Wrap(err, "query method returned an error")
 	}
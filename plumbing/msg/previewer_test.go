@@ -48,10 +48,10 @@ func TestPreview(t *testing.T) {
 		)
 		deps := requireCommonDepsWithGifAndBlockstore(require, testGen, r, bs)
 
-		previewer := NewPreviewer(deps.wallet, deps.chainStore, deps.cst, deps.blockstore)
+		previewer := NewPreviewer(deps.wallet, deps.chainStore, deps.cst, deps.blockstore, consensus.DefaultForkSchedule)
 		returnValue, err := previewer.Preview(ctx, fromAddr, fakeActorAddr, "hasReturnValue")
 		require.NoError(err)
 		require.NotNil(returnValue)
-		assert.Equal(types.NewGasUnits(100), returnValue)
+		assert.Equal(types.NewGasUnits(100)+vm.GasScheduleV0.VerifySignature+vm.GasScheduleV0.Call, returnValue)
 	})
 }
@@ -8,7 +8,6 @@ import (
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
-	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
@@ -32,41 +31,50 @@ type Queryer struct {
 	cst *hamt.CborIpldStore
 	// For vm storage.
 	bs bstore.Blockstore
+	// To know which actors are available at the height of a tipset.
+	forks consensus.ForkSchedule
 }
 
 // NewQueryer constructs a Queryer.
-func NewQueryer(repo repo.Repo, wallet *wallet.Wallet, chainReader chain.ReadStore, cst *hamt.CborIpldStore, bs bstore.Blockstore) *Queryer {
-	return &Queryer{repo, wallet, chainReader, cst, bs}
+func NewQueryer(repo repo.Repo, wallet *wallet.Wallet, chainReader chain.ReadStore, cst *hamt.CborIpldStore, bs bstore.Blockstore, forks consensus.ForkSchedule) *Queryer {
+	return &Queryer{repo, wallet, chainReader, cst, bs, forks}
 }
 
-// Query sends a read-only message to an actor.
+// Query sends a read-only message to an actor at the head of the chain.
 func (q *Queryer) Query(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	return q.QueryAt(ctx, optFrom, to, q.chainReader.Head().ToSortedCidSet(), method, params...)
+}
+
+// QueryAt sends a read-only message to an actor in the state after the
+// tipset identified by tsKey.
+func (q *Queryer) QueryAt(ctx context.Context, optFrom, to address.Address, tsKey types.SortedCidSet, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
 	encodedParams, err := abi.ToEncodedValues(params...)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "couldnt encode message params")
 	}
 
-	// We return the method signature so callers know how to decode the return value.
-	// Probably would be better to do the decoding here since we are after all accepting
-	// golang types.
-	sigGetter := mthdsig.NewGetter(q.chainReader)
-	sig, err := sigGetter.Get(ctx, to, method)
+	tsas, err := q.chainReader.GetTipSetAndState(ctx, tsKey.String())
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "unable to determine return type")
+		return nil, nil, errors.Wrap(err, "couldnt get tipset state root")
 	}
-
-	headTs := q.chainReader.Head()
-	tsas, err := q.chainReader.GetTipSetAndState(ctx, headTs.String())
+	h, err := tsas.TipSet.Height()
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "couldnt get latest state root")
+		return nil, nil, errors.Wrap(err, "couldnt get base tipset height")
 	}
-	st, err := state.LoadStateTree(ctx, q.cst, tsas.TipSetStateRoot, builtin.Actors)
+	if !tsas.TipSetStateRoot.Defined() {
+		return nil, nil, errors.Wrapf(chain.ErrStateBelowCheckpoint, "cannot query the state at height %d", h)
+	}
+	st, err := state.LoadStateTree(ctx, q.cst, tsas.TipSetStateRoot, q.forks.ActorsAt(h))
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could load tree for latest state root")
+		return nil, nil, errors.Wrap(err, "could load tree for tipset state root")
 	}
-	h, err := headTs.Height()
+
+	// We return the method signature so callers know how to decode the return value.
+	// Probably would be better to do the decoding here since we are after all accepting
+	// golang types.
+	sig, err := mthdsig.GetFromState(ctx, st, to, method)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "couldnt get base tipset height")
+		return nil, nil, errors.Wrap(err, "unable to determine return type")
 	}
 
 	vms := vm.NewStorageMap(q.bs)
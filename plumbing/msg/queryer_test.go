@@ -50,7 +50,7 @@ func TestQuery(t *testing.T) {
 		)
 		deps := requireCommonDepsWithGifAndBlockstore(require, testGen, r, bs)
 
-		queryer := NewQueryer(deps.repo, deps.wallet, deps.chainStore, deps.cst, deps.blockstore)
+		queryer := NewQueryer(deps.repo, deps.wallet, deps.chainStore, deps.cst, deps.blockstore, consensus.DefaultForkSchedule)
 		returnValue, funcSig, err := queryer.Query(ctx, fromAddr, fakeActorAddr, "hasReturnValue")
 		require.NoError(err)
 		require.NotNil(returnValue)
@@ -89,9 +89,45 @@ func TestQuery(t *testing.T) {
 		)
 		deps := requireCommonDepsWithGifAndBlockstore(require, testGen, r, bs)
 
-		queryer := NewQueryer(deps.repo, deps.wallet, deps.chainStore, deps.cst, deps.blockstore)
+		queryer := NewQueryer(deps.repo, deps.wallet, deps.chainStore, deps.cst, deps.blockstore, consensus.DefaultForkSchedule)
 		_, _, err := queryer.Query(ctx, fromAddr, fakeActorAddr, "nonZeroExitCode")
 		require.Error(err)
 		assert.Contains(err.Error(), "42")
 	})
+
+	t.Run("query at a tipset", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+		newAddr := address.NewForTestGetter()
+		ctx := context.Background()
+		r := repo.NewInMemoryRepo()
+		bs := bstore.NewBlockstore(r.Datastore())
+
+		fakeActorCodeCid := types.NewCidForTestGetter()()
+		fakeActorAddr := newAddr()
+		fromAddr := newAddr()
+		vms := vm.NewStorageMap(bs)
+		fakeActor := th.RequireNewFakeActor(require, vms, fakeActorAddr, fakeActorCodeCid)
+		builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+		defer func() {
+			delete(builtin.Actors, fakeActorCodeCid)
+		}()
+		testGen := consensus.MakeGenesisFunc(
+			consensus.AddActor(fakeActorAddr, fakeActor),
+			consensus.ActorAccount(fromAddr, types.NewAttoFILFromFIL(0)),
+		)
+		deps := requireCommonDepsWithGifAndBlockstore(require, testGen, r, bs)
+
+		queryer := NewQueryer(deps.repo, deps.wallet, deps.chainStore, deps.cst, deps.blockstore, consensus.DefaultForkSchedule)
+		genesisKey := deps.chainStore.Head().ToSortedCidSet()
+		returnValue, funcSig, err := queryer.QueryAt(ctx, fromAddr, fakeActorAddr, genesisKey, "hasReturnValue")
+		require.NoError(err)
+		v, err := abi.Deserialize(returnValue[0], funcSig.Return[0])
+		require.NoError(err)
+		_, ok := v.Val.(address.Address)
+		assert.True(ok)
+
+		_, _, err = queryer.QueryAt(ctx, fromAddr, fakeActorAddr, types.NewSortedCidSet(types.SomeCid()), "hasReturnValue")
+		assert.Error(err)
+	})
 }
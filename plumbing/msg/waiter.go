@@ -10,7 +10,6 @@ import (
 	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
 	logging "gx/ipfs/QmbkT7eMTyXfpeyB3ZMxxcxg7XH8t6uXp49jqzz4HB7BGF/go-log"
 
-	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/sampling"
@@ -26,6 +25,7 @@ type Waiter struct {
 	chainReader chain.ReadStore
 	cst         *hamt.CborIpldStore
 	bs          bstore.Blockstore
+	forks       consensus.ForkSchedule
 }
 
 // ChainMessage is an on-chain message with its block and receipt.
@@ -36,11 +36,12 @@ type ChainMessage struct {
 }
 
 // NewWaiter returns a new Waiter.
-func NewWaiter(chainStore chain.ReadStore, bs bstore.Blockstore, cst *hamt.CborIpldStore) *Waiter {
+func NewWaiter(chainStore chain.ReadStore, bs bstore.Blockstore, cst *hamt.CborIpldStore, forks consensus.ForkSchedule) *Waiter {
 	return &Waiter{
 		chainReader: chainStore,
 		cst:         cst,
 		bs:          bs,
+		forks:       forks,
 	}
 }
 
@@ -168,6 +169,78 @@ func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts types
 	}
 
 	// Apply all the tipset's messages to determine the correct receipts.
+	res, err := w.resultFromTipSet(ctx, msgCid, ts)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	return res.Receipt, nil
+}
+
+// Trace finds a message on chain and replays the tipset that includes it on
+// top of the tipset's parent state, returning the execution trace of the
+// message.
+func (w *Waiter) Trace(ctx context.Context, msgCid cid.Cid) (*vm.Trace, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for raw := range w.chainReader.BlockHistory(ctx, w.chainReader.Head()) {
+		switch v := raw.(type) {
+		case error:
+			return nil, v
+		case types.TipSet:
+			if _, err := msgIndexOfTipSet(msgCid, v, types.SortedCidSet{}); err != nil {
+				continue
+			}
+
+			res, err := w.resultFromTipSet(ctx, msgCid, v)
+			if err == chain.ErrStateBelowCheckpoint {
+				return nil, errors.Wrapf(err, "cannot trace message %s", msgCid)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if res == nil || res.Trace == nil {
+				return nil, fmt.Errorf("message %s was not applied in its tipset", msgCid)
+			}
+			return res.Trace, nil
+		default:
+			return nil, fmt.Errorf("unexpected type in channel: %T", raw)
+		}
+	}
+	return nil, fmt.Errorf("message %s not found on chain", msgCid)
+}
+
+// resultFromTipSet applies all the messages of the input tipset to its
+// parent state and returns the application result of the message with
+// msgCid. The result is nil if the message conflicted with another message
+// of the tipset and was not applied.
+func (w *Waiter) resultFromTipSet(ctx context.Context, msgCid cid.Cid, ts types.TipSet) (*consensus.ApplicationResult, error) {
+	res, err := w.ProcessTipSet(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	// If this is a failing conflict message there is no application result.
+	if res.Failures.Has(msgCid) {
+		return nil, nil
+	}
+
+	j, err := msgIndexOfTipSet(msgCid, ts, res.Failures)
+	if err != nil {
+		return nil, err
+	}
+	// TODO: out of bounds result index should return an error.
+	if j < len(res.Results) {
+		return res.Results[j], nil
+	}
+	return nil, nil
+}
+
+// ProcessTipSet applies all the messages of ts to the state of its parent
+// and returns their results. The results are in the canonical message order
+// of the tipset, skipping duplicates and the messages listed as failures.
+// It returns chain.ErrStateBelowCheckpoint if the parent state is not known.
+func (w *Waiter) ProcessTipSet(ctx context.Context, ts types.TipSet) (*consensus.ProcessTipSetResponse, error) {
 	ids, err := ts.Parents()
 	if err != nil {
 		return nil, err
@@ -176,40 +249,35 @@ func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts types
 	if err != nil {
 		return nil, err
 	}
-	st, err := state.LoadStateTree(ctx, w.cst, tsas.TipSetStateRoot, builtin.Actors)
+	parentHeight, err := tsas.TipSet.Height()
 	if err != nil {
 		return nil, err
 	}
-
 	tsHeight, err := ts.Height()
 	if err != nil {
 		return nil, err
 	}
-	tsBlockHeight := types.NewBlockHeight(tsHeight)
-	ancestors, err := chain.GetRecentAncestors(ctx, tsas.TipSet, w.chainReader, tsBlockHeight, consensus.AncestorRoundsNeeded, sampling.LookbackParameter)
-	if err != nil {
-		return nil, err
+	if !tsas.TipSetStateRoot.Defined() {
+		return nil, chain.ErrStateBelowCheckpoint
 	}
 
-	res, err := consensus.NewDefaultProcessor().ProcessTipSet(ctx, st, vm.NewStorageMap(w.bs), ts, ancestors)
+	st, err := state.LoadStateTree(ctx, w.cst, tsas.TipSetStateRoot, w.forks.ActorsAt(parentHeight))
 	if err != nil {
 		return nil, err
 	}
-
-	// If this is a failing conflict message there is no application receipt.
-	if res.Failures.Has(msgCid) {
-		return nil, nil
+	vms := vm.NewStorageMap(w.bs)
+	st, err = w.forks.UpgradeState(ctx, w.cst, st, vms, parentHeight, tsHeight)
+	if err != nil {
+		return nil, err
 	}
 
-	j, err := msgIndexOfTipSet(msgCid, ts, res.Failures)
+	tsBlockHeight := types.NewBlockHeight(tsHeight)
+	ancestors, err := chain.GetRecentAncestors(ctx, tsas.TipSet, w.chainReader, tsBlockHeight, consensus.AncestorRoundsNeeded, sampling.LookbackParameter)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: out of bounds receipt index should return an error.
-	if j < len(res.Results) {
-		rcpt = res.Results[j].Receipt
-	}
-	return rcpt, nil
+
+	return consensus.NewDefaultProcessor().ProcessTipSet(ctx, st, vms, ts, ancestors)
 }
 
 // msgIndexOfTipSet returns the order in which msgCid appears in the canonical
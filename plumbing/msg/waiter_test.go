@@ -45,12 +45,12 @@ type smsgsSet [][]*types.SignedMessage
 
 func setupTest(require *require.Assertions) (*hamt.CborIpldStore, *chain.DefaultStore, *Waiter) {
 	d := requiredCommonDeps(require, consensus.DefaultGenesis)
-	return d.cst, d.chainStore, NewWaiter(d.chainStore, d.blockstore, d.cst)
+	return d.cst, d.chainStore, NewWaiter(d.chainStore, d.blockstore, d.cst, consensus.DefaultForkSchedule)
 }
 
 func setupTestWithGif(require *require.Assertions, gif consensus.GenesisInitFunc) (*hamt.CborIpldStore, *chain.DefaultStore, *Waiter) {
 	d := requiredCommonDeps(require, gif)
-	return d.cst, d.chainStore, NewWaiter(d.chainStore, d.blockstore, d.cst)
+	return d.cst, d.chainStore, NewWaiter(d.chainStore, d.blockstore, d.cst, consensus.DefaultForkSchedule)
 }
 
 func TestWait(t *testing.T) {
@@ -143,11 +143,11 @@ func TestWaitConflicting(t *testing.T) {
 
 	// Create conflicting messages
 	m1 := types.NewMessage(addr1, addr3, 0, types.NewAttoFILFromFIL(6000), "", nil)
-	sm1, err := types.NewSignedMessage(*m1, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	sm1, err := types.NewSignedMessage(*m1, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 
 	m2 := types.NewMessage(addr1, addr2, 0, types.NewAttoFILFromFIL(6000), "", nil)
-	sm2, err := types.NewSignedMessage(*m2, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(0))
+	sm2, err := types.NewSignedMessage(*m2, &mockSigner, types.NewGasPrice(0), types.NewGasUnits(300))
 	require.NoError(err)
 
 	baseTS := chainStore.Head()
@@ -42,7 +42,12 @@ func (sg *Getter) Get(ctx context.Context, actorAddr address.Address, method str
 	if err != nil {
 		return nil, errors.Wrap(err, "couldnt get current state tree")
 	}
+	return GetFromState(ctx, st, actorAddr, method)
+}
 
+// GetFromState returns the signature for the given actor and method in the
+// given state tree.
+func GetFromState(ctx context.Context, st state.Tree, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
 	actor, err := st.GetActor(ctx, actorAddr)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get actor")
@@ -0,0 +1,42 @@
+package stdiff
+
+import (
+	"context"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// ChainReadStore is the subset of chain.ReadStore that Differ needs.
+type ChainReadStore interface {
+	GetTipSetAndState(ctx context.Context, tsKey string) (*chain.TipSetAndState, error)
+}
+
+// Differ diffs the states of tipsets in the chain.
+type Differ struct {
+	chainReader ChainReadStore
+	cst         *hamt.CborIpldStore
+}
+
+// NewDiffer returns a new Differ.
+func NewDiffer(chainReader ChainReadStore, cst *hamt.CborIpldStore) *Differ {
+	return &Differ{chainReader: chainReader, cst: cst}
+}
+
+// Diff returns the actors that differ between the states after the tipsets
+// identified by the block CIDs in a and b, ordered by address.
+func (d *Differ) Diff(ctx context.Context, a, b types.SortedCidSet) ([]*state.ActorDiff, error) {
+	aTsas, err := d.chainReader.GetTipSetAndState(ctx, a.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state of tipset %s", a)
+	}
+	bTsas, err := d.chainReader.GetTipSetAndState(ctx, b.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state of tipset %s", b)
+	}
+	return state.DiffActors(ctx, d.cst, aTsas.TipSetStateRoot, bTsas.TipSetStateRoot)
+}
@@ -0,0 +1,71 @@
+package stdiff
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	bstore "gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	offline "gx/ipfs/QmSz8kAe2JCKp2dWSG8gHSWnwSmne8YfRXTeK5HBmc9L7t/go-ipfs-exchange-offline"
+	bserv "gx/ipfs/QmZsGVGCqMCNzHLNMB6q4F6yyvomqf1VxwhJwSfgo1NGaF/go-blockservice"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
+	chainStore, err := chain.Init(ctx, r, bs, cst, consensus.DefaultGenesis)
+	require.NoError(err)
+
+	genesis := chainStore.Head()
+	genesisRoot := genesis.ToSlice()[0].StateRoot
+
+	// the child tipset adds an actor to the genesis state
+	st, err := state.LoadStateTree(ctx, cst, genesisRoot, nil)
+	require.NoError(err)
+	added := address.NewForTestGetter()()
+	require.NoError(st.SetActor(ctx, added, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	childRoot, err := st.Flush(ctx)
+	require.NoError(err)
+
+	blk := &types.Block{
+		Parents:   genesis.ToSortedCidSet(),
+		Height:    types.Uint64(1),
+		StateRoot: childRoot,
+	}
+	_, err = cst.Put(ctx, blk)
+	require.NoError(err)
+	child := th.RequireNewTipSet(require, blk)
+	th.RequirePutTsas(ctx, require, chainStore, &chain.TipSetAndState{TipSet: child, TipSetStateRoot: childRoot})
+
+	differ := NewDiffer(chainStore, cst)
+
+	diffs, err := differ.Diff(ctx, genesis.ToSortedCidSet(), child.ToSortedCidSet())
+	require.NoError(err)
+	require.Len(diffs, 1)
+	assert.Equal(added, diffs[0].Address)
+	assert.True(diffs[0].Added())
+
+	diffs, err = differ.Diff(ctx, child.ToSortedCidSet(), child.ToSortedCidSet())
+	require.NoError(err)
+	assert.Empty(diffs)
+
+	_, err = differ.Diff(ctx, genesis.ToSortedCidSet(), types.NewSortedCidSet(types.SomeCid()))
+	assert.Error(err)
+}
@@ -0,0 +1,69 @@
+package vchrs
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// VoucherNoncePrefix is the datastore prefix for the nonces of issued vouchers
+const VoucherNoncePrefix = "vouchernonces"
+
+// Store keeps track of the greatest nonce of the vouchers this node issued on
+// each lane of each payment channel, so that a new voucher never reuses the
+// nonce of an earlier one and invalidates it.
+type Store struct {
+	lk sync.Mutex
+	ds repo.Datastore
+}
+
+// New returns a new Store.
+func New(ds repo.Datastore) *Store {
+	return &Store{ds: ds}
+}
+
+// Reserve records and returns the nonce of a new voucher of payer on the given
+// lane of the given channel. If nonce is zero the voucher gets the next nonce
+// of the lane, otherwise nonce must be greater than any nonce reserved on the
+// lane before.
+func (store *Store) Reserve(payer address.Address, channel *types.ChannelID, lane, nonce uint64) (uint64, error) {
+	store.lk.Lock()
+	defer store.lk.Unlock()
+
+	key := datastore.KeyWithNamespaces([]string{VoucherNoncePrefix, payer.String(), channel.String(), strconv.FormatUint(lane, 10)})
+
+	var last uint64
+	datum, err := store.ds.Get(key)
+	if err != nil && err != datastore.ErrNotFound {
+		return 0, errors.Wrap(err, "could not read voucher nonce from datastore")
+	}
+	if err == nil {
+		if err := cbor.DecodeInto(datum, &last); err != nil {
+			return 0, errors.Wrap(err, "could not unmarshal voucher nonce")
+		}
+	}
+
+	if nonce == 0 {
+		nonce = last + 1
+	} else if nonce <= last {
+		return 0, fmt.Errorf("nonce %d is not greater than the nonce %d of an earlier voucher on lane %d", nonce, last, lane)
+	}
+
+	datum, err = cbor.DumpObject(nonce)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not marshal voucher nonce")
+	}
+	if err := store.ds.Put(key, datum); err != nil {
+		return 0, errors.Wrap(err, "could not save voucher nonce to disk")
+	}
+
+	return nonce, nil
+}
@@ -0,0 +1,50 @@
+package vchrs_test
+
+import (
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/plumbing/vchrs"
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestVoucherNonceReserve(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	r := repo.NewInMemoryRepo()
+	store := vchrs.New(r.Datastore())
+	payer := address.NewForTestGetter()()
+	channel := types.NewChannelID(5)
+
+	reserve := func(lane, nonce uint64) uint64 {
+		n, err := store.Reserve(payer, channel, lane, nonce)
+		require.NoError(err)
+		return n
+	}
+
+	// nonces start at one and increase
+	assert.Equal(uint64(1), reserve(0, 0))
+	assert.Equal(uint64(2), reserve(0, 0))
+
+	// explicit nonces may skip ahead but never go back
+	assert.Equal(uint64(10), reserve(0, 10))
+	_, err := store.Reserve(payer, channel, 0, 10)
+	assert.Error(err)
+	assert.Equal(uint64(11), reserve(0, 0))
+
+	// lanes and channels are counted separately
+	assert.Equal(uint64(1), reserve(1, 0))
+	n, err := store.Reserve(payer, types.NewChannelID(6), 0, 0)
+	require.NoError(err)
+	assert.Equal(uint64(1), n)
+
+	// the nonces survive the store
+	n, err = vchrs.New(r.Datastore()).Reserve(payer, channel, 0, 0)
+	require.NoError(err)
+	assert.Equal(uint64(12), n)
+}
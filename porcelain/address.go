@@ -0,0 +1,49 @@
+package porcelain
+
+import (
+	"context"
+
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+)
+
+// alAPI is the subset of the plumbing.API that AddressLookup uses.
+type alAPI interface {
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+}
+
+// AddressLookup asks the init actor for both forms of the given address,
+// which may be either an actor address or an ID address. The returned ID
+// address is empty if the actor was never assigned an ID.
+func AddressLookup(ctx context.Context, plumbing alAPI, addr address.Address) (address.Address, address.Address, error) {
+	if addr.Protocol() != address.ID {
+		res, _, err := plumbing.MessageQuery(ctx, address.Undef, address.InitAddress, "getIDAddress", addr)
+		if err != nil {
+			return address.Undef, address.Undef, errors.Wrap(err, "failed to look up ID address")
+		}
+
+		idAddr, err := address.NewFromBytes(res[0])
+		if err != nil {
+			return address.Undef, address.Undef, errors.Wrap(err, "could not decode ID address")
+		}
+
+		return addr, idAddr, nil
+	}
+
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, address.InitAddress, "getActorAddress", addr)
+	if err != nil {
+		return address.Undef, address.Undef, errors.Wrap(err, "failed to look up actor address")
+	}
+
+	actorAddr, err := address.NewFromBytes(res[0])
+	if err != nil {
+		return address.Undef, address.Undef, errors.Wrap(err, "could not decode actor address")
+	}
+	if actorAddr.Empty() {
+		return address.Undef, address.Undef, errors.Errorf("ID address %s has not been assigned", addr)
+	}
+
+	return actorAddr, addr, nil
+}
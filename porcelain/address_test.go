@@ -0,0 +1,77 @@
+package porcelain
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+)
+
+type addressLookupPlumbing struct {
+	actorAddr address.Address
+	idAddr    address.Address
+}
+
+func (alp *addressLookupPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	switch method {
+	case "getIDAddress":
+		if params[0].(address.Address) == alp.actorAddr {
+			return [][]byte{alp.idAddr.Bytes()}, nil, nil
+		}
+	case "getActorAddress":
+		if params[0].(address.Address) == alp.idAddr {
+			return [][]byte{alp.actorAddr.Bytes()}, nil, nil
+		}
+	}
+	return [][]byte{address.Undef.Bytes()}, nil, nil
+}
+
+func TestAddressLookup(t *testing.T) {
+	t.Parallel()
+
+	idAddr, err := address.NewIDAddress(7)
+	require.NoError(t, err)
+	plumbing := &addressLookupPlumbing{actorAddr: address.TestAddress, idAddr: idAddr}
+
+	t.Run("looks up the ID of an actor address", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		actorAddr, id, err := AddressLookup(context.Background(), plumbing, address.TestAddress)
+		require.NoError(err)
+		assert.Equal(address.TestAddress, actorAddr)
+		assert.Equal(idAddr, id)
+	})
+
+	t.Run("looks up the actor address of an ID address", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		actorAddr, id, err := AddressLookup(context.Background(), plumbing, idAddr)
+		require.NoError(err)
+		assert.Equal(address.TestAddress, actorAddr)
+		assert.Equal(idAddr, id)
+	})
+
+	t.Run("actors without an ID have an empty ID address", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		actorAddr, id, err := AddressLookup(context.Background(), plumbing, address.TestAddress2)
+		require.NoError(err)
+		assert.Equal(address.TestAddress2, actorAddr)
+		assert.True(id.Empty())
+	})
+
+	t.Run("unassigned ID addresses are an error", func(t *testing.T) {
+		unassigned, err := address.NewIDAddress(8)
+		require.NoError(t, err)
+
+		_, _, err = AddressLookup(context.Background(), plumbing, unassigned)
+		assert.Error(t, err)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"gx/ipfs/QmTu65MVbemtUxJEWgsTtzv9Zv9P8rvmqNA4eG9TrTRGYc/go-libp2p-peer"
 
 	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/plumbing"
@@ -43,6 +44,11 @@ func New(plumbing *plumbing.API) *API {
 	return &API{plumbing}
 }
 
+// AddressLookup returns both the actor address and the ID address of the given address
+func (a *API) AddressLookup(ctx context.Context, addr address.Address) (address.Address, address.Address, error) {
+	return AddressLookup(ctx, a, addr)
+}
+
 // ChainBlockHeight determines the current block height
 func (a *API) ChainBlockHeight(ctx context.Context) (*types.BlockHeight, error) {
 	return ChainBlockHeight(ctx, a)
@@ -130,6 +136,16 @@ func (a *API) MinerGetOwnerAddress(ctx context.Context, minerAddr address.Addres
 	return MinerGetOwnerAddress(ctx, a, minerAddr)
 }
 
+// MinerGetWorkerAddress queries for the worker address of the given miner
+func (a *API) MinerGetWorkerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error) {
+	return MinerGetWorkerAddress(ctx, a, minerAddr)
+}
+
+// MinerListSectors queries for the sectors the given miner has committed
+func (a *API) MinerListSectors(ctx context.Context, minerAddr address.Address) ([]*minerActor.SectorInfo, error) {
+	return MinerListSectors(ctx, a, minerAddr)
+}
+
 // MinerGetKey queries for the public key of the given miner
 func (a *API) MinerGetKey(ctx context.Context, minerAddr address.Address) ([]byte, error) {
 	return MinerGetKey(ctx, a, minerAddr)
@@ -140,6 +156,26 @@ func (a *API) MinerGetPeerID(ctx context.Context, minerAddr address.Address) (pe
 	return MinerGetPeerID(ctx, a, minerAddr)
 }
 
+// MinerGetStatus queries for the power, collateral and proving period of the given miner
+func (a *API) MinerGetStatus(ctx context.Context, minerAddr address.Address) (MinerStatus, error) {
+	return MinerGetStatus(ctx, a, minerAddr)
+}
+
+// MinerAddCollateral adds collateral to a miner and raises its pledge
+func (a *API) MinerAddCollateral(ctx context.Context, from address.Address, minerAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, amount *types.AttoFIL, pledge *big.Int) (cid.Cid, error) {
+	return MinerAddCollateral(ctx, a, from, minerAddr, gasPrice, gasLimit, amount, pledge)
+}
+
+// MinerWithdrawCollateral withdraws unused collateral from a miner
+func (a *API) MinerWithdrawCollateral(ctx context.Context, from address.Address, minerAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, amount *types.AttoFIL) (cid.Cid, error) {
+	return MinerWithdrawCollateral(ctx, a, from, minerAddr, gasPrice, gasLimit, amount)
+}
+
+// MinerGetCollateral queries for the collateral and pledge of the given miner
+func (a *API) MinerGetCollateral(ctx context.Context, minerAddr address.Address) (MinerCollateral, error) {
+	return MinerGetCollateral(ctx, a, minerAddr)
+}
+
 // MinerSetPrice configures the price of storage. See implementation for details.
 func (a *API) MinerSetPrice(ctx context.Context, from address.Address, miner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, price *types.AttoFIL, expiry *big.Int) (MinerSetPriceResponse, error) {
 	return MinerSetPrice(ctx, a, from, miner, gasPrice, gasLimit, price, expiry)
@@ -157,11 +193,60 @@ func (a *API) MinerPreviewSetPrice(
 	return MinerPreviewSetPrice(ctx, a, from, miner, price, expiry)
 }
 
+// MultisigPropose proposes a transaction from a multisig wallet
+func (a *API) MultisigPropose(
+	ctx context.Context,
+	from address.Address,
+	msigAddr address.Address,
+	gasPrice types.AttoFIL,
+	gasLimit types.GasUnits,
+	to address.Address,
+	value *types.AttoFIL,
+	method string,
+	params ...interface{},
+) (cid.Cid, error) {
+	return MultisigPropose(ctx, a, from, msigAddr, gasPrice, gasLimit, to, value, method, params...)
+}
+
+// MultisigPreviewPropose calculates the amount of Gas needed for a call to MultisigPropose.
+func (a *API) MultisigPreviewPropose(
+	ctx context.Context,
+	from address.Address,
+	msigAddr address.Address,
+	to address.Address,
+	value *types.AttoFIL,
+	method string,
+	params ...interface{},
+) (types.GasUnits, error) {
+	return MultisigPreviewPropose(ctx, a, from, msigAddr, to, value, method, params...)
+}
+
+// MultisigGetSigners queries for the signers of the given multisig wallet
+func (a *API) MultisigGetSigners(ctx context.Context, msigAddr address.Address) ([]address.Address, error) {
+	return MultisigGetSigners(ctx, a, msigAddr)
+}
+
+// MultisigGetThreshold queries for the number of approvals required by the given multisig wallet
+func (a *API) MultisigGetThreshold(ctx context.Context, msigAddr address.Address) (uint64, error) {
+	return MultisigGetThreshold(ctx, a, msigAddr)
+}
+
+// MultisigListPending queries for the pending transactions of the given multisig wallet
+func (a *API) MultisigListPending(ctx context.Context, msigAddr address.Address) (map[string]*multisig.Transaction, error) {
+	return MultisigListPending(ctx, a, msigAddr)
+}
+
 // WalletBalance returns the current balance of the given wallet address.
 func (a *API) WalletBalance(ctx context.Context, address address.Address) (*types.AttoFIL, error) {
 	return WalletBalance(ctx, a, address)
 }
 
+// WalletBalanceAt returns the balance of the given wallet address in the
+// state after the tipset identified by tsKey.
+func (a *API) WalletBalanceAt(ctx context.Context, address address.Address, tsKey types.SortedCidSet) (*types.AttoFIL, error) {
+	return WalletBalanceAt(ctx, a, address, tsKey)
+}
+
 // WalletDefaultAddress returns a default wallet address from the config.
 // If none is set it picks the first address in the wallet and sets it as the default in the config.
 func (a *API) WalletDefaultAddress() (address.Address, error) {
@@ -177,18 +262,22 @@ func (a *API) PaymentChannelLs(
 	return PaymentChannelLs(ctx, a, fromAddr, payerAddr)
 }
 
-// PaymentChannelVoucher returns a signed payment channel voucher
+// PaymentChannelVoucher returns a signed payment channel voucher, with the
+// next nonce of the lane if nonce is zero
 func (a *API) PaymentChannelVoucher(
 	ctx context.Context,
 	fromAddr address.Address,
 	channel *types.ChannelID,
 	amount *types.AttoFIL,
 	validAt *types.BlockHeight,
+	lane uint64,
+	nonce uint64,
 ) (voucher *paymentbroker.PaymentVoucher, err error) {
-	return PaymentChannelVoucher(ctx, a, fromAddr, channel, amount, validAt)
+	return PaymentChannelVoucher(ctx, a, fromAddr, channel, amount, validAt, lane, nonce)
 }
 
-// ClientListAsks returns a channel with asks from the latest chain state
-func (a *API) ClientListAsks(ctx context.Context) <-chan Ask {
-	return ClientListAsks(ctx, a)
+// ClientListAsks returns a channel with asks from the latest chain state.
+// Expired asks are left out unless includeExpired is set.
+func (a *API) ClientListAsks(ctx context.Context, includeExpired bool) <-chan Ask {
+	return ClientListAsks(ctx, a, includeExpired)
 }
@@ -24,16 +24,32 @@ type Ask struct {
 }
 
 type claPlubming interface {
+	ChainLs(ctx context.Context) <-chan interface{}
 	ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error)
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
 }
 
-// ClientListAsks returns a channel with asks from the latest chain state
-func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
+// ClientListAsks returns a channel with asks from the latest chain state.
+// Asks that have expired at the current block height are left out unless
+// includeExpired is set.
+func ClientListAsks(ctx context.Context, plumbing claPlubming, includeExpired bool) <-chan Ask {
 	out := make(chan Ask)
 
 	go func() {
 		defer close(out)
+
+		var height *types.BlockHeight
+		if !includeExpired {
+			var err error
+			height, err = ChainBlockHeight(ctx, plumbing)
+			if err != nil {
+				out <- Ask{
+					Error: err,
+				}
+				return
+			}
+		}
+
 		actorCh, err := plumbing.ActorLs(ctx)
 		if err != nil {
 			out <- Ask{
@@ -43,7 +59,7 @@ func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
 		}
 
 		for actorResult := range actorCh {
-			err := listAsksFromActorResult(ctx, plumbing, actorResult, out)
+			err := listAsksFromActorResult(ctx, plumbing, actorResult, height, out)
 			if err != nil {
 				out <- Ask{
 					Error: err,
@@ -56,7 +72,9 @@ func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
 	return out
 }
 
-func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorResult state.GetAllActorsResult, out chan Ask) error {
+// listAsksFromActorResult sends the asks of the given actor to out, leaving
+// out asks that have expired at height. A nil height includes all asks.
+func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorResult state.GetAllActorsResult, height *types.BlockHeight, out chan Ask) error {
 	if actorResult.Error != nil {
 		return actorResult.Error
 	}
@@ -86,6 +104,10 @@ func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorRes
 			return err
 		}
 
+		if height != nil && height.GreaterEqual(ask.Expiry) {
+			continue
+		}
+
 		out <- ask
 	}
 
@@ -23,9 +23,21 @@ type claPlumbing struct {
 	actorChFail bool
 	messageFail bool
 
+	height       uint64
 	MinerAddress address.Address
 }
 
+func (cla *claPlumbing) ChainLs(ctx context.Context) <-chan interface{} {
+	out := make(chan interface{}, 1)
+	ts, err := types.NewTipSet(&types.Block{Height: types.Uint64(cla.height)})
+	if err != nil {
+		panic("could not create tipset")
+	}
+	out <- ts
+	close(out)
+	return out
+}
+
 func (cla *claPlumbing) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
 	out := make(chan state.GetAllActorsResult)
 
@@ -82,7 +94,7 @@ func TestClientListAsks(t *testing.T) {
 		ctx := context.Background()
 		plumbing := &claPlumbing{}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, false)
 		result := <-results
 
 		expectedResult := porcelain.Ask{
@@ -95,6 +107,25 @@ func TestClientListAsks(t *testing.T) {
 		assert.Equal(expectedResult, result)
 	})
 
+	t.Run("expired asks are left out by default", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctx := context.Background()
+		plumbing := &claPlumbing{
+			height: 1,
+		}
+
+		var results []porcelain.Ask
+		for ask := range porcelain.ClientListAsks(ctx, plumbing, false) {
+			results = append(results, ask)
+		}
+		assert.Empty(results)
+
+		result := <-porcelain.ClientListAsks(ctx, plumbing, true)
+		assert.NoError(result.Error)
+		assert.Equal(types.NewBlockHeight(1), result.Expiry)
+	})
+
 	t.Run("failed actor ls", func(t *testing.T) {
 		assert := assert.New(t)
 
@@ -103,7 +134,7 @@ func TestClientListAsks(t *testing.T) {
 			actorFail: true,
 		}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, false)
 		result := <-results
 
 		assert.Error(result.Error, "ACTOR FAILURE")
@@ -117,7 +148,7 @@ func TestClientListAsks(t *testing.T) {
 			actorChFail: true,
 		}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, false)
 		result := <-results
 
 		assert.Error(result.Error, "ACTOR CHANNEL FAILURE")
@@ -131,7 +162,7 @@ func TestClientListAsks(t *testing.T) {
 			messageFail: true,
 		}
 
-		results := porcelain.ClientListAsks(ctx, plumbing)
+		results := porcelain.ClientListAsks(ctx, plumbing, false)
 		result := <-results
 
 		assert.Error(result.Error, "MESSAGE FAILURE")
@@ -296,6 +296,33 @@ func MinerGetOwnerAddress(ctx context.Context, plumbing mgoaAPI, minerAddr addre
 	return address.NewFromBytes(res[0])
 }
 
+// MinerGetWorkerAddress queries for the worker address of the given miner.
+// The worker sends commitSector and submitPoSt messages for the miner.
+func MinerGetWorkerAddress(ctx context.Context, plumbing mgoaAPI, minerAddr address.Address) (address.Address, error) {
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getWorker")
+	if err != nil {
+		return address.Undef, err
+	}
+
+	return address.NewFromBytes(res[0])
+}
+
+// MinerListSectors queries for the sectors the given miner has committed,
+// ordered by sector id. Each sector carries the height at which it expires.
+func MinerListSectors(ctx context.Context, plumbing mgoaAPI, minerAddr address.Address) ([]*minerActor.SectorInfo, error) {
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "listSectors")
+	if err != nil {
+		return nil, err
+	}
+
+	var sectors []*minerActor.SectorInfo
+	if err := cbor.DecodeInto(res[0], &sectors); err != nil {
+		return nil, err
+	}
+
+	return sectors, nil
+}
+
 // MinerGetKey queries for the public key of the given miner
 func MinerGetKey(ctx context.Context, plumbing mgoaAPI, minerAddr address.Address) ([]byte, error) {
 	res, _, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getKey")
@@ -344,3 +371,139 @@ func MinerGetPeerID(ctx context.Context, plumbing mgpidAPI, minerAddr address.Ad
 	}
 	return pid, nil
 }
+
+// mgsAPI is the subset of the plumbing.API that MinerGetStatus uses.
+type mgsAPI interface {
+	ChainLs(ctx context.Context) <-chan interface{}
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+}
+
+// MinerStatus describes where a miner stands in its current proving period.
+type MinerStatus struct {
+	Power      *big.Int
+	Collateral *types.AttoFIL
+
+	// ProvingPeriodStart and ProvingPeriodEnd bound the current proving
+	// period. Both are nil if the miner has never committed a sector.
+	ProvingPeriodStart *types.BlockHeight
+	ProvingPeriodEnd   *types.BlockHeight
+
+	// GracePeriodEnd is the last block at which a late PoSt is accepted.
+	// After it the miner loses its power and collateral.
+	GracePeriodEnd *types.BlockHeight
+
+	// LastPoSt is the block height of the last PoSt, nil if there was none.
+	LastPoSt *types.BlockHeight
+
+	// Late is true if the proving period is over and the miner has not yet
+	// submitted its PoSt. A PoSt submitted now incurs a fee.
+	Late bool
+}
+
+// MinerGetStatus queries for the power, collateral and proving period of the
+// given miner.
+func MinerGetStatus(ctx context.Context, plumbing mgsAPI, minerAddr address.Address) (MinerStatus, error) {
+	var status MinerStatus
+
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPower")
+	if err != nil {
+		return MinerStatus{}, errors.Wrap(err, "could not get power")
+	}
+	status.Power = big.NewInt(0).SetBytes(res[0])
+
+	res, _, err = plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getCollateral")
+	if err != nil {
+		return MinerStatus{}, errors.Wrap(err, "could not get collateral")
+	}
+	status.Collateral = types.NewAttoFILFromBytes(res[0])
+
+	res, _, err = plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getLastPoSt")
+	if err != nil {
+		return MinerStatus{}, errors.Wrap(err, "could not get last PoSt")
+	}
+	if len(res[0]) > 0 {
+		status.LastPoSt = types.NewBlockHeightFromBytes(res[0])
+	}
+
+	res, _, err = plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getProvingPeriodStart")
+	if err != nil {
+		return MinerStatus{}, errors.Wrap(err, "could not get proving period")
+	}
+	if len(res[0]) == 0 {
+		return status, nil
+	}
+	status.ProvingPeriodStart = types.NewBlockHeightFromBytes(res[0])
+	status.ProvingPeriodEnd = status.ProvingPeriodStart.Add(minerActor.ProvingPeriodBlocks)
+	status.GracePeriodEnd = status.ProvingPeriodEnd.Add(minerActor.GracePeriodBlocks)
+
+	currentHeight, err := ChainBlockHeight(ctx, plumbing)
+	if err != nil {
+		return MinerStatus{}, err
+	}
+	status.Late = status.Power.Sign() > 0 && currentHeight.GreaterThan(status.ProvingPeriodEnd)
+
+	return status, nil
+}
+
+// macAPI is the subset of the plumbing.API that MinerAddCollateral and
+// MinerWithdrawCollateral use.
+type macAPI interface {
+	MessageSendWithDefaultAddress(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+}
+
+// MinerAddCollateral sends amount to the given miner as additional collateral
+// and raises its pledge by the given number of sectors, which may be zero.
+func MinerAddCollateral(ctx context.Context, plumbing macAPI, from address.Address, minerAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, amount *types.AttoFIL, pledge *big.Int) (cid.Cid, error) {
+	return plumbing.MessageSendWithDefaultAddress(ctx, from, minerAddr, amount, gasPrice, gasLimit, "addCollateral", pledge)
+}
+
+// MinerWithdrawCollateral requests that amount of the given miner's
+// collateral is sent back to its owner.
+func MinerWithdrawCollateral(ctx context.Context, plumbing macAPI, from address.Address, minerAddr address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, amount *types.AttoFIL) (cid.Cid, error) {
+	return plumbing.MessageSendWithDefaultAddress(ctx, from, minerAddr, nil, gasPrice, gasLimit, "withdrawCollateral", amount)
+}
+
+// mgcAPI is the subset of the plumbing.API that MinerGetCollateral uses.
+type mgcAPI interface {
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+}
+
+// MinerCollateral describes the collateral held by a miner.
+type MinerCollateral struct {
+	// Collateral is the total collateral the miner holds.
+	Collateral *types.AttoFIL
+	// Locked is the part of the collateral backing committed sectors.
+	Locked *types.AttoFIL
+	// Withdrawable is the part of the collateral that may be withdrawn.
+	Withdrawable *types.AttoFIL
+	// PledgeSectors is the number of sectors the miner has pledged.
+	PledgeSectors *big.Int
+}
+
+// MinerGetCollateral queries for the collateral and pledge of the given miner
+func MinerGetCollateral(ctx context.Context, plumbing mgcAPI, minerAddr address.Address) (MinerCollateral, error) {
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getCollateral")
+	if err != nil {
+		return MinerCollateral{}, errors.Wrap(err, "could not get collateral")
+	}
+	collateral := types.NewAttoFILFromBytes(res[0])
+
+	res, _, err = plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPower")
+	if err != nil {
+		return MinerCollateral{}, errors.Wrap(err, "could not get power")
+	}
+	power := big.NewInt(0).SetBytes(res[0])
+
+	res, _, err = plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPledge")
+	if err != nil {
+		return MinerCollateral{}, errors.Wrap(err, "could not get pledge")
+	}
+
+	withdrawable := minerActor.WithdrawableCollateral(collateral, power)
+	return MinerCollateral{
+		Collateral:    collateral,
+		Locked:        collateral.Sub(withdrawable),
+		Withdrawable:  withdrawable,
+		PledgeSectors: big.NewInt(0).SetBytes(res[0]),
+	}, nil
+}
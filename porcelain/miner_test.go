@@ -469,6 +469,57 @@ func TestMinerGetOwnerAddress(t *testing.T) {
 	assert.Equal(address.TestAddress, addr)
 }
 
+type minerGetWorkerPlumbing struct{}
+
+func (mgwp *minerGetWorkerPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	if method != "getWorker" {
+		return nil, nil, errors.New("unexpected method " + method)
+	}
+	return [][]byte{address.TestAddress.Bytes()}, nil, nil
+}
+
+func TestMinerGetWorkerAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	addr, err := MinerGetWorkerAddress(context.Background(), &minerGetWorkerPlumbing{}, address.TestAddress2)
+	assert.NoError(err)
+	assert.Equal(address.TestAddress, addr)
+}
+
+type minerListSectorsPlumbing struct {
+	require *require.Assertions
+	sectors []*miner.SectorInfo
+}
+
+func (mlsp *minerListSectorsPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	if method != "listSectors" {
+		return nil, nil, errors.New("unexpected method " + method)
+	}
+	bytes, err := cbor.DumpObject(mlsp.sectors)
+	mlsp.require.NoError(err)
+	return [][]byte{bytes}, nil, nil
+}
+
+func TestMinerListSectors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	plumbing := &minerListSectorsPlumbing{
+		require: require,
+		sectors: []*miner.SectorInfo{
+			{SectorID: 1, Expiration: types.NewBlockHeight(13)},
+			{SectorID: 2},
+		},
+	}
+
+	sectors, err := MinerListSectors(context.Background(), plumbing, address.TestAddress2)
+	require.NoError(err)
+	require.Len(sectors, 2)
+	assert.Equal(uint64(1), sectors[0].SectorID)
+	assert.Equal(types.NewBlockHeight(13), sectors[0].Expiration)
+	assert.Nil(sectors[1].Expiration)
+}
+
 type minerGetPeerIDPlumbing struct{}
 
 func (mgop *minerGetPeerIDPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
@@ -515,6 +566,142 @@ func TestMinerGetAsk(t *testing.T) {
 	assert.Equal(big.NewInt(4), ask.ID)
 }
 
+type minerGetStatusPlumbing struct {
+	height             uint64
+	provingPeriodStart *types.BlockHeight
+}
+
+func (mgsp *minerGetStatusPlumbing) ChainLs(ctx context.Context) <-chan interface{} {
+	out := make(chan interface{}, 1)
+	ts, err := types.NewTipSet(&types.Block{Height: types.Uint64(mgsp.height)})
+	if err != nil {
+		panic("could not create tipset")
+	}
+	out <- ts
+	close(out)
+	return out
+}
+
+func (mgsp *minerGetStatusPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	switch method {
+	case "getPower":
+		return [][]byte{big.NewInt(3).Bytes()}, nil, nil
+	case "getCollateral":
+		return [][]byte{types.NewAttoFILFromFIL(50).Bytes()}, nil, nil
+	case "getLastPoSt":
+		return [][]byte{{}}, nil, nil
+	case "getProvingPeriodStart":
+		if mgsp.provingPeriodStart == nil {
+			return [][]byte{{}}, nil, nil
+		}
+		return [][]byte{mgsp.provingPeriodStart.Bytes()}, nil, nil
+	}
+	return nil, nil, errors.New("unexpected method " + method)
+}
+
+func TestMinerGetStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports the proving period and grace period", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		plumbing := &minerGetStatusPlumbing{height: 10, provingPeriodStart: types.NewBlockHeight(5)}
+		status, err := MinerGetStatus(context.Background(), plumbing, address.TestAddress2)
+		require.NoError(err)
+
+		assert.Equal(big.NewInt(3), status.Power)
+		assert.Equal(types.NewAttoFILFromFIL(50), status.Collateral)
+		assert.Nil(status.LastPoSt)
+		assert.Equal(types.NewBlockHeight(5), status.ProvingPeriodStart)
+		assert.Equal(types.NewBlockHeight(5).Add(miner.ProvingPeriodBlocks), status.ProvingPeriodEnd)
+		assert.Equal(status.ProvingPeriodEnd.Add(miner.GracePeriodBlocks), status.GracePeriodEnd)
+		assert.False(status.Late)
+	})
+
+	t.Run("reports a miner that has not submitted a PoSt in time as late", func(t *testing.T) {
+		plumbing := &minerGetStatusPlumbing{height: 6 + miner.ProvingPeriodBlocks.AsBigInt().Uint64(), provingPeriodStart: types.NewBlockHeight(5)}
+		status, err := MinerGetStatus(context.Background(), plumbing, address.TestAddress2)
+		require.NoError(t, err)
+		assert.True(t, status.Late)
+	})
+
+	t.Run("has no proving period before any sector is committed", func(t *testing.T) {
+		status, err := MinerGetStatus(context.Background(), &minerGetStatusPlumbing{}, address.TestAddress2)
+		require.NoError(t, err)
+		assert.Nil(t, status.ProvingPeriodStart)
+		assert.False(t, status.Late)
+	})
+}
+
+type minerCollateralPlumbing struct {
+	value  *types.AttoFIL
+	method string
+	params []interface{}
+}
+
+func (mcp *minerCollateralPlumbing) MessageSendWithDefaultAddress(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	mcp.value = value
+	mcp.method = method
+	mcp.params = params
+	return cid.Cid{}, nil
+}
+
+func (mcp *minerCollateralPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	switch method {
+	case "getCollateral":
+		return [][]byte{types.NewAttoFILFromFIL(10).Bytes()}, nil, nil
+	case "getPower":
+		return [][]byte{big.NewInt(2000).Bytes()}, nil, nil
+	case "getPledge":
+		return [][]byte{big.NewInt(5000).Bytes()}, nil, nil
+	}
+	return nil, nil, errors.New("unexpected method " + method)
+}
+
+func TestMinerCollateral(t *testing.T) {
+	t.Parallel()
+
+	t.Run("add sends the collateral as the message value", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		plumbing := &minerCollateralPlumbing{}
+		_, err := MinerAddCollateral(context.Background(), plumbing, address.Undef, address.TestAddress2, types.NewGasPrice(0), types.NewGasUnits(0), types.NewAttoFILFromFIL(3), big.NewInt(100))
+		require.NoError(err)
+
+		assert.Equal("addCollateral", plumbing.method)
+		assert.Equal(types.NewAttoFILFromFIL(3), plumbing.value)
+		assert.Equal([]interface{}{big.NewInt(100)}, plumbing.params)
+	})
+
+	t.Run("withdraw passes the amount as a param", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		plumbing := &minerCollateralPlumbing{}
+		_, err := MinerWithdrawCollateral(context.Background(), plumbing, address.Undef, address.TestAddress2, types.NewGasPrice(0), types.NewGasUnits(0), types.NewAttoFILFromFIL(3))
+		require.NoError(err)
+
+		assert.Equal("withdrawCollateral", plumbing.method)
+		assert.Nil(plumbing.value)
+		assert.Equal([]interface{}{types.NewAttoFILFromFIL(3)}, plumbing.params)
+	})
+
+	t.Run("show splits locked and withdrawable collateral", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		collateral, err := MinerGetCollateral(context.Background(), &minerCollateralPlumbing{}, address.TestAddress2)
+		require.NoError(err)
+
+		assert.True(types.NewAttoFILFromFIL(10).Equal(collateral.Collateral))
+		assert.True(types.NewAttoFILFromFIL(2).Equal(collateral.Locked))
+		assert.True(types.NewAttoFILFromFIL(8).Equal(collateral.Withdrawable))
+		assert.Equal(big.NewInt(5000), collateral.PledgeSectors)
+	})
+}
+
 func requirePeerID() peer.ID {
 	id, err := peer.IDB58Decode("QmWbMozPyW6Ecagtxq7SXBXXLY5BNdP1GwHB2WoZCKMvcb")
 	if err != nil {
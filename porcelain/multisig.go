@@ -0,0 +1,154 @@
+package porcelain
+
+import (
+	"context"
+	"math/big"
+
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// msproposeAPI is the subset of the plumbing.API that MultisigPropose uses.
+type msproposeAPI interface {
+	MessageSendWithDefaultAddress(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+}
+
+// MultisigPropose proposes that the multisig wallet at msigAddr send value to
+// the given address and invoke method with params. The params are abi encoded
+// here so that the wallet can replay them when the proposal is approved.
+// The returned cid is that of the propose message; the ID of the proposed
+// transaction is in that message's receipt.
+func MultisigPropose(
+	ctx context.Context,
+	plumbing msproposeAPI,
+	from address.Address,
+	msigAddr address.Address,
+	gasPrice types.AttoFIL,
+	gasLimit types.GasUnits,
+	to address.Address,
+	value *types.AttoFIL,
+	method string,
+	params ...interface{},
+) (cid.Cid, error) {
+	encodedParams, err := encodeMultisigParams(params...)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	if value == nil {
+		value = types.NewZeroAttoFIL()
+	}
+
+	return plumbing.MessageSendWithDefaultAddress(
+		ctx,
+		from,
+		msigAddr,
+		nil,
+		gasPrice,
+		gasLimit,
+		"propose",
+		to,
+		value,
+		method,
+		encodedParams,
+	)
+}
+
+// mspreviewAPI is the subset of the plumbing.API that MultisigPreviewPropose uses.
+type mspreviewAPI interface {
+	MessagePreview(ctx context.Context, from, to address.Address, method string, params ...interface{}) (types.GasUnits, error)
+}
+
+// MultisigPreviewPropose calculates the amount of Gas needed for a call to
+// MultisigPropose. This method accepts all the same arguments as
+// MultisigPropose apart from the gas options.
+func MultisigPreviewPropose(
+	ctx context.Context,
+	plumbing mspreviewAPI,
+	from address.Address,
+	msigAddr address.Address,
+	to address.Address,
+	value *types.AttoFIL,
+	method string,
+	params ...interface{},
+) (types.GasUnits, error) {
+	encodedParams, err := encodeMultisigParams(params...)
+	if err != nil {
+		return types.NewGasUnits(0), err
+	}
+
+	if value == nil {
+		value = types.NewZeroAttoFIL()
+	}
+
+	return plumbing.MessagePreview(
+		ctx,
+		from,
+		msigAddr,
+		"propose",
+		to,
+		value,
+		method,
+		encodedParams,
+	)
+}
+
+// msqAPI is the subset of the plumbing.API that the multisig queries use.
+type msqAPI interface {
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
+}
+
+// MultisigGetSigners queries for the signers of the given multisig wallet
+func MultisigGetSigners(ctx context.Context, plumbing msqAPI, msigAddr address.Address) ([]address.Address, error) {
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, msigAddr, "getSigners")
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []address.Address
+	if err := cbor.DecodeInto(res[0], &signers); err != nil {
+		return nil, err
+	}
+
+	return signers, nil
+}
+
+// MultisigGetThreshold queries for the number of approvals the given multisig
+// wallet requires to execute a transaction
+func MultisigGetThreshold(ctx context.Context, plumbing msqAPI, msigAddr address.Address) (uint64, error) {
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, msigAddr, "getThreshold")
+	if err != nil {
+		return 0, err
+	}
+
+	return big.NewInt(0).SetBytes(res[0]).Uint64(), nil
+}
+
+// MultisigListPending queries for the transactions of the given multisig
+// wallet that are waiting for approval, keyed by transaction ID
+func MultisigListPending(ctx context.Context, plumbing msqAPI, msigAddr address.Address) (map[string]*multisig.Transaction, error) {
+	res, _, err := plumbing.MessageQuery(ctx, address.Undef, msigAddr, "getPending")
+	if err != nil {
+		return nil, err
+	}
+
+	var pending map[string]*multisig.Transaction
+	if err := cbor.DecodeInto(res[0], &pending); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+func encodeMultisigParams(params ...interface{}) ([]byte, error) {
+	if len(params) == 0 {
+		return []byte{}, nil
+	}
+	return abi.ToEncodedValues(params...)
+}
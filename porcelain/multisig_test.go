@@ -0,0 +1,146 @@
+package porcelain_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/multisig"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type multisigProposePlumbing struct {
+	to     address.Address
+	method string
+	params []interface{}
+}
+
+func (mpp *multisigProposePlumbing) MessageSendWithDefaultAddress(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	mpp.to = to
+	mpp.method = method
+	mpp.params = params
+	return cid.Cid{}, nil
+}
+
+func TestMultisigPropose(t *testing.T) {
+	t.Parallel()
+
+	t.Run("encodes the proposed call for the wallet", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		addrGetter := address.NewForTestGetter()
+		msigAddr, target := addrGetter(), addrGetter()
+		plumbing := &multisigProposePlumbing{}
+
+		_, err := porcelain.MultisigPropose(
+			context.Background(),
+			plumbing,
+			address.Undef,
+			msigAddr,
+			types.NewGasPrice(0),
+			types.NewGasUnits(0),
+			target,
+			types.NewAttoFILFromFIL(2),
+			"updatePeerID",
+			big.NewInt(7),
+		)
+		require.NoError(err)
+
+		assert.Equal(msigAddr, plumbing.to)
+		assert.Equal("propose", plumbing.method)
+		require.Len(plumbing.params, 4)
+		assert.Equal(target, plumbing.params[0])
+		assert.Equal(types.NewAttoFILFromFIL(2), plumbing.params[1])
+		assert.Equal("updatePeerID", plumbing.params[2])
+
+		expected, err := abi.ToEncodedValues(big.NewInt(7))
+		require.NoError(err)
+		assert.Equal(expected, plumbing.params[3])
+	})
+
+	t.Run("defaults to zero value and no params", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		addrGetter := address.NewForTestGetter()
+		plumbing := &multisigProposePlumbing{}
+
+		_, err := porcelain.MultisigPropose(context.Background(), plumbing, address.Undef, addrGetter(), types.NewGasPrice(0), types.NewGasUnits(0), addrGetter(), nil, "")
+		require.NoError(err)
+
+		require.Len(plumbing.params, 4)
+		assert.Equal(types.NewZeroAttoFIL(), plumbing.params[1])
+		assert.Equal([]byte{}, plumbing.params[3])
+	})
+}
+
+type multisigQueryPlumbing struct {
+	require *require.Assertions
+	signers []address.Address
+	pending map[string]*multisig.Transaction
+}
+
+func (mqp *multisigQueryPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	var out interface{}
+	switch method {
+	case "getSigners":
+		out = mqp.signers
+	case "getPending":
+		out = mqp.pending
+	case "getThreshold":
+		return [][]byte{big.NewInt(2).Bytes()}, nil, nil
+	}
+
+	bytes, err := cbor.DumpObject(out)
+	mqp.require.NoError(err)
+	return [][]byte{bytes}, nil, nil
+}
+
+func TestMultisigQueries(t *testing.T) {
+	t.Parallel()
+
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	addrGetter := address.NewForTestGetter()
+	msigAddr, signer1, signer2 := addrGetter(), addrGetter(), addrGetter()
+
+	plumbing := &multisigQueryPlumbing{
+		require: require,
+		signers: []address.Address{signer1, signer2},
+		pending: map[string]*multisig.Transaction{
+			"0": {
+				ID:       0,
+				Proposer: signer1,
+				To:       addrGetter(),
+				Value:    types.NewAttoFILFromFIL(5),
+				Approved: []address.Address{signer1},
+			},
+		},
+	}
+
+	signers, err := porcelain.MultisigGetSigners(ctx, plumbing, msigAddr)
+	require.NoError(err)
+	assert.Equal(plumbing.signers, signers)
+
+	threshold, err := porcelain.MultisigGetThreshold(ctx, plumbing, msigAddr)
+	require.NoError(err)
+	assert.Equal(uint64(2), threshold)
+
+	pending, err := porcelain.MultisigListPending(ctx, plumbing, msigAddr)
+	require.NoError(err)
+	require.Len(pending, 1)
+	assert.Equal(signer1, pending["0"].Proposer)
+	assert.Equal(types.NewAttoFILFromFIL(5), pending["0"].Value)
+}
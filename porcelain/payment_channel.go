@@ -55,10 +55,15 @@ func PaymentChannelLs(
 type pcvPlumbing interface {
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error)
 	SignBytes(data []byte, addr address.Address) (types.Signature, error)
+	VoucherNonceReserve(payer address.Address, channel *types.ChannelID, lane, nonce uint64) (uint64, error)
 	WalletDefaultAddress() (address.Address, error)
 }
 
-// PaymentChannelVoucher returns a signed payment channel voucher
+// PaymentChannelVoucher returns a signed payment channel voucher for the given
+// lane of the channel with the given nonce. If nonce is zero the voucher gets
+// one more than the greatest nonce this node issued on the lane, otherwise the
+// nonce must be greater than that, so that the voucher does not invalidate an
+// earlier one.
 func PaymentChannelVoucher(
 	ctx context.Context,
 	plumbing pcvPlumbing,
@@ -66,6 +71,8 @@ func PaymentChannelVoucher(
 	channel *types.ChannelID,
 	amount *types.AttoFIL,
 	validAt *types.BlockHeight,
+	lane uint64,
+	nonce uint64,
 ) (voucher *paymentbroker.PaymentVoucher, err error) {
 	if fromAddr.Empty() {
 		fromAddr, err = plumbing.WalletDefaultAddress()
@@ -74,12 +81,17 @@ func PaymentChannelVoucher(
 		}
 	}
 
+	nonce, err = plumbing.VoucherNonceReserve(fromAddr, channel, lane, nonce)
+	if err != nil {
+		return nil, err
+	}
+
 	values, _, err := plumbing.MessageQuery(
 		ctx,
 		fromAddr,
 		address.PaymentBrokerAddress,
 		"voucher",
-		channel, amount, validAt,
+		channel, amount, validAt, lane, nonce,
 	)
 	if err != nil {
 		return nil, err
@@ -89,7 +101,7 @@ func PaymentChannelVoucher(
 		return nil, err
 	}
 
-	sig, err := paymentbroker.SignVoucher(channel, amount, validAt, fromAddr, plumbing)
+	sig, err := paymentbroker.SignVoucher(channel, amount, validAt, uint64(voucher.Lane), uint64(voucher.Nonce), voucher.Condition, fromAddr, plumbing)
 	if err != nil {
 		return nil, err
 	}
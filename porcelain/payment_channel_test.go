@@ -53,11 +53,13 @@ func TestPaymentChannelLs(t *testing.T) {
 }
 
 type testPaymentChannelVoucherPlumbing struct {
-	require *require.Assertions
-	voucher *paymentbroker.PaymentVoucher
+	require   *require.Assertions
+	voucher   *paymentbroker.PaymentVoucher
+	lastNonce uint64
 }
 
 func (p *testPaymentChannelVoucherPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, *exec.FunctionSignature, error) {
+	p.voucher.Nonce = types.Uint64(params[4].(uint64))
 	result, err := actor.MarshalStorage(p.voucher)
 	p.require.NoError(err)
 	return [][]byte{result}, nil, nil
@@ -67,6 +69,14 @@ func (p *testPaymentChannelVoucherPlumbing) SignBytes(data []byte, addr address.
 	return []byte("test"), nil
 }
 
+func (p *testPaymentChannelVoucherPlumbing) VoucherNonceReserve(payer address.Address, channel *types.ChannelID, lane, nonce uint64) (uint64, error) {
+	if nonce == 0 {
+		nonce = p.lastNonce + 1
+	}
+	p.lastNonce = nonce
+	return nonce, nil
+}
+
 func (p *testPaymentChannelVoucherPlumbing) WalletDefaultAddress() (address.Address, error) {
 	return address.Undef, nil
 }
@@ -100,6 +110,8 @@ func TestPaymentChannelVoucher(t *testing.T) {
 			types.NewChannelID(5),
 			types.NewAttoFILFromFIL(10),
 			types.NewBlockHeight(0),
+			uint64(0),
+			uint64(1),
 		)
 		require.NoError(err)
 		assert.Equal(expectedVoucher.Channel, voucher.Channel)
@@ -108,5 +120,30 @@ func TestPaymentChannelVoucher(t *testing.T) {
 		assert.Equal(expectedVoucher.Amount, voucher.Amount)
 		assert.Equal(expectedVoucher.ValidAt, voucher.ValidAt)
 		assert.NotEqual(expectedVoucher.Signature, voucher.Signature)
+		assert.Equal(types.Uint64(1), voucher.Nonce)
+	})
+
+	t.Run("uses the next nonce of the lane when none is given", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		plumbing := &testPaymentChannelVoucherPlumbing{
+			require:   require,
+			voucher:   &paymentbroker.PaymentVoucher{Channel: *types.NewChannelID(5)},
+			lastNonce: 4,
+		}
+
+		voucher, err := porcelain.PaymentChannelVoucher(
+			context.Background(),
+			plumbing,
+			address.Undef,
+			types.NewChannelID(5),
+			types.NewAttoFILFromFIL(10),
+			types.NewBlockHeight(0),
+			uint64(0),
+			uint64(0),
+		)
+		require.NoError(err)
+		assert.Equal(types.Uint64(5), voucher.Nonce)
 	})
 }
@@ -22,6 +22,7 @@ type cpPlumbing interface {
 	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
 	ChainLs(ctx context.Context) <-chan interface{}
 	SignBytes(data []byte, addr address.Address) (types.Signature, error)
+	VoucherNonceReserve(payer address.Address, channel *types.ChannelID, lane, nonce uint64) (uint64, error)
 }
 
 // CreatePaymentsParams structures all the parameters for the CreatePayments command. All values are required.
@@ -53,6 +54,22 @@ type CreatePaymentsParams struct {
 
 	// GasLimit is the maximum amount of gas to be paid creating the payment channel.
 	GasLimit types.GasUnits
+
+	// PaymentCondition is an optional condition attached to every voucher. The
+	// target can only redeem the vouchers once it holds, see
+	// PieceCommittedCondition.
+	PaymentCondition *paymentbroker.Condition
+}
+
+// PieceCommittedCondition returns a voucher condition that holds once the given
+// miner has committed a sector containing the deal with the given id, which
+// must be a deal for the given piece.
+func PieceCommittedCondition(dealID uint64, minerAddr address.Address, pieceRef cid.Cid) *paymentbroker.Condition {
+	return &paymentbroker.Condition{
+		To:     address.StorageMarketAddress,
+		Method: "isPieceCommitted",
+		Params: []interface{}{dealID, minerAddr, pieceRef.Bytes()},
+	}
 }
 
 // CreatePaymentsReturn collects relevant stats from the create payments process
@@ -163,14 +180,24 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 	return response, nil
 }
 
+// createPayment adds a voucher for amount to the response. All vouchers are
+// created on the channel's default lane, each with the next nonce, which is
+// recorded so that later vouchers on the lane do not reuse it.
 func createPayment(ctx context.Context, plumbing cpPlumbing, response *CreatePaymentsReturn, amount *types.AttoFIL, validAt *types.BlockHeight) error {
+	nonce, err := plumbing.VoucherNonceReserve(response.From, response.Channel, 0, 0)
+	if err != nil {
+		return err
+	}
+
 	ret, _, err := plumbing.MessageQuery(ctx,
 		response.From,
 		address.PaymentBrokerAddress,
 		"voucher",
 		response.Channel,
 		amount,
-		validAt)
+		validAt,
+		uint64(0),
+		nonce)
 	if err != nil {
 		return err
 	}
@@ -179,8 +206,9 @@ func createPayment(ctx context.Context, plumbing cpPlumbing, response *CreatePay
 	if err := cbor.DecodeInto(ret[0], &voucher); err != nil {
 		return err
 	}
+	voucher.Condition = response.PaymentCondition
 
-	sig, err := paymentbroker.SignVoucher(&voucher.Channel, amount, validAt, voucher.Payer, plumbing)
+	sig, err := paymentbroker.SignVoucher(&voucher.Channel, amount, validAt, uint64(voucher.Lane), uint64(voucher.Nonce), voucher.Condition, voucher.Payer, plumbing)
 	if err != nil {
 		return err
 	}
@@ -24,8 +24,9 @@ const (
 )
 
 type paymentsTestPlumbing struct {
-	tipSets []*types.TipSet
-	msgCid  cid.Cid
+	tipSets   []*types.TipSet
+	msgCid    cid.Cid
+	lastNonce uint64
 
 	messageSend  func(ctx context.Context, from, to address.Address, value *types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
 	messageWait  func(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
@@ -67,6 +68,8 @@ func newTestCreatePaymentsPlumbing() *paymentsTestPlumbing {
 				Target:  target,
 				Amount:  *params[1].(*types.AttoFIL),
 				ValidAt: *params[2].(*types.BlockHeight),
+				Lane:    types.Uint64(params[3].(uint64)),
+				Nonce:   types.Uint64(params[4].(uint64)),
 			}
 			voucherBytes, err := actor.MarshalStorage(voucher)
 			if err != nil {
@@ -107,6 +110,14 @@ func (ptp *paymentsTestPlumbing) SignBytes(data []byte, addr address.Address) (t
 	return []byte("signature"), nil
 }
 
+func (ptp *paymentsTestPlumbing) VoucherNonceReserve(payer address.Address, channel *types.ChannelID, lane, nonce uint64) (uint64, error) {
+	if nonce == 0 {
+		nonce = ptp.lastNonce + 1
+	}
+	ptp.lastNonce = nonce
+	return nonce, nil
+}
+
 func validPaymentsConfig() CreatePaymentsParams {
 	addresses := address.NewForTestGetter()
 	from := addresses()
@@ -171,6 +182,22 @@ func TestCreatePayments(t *testing.T) {
 		assert.Equal(config.Value, paymentResponse.Vouchers[9].Amount)
 	})
 
+	t.Run("Attaches the payment condition to every voucher", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		config := validPaymentsConfig()
+		config.PaymentCondition = PieceCommittedCondition(0, config.To, types.SomeCid())
+		paymentResponse, err := CreatePayments(context.Background(), newTestCreatePaymentsPlumbing(), config)
+		require.NoError(err)
+
+		require.Len(paymentResponse.Vouchers, 10)
+		for i, voucher := range paymentResponse.Vouchers {
+			assert.Equal(config.PaymentCondition, voucher.Condition)
+			assert.Equal(types.Uint64(i+1), voucher.Nonce)
+		}
+	})
+
 	t.Run("Validates from", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
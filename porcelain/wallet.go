@@ -19,7 +19,22 @@ type wbPlumbing interface {
 
 // WalletBalance gets the current balance associated with an address
 func WalletBalance(ctx context.Context, plumbing wbPlumbing, addr address.Address) (*types.AttoFIL, error) {
-	act, err := plumbing.ActorGet(ctx, addr)
+	return actorBalance(plumbing.ActorGet(ctx, addr))
+}
+
+type wbaPlumbing interface {
+	ActorGetAt(ctx context.Context, addr address.Address, tsKey types.SortedCidSet) (*actor.Actor, error)
+}
+
+// WalletBalanceAt gets the balance associated with an address in the state
+// after the tipset identified by tsKey
+func WalletBalanceAt(ctx context.Context, plumbing wbaPlumbing, addr address.Address, tsKey types.SortedCidSet) (*types.AttoFIL, error) {
+	return actorBalance(plumbing.ActorGetAt(ctx, addr, tsKey))
+}
+
+// actorBalance returns the balance of act, which was looked up with the
+// given error.
+func actorBalance(act *actor.Actor, err error) (*types.AttoFIL, error) {
 	if err != nil {
 		if state.IsActorNotFoundError(err) {
 			// if the account doesn't exit, the balance should be zero
@@ -2,6 +2,7 @@ package porcelain_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -18,7 +19,8 @@ import (
 )
 
 type wbTestPlumbing struct {
-	balance *types.AttoFIL
+	balance    *types.AttoFIL
+	balancesAt map[string]*types.AttoFIL
 }
 
 type wdaTestPlumbing struct {
@@ -41,6 +43,14 @@ func (wbtp *wbTestPlumbing) ActorGet(ctx context.Context, addr address.Address)
 	return testActor, nil
 }
 
+func (wbtp *wbTestPlumbing) ActorGetAt(ctx context.Context, addr address.Address, tsKey types.SortedCidSet) (*actor.Actor, error) {
+	balance, ok := wbtp.balancesAt[tsKey.String()]
+	if !ok {
+		return nil, errors.New("no such tipset")
+	}
+	return actor.NewActor(cid.Undef, balance), nil
+}
+
 func (wdatp *wdaTestPlumbing) ConfigGet(dottedPath string) (interface{}, error) {
 	return wdatp.config.Get(dottedPath)
 }
@@ -74,6 +84,25 @@ func TestWalletBalance(t *testing.T) {
 	})
 }
 
+func TestWalletBalanceAt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	tsKey := types.NewSortedCidSet(types.SomeCid())
+	expectedBalance := types.NewAttoFILFromFIL(7)
+	plumbing := &wbTestPlumbing{
+		balancesAt: map[string]*types.AttoFIL{tsKey.String(): expectedBalance},
+	}
+
+	balance, err := porcelain.WalletBalanceAt(ctx, plumbing, address.Undef, tsKey)
+	require.NoError(err)
+	assert.Equal(expectedBalance, balance)
+
+	_, err = porcelain.WalletBalanceAt(ctx, plumbing, address.Undef, types.NewSortedCidSet())
+	assert.Error(err)
+}
+
 func TestWalletDefaultAddress(t *testing.T) {
 	t.Parallel()
 
@@ -15,6 +15,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/porcelain"
@@ -171,6 +172,19 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 	proposal.Payment.ChannelMsgCid = &cpResp.ChannelMsgCid
 	proposal.Payment.Vouchers = cpResp.Vouchers
 
+	// the miner publishes the deal on chain, which requires our signature
+	proposal.ChainProposal, err = storagemarket.NewSignedDealProposal(storagemarket.DealProposal{
+		Client:     fromAddress,
+		Miner:      miner,
+		PieceRef:   data,
+		Size:       proposal.Size,
+		Duration:   duration,
+		TotalPrice: totalPrice,
+	}, smc.api)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign chain deal")
+	}
+
 	signedProposal, err := proposal.NewSignedProposal(fromAddress, smc.api)
 	if err != nil {
 		return nil, err
@@ -23,6 +23,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/exec"
@@ -41,7 +42,9 @@ const queryDealProtocol = protocol.ID("/fil/storage/qry/1.0.0")
 
 // TODO: replace this with a queries to pick reasonable gas price and limits.
 const submitPostGasPrice = 0
-const submitPostGasLimit = 300
+const submitPostGasLimit = 1000
+const publishDealsGasPrice = 0
+const publishDealsGasLimit = 1000
 
 const waitForPaymentChannelDuration = 2 * time.Minute
 
@@ -49,8 +52,13 @@ const dealsAwatingSealDatastorePrefix = "dealsAwaitingSeal"
 
 // Miner represents a storage miner.
 type Miner struct {
-	minerAddr      address.Address
-	minerOwnerAddr address.Address
+	minerAddr address.Address
+
+	// minerOwnerAddr and minerWorkerAddr are refreshed from chain state on
+	// every new head so that ownership and worker changes are picked up.
+	minerAddrsLk    sync.Mutex
+	minerOwnerAddr  address.Address
+	minerWorkerAddr address.Address
 
 	dealsAwaitingSealDs repo.Datastore
 
@@ -106,10 +114,11 @@ func init() {
 }
 
 // NewMiner is
-func NewMiner(minerAddr, minerOwnerAddr address.Address, nd node, dealsDs repo.Datastore, porcelainAPI minerPorcelain) (*Miner, error) {
+func NewMiner(minerAddr, minerOwnerAddr, minerWorkerAddr address.Address, nd node, dealsDs repo.Datastore, porcelainAPI minerPorcelain) (*Miner, error) {
 	sm := &Miner{
 		minerAddr:           minerAddr,
 		minerOwnerAddr:      minerOwnerAddr,
+		minerWorkerAddr:     minerWorkerAddr,
 		porcelainAPI:        porcelainAPI,
 		dealsAwaitingSealDs: dealsDs,
 		node:                nd,
@@ -163,6 +172,10 @@ func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.Sig
 		return sm.proposalRejector(sm, p, fmt.Sprint("invalid deal signature"))
 	}
 
+	if err := validateChainProposal(p); err != nil {
+		return sm.proposalRejector(sm, p, err.Error())
+	}
+
 	if err := sm.validateDealPayment(ctx, p); err != nil {
 		return sm.proposalRejector(sm, p, err.Error())
 	}
@@ -171,6 +184,29 @@ func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.Sig
 	return sm.proposalAcceptor(sm, p)
 }
 
+// validateChainProposal checks that the deal the miner is asked to publish
+// on chain is the proposed deal and is signed by the payer.
+func validateChainProposal(p *storagedeal.Proposal) error {
+	if p.ChainProposal == nil {
+		return errors.New("proposal contains no deal to publish on chain")
+	}
+
+	cp := p.ChainProposal.Proposal
+	if cp.Client != p.Payment.Payer {
+		return fmt.Errorf("chain deal client (%s) is not the payer (%s)", cp.Client, p.Payment.Payer)
+	}
+	if cp.Miner != p.MinerAddress || !cp.PieceRef.Equals(p.PieceRef) || cp.Duration != p.Duration ||
+		cp.Size == nil || p.Size == nil || !cp.Size.Equal(p.Size) ||
+		cp.TotalPrice == nil || !cp.TotalPrice.Equal(p.TotalPrice) {
+		return errors.New("chain deal does not match the proposal")
+	}
+	if !p.ChainProposal.VerifySignature() {
+		return errors.New("invalid chain deal signature")
+	}
+
+	return nil
+}
+
 func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.Proposal) error {
 	// compute expected total price for deal (storage price * duration * bytes)
 	price, err := sm.getStoragePrice()
@@ -196,8 +232,9 @@ func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.Proposa
 	}
 
 	// confirm we are target of channel
-	if channel.Target != sm.minerOwnerAddr {
-		return fmt.Errorf("miner account (%s) is not target of payment channel (%s)", sm.minerOwnerAddr.String(), channel.Target.String())
+	ownerAddr := sm.ownerAddr()
+	if channel.Target != ownerAddr {
+		return fmt.Errorf("miner account (%s) is not target of payment channel (%s)", ownerAddr.String(), channel.Target.String())
 	}
 
 	// confirm channel contains enough funds
@@ -226,7 +263,7 @@ func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.Proposa
 	lastValidAt := expectedFirstPayment
 	for _, v := range p.Payment.Vouchers {
 		// confirm signature is valid against expected actor and channel id
-		if !paymentbroker.VerifyVoucherSignature(p.Payment.Payer, p.Payment.Channel, &v.Amount, &v.ValidAt, v.Signature) {
+		if !paymentbroker.VerifyVoucherSignature(p.Payment.Payer, p.Payment.Channel, &v.Amount, &v.ValidAt, uint64(v.Lane), uint64(v.Nonce), v.Condition, v.Signature) {
 			return errors.New("invalid signature in voucher")
 		}
 
@@ -425,6 +462,22 @@ func (sm *Miner) processStorageDeal(c cid.Cid) {
 		}
 	}
 
+	// Publish the deal before its piece goes into a sector, so that its ID
+	// is known when the sector is committed.
+	dealID, err := sm.publishDeal(ctx, d.Proposal.ChainProposal)
+	if err != nil {
+		fail("failed to publish deal", fmt.Sprintf("failed to publish deal: %s", err))
+		return
+	}
+
+	err = sm.updateDealResponse(c, func(resp *storagedeal.Response) {
+		resp.State = storagedeal.Published
+		resp.DealID = dealID
+	})
+	if err != nil {
+		log.Errorf("could not update to 'Published': %s", err)
+	}
+
 	pi := &sectorbuilder.PieceInfo{
 		Ref:  d.Proposal.PieceRef,
 		Size: d.Proposal.Size.Uint64(),
@@ -460,6 +513,66 @@ func (sm *Miner) processStorageDeal(c cid.Cid) {
 	}
 }
 
+// publishDeal publishes the deal in the storage market and returns its ID
+// once the message has been included in the chain.
+func (sm *Miner) publishDeal(ctx context.Context, proposal *storagemarket.SignedDealProposal) (uint64, error) {
+	deals, err := cbor.DumpObject([]storagemarket.SignedDealProposal{*proposal})
+	if err != nil {
+		return 0, errors.Wrap(err, "could not encode deal")
+	}
+
+	gasPrice := types.NewGasPrice(publishDealsGasPrice)
+	gasLimit := types.NewGasUnits(publishDealsGasLimit)
+
+	msgCid, err := sm.porcelainAPI.MessageSend(ctx, sm.workerAddr(), address.StorageMarketAddress, types.ZeroAttoFIL, gasPrice, gasLimit, "publishStorageDeals", deals)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not send publishStorageDeals message")
+	}
+
+	var dealIDs []uint64
+	err = sm.porcelainAPI.MessageWait(ctx, msgCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != 0 {
+			return fmt.Errorf("publishStorageDeals failed with exit code %d", receipt.ExitCode)
+		}
+
+		val, err := abi.Deserialize(receipt.Return[0], abi.UintArray)
+		if err != nil {
+			return errors.Wrap(err, "could not decode deal IDs")
+		}
+
+		ids, ok := val.Val.([]uint64)
+		if !ok {
+			return fmt.Errorf("expected []uint64 to be returned, but got %T instead", val.Val)
+		}
+		dealIDs = ids
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(dealIDs) != 1 {
+		return 0, fmt.Errorf("expected one deal ID, got %d", len(dealIDs))
+	}
+
+	return dealIDs[0], nil
+}
+
+// DealIDsForSector returns the IDs of the published deals whose pieces were
+// staged into the given sector. They are committed along with the sector.
+func (sm *Miner) DealIDsForSector(sectorID uint64) []uint64 {
+	dealIDs := []uint64{}
+	for _, dealCid := range sm.dealsAwaitingSeal.dealsInSector(sectorID) {
+		d := sm.porcelainAPI.DealGet(dealCid)
+		if d == nil || d.Response == nil {
+			log.Errorf("could not retrieve deal with proposal CID %s", dealCid)
+			continue
+		}
+		dealIDs = append(dealIDs, d.Response.DealID)
+	}
+	return dealIDs
+}
+
 // dealsAwaitingSealStruct is a container for keeping track of which sectors have
 // pieces from which deals. We need it to accommodate a race condition where
 // a sector commit message is added to chain before we can add the sector/deal
@@ -538,6 +651,15 @@ func (dealsAwaitingSeal *dealsAwaitingSealStruct) add(sectorID uint64, dealCid c
 	}
 }
 
+// dealsInSector returns the cids of the deals with pieces in the sector that
+// have not been sealed yet.
+func (dealsAwaitingSeal *dealsAwaitingSealStruct) dealsInSector(sectorID uint64) []cid.Cid {
+	dealsAwaitingSeal.l.Lock()
+	defer dealsAwaitingSeal.l.Unlock()
+
+	return append([]cid.Cid{}, dealsAwaitingSeal.SectorsToDeals[sectorID]...)
+}
+
 func (dealsAwaitingSeal *dealsAwaitingSealStruct) success(sector *sectorbuilder.SealedSectorMetadata) {
 	dealsAwaitingSeal.l.Lock()
 	defer dealsAwaitingSeal.l.Unlock()
@@ -647,30 +769,74 @@ func (sm *Miner) isBootstrapMinerActor(ctx context.Context) (bool, error) {
 	return isBootstrap, nil
 }
 
+// sectorCommitmentsPageSize is the number of sector commitments requested
+// from the miner actor at a time.
+const sectorCommitmentsPageSize = 1000
+
 // getActorSectorCommitments is a convenience method used to obtain miner actor
-// commitments.
+// commitments. The miner actor returns the commitments of a range of sector
+// ids at a time, so the ranges are requested up to the last used sector id.
 func (sm *Miner) getActorSectorCommitments(ctx context.Context) (map[string]types.Commitments, error) {
+	lastUsedSectorID, err := sm.getLastUsedSectorID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commitments := map[string]types.Commitments{}
+	for start := uint64(0); start <= lastUsedSectorID; start += sectorCommitmentsPageSize {
+		returnValues, sig, err := sm.porcelainAPI.MessageQuery(
+			ctx,
+			address.Undef,
+			sm.minerAddr,
+			"getSectorCommitments",
+			start,
+			big.NewInt(sectorCommitmentsPageSize),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "query method failed")
+		}
+
+		commitmentsVal, err := abi.Deserialize(returnValues[0], sig.Return[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "deserialization failed")
+		}
+
+		page, ok := commitmentsVal.Val.(map[string]types.Commitments)
+		if !ok {
+			return nil, errors.Wrap(err, "type assertion failed")
+		}
+
+		for key, comms := range page {
+			commitments[key] = comms
+		}
+	}
+
+	return commitments, nil
+}
+
+// getLastUsedSectorID returns the highest sector id the miner actor has seen.
+func (sm *Miner) getLastUsedSectorID(ctx context.Context) (uint64, error) {
 	returnValues, sig, err := sm.porcelainAPI.MessageQuery(
 		ctx,
 		address.Undef,
 		sm.minerAddr,
-		"getSectorCommitments",
+		"getLastUsedSectorID",
 	)
 	if err != nil {
-		return nil, errors.Wrap(err, "query method failed")
+		return 0, errors.Wrap(err, "query method failed")
 	}
 
-	commitmentsVal, err := abi.Deserialize(returnValues[0], sig.Return[0])
+	lastUsedSectorIDVal, err := abi.Deserialize(returnValues[0], sig.Return[0])
 	if err != nil {
-		return nil, errors.Wrap(err, "deserialization failed")
+		return 0, errors.Wrap(err, "deserialization failed")
 	}
 
-	commitments, ok := commitmentsVal.Val.(map[string]types.Commitments)
+	lastUsedSectorID, ok := lastUsedSectorIDVal.Val.(uint64)
 	if !ok {
-		return nil, errors.Wrap(err, "type assertion failed")
+		return 0, errors.New("type assertion failed")
 	}
 
-	return commitments, nil
+	return lastUsedSectorID, nil
 }
 
 // OnNewHeaviestTipSet is a callback called by node, every time the the latest
@@ -679,6 +845,8 @@ func (sm *Miner) getActorSectorCommitments(ctx context.Context) (map[string]type
 func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) {
 	ctx := context.Background()
 
+	sm.refreshMinerAddresses(ctx)
+
 	isBootstrapMinerActor, err := sm.isBootstrapMinerActor(ctx)
 	if err != nil {
 		log.Errorf("could not determine if actor created for bootstrapping: %s", err)
@@ -760,6 +928,52 @@ func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) {
 	}
 }
 
+// ownerAddr returns the last known owner of the miner actor.
+func (sm *Miner) ownerAddr() address.Address {
+	sm.minerAddrsLk.Lock()
+	defer sm.minerAddrsLk.Unlock()
+	return sm.minerOwnerAddr
+}
+
+// workerAddr returns the last known worker of the miner actor.
+func (sm *Miner) workerAddr() address.Address {
+	sm.minerAddrsLk.Lock()
+	defer sm.minerAddrsLk.Unlock()
+	return sm.minerWorkerAddr
+}
+
+// refreshMinerAddresses updates the owner and worker of the miner actor from
+// the current chain state. Addresses that cannot be queried are left as they
+// were.
+func (sm *Miner) refreshMinerAddresses(ctx context.Context) {
+	ownerAddr, err := sm.queryMinerAddress(ctx, "getOwner")
+	if err != nil {
+		log.Warningf("failed to refresh miner owner: %s", err)
+	}
+	workerAddr, err := sm.queryMinerAddress(ctx, "getWorker")
+	if err != nil {
+		log.Warningf("failed to refresh miner worker: %s", err)
+	}
+
+	sm.minerAddrsLk.Lock()
+	defer sm.minerAddrsLk.Unlock()
+	if !ownerAddr.Empty() {
+		sm.minerOwnerAddr = ownerAddr
+	}
+	if !workerAddr.Empty() {
+		sm.minerWorkerAddr = workerAddr
+	}
+}
+
+func (sm *Miner) queryMinerAddress(ctx context.Context, method string) (address.Address, error) {
+	res, _, err := sm.porcelainAPI.MessageQuery(ctx, address.Undef, sm.minerAddr, method)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	return address.NewFromBytes(res[0])
+}
+
 func (sm *Miner) getProvingPeriodStart() (*types.BlockHeight, error) {
 	res, _, err := sm.porcelainAPI.MessageQuery(
 		context.Background(),
@@ -832,7 +1046,7 @@ func (sm *Miner) submitPoSt(start, end *types.BlockHeight, seed proofs.PoStChall
 	gasPrice := types.NewGasPrice(submitPostGasPrice)
 	gasLimit := types.NewGasUnits(submitPostGasLimit)
 
-	_, err = sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, sm.minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "submitPoSt", proofs)
+	_, err = sm.porcelainAPI.MessageSend(ctx, sm.workerAddr(), sm.minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "submitPoSt", proofs)
 	if err != nil {
 		log.Errorf("failed to submit PoSt: %s", err)
 		return
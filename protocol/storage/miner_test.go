@@ -11,6 +11,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
@@ -75,6 +76,40 @@ func TestReceiveStorageProposal(t *testing.T) {
 		assert.Equal("proposed price (2500) is less than expected (5000) given asking price of 0.0005", res.Message)
 	})
 
+	t.Run("Rejects proposals whose chain deal does not match", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		porcelainAPI, miner, proposal := defaultMinerTestSetup(require, VoucherInterval, defaultAmountInc)
+
+		proposal.ChainProposal.Proposal.TotalPrice = types.NewAttoFILFromFIL(1)
+		signed, err := proposal.Proposal.NewSignedProposal(porcelainAPI.payerAddress, porcelainAPI.signer)
+		require.NoError(err)
+
+		res, err := miner.receiveStorageProposal(context.Background(), signed)
+		require.NoError(err)
+
+		assert.Equal(storagedeal.Rejected, res.State)
+		assert.Equal("chain deal does not match the proposal", res.Message)
+	})
+
+	t.Run("Rejects proposals without a chain deal", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		porcelainAPI, miner, proposal := defaultMinerTestSetup(require, VoucherInterval, defaultAmountInc)
+
+		proposal.ChainProposal = nil
+		signed, err := proposal.Proposal.NewSignedProposal(porcelainAPI.payerAddress, porcelainAPI.signer)
+		require.NoError(err)
+
+		res, err := miner.receiveStorageProposal(context.Background(), signed)
+		require.NoError(err)
+
+		assert.Equal(storagedeal.Rejected, res.State)
+		assert.Contains(res.Message, "no deal to publish on chain")
+	})
+
 	t.Run("Rejects proposals with invalid payment channel", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
@@ -466,7 +501,8 @@ func testPaymentVouchers(porcelainAPI *minerTestPorcelain, voucherInterval int,
 	for i := 0; i < 10; i++ {
 		validAt := porcelainAPI.paymentStart.Add(types.NewBlockHeight(uint64((i + 1) * voucherInterval)))
 		amount := types.NewAttoFILFromFIL(uint64(i+1) * amountInc)
-		signature, err := paymentbroker.SignVoucher(porcelainAPI.channelID, amount, validAt, porcelainAPI.payerAddress, porcelainAPI.signer)
+		nonce := uint64(i + 1)
+		signature, err := paymentbroker.SignVoucher(porcelainAPI.channelID, amount, validAt, 0, nonce, nil, porcelainAPI.payerAddress, porcelainAPI.signer)
 		porcelainAPI.require.NoError(err, "could not sign valid proposal")
 
 		vouchers[i] = &paymentbroker.PaymentVoucher{
@@ -475,6 +511,7 @@ func testPaymentVouchers(porcelainAPI *minerTestPorcelain, voucherInterval int,
 			Target:    porcelainAPI.targetAddress,
 			Amount:    *amount,
 			ValidAt:   *validAt,
+			Nonce:     types.Uint64(nonce),
 			Signature: signature,
 		}
 	}
@@ -498,6 +535,17 @@ func testSignedDealProposal(porcelainAPI *minerTestPorcelain, vouchers []*paymen
 		},
 	}
 
+	chainProposal, err := storagemarket.NewSignedDealProposal(storagemarket.DealProposal{
+		Client:     proposal.Payment.Payer,
+		Miner:      proposal.MinerAddress,
+		PieceRef:   proposal.PieceRef,
+		Size:       proposal.Size,
+		Duration:   proposal.Duration,
+		TotalPrice: proposal.TotalPrice,
+	}, porcelainAPI.signer)
+	porcelainAPI.require.NoError(err)
+	proposal.ChainProposal = chainProposal
+
 	signedProposal, err := proposal.NewSignedProposal(porcelainAPI.payerAddress, porcelainAPI.signer)
 	porcelainAPI.require.NoError(err)
 	return signedProposal
@@ -32,6 +32,8 @@ const (
 
 	// Staged means that the data in the deal has been staged into a sector
 	Staged
+	// Published means the deal has been published in the storage market
+	Published
 )
 
 func (s State) String() string {
@@ -52,6 +54,8 @@ func (s State) String() string {
 		return "complete"
 	case Staged:
 		return "staged"
+	case Published:
+		return "published"
 	default:
 		return fmt.Sprintf("<unrecognized %d>", s)
 	}
@@ -5,6 +5,7 @@ import (
 	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/types"
 )
@@ -62,6 +63,10 @@ type Proposal struct {
 	// will use to pay the miner. It should be verifiable by the
 	// miner using on-chain information.
 	Payment PaymentInfo
+
+	// ChainProposal is the deal as the miner publishes it on chain, signed
+	// by the payer. It must agree with the rest of the proposal.
+	ChainProposal *storagemarket.SignedDealProposal
 }
 
 // Unmarshal a Proposal from bytes.
@@ -109,6 +114,11 @@ type Response struct {
 	// Proposal is the cid of the StorageDealProposal object this response is for
 	ProposalCid cid.Cid
 
+	// DealID is the ID the storage market assigned to the deal when the miner
+	// published it. It is only set once the deal has reached the Published
+	// state, and is kept through the Staged and Posted states.
+	DealID uint64
+
 	// ProofInfo is a collection of information needed to convince the client that
 	// the miner has sealed the data into a sector.
 	ProofInfo *ProofInfo
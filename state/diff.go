@@ -0,0 +1,212 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmVmDhyTTUcQXFD1rRQ64fGLMSAoaQvNH3hwuaCFAPq2hy/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// ActorDiff describes an actor that differs between two state trees. Before
+// is nil if the actor was added and After is nil if it was removed.
+type ActorDiff struct {
+	Address address.Address `json:"address"`
+	Before  *actor.Actor    `json:"before"`
+	After   *actor.Actor    `json:"after"`
+}
+
+// Added returns true if the actor only exists in the second tree.
+func (d *ActorDiff) Added() bool {
+	return d.Before == nil
+}
+
+// Removed returns true if the actor only exists in the first tree.
+func (d *ActorDiff) Removed() bool {
+	return d.After == nil
+}
+
+// Changes describes the fields of a modified actor that differ between the
+// two trees. It is empty for added and removed actors.
+func (d *ActorDiff) Changes() []string {
+	if d.Added() || d.Removed() {
+		return nil
+	}
+
+	var changes []string
+	if !d.Before.Balance.Equal(d.After.Balance) {
+		changes = append(changes, fmt.Sprintf("balance %s -> %s", d.Before.Balance, d.After.Balance))
+	}
+	if d.Before.Nonce != d.After.Nonce {
+		changes = append(changes, fmt.Sprintf("nonce %d -> %d", d.Before.Nonce, d.After.Nonce))
+	}
+	if !d.Before.Code.Equals(d.After.Code) {
+		changes = append(changes, fmt.Sprintf("code %s -> %s", d.Before.Code, d.After.Code))
+	}
+	if !d.Before.Head.Equals(d.After.Head) {
+		changes = append(changes, fmt.Sprintf("head %s -> %s", d.Before.Head, d.After.Head))
+	}
+	return changes
+}
+
+// DiffActors returns the actors that differ between the state trees rooted
+// at before and after, ordered by address. The two HAMTs are walked in
+// parallel and subtrees with the same CID in both are skipped, so the cost
+// of a diff is proportional to what changed rather than to the size of the
+// state.
+func DiffActors(ctx context.Context, store *hamt.CborIpldStore, before, after cid.Cid) ([]*ActorDiff, error) {
+	if before.Equals(after) {
+		return nil, nil
+	}
+
+	beforeNode, err := hamt.LoadNode(ctx, store, before)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state %s", before)
+	}
+	afterNode, err := hamt.LoadNode(ctx, store, after)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state %s", after)
+	}
+
+	var diffs []*ActorDiff
+	if err := diffNodes(ctx, store, beforeNode, afterNode, &diffs); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Address.String() < diffs[j].Address.String()
+	})
+	return diffs, nil
+}
+
+// diffNodes appends the actors that differ between the HAMT nodes a and b,
+// which are at the same position in their trees, to diffs.
+func diffNodes(ctx context.Context, store *hamt.CborIpldStore, a, b *hamt.Node, diffs *[]*ActorDiff) error {
+	width := a.Bitfield.BitLen()
+	if b.Bitfield.BitLen() > width {
+		width = b.Bitfield.BitLen()
+	}
+
+	for i := 0; i < width; i++ {
+		pa, pb := pointerAt(a, i), pointerAt(b, i)
+		if pa == nil && pb == nil {
+			continue
+		}
+
+		// both sides link to a subtree covering the same keys, only descend
+		// into it if it changed
+		if pa != nil && pb != nil && pa.Link.Defined() && pb.Link.Defined() {
+			if pa.Link.Equals(pb.Link) {
+				continue
+			}
+			na, err := hamt.LoadNode(ctx, store, pa.Link)
+			if err != nil {
+				return err
+			}
+			nb, err := hamt.LoadNode(ctx, store, pb.Link)
+			if err != nil {
+				return err
+			}
+			if err := diffNodes(ctx, store, na, nb, diffs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// otherwise at least one side holds its actors inline, which means
+		// there are few of them, so compare them one by one
+		actorsA, err := pointerActors(ctx, store, pa)
+		if err != nil {
+			return err
+		}
+		actorsB, err := pointerActors(ctx, store, pb)
+		if err != nil {
+			return err
+		}
+		*diffs = append(*diffs, diffActorSets(actorsA, actorsB)...)
+	}
+	return nil
+}
+
+// pointerAt returns the pointer of nd at bit position i of its bitfield or
+// nil if there is none.
+func pointerAt(nd *hamt.Node, i int) *hamt.Pointer {
+	if nd.Bitfield.Bit(i) == 0 {
+		return nil
+	}
+
+	// pointers are only stored for set bits, in order
+	idx := 0
+	for j := 0; j < i; j++ {
+		idx += int(nd.Bitfield.Bit(j))
+	}
+	return nd.Pointers[idx]
+}
+
+// pointerActors returns the actors stored under p, including those in the
+// subtree it links to.
+func pointerActors(ctx context.Context, store *hamt.CborIpldStore, p *hamt.Pointer) (map[address.Address]*actor.Actor, error) {
+	actors := make(map[address.Address]*actor.Actor)
+	if p == nil {
+		return actors, nil
+	}
+
+	for _, kv := range p.KVs {
+		var act actor.Actor
+		if err := hackTransferObject(kv.Value, &act); err != nil {
+			return nil, err
+		}
+		addr, err := address.NewFromString(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		actors[addr] = &act
+	}
+
+	if p.Link.Defined() {
+		nd, err := hamt.LoadNode(ctx, store, p.Link)
+		if err != nil {
+			return nil, err
+		}
+		err = forEachActor(ctx, store, nd, func(addr address.Address, act *actor.Actor) error {
+			actors[addr] = act
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return actors, nil
+}
+
+// diffActorSets returns the actors that differ between a and b.
+func diffActorSets(a, b map[address.Address]*actor.Actor) []*ActorDiff {
+	var diffs []*ActorDiff
+	for addr, before := range a {
+		after, ok := b[addr]
+		if ok && actorsEqual(before, after) {
+			continue
+		}
+		diffs = append(diffs, &ActorDiff{Address: addr, Before: before, After: after})
+	}
+	for addr, after := range b {
+		if _, ok := a[addr]; !ok {
+			diffs = append(diffs, &ActorDiff{Address: addr, After: after})
+		}
+	}
+	return diffs
+}
+
+// actorsEqual returns true if the two actors have the same code, storage
+// head, nonce and balance.
+func actorsEqual(a, b *actor.Actor) bool {
+	return a.Code.Equals(b.Code) &&
+		a.Head.Equals(b.Head) &&
+		a.Nonce == b.Nonce &&
+		a.Balance.Equal(b.Balance)
+}
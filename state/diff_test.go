@@ -0,0 +1,123 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestDiffActors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+
+	addrGetter := address.NewForTestGetter()
+	unchanged, changed, removed, added := addrGetter(), addrGetter(), addrGetter(), addrGetter()
+
+	before := NewEmptyStateTree(cst)
+	require.NoError(before.SetActor(ctx, unchanged, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	require.NoError(before.SetActor(ctx, changed, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(2))))
+	require.NoError(before.SetActor(ctx, removed, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(3))))
+
+	after := NewEmptyStateTree(cst)
+	require.NoError(after.SetActor(ctx, unchanged, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	changedActor := actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(2))
+	changedActor.IncNonce()
+	require.NoError(after.SetActor(ctx, changed, changedActor))
+	require.NoError(after.SetActor(ctx, added, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(4))))
+
+	beforeRoot, err := before.Flush(ctx)
+	require.NoError(err)
+	afterRoot, err := after.Flush(ctx)
+	require.NoError(err)
+
+	diffs, err := DiffActors(ctx, cst, beforeRoot, afterRoot)
+	require.NoError(err)
+	require.Len(diffs, 3)
+
+	byAddr := make(map[address.Address]*ActorDiff)
+	for _, d := range diffs {
+		byAddr[d.Address] = d
+	}
+	assert.NotContains(byAddr, unchanged)
+
+	assert.Equal(types.Uint64(0), byAddr[changed].Before.Nonce)
+	assert.Equal(types.Uint64(1), byAddr[changed].After.Nonce)
+	assert.Equal([]string{"nonce 0 -> 1"}, byAddr[changed].Changes())
+
+	assert.True(byAddr[removed].Removed())
+	assert.NotNil(byAddr[removed].Before)
+
+	assert.True(byAddr[added].Added())
+	assert.NotNil(byAddr[added].After)
+
+	// the diff is ordered by address
+	for i := 1; i < len(diffs); i++ {
+		assert.True(diffs[i-1].Address.String() < diffs[i].Address.String())
+	}
+}
+
+func TestDiffActorsLargeTrees(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+
+	// enough actors for the HAMT to push some of them into subtrees
+	addrGetter := address.NewForTestGetter()
+	addrs := make([]address.Address, 500)
+	st := NewEmptyStateTree(cst)
+	for i := range addrs {
+		addrs[i] = addrGetter()
+		require.NoError(st.SetActor(ctx, addrs[i], actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+	}
+	beforeRoot, err := st.Flush(ctx)
+	require.NoError(err)
+
+	t.Run("identical roots have no diff", func(t *testing.T) {
+		diffs, err := DiffActors(ctx, cst, beforeRoot, beforeRoot)
+		require.NoError(err)
+		assert.Empty(diffs)
+	})
+
+	t.Run("only changed actors are reported", func(t *testing.T) {
+		st, err := LoadStateTree(ctx, cst, beforeRoot, nil)
+		require.NoError(err)
+		require.NoError(st.SetActor(ctx, addrs[42], actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(2))))
+		added := addrGetter()
+		require.NoError(st.SetActor(ctx, added, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(3))))
+		afterRoot, err := st.Flush(ctx)
+		require.NoError(err)
+
+		diffs, err := DiffActors(ctx, cst, beforeRoot, afterRoot)
+		require.NoError(err)
+		require.Len(diffs, 2)
+
+		byAddr := make(map[address.Address]*ActorDiff)
+		for _, d := range diffs {
+			byAddr[d.Address] = d
+		}
+		require.Contains(byAddr, addrs[42])
+		assert.Equal(1, len(byAddr[addrs[42]].Changes()))
+		require.Contains(byAddr, added)
+		assert.True(byAddr[added].Added())
+
+		// diffing the other way around swaps added and removed
+		reversed, err := DiffActors(ctx, cst, afterRoot, beforeRoot)
+		require.NoError(err)
+		require.Len(reversed, 2)
+		for _, d := range reversed {
+			if d.Address == added {
+				assert.True(d.Removed())
+			}
+		}
+	})
+}
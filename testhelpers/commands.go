@@ -102,6 +102,7 @@ type TestDaemon struct {
 	keyFiles         []string
 	withMiner        string
 	autoSealInterval string
+	checkpoint       string
 	isRelay          bool
 
 	firstRun bool
@@ -475,7 +476,7 @@ func (td *TestDaemon) CreateMinerAddr(peer *TestDaemon, fromAddr string) address
 	var minerAddr address.Address
 	wg.Add(1)
 	go func() {
-		miner := td.RunSuccess("miner", "create", "--from", fromAddr, "--gas-price", "0", "--gas-limit", "300", "100", "20")
+		miner := td.RunSuccess("miner", "create", "--from", fromAddr, "--gas-price", "0", "--gas-limit", "1000", "100", "20")
 		addr, err := address.NewFromString(strings.Trim(miner.ReadStdout(), "\n"))
 		require.NoError(err)
 		require.NotEqual(addr, address.Undef)
@@ -749,6 +750,13 @@ func WithMiner(m string) func(*TestDaemon) {
 	}
 }
 
+// Checkpoint allows setting the --checkpoint flag on init.
+func Checkpoint(key string) func(*TestDaemon) {
+	return func(td *TestDaemon) {
+		td.checkpoint = key
+	}
+}
+
 // IsRelay starts the daemon with the --is-relay option.
 func IsRelay(td *TestDaemon) {
 	td.isRelay = true
@@ -796,6 +804,10 @@ func NewDaemon(t *testing.T, options ...func(*TestDaemon)) *TestDaemon {
 		initopts = append(initopts, fmt.Sprintf("--default-address=%s", td.defaultAddress))
 	}
 
+	if td.checkpoint != "" {
+		initopts = append(initopts, fmt.Sprintf("--checkpoint=%s", td.checkpoint))
+	}
+
 	if td.autoSealInterval != "" {
 		initopts = append(initopts, fmt.Sprintf("--auto-seal-interval-seconds=%s", td.autoSealInterval))
 	}
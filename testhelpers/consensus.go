@@ -129,14 +129,14 @@ func (tbr *TestBlockRewarder) BlockReward(ctx context.Context, st state.Tree, mi
 }
 
 // GasReward does nothing
-func (tbr *TestBlockRewarder) GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, msg *types.SignedMessage, cost *types.AttoFIL) error {
+func (tbr *TestBlockRewarder) GasReward(ctx context.Context, st state.Tree, minerAddr address.Address, from address.Address, cost *types.AttoFIL) error {
 	// do nothing to keep state root the same
 	return nil
 }
 
 // NewTestProcessor creates a processor with a test validator and test rewarder
 func NewTestProcessor() *consensus.DefaultProcessor {
-	return consensus.NewConfiguredProcessor(&TestSignedMessageValidator{}, &TestBlockRewarder{})
+	return consensus.NewConfiguredProcessor(&TestSignedMessageValidator{}, &TestBlockRewarder{}, consensus.DefaultForkSchedule)
 }
 
 type testSigner struct{}
@@ -159,7 +159,7 @@ func ApplyTestMessageWithGas(st state.Tree, store vm.StorageMap, msg *types.Mess
 	if err != nil {
 		panic(err)
 	}
-	applier := consensus.NewConfiguredProcessor(consensus.NewDefaultMessageValidator(), consensus.NewDefaultBlockRewarder())
+	applier := consensus.NewDefaultProcessor()
 	return newMessageApplier(smsg, applier, st, store, bh, minerOwner, nil)
 }
 
@@ -183,8 +183,24 @@ func CreateAndApplyTestMessage(t *testing.T, st state.Tree, vms vm.StorageMap, t
 	return applyTestMessageWithAncestors(st, vms, msg, types.NewBlockHeight(bh), ancestors)
 }
 
+// RequireApplyTestMessageFrom sends a message from the given address, using
+// its next nonce, and requires that it is applied without a processing error.
+func RequireApplyTestMessageFrom(t *testing.T, st state.Tree, vms vm.StorageMap, from, to address.Address, bh uint64, method string, params ...interface{}) *consensus.ApplicationResult {
+	t.Helper()
+
+	fromActor, err := st.GetActor(context.Background(), from)
+	require.NoError(t, err)
+	nonce, err := actor.NextNonce(fromActor)
+	require.NoError(t, err)
+
+	msg := types.NewMessage(from, to, nonce, nil, method, actor.MustConvertParams(params...))
+	result, err := ApplyTestMessage(st, vms, msg, types.NewBlockHeight(bh))
+	require.NoError(t, err)
+	return result
+}
+
 func applyTestMessageWithAncestors(st state.Tree, store vm.StorageMap, msg *types.Message, bh *types.BlockHeight, ancestors []types.TipSet) (*consensus.ApplicationResult, error) {
-	smsg, err := types.NewSignedMessage(*msg, testSigner{}, types.NewGasPrice(0), types.NewGasUnits(300))
+	smsg, err := types.NewSignedMessage(*msg, testSigner{}, types.NewGasPrice(0), types.NewGasUnits(1000))
 	if err != nil {
 		panic(err)
 	}
@@ -194,5 +210,5 @@ func applyTestMessageWithAncestors(st state.Tree, store vm.StorageMap, msg *type
 }
 
 func newTestApplier() *consensus.DefaultProcessor {
-	return consensus.NewConfiguredProcessor(&TestSignedMessageValidator{}, &TestBlockRewarder{})
+	return consensus.NewConfiguredProcessor(&TestSignedMessageValidator{}, &TestBlockRewarder{}, consensus.DefaultForkSchedule)
 }
@@ -3,6 +3,7 @@ package testhelpers
 import (
 	"context"
 	"math/big"
+	"testing"
 
 	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -15,6 +16,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
@@ -81,6 +83,26 @@ func RequireNewFakeActorWithTokens(require *require.Assertions, vms vm.StorageMa
 	return act
 }
 
+// RequireInstallActor initializes the state of act with the given executable
+// actor and installs it in the state tree at addr, requiring that its steps
+// succeed.
+func RequireInstallActor(t *testing.T, st state.Tree, vms vm.StorageMap, addr address.Address, act *actor.Actor, impl exec.ExecutableActor, initializerData interface{}) {
+	t.Helper()
+
+	require.NoError(t, impl.InitializeState(vms.NewStorage(addr, act), initializerData))
+	require.NoError(t, st.SetActor(context.Background(), addr, act))
+}
+
+// RequireBalance returns the balance of the actor at addr, requiring that the
+// actor exists.
+func RequireBalance(t *testing.T, st state.Tree, addr address.Address) *types.AttoFIL {
+	t.Helper()
+
+	act, err := st.GetActor(context.Background(), addr)
+	require.NoError(t, err)
+	return act.Balance
+}
+
 // RequireRandomPeerID returns a new libp2p peer ID or panics.
 func RequireRandomPeerID(require *require.Assertions) peer.ID {
 	pid, err := RandPeerID()
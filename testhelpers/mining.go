@@ -29,8 +29,11 @@ func CreateMinerMessage(from address.Address, nonce uint64, pledge uint64, pid p
 }
 
 // CommitSectorMessage creates a message to commit a sector.
-func CommitSectorMessage(miner, from address.Address, nonce, sectorID uint64, commD, commR, commRStar, proof []byte) (*types.Message, error) {
-	params, err := abi.ToEncodedValues(sectorID, commD, commR, commRStar, proof)
+func CommitSectorMessage(miner, from address.Address, nonce, sectorID uint64, commD, commR, commRStar, proof []byte, dealIDs ...uint64) (*types.Message, error) {
+	if dealIDs == nil {
+		dealIDs = []uint64{}
+	}
+	params, err := abi.ToEncodedValues(sectorID, commD, commR, commRStar, proof, dealIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +40,7 @@ func main() {
 	filwal := flag.String("fil-wallet", "", "(required) set the wallet address for the controlled filecoin node to send funds from")
 	expiry := flag.Duration("limiter-expiry", defaultLimiterExpiry, "minimum time duration between faucet request to the same wallet addr")
 	faucetval := flag.Int64("faucet-val", 500, "set the amount of fil to pay to each requester")
+	gaslimit := flag.Uint64("gas-limit", 300, "set the gas limit of each payment message")
 	flag.Parse()
 
 	if *filwal == "" {
@@ -81,7 +82,7 @@ func main() {
 			return
 		}
 
-		reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&gas-price=0&gas-limit=0", *filapi, addr.String(), *faucetval, *filwal)
+		reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&gas-price=0&gas-limit=%d", *filapi, addr.String(), *faucetval, *filwal, *gaslimit)
 		log.Infof("Request URL: %s", reqStr)
 
 		resp, err := http.Post(reqStr, "application/json", nil)
@@ -0,0 +1,53 @@
+// replay re-runs the state transitions of the tipsets stored in a filecoin
+// repo and reports the blocks whose StateRoot does not match the state it
+// computes. It reads the repo directly, so the node must not be running.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/node"
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+func main() {
+	repoDir := flag.String("repodir", "", "set the directory of the repo, defaults to ~/.filecoin")
+	from := flag.Uint64("from", 1, "height of the first tipset to replay")
+	to := flag.Uint64("to", 0, "height of the last tipset to replay, defaults to the head")
+	flag.Parse()
+
+	if err := run(*repoDir, *from, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err) // nolint: errcheck
+		os.Exit(1)
+	}
+}
+
+func run(repoDir string, from, to uint64) error {
+	ctx := context.Background()
+
+	rep, err := repo.OpenFSRepo(repo.GetRepoDir(repoDir))
+	if err != nil {
+		return err
+	}
+	defer rep.Close() // nolint: errcheck
+
+	replayer, err := node.NewReplayer(ctx, rep)
+	if err != nil {
+		return err
+	}
+
+	if to == 0 {
+		to, err = replayer.HeadHeight()
+		if err != nil {
+			return err
+		}
+	}
+
+	return replayer.Replay(ctx, from, to, func(res *chain.ReplayResult) error {
+		return chain.PrintReplayResult(os.Stdout, res)
+	})
+}
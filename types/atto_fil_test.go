@@ -174,6 +174,16 @@ func TestMulInt(t *testing.T) {
 	})
 }
 
+func TestDivBigInt(t *testing.T) {
+	attoFIL := AttoFIL{val: big.NewInt(1000)}
+
+	t.Run("correctly divides the values and rounds down", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal(&AttoFIL{val: big.NewInt(40)}, attoFIL.DivBigInt(big.NewInt(25)))
+		assert.Equal(&AttoFIL{val: big.NewInt(142)}, attoFIL.DivBigInt(big.NewInt(7)))
+	})
+}
+
 func TestDivCeil(t *testing.T) {
 	x := AttoFIL{val: big.NewInt(200)}
 
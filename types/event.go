@@ -0,0 +1,26 @@
+package types
+
+import (
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+func init() {
+	cbor.RegisterCborType(Event{})
+}
+
+// Event is a notification an actor emits while processing a message, e.g.
+// that it has added an ask. Events of messages that are applied successfully
+// are recorded in their receipts.
+type Event struct {
+	// Actor is the address of the actor that emitted the event.
+	Actor address.Address `json:"actor"`
+
+	// Name identifies the kind of event. Names are defined by each actor.
+	Name string `json:"name"`
+
+	// Data is the cbor encoded value the actor emitted with the event. Its
+	// type depends on the actor and name of the event.
+	Data []byte `json:"data"`
+}
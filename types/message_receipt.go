@@ -20,4 +20,9 @@ type MessageReceipt struct {
 
 	// GasAttoFIL Charge is the actual amount of FIL transferred from the sender to the miner for processing the message
 	GasAttoFIL *AttoFIL `json:"gasAttoFIL"`
+
+	// Events are the events emitted by the actors the message was processed
+	// by, in the order they were emitted. They are only recorded if the
+	// message succeeded.
+	Events []*Event `json:"events" refmt:",omitempty"`
 }
@@ -0,0 +1,62 @@
+package vm
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/initactor"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+// actorGetter is the part of a state tree needed to find the init actor.
+// Both state.Tree and state.CachedTree satisfy it.
+type actorGetter interface {
+	GetActor(context.Context, address.Address) (*actor.Actor, error)
+}
+
+// ResolveAddress returns the actor address that the init actor assigned the
+// ID address addr to. Addresses using any other protocol are returned as they
+// are. Resolving an ID that has not been assigned is a revert error.
+func ResolveAddress(ctx context.Context, st actorGetter, storageMap StorageMap, addr address.Address) (address.Address, error) {
+	if addr.Empty() || addr.Protocol() != address.ID {
+		return addr, nil
+	}
+
+	initActor, err := st.GetActor(ctx, address.InitAddress)
+	if state.IsActorNotFoundError(err) {
+		return address.Undef, errors.NewRevertErrorf("cannot resolve ID address %s without an init actor", addr)
+	} else if err != nil {
+		return address.Undef, errors.FaultErrorWrap(err, "failed to get init actor")
+	}
+
+	resolved, err := initactor.LookupAddress(ctx, storageMap.NewStorage(address.InitAddress, initActor), addr)
+	if err != nil {
+		return address.Undef, err
+	}
+	if resolved.Empty() {
+		return address.Undef, errors.NewRevertErrorf("ID address %s has not been assigned", addr)
+	}
+
+	return resolved, nil
+}
+
+// AssignID has the init actor assign the next ID to addr, unless addr already
+// has one. It must be called whenever an actor is added to the state, be it
+// created by another actor or implicitly by a transfer to a new address.
+// State trees without an init actor don't assign IDs.
+func AssignID(ctx context.Context, st actorGetter, storageMap StorageMap, addr address.Address) error {
+	initActor, err := st.GetActor(ctx, address.InitAddress)
+	if state.IsActorNotFoundError(err) {
+		return nil
+	} else if err != nil {
+		return errors.FaultErrorWrap(err, "failed to get init actor")
+	}
+
+	if _, err := initactor.AssignID(ctx, storageMap.NewStorage(address.InitAddress, initActor), addr); err != nil {
+		return errors.FaultErrorWrap(err, "failed to assign ID to new actor")
+	}
+
+	return nil
+}
@@ -5,12 +5,14 @@ import (
 	"context"
 	"encoding/binary"
 	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/sampling"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -26,8 +28,11 @@ type Context struct {
 	state       *state.CachedTree
 	storageMap  StorageMap
 	gasTracker  *GasTracker
+	gasSchedule *GasSchedule
 	blockHeight *types.BlockHeight
 	ancestors   []types.TipSet
+	trace       *Trace
+	events      []*types.Event
 
 	deps *deps // Inject external dependencies so we can unit test robustly.
 }
@@ -35,6 +40,8 @@ type Context struct {
 var _ exec.VMContext = (*Context)(nil)
 
 // NewContextParams is passed to NewVMContext to construct a new context.
+// GasSchedule is the gas schedule in effect at BlockHeight; the genesis
+// schedule is used if it is nil.
 type NewContextParams struct {
 	From        *actor.Actor
 	To          *actor.Actor
@@ -42,30 +49,40 @@ type NewContextParams struct {
 	State       *state.CachedTree
 	StorageMap  StorageMap
 	GasTracker  *GasTracker
+	GasSchedule *GasSchedule
 	BlockHeight *types.BlockHeight
 	Ancestors   []types.TipSet
 }
 
 // NewVMContext returns an initialized context.
 func NewVMContext(params NewContextParams) *Context {
-	return &Context{
+	ctx := &Context{
 		from:        params.From,
 		to:          params.To,
 		message:     params.Message,
 		state:       params.State,
 		storageMap:  params.StorageMap,
 		gasTracker:  params.GasTracker,
+		gasSchedule: params.GasSchedule,
 		blockHeight: params.BlockHeight,
 		ancestors:   params.Ancestors,
 		deps:        makeDeps(params.State),
 	}
+	if ctx.gasSchedule == nil {
+		ctx.gasSchedule = GasScheduleV0
+	}
+	if params.Message != nil {
+		ctx.trace = newTrace(params.Message)
+	}
+	return ctx
 }
 
 var _ exec.VMContext = (*Context)(nil)
 
 // Storage returns an implementation of the storage module for this context.
+// Reading and writing the storage is charged to the message.
 func (ctx *Context) Storage() exec.Storage {
-	return ctx.storageMap.NewStorage(ctx.message.To, ctx.to)
+	return ctx.storageMap.NewStorage(ctx.message.To, ctx.to).metered(ctx.gasTracker, ctx.gasSchedule)
 }
 
 // Message retrieves the message associated with this context.
@@ -96,6 +113,17 @@ func (ctx *Context) Charge(cost types.GasUnits) error {
 	return ctx.gasTracker.Charge(cost)
 }
 
+// GasSchedule returns the gas schedule in effect for this context.
+func (ctx *Context) GasSchedule() *GasSchedule {
+	return ctx.gasSchedule
+}
+
+// Trace returns the execution trace of this context's message and the
+// messages it has sent so far.
+func (ctx *Context) Trace() *Trace {
+	return ctx.trace
+}
+
 // GasUnits retrieves the gas cost so far
 func (ctx *Context) GasUnits() types.GasUnits {
 	return ctx.gasTracker.gasConsumedByMessage
@@ -147,6 +175,11 @@ func (ctx *Context) Send(to address.Address, method string, value *types.AttoFIL
 		return nil, 1, errors.RevertErrorWrap(err, "encoding params failed")
 	}
 
+	to, err = ResolveAddress(context.TODO(), ctx.state, ctx.storageMap, to)
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
 	msg := types.NewMessage(from, to, 0, value, method, paramData)
 	if msg.From == msg.To {
 		// TODO: handle this
@@ -154,6 +187,9 @@ func (ctx *Context) Send(to address.Address, method string, value *types.AttoFIL
 	}
 
 	toActor, err := deps.GetOrCreateActor(context.TODO(), msg.To, func() (*actor.Actor, error) {
+		if err := AssignID(context.TODO(), ctx.state, ctx.storageMap, msg.To); err != nil {
+			return nil, err
+		}
 		return &actor.Actor{}, nil
 	})
 	if err != nil {
@@ -167,15 +203,18 @@ func (ctx *Context) Send(to address.Address, method string, value *types.AttoFIL
 		State:       ctx.state,
 		StorageMap:  ctx.storageMap,
 		GasTracker:  ctx.gasTracker,
+		GasSchedule: ctx.gasSchedule,
 		BlockHeight: ctx.blockHeight,
 		Ancestors:   ctx.ancestors,
 	}
 	innerCtx := NewVMContext(innerParams)
 
 	out, ret, err := deps.Send(context.Background(), innerCtx)
+	ctx.trace.Subcalls = append(ctx.trace.Subcalls, innerCtx.trace)
 	if err != nil {
 		return nil, ret, err
 	}
+	ctx.events = append(ctx.events, innerCtx.events...)
 
 	return out, ret, nil
 }
@@ -202,7 +241,8 @@ func computeActorAddress(creator address.Address, nonce uint64) (address.Address
 	return address.NewActorAddress(buf.Bytes())
 }
 
-// CreateNewActor creates and initializes an actor at the given address.
+// CreateNewActor creates and initializes an actor at the given address and has
+// the init actor assign it an ID.
 // If the address is occupied by a non-empty actor, this method will fail.
 func (ctx *Context) CreateNewActor(addr address.Address, code cid.Cid, initializerData interface{}) error {
 	// Check existing address. If nothing there, create empty actor.
@@ -218,10 +258,18 @@ func (ctx *Context) CreateNewActor(addr address.Address, code cid.Cid, initializ
 		return errors.NewRevertErrorf("attempt to create actor at address %s but a non-empty actor is already installed", addr.String())
 	}
 
+	if err := ctx.Charge(ctx.gasSchedule.CreateActor); err != nil {
+		return errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
 	// make this the right 'type' of actor
 	newActor.Code = code
 
-	childStorage := ctx.storageMap.NewStorage(addr, newActor)
+	if err := AssignID(context.TODO(), ctx.state, ctx.storageMap, addr); err != nil {
+		return err
+	}
+
+	childStorage := ctx.storageMap.NewStorage(addr, newActor).metered(ctx.gasTracker, ctx.gasSchedule)
 	execActor, err := ctx.state.GetBuiltinActorCode(code)
 	if err != nil {
 		return errors.NewRevertErrorf("attempt to create executable actor from non-existent code %s", code.String())
@@ -244,6 +292,48 @@ func (ctx *Context) SampleChainRandomness(sampleHeight *types.BlockHeight) ([]by
 	return sampling.SampleChainRandomness(sampleHeight, ctx.ancestors)
 }
 
+// VerifySeal verifies a proof of replication.
+func (ctx *Context) VerifySeal(req proofs.VerifySealRequest) (proofs.VerifySealResponse, error) {
+	if err := ctx.Charge(ctx.gasSchedule.VerifySeal); err != nil {
+		return proofs.VerifySealResponse{}, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return ctx.deps.Verifier.VerifySeal(req)
+}
+
+// VerifyPoST verifies a proof of spacetime.
+func (ctx *Context) VerifyPoST(req proofs.VerifyPoSTRequest) (proofs.VerifyPoSTResponse, error) {
+	if err := ctx.Charge(ctx.gasSchedule.VerifyPoSt); err != nil {
+		return proofs.VerifyPoSTResponse{}, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return ctx.deps.Verifier.VerifyPoST(req)
+}
+
+// Emit records an event emitted by the actor processing the message. Events
+// end up in the message's receipt, so they are charged like storage writes.
+func (ctx *Context) Emit(name string, data interface{}) error {
+	raw, err := cbor.DumpObject(data)
+	if err != nil {
+		return errors.FaultErrorWrap(err, "failed to encode event data")
+	}
+
+	if err := ctx.Charge(ctx.gasSchedule.StorageWriteCost(len(name) + len(raw))); err != nil {
+		return errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	ctx.events = append(ctx.events, &types.Event{
+		Actor: ctx.message.To,
+		Name:  name,
+		Data:  raw,
+	})
+	return nil
+}
+
+// Events returns the events emitted while processing the message of this
+// context, including those emitted by the messages it sent.
+func (ctx *Context) Events() []*types.Event {
+	return ctx.events
+}
+
 // Dependency injection setup.
 
 // makeDeps returns a VMContext's external dependencies with their standard values set.
@@ -252,6 +342,7 @@ func makeDeps(st *state.CachedTree) *deps {
 		EncodeValues: abi.EncodeValues,
 		Send:         Send,
 		ToValues:     abi.ToValues,
+		Verifier:     &proofs.RustVerifier{},
 	}
 	if st != nil {
 		deps.GetOrCreateActor = st.GetOrCreateActor
@@ -264,4 +355,5 @@ type deps struct {
 	GetOrCreateActor func(context.Context, address.Address, func() (*actor.Actor, error)) (*actor.Actor, error)
 	Send             func(context.Context, *Context) ([][]byte, uint8, error)
 	ToValues         func([]interface{}) ([]*abi.Value, error)
+	Verifier         proofs.Verifier
 }
@@ -53,7 +53,7 @@ func TestVMContextStorage(t *testing.T) {
 		Message:     msg,
 		State:       cstate,
 		StorageMap:  vms,
-		GasTracker:  NewGasTracker(),
+		GasTracker:  newTestGasTracker(),
 		BlockHeight: types.NewBlockHeight(0),
 	}
 	vmCtx := NewVMContext(vmCtxParams)
@@ -94,7 +94,7 @@ func TestVMContextSendFailures(t *testing.T) {
 		Message:     newMsg(),
 		State:       tree,
 		StorageMap:  vms,
-		GasTracker:  NewGasTracker(),
+		GasTracker:  newTestGasTracker(),
 		BlockHeight: types.NewBlockHeight(0),
 	}
 
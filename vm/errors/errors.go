@@ -55,14 +55,33 @@ func NewCodedRevertErrorf(code uint8, format string, args ...interface{}) error
 	return NewCodedRevertError(code, fmt.Sprintf(format, args...))
 }
 
+// NewInsufficientGasError creates a new RevertError for a message that ran
+// out of gas. Wrapping it in a FaultError or RevertError keeps its code.
+func NewInsufficientGasError(msg string) error {
+	return NewCodedRevertError(ErrInsufficientGas, msg)
+}
+
+// IsInsufficientGas indicates the root Cause() of err is a message running
+// out of gas.
+func IsInsufficientGas(err error) bool {
+	re, ok := errors.Cause(err).(*RevertError)
+	return ok && re.code == ErrInsufficientGas
+}
+
 // RevertErrorWrap wraps a given error in a RevertError.
 func RevertErrorWrap(err error, msg string) error {
+	if IsInsufficientGas(err) {
+		return &RevertError{err: err, msg: msg, code: ErrInsufficientGas}
+	}
 	return &RevertError{err: err, msg: msg}
 }
 
 // RevertErrorWrapf wraps a given error in a RevertError and adds a message
 // using Sprintf formatting.
 func RevertErrorWrapf(err error, format string, args ...interface{}) error { // nolint: deadcode
+	if IsInsufficientGas(err) {
+		return &RevertError{err: err, msg: fmt.Sprintf(format, args...), code: ErrInsufficientGas}
+	}
 	return &RevertError{err: err, msg: fmt.Sprintf(format, args...), code: 1}
 }
 
@@ -121,14 +140,23 @@ func NewFaultErrorf(format string, args ...interface{}) error {
 	return NewFaultError(fmt.Sprintf(format, args...))
 }
 
-// FaultErrorWrap wraps a given error in a FaultError.
+// FaultErrorWrap wraps a given error in a FaultError. Running out of gas is
+// never a fault, so an insufficient gas error is wrapped in a RevertError
+// instead.
 func FaultErrorWrap(err error, msg string) error {
+	if IsInsufficientGas(err) {
+		return RevertErrorWrap(err, msg)
+	}
 	return &FaultError{err: err, msg: msg}
 }
 
 // FaultErrorWrapf wraps a given error in a FaultError and adds a message
-// using Sprintf formatting.
+// using Sprintf formatting. Like FaultErrorWrap it keeps an insufficient gas
+// error a revert.
 func FaultErrorWrapf(err error, format string, args ...interface{}) error {
+	if IsInsufficientGas(err) {
+		return RevertErrorWrapf(err, format, args...)
+	}
 	return &FaultError{err: err, msg: fmt.Sprintf(format, args...)}
 }
 
@@ -49,6 +49,26 @@ func TestRevertError(t *testing.T) {
 	assert.Equal(re, errors.Cause(wrapped2))
 }
 
+func TestInsufficientGasError(t *testing.T) {
+	assert := assert.New(t)
+
+	ige := NewInsufficientGasError("out of gas")
+	assert.True(ShouldRevert(ige))
+	assert.True(IsInsufficientGas(ige))
+	assert.Equal(uint8(ErrInsufficientGas), CodeError(ige))
+	assert.False(IsInsufficientGas(NewRevertError("boom")))
+
+	fe := FaultErrorWrap(ige, "msg")
+	assert.False(IsFault(fe))
+	assert.True(ShouldRevert(fe))
+	assert.Equal(uint8(ErrInsufficientGas), CodeError(fe))
+	assert.Contains(fe.Error(), "out of gas")
+
+	re := RevertErrorWrapf(errors.Wrap(ige, "wrapped"), "%d", 42)
+	assert.True(IsInsufficientGas(re))
+	assert.Equal(uint8(ErrInsufficientGas), CodeError(re))
+}
+
 func TestApplyErrorPermanent(t *testing.T) {
 	t.Run("random errors dont satisfy", func(t *testing.T) {
 		assert := assert.New(t)
@@ -16,6 +16,10 @@ const (
 	ErrNoActorCode
 )
 
+// ErrInsufficientGas is the error code for a message that ran out of gas. It
+// predates ReservedErrors and is shared with the exec error codes.
+const ErrInsufficientGas = 36
+
 // Errors is a map from exit codes to errors.
 // Most errors should live in the actors that throw them. However some
 // errors will be pervasive so we define them centrally here.
@@ -0,0 +1,68 @@
+package vm
+
+import (
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// GasSchedule lists the gas the VM charges for the work it does on behalf of
+// actors. Actors still charge for their own computation themselves.
+type GasSchedule struct {
+	// Call is charged for every message sent, whether it is sent by an
+	// account or by an actor.
+	Call types.GasUnits
+
+	// VerifySignature is charged for checking the signature of a message.
+	VerifySignature types.GasUnits
+
+	// CreateActor is charged for creating and initializing a new actor, on
+	// top of the storage the new actor writes.
+	CreateActor types.GasUnits
+
+	// StorageRead and StorageReadPerKiB are charged for every chunk an actor
+	// reads from its storage, the latter for each started KiB of the chunk.
+	StorageRead       types.GasUnits
+	StorageReadPerKiB types.GasUnits
+
+	// StorageWrite and StorageWritePerKiB are charged for every chunk an
+	// actor writes to its storage, the latter for each started KiB of the
+	// chunk.
+	StorageWrite       types.GasUnits
+	StorageWritePerKiB types.GasUnits
+
+	// VerifySeal is charged for verifying a proof of replication.
+	VerifySeal types.GasUnits
+
+	// VerifyPoSt is charged for verifying a proof of spacetime.
+	VerifyPoSt types.GasUnits
+}
+
+// GasScheduleV0 is the gas schedule in effect from genesis. Later versions
+// are introduced by network upgrades.
+var GasScheduleV0 = &GasSchedule{
+	Call:               5,
+	VerifySignature:    10,
+	CreateActor:        20,
+	StorageRead:        1,
+	StorageReadPerKiB:  1,
+	StorageWrite:       2,
+	StorageWritePerKiB: 4,
+	VerifySeal:         50,
+	VerifyPoSt:         50,
+}
+
+// StorageReadCost returns the gas charged for reading a chunk of the given
+// size.
+func (gs *GasSchedule) StorageReadCost(size int) types.GasUnits {
+	return gs.StorageRead + gs.StorageReadPerKiB*kibibytes(size)
+}
+
+// StorageWriteCost returns the gas charged for writing a chunk of the given
+// size.
+func (gs *GasSchedule) StorageWriteCost(size int) types.GasUnits {
+	return gs.StorageWrite + gs.StorageWritePerKiB*kibibytes(size)
+}
+
+// kibibytes returns the number of started KiB in size bytes.
+func kibibytes(size int) types.GasUnits {
+	return types.GasUnits((size + 1023) / 1024)
+}
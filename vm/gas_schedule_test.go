@@ -0,0 +1,141 @@
+package vm
+
+import (
+	"context"
+	"testing"
+
+	"gx/ipfs/QmNf3wujpV2Y7Lnj2hy2UrmuX8bhMDStRHbnSLh7Ypf36h/go-hamt-ipld"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/assert"
+	"gx/ipfs/QmPVkJMTeRC6iBByPWdrRkD3BE5UXsj5HPzb4kPqL186mS/testify/require"
+	"gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	"gx/ipfs/QmRu7tiRnFk9mMPpVECQTBQJqXtmG132jJxA1w9A7TtpBz/go-ipfs-blockstore"
+	"gx/ipfs/QmUadX5EcvrBmxAV9sE7wUWtWSqxns5K84qKJBixmcT1w9/go-datastore"
+	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm/errors"
+)
+
+// newTestGasTracker returns a gas tracker that allows messages to use all
+// the gas in a block.
+func newTestGasTracker() *GasTracker {
+	gasTracker := NewGasTracker()
+	gasTracker.MsgGasLimit = types.BlockGasLimit
+	return gasTracker
+}
+
+func TestContextGasSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(GasScheduleV0, NewVMContext(NewContextParams{}).GasSchedule())
+
+	v1 := &GasSchedule{Call: 1000}
+	assert.Equal(v1, NewVMContext(NewContextParams{GasSchedule: v1}).GasSchedule())
+}
+
+func TestGasScheduleStorageCost(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule := &GasSchedule{StorageRead: 1, StorageReadPerKiB: 2, StorageWrite: 3, StorageWritePerKiB: 4}
+
+	assert.Equal(types.NewGasUnits(1), schedule.StorageReadCost(0))
+	assert.Equal(types.NewGasUnits(3), schedule.StorageReadCost(1))
+	assert.Equal(types.NewGasUnits(3), schedule.StorageReadCost(1024))
+	assert.Equal(types.NewGasUnits(5), schedule.StorageReadCost(1025))
+	assert.Equal(types.NewGasUnits(7), schedule.StorageWriteCost(10))
+}
+
+func TestVMContextChargesStorage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cstate := state.NewCachedStateTree(state.NewEmptyStateTree(hamt.NewCborStore()))
+	vms := NewStorageMap(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+
+	toAddr := address.NewForTestGetter()()
+	toActor, err := account.NewActor(nil)
+	require.NoError(err)
+	require.NoError(cstate.SetActor(ctx, toAddr, toActor))
+
+	node, err := cbor.WrapObject([]byte("hello"), types.DefaultHashFunction, -1)
+	require.NoError(err)
+	size := len(node.RawData())
+
+	newCtx := func(gasTracker *GasTracker) *Context {
+		return NewVMContext(NewContextParams{
+			To:          toActor,
+			Message:     types.NewMessage(address.TestAddress, toAddr, 0, nil, "hello", nil),
+			State:       cstate,
+			StorageMap:  vms,
+			GasTracker:  gasTracker,
+			BlockHeight: types.NewBlockHeight(0),
+		})
+	}
+
+	t.Run("writing and reading storage is charged", func(t *testing.T) {
+		vmCtx := newCtx(newTestGasTracker())
+		require.NoError(vmCtx.WriteStorage(node.RawData()))
+		assert.Equal(GasScheduleV0.StorageWriteCost(size), vmCtx.GasUnits())
+
+		_, err := vmCtx.ReadStorage()
+		require.NoError(err)
+		assert.Equal(GasScheduleV0.StorageWriteCost(size)+GasScheduleV0.StorageReadCost(size), vmCtx.GasUnits())
+	})
+
+	t.Run("writing storage fails without enough gas", func(t *testing.T) {
+		gasTracker := NewGasTracker()
+		gasTracker.MsgGasLimit = GasScheduleV0.StorageWriteCost(size) - 1
+
+		err := newCtx(gasTracker).WriteStorage(node.RawData())
+		assert.Error(err)
+		assert.True(errors.ShouldRevert(err))
+	})
+}
+
+func TestSendChargesCall(t *testing.T) {
+	assert := assert.New(t)
+
+	fakeActor := actor.NewActor(types.NewCidForTestGetter()(), types.NewZeroAttoFIL())
+	tree := state.NewCachedStateTree(&state.MockStateTree{NoMocks: true, BuiltinActors: map[cid.Cid]exec.ExecutableActor{
+		fakeActor.Code: &actor.FakeActor{},
+	}})
+	vms := NewStorageMap(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+	msg := types.NewMessageForTestGetter()()
+	msg.Value = nil
+	msg.Method = "hasReturnValue"
+
+	newCtx := func(gasTracker *GasTracker) *Context {
+		return NewVMContext(NewContextParams{
+			To:          fakeActor,
+			Message:     msg,
+			State:       tree,
+			StorageMap:  vms,
+			GasTracker:  gasTracker,
+			BlockHeight: types.NewBlockHeight(0),
+		})
+	}
+
+	t.Run("calls are charged on top of what the actor charges", func(t *testing.T) {
+		vmCtx := newCtx(newTestGasTracker())
+		_, code, err := Send(context.Background(), vmCtx)
+		assert.NoError(err)
+		assert.Equal(uint8(0), code)
+		assert.Equal(GasScheduleV0.Call+types.NewGasUnits(100), vmCtx.GasUnits())
+	})
+
+	t.Run("running out of gas in an actor reverts with ErrInsufficientGas", func(t *testing.T) {
+		gasTracker := NewGasTracker()
+		gasTracker.MsgGasLimit = GasScheduleV0.Call + types.NewGasUnits(99)
+
+		_, code, err := Send(context.Background(), newCtx(gasTracker))
+		assert.Error(err)
+		assert.True(errors.ShouldRevert(err))
+		assert.Equal(uint8(exec.ErrInsufficientGas), code)
+	})
+}
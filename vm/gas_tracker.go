@@ -32,7 +32,7 @@ func (gasTracker *GasTracker) Charge(cost types.GasUnits) error {
 	if gasTracker.gasConsumedByMessage+cost > gasTracker.MsgGasLimit {
 		gasTracker.gasConsumedByMessage = gasTracker.MsgGasLimit
 		gasTracker.gasConsumedByBlock += gasTracker.MsgGasLimit
-		return errors.NewRevertError("gas cost exceeds gas limit")
+		return errors.NewInsufficientGasError("gas cost exceeds gas limit")
 	}
 
 	gasTracker.gasConsumedByMessage += cost
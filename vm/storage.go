@@ -89,6 +89,11 @@ type Storage struct {
 	actor      *actor.Actor
 	chunks     map[cid.Cid]ipld.Node
 	blockstore blockstore.Blockstore
+
+	// gasTracker and gasSchedule meter the storage of actors while they
+	// execute. Storage without a gas tracker is free.
+	gasTracker  *GasTracker
+	gasSchedule *GasSchedule
 }
 
 var _ exec.Storage = (*Storage)(nil)
@@ -118,6 +123,10 @@ func (s Storage) Put(v interface{}) (cid.Cid, error) {
 		return cid.Undef, exec.Errors[exec.ErrDecode]
 	}
 
+	if err := s.chargeWrite(len(nd.RawData())); err != nil {
+		return cid.Undef, err
+	}
+
 	c := nd.Cid()
 	s.chunks[c] = nd
 
@@ -129,6 +138,9 @@ func (s Storage) Put(v interface{}) (cid.Cid, error) {
 func (s Storage) Get(cid cid.Cid) ([]byte, error) {
 	n, ok := s.chunks[cid]
 	if ok {
+		if err := s.chargeRead(len(n.RawData())); err != nil {
+			return []byte{}, err
+		}
 		return n.RawData(), nil
 	}
 
@@ -140,6 +152,10 @@ func (s Storage) Get(cid cid.Cid) ([]byte, error) {
 		return []byte{}, err
 	}
 
+	if err := s.chargeRead(len(blk.RawData())); err != nil {
+		return []byte{}, err
+	}
+
 	return blk.RawData(), nil
 }
 
@@ -164,6 +180,32 @@ func (s Storage) Commit(newCid cid.Cid, oldCid cid.Cid) error {
 	return nil
 }
 
+// metered returns a copy of the storage that charges the given gas tracker
+// for reads and writes according to the gas schedule.
+func (s Storage) metered(gasTracker *GasTracker, gasSchedule *GasSchedule) Storage {
+	s.gasTracker = gasTracker
+	s.gasSchedule = gasSchedule
+	return s
+}
+
+// chargeRead charges for reading a chunk of the given size, if the storage
+// is metered.
+func (s Storage) chargeRead(size int) error {
+	if s.gasTracker == nil {
+		return nil
+	}
+	return s.gasTracker.Charge(s.gasSchedule.StorageReadCost(size))
+}
+
+// chargeWrite charges for writing a chunk of the given size, if the storage
+// is metered.
+func (s Storage) chargeWrite(size int) error {
+	if s.gasTracker == nil {
+		return nil
+	}
+	return s.gasTracker.Charge(s.gasSchedule.StorageWriteCost(size))
+}
+
 // Head return the current head of the actor's memory
 func (s Storage) Head() cid.Cid {
 	return s.actor.Head
@@ -0,0 +1,49 @@
+package vm
+
+import (
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// Trace records the execution of a message in the VM along with the messages
+// it sent to other actors, forming a tree with the applied message at its
+// root.
+type Trace struct {
+	From   address.Address `json:"from"`
+	To     address.Address `json:"to"`
+	Method string          `json:"method"`
+	Params []byte          `json:"params"`
+	Value  *types.AttoFIL  `json:"value"`
+
+	// GasUsed is the gas charged while executing the message, including the
+	// gas charged by the messages it sent.
+	GasUsed  types.GasUnits `json:"gasUsed"`
+	ExitCode uint8          `json:"exitCode"`
+
+	// Error is the error the message failed with, if any.
+	Error string `json:"error,omitempty"`
+
+	// Subcalls are the messages sent while executing the message, in the
+	// order they were sent.
+	Subcalls []*Trace `json:"subcalls,omitempty"`
+}
+
+// newTrace returns a trace of the given message that has yet to be executed.
+func newTrace(msg *types.Message) *Trace {
+	return &Trace{
+		From:   msg.From,
+		To:     msg.To,
+		Method: msg.Method,
+		Params: msg.Params,
+		Value:  msg.Value,
+	}
+}
+
+// Finish records the outcome of executing the traced message.
+func (t *Trace) Finish(gasUsed types.GasUnits, exitCode uint8, err error) {
+	t.GasUsed = gasUsed
+	t.ExitCode = exitCode
+	if err != nil {
+		t.Error = err.Error()
+	}
+}
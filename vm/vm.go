@@ -8,6 +8,7 @@ import (
 	cbor "gx/ipfs/QmcZLyosDwMKdB6NLRsiss9HXzDPhVhhRtPy67JFKTDQDX/go-ipld-cbor"
 
 	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm/errors"
 )
@@ -18,7 +19,13 @@ func Send(ctx context.Context, vmCtx *Context) ([][]byte, uint8, error) {
 	deps := sendDeps{
 		transfer: Transfer,
 	}
-	return send(ctx, deps, vmCtx)
+
+	gasBefore := vmCtx.GasUnits()
+	ret, code, err := send(ctx, deps, vmCtx)
+	if vmCtx.trace != nil {
+		vmCtx.trace.Finish(vmCtx.GasUnits()-gasBefore, code, err)
+	}
+	return ret, code, err
 }
 
 type sendDeps struct {
@@ -27,6 +34,10 @@ type sendDeps struct {
 
 // send executes a message pass inside the VM. It exists alongside Send so that we can inject its dependencies during test.
 func send(ctx context.Context, deps sendDeps, vmCtx *Context) ([][]byte, uint8, error) {
+	if err := vmCtx.Charge(vmCtx.gasSchedule.Call); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
 	if vmCtx.message.Value != nil {
 		if err := deps.transfer(vmCtx.from, vmCtx.to, vmCtx.message.Value); err != nil {
 			if errors.ShouldRevert(err) {
@@ -73,7 +73,7 @@ func TestSendErrorHandling(t *testing.T) {
 			Message:     msg,
 			State:       tree,
 			StorageMap:  vms,
-			GasTracker:  NewGasTracker(),
+			GasTracker:  newTestGasTracker(),
 			BlockHeight: types.NewBlockHeight(0),
 		}
 		vmCtx := NewVMContext(vmCtxParams)
@@ -99,7 +99,7 @@ func TestSendErrorHandling(t *testing.T) {
 			Message:     msg,
 			State:       tree,
 			StorageMap:  vms,
-			GasTracker:  NewGasTracker(),
+			GasTracker:  newTestGasTracker(),
 			BlockHeight: types.NewBlockHeight(0),
 		}
 		vmCtx := NewVMContext(vmCtxParams)
@@ -131,7 +131,7 @@ func TestSendErrorHandling(t *testing.T) {
 			Message:     msg,
 			State:       tree,
 			StorageMap:  vms,
-			GasTracker:  NewGasTracker(),
+			GasTracker:  newTestGasTracker(),
 			BlockHeight: types.NewBlockHeight(0),
 		}
 		vmCtx := NewVMContext(vmCtxParams)